@@ -1,20 +1,38 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/bnema/sharm/config"
+	"github.com/bnema/sharm/internal/adapter/captcha"
 	"github.com/bnema/sharm/internal/adapter/converter/ffmpeg"
+	"github.com/bnema/sharm/internal/adapter/converter/remote"
 	HTTPAdapter "github.com/bnema/sharm/internal/adapter/http"
+	"github.com/bnema/sharm/internal/adapter/http/middleware"
+	"github.com/bnema/sharm/internal/adapter/identity/oidc"
+	"github.com/bnema/sharm/internal/adapter/notify/webhook"
+	"github.com/bnema/sharm/internal/adapter/storage/filestore"
+	"github.com/bnema/sharm/internal/adapter/storage/jsonfile"
+	"github.com/bnema/sharm/internal/adapter/storage/s3"
+	"github.com/bnema/sharm/internal/adapter/storage/sidecar"
 	sqlitestore "github.com/bnema/sharm/internal/adapter/storage/sqlite"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/disk"
 	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/infrastructure/metrics"
+	"github.com/bnema/sharm/internal/port"
 	"github.com/bnema/sharm/internal/service"
+	"golang.org/x/net/netutil"
 )
 
 var (
@@ -29,6 +47,14 @@ func main() {
 		logger.Error.Printf("failed to load config: %v", err)
 		os.Exit(1)
 	}
+	if err := logger.SetFormat(cfg.LogFormat); err != nil {
+		logger.Error.Printf("failed to set log format: %v", err)
+		os.Exit(1)
+	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		logger.Error.Printf("failed to set log level: %v", err)
+		os.Exit(1)
+	}
 
 	logger.Info.Printf("starting sharm on port %d, domain=%s", cfg.Port, cfg.Domain)
 
@@ -37,39 +63,238 @@ func main() {
 		os.Exit(1)
 	}
 
-	store, err := sqlitestore.NewStore(cfg.DataDir)
+	store, jobQueue, err := openMetadataStore(cfg.MetadataBackend, cfg.DataDir)
 	if err != nil {
 		logger.Error.Printf("failed to create store: %v", err)
 		os.Exit(1)
 	}
 	defer func() { _ = store.Close() }()
 
-	converter := ffmpeg.NewConverter()
-	jobQueue := sqlitestore.NewJobQueue(store)
-	eventBus := service.NewEventBus()
+	if len(os.Args) > 1 && os.Args[1] == "restore-metadata" {
+		if err := restoreMetadata(store, cfg.DataDir); err != nil {
+			logger.Error.Printf("restore failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var mediaStore port.MediaStore = store
+	if cfg.MetadataSidecar {
+		uploadDir := filepath.Join(cfg.DataDir, "uploads")
+		if err := os.MkdirAll(uploadDir, 0750); err != nil {
+			logger.Error.Printf("failed to create upload directory: %v", err)
+			os.Exit(1)
+		}
+		mediaStore = sidecar.NewStore(store, uploadDir)
+	}
+
+	converter := ffmpeg.NewConverter(cfg.AV1Preset, cfg.AV1CRF, cfg.H264PixFmt, cfg.ThumbnailSeek, cfg.ConvertTimeout)
+	if err := converter.Available(); err != nil {
+		logger.Error.Printf("ffmpeg is required for conversions and thumbnails: %v", err)
+		os.Exit(1)
+	}
+	if version, err := converter.Version(); err == nil {
+		logger.Info.Printf("using %s", version)
+	}
+	var mediaConverter port.MediaConverter = converter
+	if cfg.TranscoderURL != "" {
+		mediaConverter = remote.NewConverter(cfg.TranscoderURL, cfg.TranscoderToken, cfg.TranscoderPoll, cfg.ConvertTimeout, converter)
+		logger.Info.Printf("encoding on external transcoder %s", cfg.TranscoderURL)
+	}
+	metrics.RegisterJobsPending(jobQueue.PendingCount)
+	eventBus := service.NewEventBus(cfg.SSEMaxPerMedia, cfg.SSEMaxSubscribers)
+
+	if cfg.WebhookURL != "" {
+		hook := webhook.NewWebhook(cfg.WebhookURL, webhook.Format(cfg.WebhookFormat), cfg.Domain, cfg.WebhookTimeout)
+		eventBus.Listen(service.NewConversionNotifier(mediaStore, hook).Handle)
+		logger.Info.Printf("conversion webhook enabled, format=%s", hook.Format())
+	}
+
+	var blobs port.BlobStore = filestore.NewStore(cfg.DataDir)
+	var blobOrigin string
+	if cfg.StorageBackend == "s3" {
+		bucket, err := s3.NewStore(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey, cfg.S3PathStyle, cfg.S3PresignExpiry)
+		if err != nil {
+			logger.Error.Printf("failed to set up s3 storage: %v", err)
+			os.Exit(1)
+		}
+		blobs = bucket
+		blobOrigin = bucket.Origin()
+		logger.Info.Printf("storing media in s3 bucket %s at %s", cfg.S3Bucket, cfg.S3Endpoint)
+	}
 
-	mediaSvc := service.NewMediaService(store, converter, jobQueue, cfg.DataDir)
-	authSvc := service.NewAuthService(store, cfg.SecretKey)
+	mediaSvc := service.NewMediaService(mediaStore, mediaConverter, jobQueue, eventBus, cfg.DataDir, service.MediaConfig{
+		Blobs:           blobs,
+		LazyVariants:    cfg.LazyVariants,
+		TranscodePolicy: cfg.TranscodePolicy,
+		AnimationLimits: domain.AnimationLimits{MaxFrames: cfg.MaxAnimationFrames, MaxDimension: cfg.MaxAnimationDimension},
+		Storyboards:     cfg.Storyboards,
+		VerifyUploads:   cfg.VerifyUploads,
+		DefaultCodecs:   cfg.DefaultCodecs,
+		ForceH264:       cfg.ForceH264,
+		DedupUploads:    cfg.DedupUploads,
+		MaxPixels:       cfg.MaxPixels,
+	})
+	authSvc := service.NewAuthService(store, cfg.SecretKey, cfg.AuthTokenTTL)
 
 	// Worker pool for async jobs (conversion, thumbnails)
 	workerCtx, workerCancel := context.WithCancel(context.Background())
 	defer workerCancel()
 
-	workerPool := service.NewWorkerPool(jobQueue, store, converter, eventBus, cfg.DataDir, 2)
+	workerPool := service.NewWorkerPool(jobQueue, mediaStore, mediaConverter, eventBus, cfg.DataDir, 2, cfg.TranscodePolicy, cfg.ConvertMemoryBudgetMB, blobs)
+	eventBus.Listen(workerPool.Handle)
 	workerPool.Start(workerCtx)
 
-	server := HTTPAdapter.NewServer(authSvc, mediaSvc, eventBus, cfg.Domain, cfg.MaxUploadSizeMB, Version, cfg.BehindProxy, cfg.SecretKey)
+	diskMonitor := service.NewDiskMonitor(cfg.DataDir, uint64(cfg.MinFreeDiskMB)*1024*1024) //nolint:gosec // validated >= 0
+	diskMonitor.Check()
+
+	var identityProvider HTTPAdapter.IdentityProvider
+	if cfg.OIDCIssuerURL != "" {
+		provider, err := oidc.NewProvider(context.Background(), cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL)
+		if err != nil {
+			logger.Error.Printf("failed to set up oidc: %v", err)
+			os.Exit(1)
+		}
+		identityProvider = provider
+		logger.Info.Printf("oidc login enabled, issuer=%s", cfg.OIDCIssuerURL)
+	}
+
+	csp := middleware.CSPConfig{
+		ScriptSrc:  cfg.CSPScriptSrc,
+		StyleSrc:   cfg.CSPStyleSrc,
+		FontSrc:    cfg.CSPFontSrc,
+		ConnectSrc: cfg.CSPConnectSrc,
+	}
+	if blobOrigin != "" {
+		// Media files redirect to presigned bucket URLs.
+		csp = csp.AllowMedia(blobOrigin)
+	}
+
+	var anonymousUpload *HTTPAdapter.AnonymousUpload
+	if cfg.AnonymousUpload {
+		anonymousUpload = &HTTPAdapter.AnonymousUpload{
+			MaxSizeMB:     cfg.AnonymousMaxSizeMB,
+			Retention:     cfg.AnonymousRetention,
+			RatePerMinute: cfg.AnonymousRatePerMin,
+		}
+		if cfg.CaptchaProvider != "" {
+			verifier, err := captcha.NewVerifier(captcha.Provider(cfg.CaptchaProvider), cfg.CaptchaSiteKey, cfg.CaptchaSecretKey)
+			if err != nil {
+				logger.Error.Printf("failed to set up captcha: %v", err)
+				os.Exit(1)
+			}
+			anonymousUpload.Challenge = verifier
+			csp = csp.Allow(verifier.Origins())
+		}
+		logger.Info.Printf("anonymous uploads enabled: %d MB, %s retention, captcha=%s",
+			cfg.AnonymousMaxSizeMB, domain.RetentionLabel(cfg.AnonymousRetention), cmp.Or(cfg.CaptchaProvider, "none"))
+	}
+
+	server := HTTPAdapter.NewServer(authSvc, mediaSvc, eventBus, HTTPAdapter.ServerConfig{
+		HandlerConfig: HTTPAdapter.HandlerConfig{
+			Domain:    cfg.Domain,
+			Version:   Version,
+			MaxSizeMB: cfg.MaxUploadSizeMB,
+			TypeMaxSizeMB: map[domain.MediaType]int{
+				domain.MediaTypeImage: cfg.MaxImageSizeMB,
+				domain.MediaTypeAudio: cfg.MaxAudioSizeMB,
+				domain.MediaTypeVideo: cfg.MaxVideoSizeMB,
+			},
+			ChunkMaxBytes:      cfg.ChunkMaxBytes,
+			OGImagePath:        cfg.OGDefaultImage,
+			DiskStatus:         diskMonitor,
+			DashboardCacheTTL:  cfg.DashboardCacheTTL,
+			AllowedMIMETypes:   cfg.AllowedMIMETypes,
+			RejectTypeMismatch: cfg.RejectTypeMismatch,
+			Retention: domain.RetentionPolicy{
+				Default: cfg.DefaultRetention,
+				Min:     cfg.MinRetention,
+				Max:     cfg.MaxRetention,
+				Presets: cfg.RetentionPresets,
+			},
+			Location:              cfg.Timezone,
+			ServeStallTimeout:     cfg.ServeStallTimeout,
+			BlobLinks:             mediaSvc,
+			BotCodec:              cfg.BotPreferredCodec,
+			ProcessingPlaceholder: cfg.ProcessingPlaceholder,
+		},
+		BehindProxy:         cfg.BehindProxy,
+		SecretKey:           cfg.SecretKey,
+		MetricsEnabled:      cfg.MetricsEnabled,
+		MetricsToken:        cfg.MetricsToken,
+		Identity:            identityProvider,
+		PasswordLogin:       cfg.PasswordLogin,
+		UploadRatePerMinute: cfg.UploadRatePerMinute,
+		CSP:                 csp,
+		Readiness: []HTTPAdapter.ReadinessCheck{
+			{Name: "database", Check: store.Ping},
+			{Name: "data_dir", Check: func(context.Context) error { return disk.CheckWritable(cfg.DataDir) }},
+			{Name: "ffmpeg", Check: func(context.Context) error { return converter.Available() }},
+		},
+		Workers:       workerPool,
+		SSEJSONEvents: cfg.SSEJSONEvents,
+		Backup:        store,
+		DataDir:       cfg.DataDir,
+		Anonymous:     anonymousUpload,
+	})
 
-	// Periodic cleanup of expired media
+	// Periodic cleanup of expired and failed media, free space checks and
+	// database maintenance
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
+		diskTicker := time.NewTicker(1 * time.Minute)
+		defer diskTicker.Stop()
+		checkpointC, stopCheckpoint := optionalTicker(cfg.DBCheckpointInterval)
+		defer stopCheckpoint()
+		vacuumC, stopVacuum := optionalTicker(cfg.DBVacuumInterval)
+		defer stopVacuum()
 		for {
 			select {
 			case <-ticker.C:
 				if err := mediaSvc.Cleanup(); err != nil {
 					logger.Error.Printf("cleanup failed: %v", err)
 				}
+				if cfg.FailedRetentionHours > 0 {
+					removed, err := mediaSvc.CleanupFailed(time.Duration(cfg.FailedRetentionHours) * time.Hour)
+					if err != nil {
+						logger.Error.Printf("failed media cleanup failed: %v", err)
+					} else if removed > 0 {
+						logger.Info.Printf("removed %d failed media", removed)
+					}
+				}
+			case <-diskTicker.C:
+				diskMonitor.Check()
+			case <-checkpointC:
+				if err := store.Checkpoint(workerCtx); err != nil {
+					logger.Error.Printf("wal checkpoint failed: %v", err)
+				}
+			case <-vacuumC:
+				start := time.Now()
+				if err := store.Vacuum(workerCtx); err != nil {
+					logger.Error.Printf("vacuum failed: %v", err)
+				} else {
+					logger.Info.Printf("vacuumed database in %s", time.Since(start).Round(time.Millisecond))
+				}
+			case <-workerCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// Periodic sweep of abandoned chunked uploads
+	go func() {
+		ticker := time.NewTicker(chunkSweepInterval(cfg.ChunkTTL))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				removed, err := HTTPAdapter.SweepStaleChunks(cfg.ChunkTTL)
+				if err != nil {
+					logger.Error.Printf("chunk sweep failed: %v", err)
+				} else if removed > 0 {
+					logger.Info.Printf("removed %d stale chunk uploads", removed)
+				}
 			case <-workerCtx.Done():
 				return
 			}
@@ -78,15 +303,35 @@ func main() {
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      server,
-		ReadTimeout:  5 * time.Minute,
-		WriteTimeout: 10 * time.Minute,
-		IdleTimeout:  120 * time.Second,
+		Addr:              addr,
+		Handler:           server,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       5 * time.Minute,
+		WriteTimeout:      10 * time.Minute,
+		IdleTimeout:       120 * time.Second,
+	}
+	if cfg.H2C {
+		// Cleartext HTTP/2 lets a TLS-terminating proxy multiplex the
+		// dashboard's SSE streams and media requests over one connection.
+		httpServer.Protocols = new(http.Protocols)
+		httpServer.Protocols.SetHTTP1(true)
+		httpServer.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error.Printf("failed to listen on %s: %v", addr, err)
+		os.Exit(1)
+	}
+	if cfg.MaxConnections > 0 {
+		// Connections beyond the limit wait in the accept backlog
+		listener = netutil.LimitListener(listener, cfg.MaxConnections)
 	}
 
 	// Graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		sig := <-sigChan
@@ -100,14 +345,86 @@ func main() {
 			logger.Error.Printf("http shutdown error: %v", err)
 		}
 
-		// Stop workers (lets in-flight jobs finish)
+		// Stop workers; in-flight encodes are killed and their jobs requeued
 		workerCancel()
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), workerDrainTimeout)
+		defer drainCancel()
+		if err := workerPool.Wait(drainCtx); err != nil {
+			logger.Warn.Printf("workers did not stop within %s, unfinished jobs resume on next start", workerDrainTimeout)
+		}
 
 		logger.Info.Printf("shutdown complete")
 	}()
 
 	logger.Info.Printf("server listening on %s", addr)
-	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 		logger.Error.Printf("server failed: %v", err)
+		return
+	}
+	<-shutdownDone
+}
+
+// optionalTicker ticks every interval, or never when interval is zero.
+func optionalTicker(interval time.Duration) (<-chan time.Time, func()) {
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
+
+// workerDrainTimeout bounds how long shutdown waits for workers to requeue
+// their in-flight jobs.
+const workerDrainTimeout = 15 * time.Second
+
+// chunkSweepInterval checks for stale chunks a few times per TTL, at most once a minute.
+func chunkSweepInterval(ttl time.Duration) time.Duration {
+	interval := ttl / 4
+	if interval < time.Minute {
+		return time.Minute
+	}
+	return interval
+}
+
+// metadataStore is the media and user store main needs, with the health
+// and maintenance hooks every backend provides.
+type metadataStore interface {
+	port.MediaStore
+	port.UserStore
+	Ping(ctx context.Context) error
+	Checkpoint(ctx context.Context) error
+	Vacuum(ctx context.Context) error
+	BackupName() string
+	Backup(ctx context.Context, w io.Writer) error
+	Restore(ctx context.Context, snapshotPath string) error
+	Close() error
+}
+
+// openMetadataStore opens the configured metadata backend and the job queue
+// kept alongside it. SQLite runs its migrations on open; the JSON file is
+// created on first start.
+func openMetadataStore(backend, dataDir string) (metadataStore, port.JobQueue, error) {
+	if backend == "jsonfile" {
+		store, err := jsonfile.NewStore(dataDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Info.Printf("storing metadata in %s", filepath.Join(dataDir, "sharm.json"))
+		return store, jsonfile.NewJobQueue(store), nil
+	}
+	store, err := sqlitestore.NewStore(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, sqlitestore.NewJobQueue(store), nil
+}
+
+// restoreMetadata rebuilds database records from metadata sidecars.
+func restoreMetadata(store port.MediaStore, dataDir string) error {
+	restored, err := sidecar.Restore(filepath.Join(dataDir, "uploads"), store)
+	if err != nil {
+		return err
 	}
+	logger.Info.Printf("restored %d media from sidecars", restored)
+	return nil
 }
@@ -6,17 +6,110 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // the runtime image ships no zoneinfo
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+// SVT-AV1 accepts presets 0-13 and CRF 1-63.
+const (
+	maxAV1Preset = 13
+	minAV1CRF    = 1
+	maxAV1CRF    = 63
 )
 
 type Config struct {
-	Port                 int
-	Domain               string
-	MaxUploadSizeMB      int
-	DefaultRetentionDays int
-	DataDir              string
-	SecretKey            string
-	BehindProxy          bool
+	Port                  int
+	Domain                string
+	MaxUploadSizeMB       int
+	MaxImageSizeMB        int
+	MaxAudioSizeMB        int
+	MaxVideoSizeMB        int
+	DefaultRetention      time.Duration
+	MinRetention          time.Duration
+	MaxRetention          time.Duration
+	RetentionPresets      []time.Duration
+	FailedRetentionHours  int
+	DataDir               string
+	SecretKey             string
+	AuthTokenTTL          time.Duration
+	BehindProxy           bool
+	ChunkTTL              time.Duration
+	ChunkMaxBytes         int64
+	OGDefaultImage        string
+	ProcessingPlaceholder string
+	AV1Preset             int
+	AV1CRF                int
+	H264PixFmt            string
+	ThumbnailSeek         domain.ThumbnailSeek
+	ConvertTimeout        time.Duration
+	ConvertMemoryBudgetMB int64
+	TranscoderURL         string
+	TranscoderToken       string
+	TranscoderPoll        time.Duration
+	MaxAnimationFrames    int
+	MaxAnimationDimension int
+	MaxPixels             int64
+	DefaultCodecs         []domain.Codec
+	ForceH264             bool
+	DedupUploads          bool
+	LazyVariants          bool
+	Storyboards           bool
+	VerifyUploads         bool
+	TranscodePolicy       domain.TranscodePolicy
+	MetadataSidecar       bool
+	MetadataBackend       string
+	StorageBackend        string
+	S3Endpoint            string
+	S3Region              string
+	S3Bucket              string
+	S3AccessKeyID         string
+	S3SecretAccessKey     string
+	S3PathStyle           bool
+	S3PresignExpiry       time.Duration
+	DBCheckpointInterval  time.Duration
+	DBVacuumInterval      time.Duration
+	MinFreeDiskMB         int
+	MetricsEnabled        bool
+	MetricsToken          string
+	WebhookURL            string
+	WebhookFormat         string
+	WebhookTimeout        time.Duration
+	DashboardCacheTTL     time.Duration
+	AllowedMIMETypes      []string
+	RejectTypeMismatch    bool
+	ReadHeaderTimeout     time.Duration
+	ServeStallTimeout     time.Duration
+	MaxConnections        int
+	SSEMaxPerMedia        int
+	SSEMaxSubscribers     int
+	SSEJSONEvents         bool
+	BotPreferredCodec     domain.Codec
+	H2C                   bool
+	Timezone              *time.Location
+	LogFormat             string
+	LogLevel              string
+	UploadRatePerMinute   int
+	AnonymousUpload       bool
+	AnonymousMaxSizeMB    int
+	AnonymousRetention    time.Duration
+	AnonymousRatePerMin   int
+	CaptchaProvider       string
+	CaptchaSiteKey        string
+	CaptchaSecretKey      string
+	OIDCIssuerURL         string
+	OIDCClientID          string
+	OIDCClientSecret      string
+	OIDCRedirectURL       string
+	PasswordLogin         bool
+	CSPScriptSrc          string
+	CSPStyleSrc           string
+	CSPFontSrc            string
+	CSPConnectSrc         string
 }
 
 func Load() (*Config, error) {
@@ -30,11 +123,385 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid MAX_UPLOAD_SIZE_MB: %w", err)
 	}
 
-	defaultRetentionDays, err := strconv.Atoi(getEnv("DEFAULT_RETENTION_DAYS", "7"))
+	// Per-type limits default to 0, meaning MAX_UPLOAD_SIZE_MB applies
+	maxImageSizeMB, err := strconv.Atoi(getEnv("MAX_IMAGE_SIZE_MB", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_IMAGE_SIZE_MB: %w", err)
+	}
+
+	maxAudioSizeMB, err := strconv.Atoi(getEnv("MAX_AUDIO_SIZE_MB", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_AUDIO_SIZE_MB: %w", err)
+	}
+
+	maxVideoSizeMB, err := strconv.Atoi(getEnv("MAX_VIDEO_SIZE_MB", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_VIDEO_SIZE_MB: %w", err)
+	}
+
+	defaultRetention, err := domain.ParseRetention(getEnv("DEFAULT_RETENTION_DAYS", "7"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid DEFAULT_RETENTION_DAYS: %w", err)
 	}
 
+	minRetention, err := domain.ParseRetention(getEnv("MIN_RETENTION_DAYS", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MIN_RETENTION_DAYS: %w", err)
+	}
+	if minRetention < time.Minute {
+		return nil, fmt.Errorf("invalid MIN_RETENTION_DAYS: must be at least 1m")
+	}
+
+	maxRetention, err := domain.ParseRetention(getEnv("MAX_RETENTION_DAYS", "365"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_RETENTION_DAYS: %w", err)
+	}
+	if maxRetention > 0 && maxRetention < minRetention {
+		return nil, fmt.Errorf("invalid MAX_RETENTION_DAYS: must not be below MIN_RETENTION_DAYS")
+	}
+	if defaultRetention < minRetention || (maxRetention > 0 && defaultRetention > maxRetention) {
+		return nil, fmt.Errorf("invalid DEFAULT_RETENTION_DAYS: must be between MIN_RETENTION_DAYS and MAX_RETENTION_DAYS")
+	}
+
+	retentionPresets := domain.DefaultRetentionPresets
+	if value := getEnv("RETENTION_PRESETS", ""); value != "" {
+		retentionPresets = nil
+		for _, item := range splitList(value) {
+			preset, err := domain.ParseRetention(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RETENTION_PRESETS: %w", err)
+			}
+			retentionPresets = append(retentionPresets, preset)
+		}
+	}
+
+	failedRetentionHours, err := strconv.Atoi(getEnv("FAILED_RETENTION_HOURS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FAILED_RETENTION_HOURS: %w", err)
+	}
+	if failedRetentionHours < 0 {
+		return nil, fmt.Errorf("invalid FAILED_RETENTION_HOURS: must not be negative")
+	}
+
+	chunkTTL, err := time.ParseDuration(getEnv("CHUNK_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHUNK_TTL: %w", err)
+	}
+	if chunkTTL <= 0 {
+		return nil, fmt.Errorf("invalid CHUNK_TTL: must be positive")
+	}
+
+	chunkMaxBytes, err := strconv.ParseInt(getEnv("CHUNK_MAX_BYTES", "10737418240"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHUNK_MAX_BYTES: %w", err)
+	}
+
+	av1Preset, err := strconv.Atoi(getEnv("AV1_PRESET", "6"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AV1_PRESET: %w", err)
+	}
+	if av1Preset < 0 || av1Preset > maxAV1Preset {
+		return nil, fmt.Errorf("invalid AV1_PRESET: %d is outside SVT-AV1's 0-%d range", av1Preset, maxAV1Preset)
+	}
+
+	av1CRF, err := strconv.Atoi(getEnv("AV1_CRF", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AV1_CRF: %w", err)
+	}
+	if av1CRF < minAV1CRF || av1CRF > maxAV1CRF {
+		return nil, fmt.Errorf("invalid AV1_CRF: %d is outside SVT-AV1's %d-%d range", av1CRF, minAV1CRF, maxAV1CRF)
+	}
+
+	// H264 output is normalised to yuv420p unless disabled with "none".
+	h264PixFmt := getEnv("H264_PIX_FMT", "yuv420p")
+	if h264PixFmt == "none" {
+		h264PixFmt = ""
+	}
+
+	defaultCodecs, err := domain.ParseCodecs(getEnv("DEFAULT_CODECS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEFAULT_CODECS: %w", err)
+	}
+
+	botPreferredCodec := domain.Codec(strings.ToLower(getEnv("BOT_PREFERRED_CODEC", "h264")))
+	switch botPreferredCodec {
+	case domain.CodecH264, domain.CodecAV1:
+	case "none":
+		botPreferredCodec = ""
+	default:
+		return nil, fmt.Errorf("invalid BOT_PREFERRED_CODEC: must be h264, av1 or none")
+	}
+
+	thumbnailSeek := domain.DefaultThumbnailSeek
+	if v := getEnv("THUMBNAIL_SEEK", ""); v != "" {
+		thumbnailSeek, err = domain.ParseThumbnailSeek(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid THUMBNAIL_SEEK: %w", err)
+		}
+	}
+
+	convertTimeout, err := time.ParseDuration(getEnv("CONVERT_TIMEOUT", "30m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONVERT_TIMEOUT: %w", err)
+	}
+	if convertTimeout <= 0 {
+		return nil, fmt.Errorf("invalid CONVERT_TIMEOUT: must be positive")
+	}
+
+	convertMemoryBudgetMB, err := strconv.ParseInt(getEnv("CONVERT_MEMORY_BUDGET_MB", "0"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONVERT_MEMORY_BUDGET_MB: %w", err)
+	}
+	if convertMemoryBudgetMB < 0 {
+		return nil, fmt.Errorf("invalid CONVERT_MEMORY_BUDGET_MB: must not be negative")
+	}
+
+	maxAnimationFrames, err := strconv.Atoi(getEnv("MAX_ANIMATION_FRAMES", "3000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_ANIMATION_FRAMES: %w", err)
+	}
+	if maxAnimationFrames < 0 {
+		return nil, fmt.Errorf("invalid MAX_ANIMATION_FRAMES: must not be negative")
+	}
+
+	maxAnimationDimension, err := strconv.Atoi(getEnv("MAX_ANIMATION_DIMENSION", "4096"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_ANIMATION_DIMENSION: %w", err)
+	}
+	if maxAnimationDimension < 0 {
+		return nil, fmt.Errorf("invalid MAX_ANIMATION_DIMENSION: must not be negative")
+	}
+
+	maxPixels, err := strconv.ParseInt(getEnv("MAX_PIXELS", "268435456"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_PIXELS: %w", err)
+	}
+	if maxPixels < 0 {
+		return nil, fmt.Errorf("invalid MAX_PIXELS: must not be negative")
+	}
+
+	// SKIP_WEB_OPTIMIZED predates TRANSCODE_POLICY and is kept as an alias
+	// for passthrough.
+	transcodePolicy := domain.TranscodePolicyAlways
+	if getEnv("SKIP_WEB_OPTIMIZED", "false") == "true" {
+		transcodePolicy = domain.TranscodePolicyPassthrough
+	}
+	if value := getEnv("TRANSCODE_POLICY", ""); value != "" {
+		var ok bool
+		if transcodePolicy, ok = domain.ParseTranscodePolicy(value); !ok {
+			return nil, fmt.Errorf("invalid TRANSCODE_POLICY: %q is not always or passthrough", value)
+		}
+	}
+
+	dbCheckpointInterval, err := time.ParseDuration(getEnv("DB_CHECKPOINT_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CHECKPOINT_INTERVAL: %w", err)
+	}
+	if dbCheckpointInterval < 0 {
+		return nil, fmt.Errorf("invalid DB_CHECKPOINT_INTERVAL: must not be negative")
+	}
+
+	dbVacuumInterval, err := time.ParseDuration(getEnv("DB_VACUUM_INTERVAL", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_VACUUM_INTERVAL: %w", err)
+	}
+	if dbVacuumInterval < 0 {
+		return nil, fmt.Errorf("invalid DB_VACUUM_INTERVAL: must not be negative")
+	}
+
+	minFreeDiskMB, err := strconv.Atoi(getEnv("MIN_FREE_DISK_MB", "1024"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MIN_FREE_DISK_MB: %w", err)
+	}
+	if minFreeDiskMB < 0 {
+		return nil, fmt.Errorf("invalid MIN_FREE_DISK_MB: must not be negative")
+	}
+
+	authTokenTTL, err := time.ParseDuration(getEnv("AUTH_TOKEN_TTL", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_TOKEN_TTL: %w", err)
+	}
+	if authTokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid AUTH_TOKEN_TTL: must be positive")
+	}
+
+	dashboardCacheTTL, err := time.ParseDuration(getEnv("DASHBOARD_CACHE_TTL", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DASHBOARD_CACHE_TTL: %w", err)
+	}
+
+	readHeaderTimeout, err := time.ParseDuration(getEnv("READ_HEADER_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid READ_HEADER_TIMEOUT: %w", err)
+	}
+	if readHeaderTimeout <= 0 {
+		return nil, fmt.Errorf("invalid READ_HEADER_TIMEOUT: must be positive")
+	}
+
+	serveStallTimeout, err := time.ParseDuration(getEnv("SERVE_STALL_TIMEOUT", "1m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVE_STALL_TIMEOUT: %w", err)
+	}
+	if serveStallTimeout < 0 {
+		return nil, fmt.Errorf("invalid SERVE_STALL_TIMEOUT: must not be negative")
+	}
+
+	maxConnections, err := strconv.Atoi(getEnv("MAX_CONNECTIONS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_CONNECTIONS: %w", err)
+	}
+	if maxConnections < 0 {
+		return nil, fmt.Errorf("invalid MAX_CONNECTIONS: must not be negative")
+	}
+
+	sseMaxPerMedia, err := strconv.Atoi(getEnv("SSE_MAX_PER_MEDIA", "8"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSE_MAX_PER_MEDIA: %w", err)
+	}
+	if sseMaxPerMedia < 0 {
+		return nil, fmt.Errorf("invalid SSE_MAX_PER_MEDIA: must not be negative")
+	}
+	sseMaxSubscribers, err := strconv.Atoi(getEnv("SSE_MAX_SUBSCRIBERS", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSE_MAX_SUBSCRIBERS: %w", err)
+	}
+	if sseMaxSubscribers < 0 {
+		return nil, fmt.Errorf("invalid SSE_MAX_SUBSCRIBERS: must not be negative")
+	}
+
+	uploadRatePerMinute, err := strconv.Atoi(getEnv("UPLOAD_RATE_PER_MINUTE", "120"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPLOAD_RATE_PER_MINUTE: %w", err)
+	}
+	if uploadRatePerMinute < 0 {
+		return nil, fmt.Errorf("invalid UPLOAD_RATE_PER_MINUTE: must not be negative")
+	}
+
+	// Anonymous uploads get their own, tighter limits. Their media always
+	// expires, so a public instance cannot be filled up for good.
+	anonymousMaxSizeMB, err := strconv.Atoi(getEnv("ANONYMOUS_MAX_SIZE_MB", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ANONYMOUS_MAX_SIZE_MB: %w", err)
+	}
+	if anonymousMaxSizeMB <= 0 {
+		return nil, fmt.Errorf("invalid ANONYMOUS_MAX_SIZE_MB: must be positive")
+	}
+	anonymousRetention, err := domain.ParseRetention(getEnv("ANONYMOUS_RETENTION", "1d"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ANONYMOUS_RETENTION: %w", err)
+	}
+	if anonymousRetention == domain.RetentionNever || anonymousRetention < time.Minute {
+		return nil, fmt.Errorf("invalid ANONYMOUS_RETENTION: must be at least 1m and not never")
+	}
+	anonymousRatePerMin, err := strconv.Atoi(getEnv("ANONYMOUS_RATE_PER_MINUTE", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ANONYMOUS_RATE_PER_MINUTE: %w", err)
+	}
+	if anonymousRatePerMin <= 0 {
+		return nil, fmt.Errorf("invalid ANONYMOUS_RATE_PER_MINUTE: must be positive")
+	}
+
+	captchaProvider := getEnv("CAPTCHA_PROVIDER", "")
+	captchaSiteKey := getEnv("CAPTCHA_SITE_KEY", "")
+	captchaSecretKey := getEnv("CAPTCHA_SECRET_KEY", "")
+	switch captchaProvider {
+	case "":
+	case "turnstile", "hcaptcha":
+		if captchaSiteKey == "" || captchaSecretKey == "" {
+			return nil, fmt.Errorf("invalid captcha config: CAPTCHA_SITE_KEY and CAPTCHA_SECRET_KEY are required with CAPTCHA_PROVIDER")
+		}
+	default:
+		return nil, fmt.Errorf("invalid CAPTCHA_PROVIDER: must be turnstile or hcaptcha")
+	}
+
+	timezone, err := time.LoadLocation(getEnv("TZ", "UTC"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TZ: %w", err)
+	}
+
+	logFormat := getEnv("LOG_FORMAT", "text")
+	if logFormat != "text" && logFormat != "json" {
+		return nil, fmt.Errorf("invalid LOG_FORMAT: must be text or json")
+	}
+
+	logLevel := strings.ToLower(getEnv("LOG_LEVEL", "info"))
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, logLevel) {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: must be debug, info, warn or error")
+	}
+
+	metadataBackend := strings.ToLower(getEnv("METADATA_BACKEND", "sqlite"))
+	if metadataBackend != "sqlite" && metadataBackend != "jsonfile" {
+		return nil, fmt.Errorf("invalid METADATA_BACKEND: must be sqlite or jsonfile")
+	}
+
+	storageBackend := strings.ToLower(getEnv("STORAGE_BACKEND", "local"))
+	if storageBackend != "local" && storageBackend != "s3" {
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND: must be local or s3")
+	}
+	s3Endpoint := getEnv("S3_ENDPOINT", "")
+	s3Bucket := getEnv("S3_BUCKET", "")
+	s3AccessKeyID := getEnv("S3_ACCESS_KEY_ID", "")
+	s3SecretAccessKey := getEnv("S3_SECRET_ACCESS_KEY", "")
+	if storageBackend == "s3" {
+		if !strings.HasPrefix(s3Endpoint, "https://") && !strings.HasPrefix(s3Endpoint, "http://") {
+			return nil, fmt.Errorf("invalid S3_ENDPOINT: must be an http or https URL")
+		}
+		if s3Bucket == "" || s3AccessKeyID == "" || s3SecretAccessKey == "" {
+			return nil, fmt.Errorf("invalid S3 config: S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required with STORAGE_BACKEND=s3")
+		}
+	}
+	s3PresignExpiry, err := time.ParseDuration(getEnv("S3_PRESIGN_EXPIRY", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3_PRESIGN_EXPIRY: %w", err)
+	}
+	// SigV4 caps presigned URLs at a week.
+	if s3PresignExpiry < time.Second || s3PresignExpiry > 7*24*time.Hour {
+		return nil, fmt.Errorf("invalid S3_PRESIGN_EXPIRY: must be between 1s and 168h")
+	}
+
+	transcoderURL := strings.TrimSuffix(getEnv("TRANSCODER_URL", ""), "/")
+	if transcoderURL != "" && !strings.HasPrefix(transcoderURL, "https://") && !strings.HasPrefix(transcoderURL, "http://") {
+		return nil, fmt.Errorf("invalid TRANSCODER_URL: must be an http or https URL")
+	}
+	transcoderPoll, err := time.ParseDuration(getEnv("TRANSCODER_POLL_INTERVAL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRANSCODER_POLL_INTERVAL: %w", err)
+	}
+	if transcoderPoll <= 0 {
+		return nil, fmt.Errorf("invalid TRANSCODER_POLL_INTERVAL: must be positive")
+	}
+
+	webhookURL := getEnv("WEBHOOK_URL", "")
+	if webhookURL != "" && !strings.HasPrefix(webhookURL, "https://") && !strings.HasPrefix(webhookURL, "http://") {
+		return nil, fmt.Errorf("invalid WEBHOOK_URL: must be an http or https URL")
+	}
+	webhookFormat := getEnv("WEBHOOK_FORMAT", "auto")
+	switch webhookFormat {
+	case "auto", "generic", "discord", "slack":
+	default:
+		return nil, fmt.Errorf("invalid WEBHOOK_FORMAT: must be auto, generic, discord or slack")
+	}
+	webhookTimeout, err := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_TIMEOUT: %w", err)
+	}
+	if webhookTimeout <= 0 {
+		return nil, fmt.Errorf("invalid WEBHOOK_TIMEOUT: must be positive")
+	}
+
+	// OIDC login is enabled by setting an issuer. Password login can only be
+	// turned off when there is another way in.
+	oidcIssuerURL := getEnv("OIDC_ISSUER_URL", "")
+	oidcClientID := getEnv("OIDC_CLIENT_ID", "")
+	oidcRedirectURL := getEnv("OIDC_REDIRECT_URL", "")
+	if oidcIssuerURL != "" && (oidcClientID == "" || oidcRedirectURL == "") {
+		return nil, fmt.Errorf("invalid OIDC config: OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER_URL")
+	}
+	passwordLogin := getEnv("PASSWORD_LOGIN", "true") == "true"
+	if !passwordLogin && oidcIssuerURL == "" {
+		return nil, fmt.Errorf("invalid PASSWORD_LOGIN: cannot be disabled without OIDC_ISSUER_URL")
+	}
+
 	secretKey := getEnv("SECRET_KEY", getEnv("AUTH_SECRET", ""))
 	if secretKey == "" {
 		dataDir := getEnv("DATA_DIR", "/data")
@@ -53,13 +520,93 @@ func Load() (*Config, error) {
 	behindProxy := getEnv("BEHIND_PROXY", "false") == "true"
 
 	return &Config{
-		Port:                 port,
-		Domain:               getEnv("DOMAIN", "localhost:7890"),
-		MaxUploadSizeMB:      maxUploadSizeMB,
-		DefaultRetentionDays: defaultRetentionDays,
-		DataDir:              getEnv("DATA_DIR", "/data"),
-		SecretKey:            secretKey,
-		BehindProxy:          behindProxy,
+		Port:                  port,
+		Domain:                getEnv("DOMAIN", "localhost:7890"),
+		MaxUploadSizeMB:       maxUploadSizeMB,
+		MaxImageSizeMB:        maxImageSizeMB,
+		MaxAudioSizeMB:        maxAudioSizeMB,
+		MaxVideoSizeMB:        maxVideoSizeMB,
+		DefaultRetention:      defaultRetention,
+		MinRetention:          minRetention,
+		MaxRetention:          maxRetention,
+		RetentionPresets:      retentionPresets,
+		FailedRetentionHours:  failedRetentionHours,
+		DataDir:               getEnv("DATA_DIR", "/data"),
+		SecretKey:             secretKey,
+		AuthTokenTTL:          authTokenTTL,
+		BehindProxy:           behindProxy,
+		ChunkTTL:              chunkTTL,
+		ChunkMaxBytes:         chunkMaxBytes,
+		OGDefaultImage:        getEnv("OG_DEFAULT_IMAGE", ""),
+		ProcessingPlaceholder: getEnv("PROCESSING_PLACEHOLDER", ""),
+		AV1Preset:             av1Preset,
+		AV1CRF:                av1CRF,
+		H264PixFmt:            h264PixFmt,
+		ThumbnailSeek:         thumbnailSeek,
+		ConvertTimeout:        convertTimeout,
+		ConvertMemoryBudgetMB: convertMemoryBudgetMB,
+		TranscoderURL:         transcoderURL,
+		TranscoderToken:       getEnv("TRANSCODER_TOKEN", ""),
+		TranscoderPoll:        transcoderPoll,
+		MaxAnimationFrames:    maxAnimationFrames,
+		MaxAnimationDimension: maxAnimationDimension,
+		MaxPixels:             maxPixels,
+		DefaultCodecs:         defaultCodecs,
+		ForceH264:             getEnv("FORCE_H264", "true") == "true",
+		DedupUploads:          getEnv("DEDUP_UPLOADS", "false") == "true",
+		LazyVariants:          getEnv("LAZY_VARIANTS", "false") == "true",
+		Storyboards:           getEnv("STORYBOARDS", "false") == "true",
+		VerifyUploads:         getEnv("VERIFY_UPLOADS", "false") == "true",
+		TranscodePolicy:       transcodePolicy,
+		MetadataSidecar:       getEnv("METADATA_SIDECAR", "false") == "true",
+		MetadataBackend:       metadataBackend,
+		StorageBackend:        storageBackend,
+		S3Endpoint:            s3Endpoint,
+		S3Region:              getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:              s3Bucket,
+		S3AccessKeyID:         s3AccessKeyID,
+		S3SecretAccessKey:     s3SecretAccessKey,
+		S3PathStyle:           getEnv("S3_PATH_STYLE", "true") == "true",
+		S3PresignExpiry:       s3PresignExpiry,
+		DBCheckpointInterval:  dbCheckpointInterval,
+		DBVacuumInterval:      dbVacuumInterval,
+		MinFreeDiskMB:         minFreeDiskMB,
+		MetricsEnabled:        getEnv("METRICS_ENABLED", "false") == "true",
+		MetricsToken:          getEnv("METRICS_TOKEN", ""),
+		WebhookURL:            webhookURL,
+		WebhookFormat:         webhookFormat,
+		WebhookTimeout:        webhookTimeout,
+		DashboardCacheTTL:     dashboardCacheTTL,
+		AllowedMIMETypes:      splitList(getEnv("ALLOWED_MIME_TYPES", "")),
+		RejectTypeMismatch:    getEnv("REJECT_TYPE_MISMATCH", "false") == "true",
+		ReadHeaderTimeout:     readHeaderTimeout,
+		ServeStallTimeout:     serveStallTimeout,
+		MaxConnections:        maxConnections,
+		SSEMaxPerMedia:        sseMaxPerMedia,
+		SSEMaxSubscribers:     sseMaxSubscribers,
+		SSEJSONEvents:         getEnv("SSE_JSON_EVENTS", "false") == "true",
+		BotPreferredCodec:     botPreferredCodec,
+		H2C:                   getEnv("H2C", "false") == "true",
+		Timezone:              timezone,
+		LogFormat:             logFormat,
+		LogLevel:              logLevel,
+		UploadRatePerMinute:   uploadRatePerMinute,
+		AnonymousUpload:       getEnv("ANONYMOUS_UPLOAD", "false") == "true",
+		AnonymousMaxSizeMB:    anonymousMaxSizeMB,
+		AnonymousRetention:    anonymousRetention,
+		AnonymousRatePerMin:   anonymousRatePerMin,
+		CaptchaProvider:       captchaProvider,
+		CaptchaSiteKey:        captchaSiteKey,
+		CaptchaSecretKey:      captchaSecretKey,
+		OIDCIssuerURL:         oidcIssuerURL,
+		OIDCClientID:          oidcClientID,
+		OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:       oidcRedirectURL,
+		PasswordLogin:         passwordLogin,
+		CSPScriptSrc:          getEnv("CSP_SCRIPT_SRC", ""),
+		CSPStyleSrc:           getEnv("CSP_STYLE_SRC", ""),
+		CSPFontSrc:            getEnv("CSP_FONT_SRC", ""),
+		CSPConnectSrc:         getEnv("CSP_CONNECT_SRC", ""),
 	}, nil
 }
 
@@ -71,6 +618,17 @@ func generateSecretKey() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,51 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVerifier_UnknownProvider(t *testing.T) {
+	_, err := NewVerifier("recaptcha", "site", "secret")
+	assert.Error(t, err)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "203.0.113.7", r.PostForm.Get("remoteip"))
+		switch r.PostForm.Get("response") {
+		case "good":
+			_, _ = w.Write([]byte(`{"success":true}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	v, err := NewVerifier(ProviderTurnstile, "site", "secret")
+	require.NoError(t, err)
+	v.verifyURL = srv.URL
+	ctx := context.Background()
+
+	assert.NoError(t, v.Verify(ctx, "good", "203.0.113.7"))
+
+	err = v.Verify(ctx, "bad", "203.0.113.7")
+	assert.ErrorIs(t, err, domain.ErrChallengeFailed)
+	assert.Contains(t, err.Error(), "invalid-input-response")
+
+	assert.ErrorIs(t, v.Verify(ctx, "", "203.0.113.7"), domain.ErrChallengeFailed)
+
+	err = v.Verify(ctx, "broken", "203.0.113.7")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrChallengeFailed, "an unreachable provider is not the visitor's fault")
+}
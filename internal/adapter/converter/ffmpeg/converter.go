@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
 	"github.com/bnema/sharm/internal/port"
 )
 
@@ -31,15 +34,55 @@ func validatePath(path string) error {
 	return nil
 }
 
-const convertTimeout = 30 * time.Minute
+type Converter struct {
+	// SVT-AV1 settings: lower presets are slower but compress better,
+	// lower CRF means higher quality and larger files.
+	av1Preset int
+	av1CRF    int
+	// h264PixFmt is the pixel format H264 output is normalised to when the
+	// source differs; empty keeps the source format.
+	h264PixFmt string
+	// thumbnailSeek picks the frame thumbnails are captured from.
+	thumbnailSeek domain.ThumbnailSeek
+	// timeout bounds each ffmpeg run.
+	timeout time.Duration
+}
+
+func NewConverter(av1Preset, av1CRF int, h264PixFmt string, thumbnailSeek domain.ThumbnailSeek, timeout time.Duration) *Converter {
+	return &Converter{
+		av1Preset:     av1Preset,
+		av1CRF:        av1CRF,
+		h264PixFmt:    h264PixFmt,
+		thumbnailSeek: thumbnailSeek,
+		timeout:       timeout,
+	}
+}
 
-type Converter struct{}
+// binaries are the programs the converter runs.
+var binaries = []string{"ffmpeg", "ffprobe"}
 
-func NewConverter() port.MediaConverter {
-	return &Converter{}
+// Available reports an error naming the first of ffmpeg and ffprobe that
+// cannot be found in PATH.
+func (c *Converter) Available() error {
+	for _, name := range binaries {
+		if _, err := exec.LookPath(name); err != nil {
+			return fmt.Errorf("%s not found: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Version returns the first line of `ffmpeg -version`, which names the build.
+func (c *Converter) Version() (string, error) {
+	out, err := exec.Command("ffmpeg", "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg -version: %w", err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line), nil
 }
 
-func (c *Converter) Convert(inputPath, outputDir, id string) (outputPath, codec string, err error) {
+func (c *Converter) Convert(ctx context.Context, inputPath, outputDir, id string) (outputPath, codec string, err error) {
 	if validateErr := validatePath(inputPath); validateErr != nil {
 		return "", "", fmt.Errorf("invalid input path: %w", validateErr)
 	}
@@ -51,9 +94,13 @@ func (c *Converter) Convert(inputPath, outputDir, id string) (outputPath, codec
 	webmPath := basePath + ".webm"
 	mp4Path := basePath + ".mp4"
 
-	err = c.convertAV1(inputPath, webmPath, 0)
+	err = encodeTo(webmPath, func(tmpPath string) error {
+		return c.convertAV1(ctx, inputPath, tmpPath, 0)
+	})
 	if err != nil {
-		err = c.convertH264(inputPath, mp4Path, 0)
+		err = encodeTo(mp4Path, func(tmpPath string) error {
+			return c.convertH264(ctx, inputPath, tmpPath, 0)
+		})
 		if err != nil {
 			return "", "", fmt.Errorf("both AV1 and H264 conversion failed: %w", err)
 		}
@@ -63,7 +110,7 @@ func (c *Converter) Convert(inputPath, outputDir, id string) (outputPath, codec
 	return webmPath, string(domain.CodecAV1), nil
 }
 
-func (c *Converter) ConvertCodec(inputPath, outputDir, id string, codec domain.Codec, fps int) (outputPath string, err error) {
+func (c *Converter) ConvertCodec(ctx context.Context, inputPath, outputDir, id string, codec domain.Codec, fps int, src *domain.ProbeResult) (outputPath string, err error) {
 	if validateErr := validatePath(inputPath); validateErr != nil {
 		return "", fmt.Errorf("invalid input path: %w", validateErr)
 	}
@@ -75,13 +122,19 @@ func (c *Converter) ConvertCodec(inputPath, outputDir, id string, codec domain.C
 	switch codec {
 	case domain.CodecAV1:
 		outputPath = basePath + "_av1.webm"
-		err = c.convertAV1(inputPath, outputPath, fps)
+		err = encodeTo(outputPath, func(tmpPath string) error {
+			return c.convertAV1(ctx, inputPath, tmpPath, fps)
+		})
 	case domain.CodecH264:
 		outputPath = basePath + "_h264.mp4"
-		err = c.convertH264(inputPath, outputPath, fps)
+		err = encodeTo(outputPath, func(tmpPath string) error {
+			return c.convertH264(ctx, inputPath, tmpPath, fps)
+		})
 	case domain.CodecOpus:
 		outputPath = basePath + "_opus.ogg"
-		err = c.convertOpus(inputPath, outputPath)
+		err = encodeTo(outputPath, func(tmpPath string) error {
+			return c.convertOpus(ctx, inputPath, tmpPath, src)
+		})
 	default:
 		return "", fmt.Errorf("unsupported codec: %s", codec)
 	}
@@ -92,99 +145,310 @@ func (c *Converter) ConvertCodec(inputPath, outputDir, id string, codec domain.C
 	return outputPath, nil
 }
 
-func (c *Converter) convertAV1(inputPath, outputPath string, fps int) error {
+// encodeTo runs encode into a hidden temporary file next to outputPath and
+// renames it into place once it succeeded. A reconversion never rewrites
+// the file being served: downloads already reading the old one keep it
+// open until they finish. The temporary name keeps the extension, which
+// ffmpeg picks the container from.
+func encodeTo(outputPath string, encode func(tmpPath string) error) error {
+	dir, base := filepath.Split(outputPath)
+	ext := filepath.Ext(base)
+	tmpPath := filepath.Join(dir, "."+strings.TrimSuffix(base, ext)+".part"+ext)
+	if err := encode(tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("move output into place: %w", err)
+	}
+	return nil
+}
+
+func (c *Converter) convertAV1(ctx context.Context, inputPath, outputPath string, fps int) error {
 	if validateErr := validatePath(inputPath); validateErr != nil {
 		return fmt.Errorf("invalid input path: %w", validateErr)
 	}
 	if validateErr := validatePath(outputPath); validateErr != nil {
 		return fmt.Errorf("invalid output path: %w", validateErr)
 	}
+	args := c.av1Args(inputPath, outputPath, fps, c.hdrStream(inputPath))
+	return c.runFFmpeg(ctx, args)
+}
+
+// av1Args builds the AV1 encode. HDR sources keep their 10-bit BT.2020
+// colour and transfer tags so players render them as HDR.
+func (c *Converter) av1Args(inputPath, outputPath string, fps int, hdr *domain.ProbeStream) []string {
 	args := []string{
 		"-nostdin", // Security: prevent stdin-based attacks
 		"-i", inputPath,
 		"-c:v", "libsvtav1",
-		"-crf", "30",
-		"-preset", "6",
+		"-crf", strconv.Itoa(c.av1CRF),
+		"-preset", strconv.Itoa(c.av1Preset),
+	}
+	if hdr != nil {
+		args = append(args,
+			"-pix_fmt", "yuv420p10le",
+			"-color_primaries", "bt2020",
+			"-colorspace", "bt2020nc",
+		)
+		if hdr.ColorTransfer != "" {
+			args = append(args, "-color_trc", hdr.ColorTransfer)
+		}
+	}
+	args = append(args,
 		"-c:a", "libopus",
 		"-b:a", "128k",
-	}
+	)
 	if fps > 0 {
 		args = append(args, "-r", fmt.Sprintf("%d", fps))
 	}
-	args = append(args, "-y", outputPath)
-	ctx, cancel := context.WithTimeout(context.Background(), convertTimeout)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	return cmd.Run()
+	return append(args, "-y", outputPath)
 }
 
-func (c *Converter) convertH264(inputPath, outputPath string, fps int) error {
+func (c *Converter) convertH264(ctx context.Context, inputPath, outputPath string, fps int) error {
 	if err := validatePath(inputPath); err != nil {
 		return fmt.Errorf("invalid input path: %w", err)
 	}
 	if err := validatePath(outputPath); err != nil {
 		return fmt.Errorf("invalid output path: %w", err)
 	}
+	args := h264Args(inputPath, outputPath, fps, c.videoStream(inputPath), c.h264PixFmt)
+	return c.runFFmpeg(ctx, args)
+}
+
+// evenDimensionsFilter crops a pixel off odd widths and heights, which
+// 4:2:0 chroma subsampling cannot encode.
+const evenDimensionsFilter = "crop=trunc(iw/2)*2:trunc(ih/2)*2"
+
+// hdrToneMapFilter converts HDR to 8-bit BT.709 SDR: linearise, map the
+// highlights down with Hable, then re-encode the transfer for SDR displays.
+const hdrToneMapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709," +
+	"tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+
+// h264Args builds the H264 encode. 8-bit H264 cannot carry HDR, so HDR
+// sources are tone-mapped to SDR instead of coming out washed out. Other
+// sources are converted to pixFmt when their format differs or is unknown:
+// libx264 otherwise keeps 10-bit or 4:4:4 input, which many decoders
+// (Discord, mobile hardware) cannot play.
+func h264Args(inputPath, outputPath string, fps int, src *domain.ProbeStream, pixFmt string) []string {
 	args := []string{
 		"-nostdin", // Security: prevent stdin-based attacks
 		"-i", inputPath,
+	}
+	switch {
+	case src != nil && src.CodecName == "gif":
+		// GIFs are palette-based and may have odd dimensions, neither of
+		// which browsers can play as H264.
+		args = append(args, "-vf", evenDimensionsFilter, "-pix_fmt", "yuv420p")
+	case src != nil && src.IsHDR():
+		args = append(args,
+			"-vf", hdrToneMapFilter,
+			"-color_primaries", "bt709",
+			"-color_trc", "bt709",
+			"-colorspace", "bt709",
+		)
+	case pixFmt != "" && (src == nil || src.PixFmt != pixFmt):
+		args = append(args, "-pix_fmt", pixFmt)
+	}
+	args = append(args,
 		"-c:v", "libx264",
 		"-crf", "23",
 		"-preset", "medium",
 		"-c:a", "aac",
 		"-b:a", "128k",
 		"-movflags", "+faststart",
-	}
+	)
 	if fps > 0 {
 		args = append(args, "-r", fmt.Sprintf("%d", fps))
 	}
-	args = append(args, "-y", outputPath)
-	ctx, cancel := context.WithTimeout(context.Background(), convertTimeout)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	return cmd.Run()
+	return append(args, "-y", outputPath)
+}
+
+// videoStream returns the input's video stream, or nil when there is none
+// or probing fails.
+func (c *Converter) videoStream(inputPath string) *domain.ProbeStream {
+	probe, err := c.Probe(inputPath)
+	if err != nil {
+		return nil
+	}
+	return probe.VideoStream()
+}
+
+// hdrStream returns the input's video stream if it is HDR, or nil for SDR
+// input and when probing fails.
+func (c *Converter) hdrStream(inputPath string) *domain.ProbeStream {
+	if vs := c.videoStream(inputPath); vs != nil && vs.IsHDR() {
+		return vs
+	}
+	return nil
 }
 
-func (c *Converter) convertOpus(inputPath, outputPath string) error {
+func (c *Converter) convertOpus(ctx context.Context, inputPath, outputPath string, src *domain.ProbeResult) error {
 	if err := validatePath(inputPath); err != nil {
 		return fmt.Errorf("invalid input path: %w", err)
 	}
 	if err := validatePath(outputPath); err != nil {
 		return fmt.Errorf("invalid output path: %w", err)
 	}
-	args := []string{
+	var audio *domain.ProbeStream
+	if src != nil {
+		audio = src.AudioStream()
+	}
+	return c.runFFmpeg(ctx, opusArgs(inputPath, outputPath, audio))
+}
+
+// Opus bitrates: 128k is transparent for most lossy sources, lossless ones
+// get more headroom.
+const (
+	opusBitrate         = "128k"
+	opusLosslessBitrate = "192k"
+)
+
+// opusArgs builds the ffmpeg arguments encoding the audio of inputPath to
+// Opus, at a higher bitrate when src is lossless.
+func opusArgs(inputPath, outputPath string, src *domain.ProbeStream) []string {
+	bitrate := opusBitrate
+	if src != nil && src.IsLossless() {
+		bitrate = opusLosslessBitrate
+	}
+	return []string{
 		"-nostdin", // Security: prevent stdin-based attacks
 		"-i", inputPath,
 		"-c:a", "libopus",
-		"-b:a", "128k",
+		"-b:a", bitrate,
 		"-vn",
 		"-y",
 		outputPath,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), convertTimeout)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	return cmd.Run()
 }
 
-func (c *Converter) Thumbnail(inputPath, outputPath string) error {
+func (c *Converter) Thumbnail(ctx context.Context, inputPath, outputPath string) error {
 	if err := validatePath(inputPath); err != nil {
 		return fmt.Errorf("invalid input path: %w", err)
 	}
 	if err := validatePath(outputPath); err != nil {
 		return fmt.Errorf("invalid output path: %w", err)
 	}
-	args := []string{
+	var duration float64
+	if probe, err := c.Probe(inputPath); err == nil {
+		duration = domain.ParseDuration(probe.Format.Duration)
+	}
+	return c.runFFmpeg(ctx, thumbnailArgs(inputPath, outputPath, c.thumbnailSeek.At(duration)))
+}
+
+func thumbnailArgs(inputPath, outputPath string, seek time.Duration) []string {
+	return []string{
 		"-nostdin", // Security: prevent stdin-based attacks
 		"-i", inputPath,
 		"-vframes", "1",
-		"-ss", "00:00:01",
+		"-ss", fmt.Sprintf("%.3f", seek.Seconds()),
 		"-f", "image2",
 		"-y",
 		outputPath,
 	}
-	cmd := exec.Command("ffmpeg", args...)
-	return cmd.Run()
+}
+
+// verifySeconds bounds how much of a file Verify decodes, keeping the
+// check quick on long videos.
+const verifySeconds = 5
+
+// Verify decodes the start of inputPath, discarding the frames, and fails
+// on the first decoding error.
+func (c *Converter) Verify(ctx context.Context, inputPath string) error {
+	if err := validatePath(inputPath); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+	return c.runFFmpeg(ctx, verifyArgs(inputPath))
+}
+
+func verifyArgs(inputPath string) []string {
+	return []string{
+		"-nostdin", // Security: prevent stdin-based attacks
+		"-v", "error",
+		"-xerror",
+		"-i", inputPath,
+		"-t", strconv.Itoa(verifySeconds),
+		"-f", "null",
+		"-",
+	}
+}
+
+// maxStderrBytes bounds how much of ffmpeg's stderr is kept; errors are
+// printed last, so only the tail matters.
+const maxStderrBytes = 64 * 1024
+
+// maxErrorLineLen bounds the ffmpeg error line added to returned errors,
+// which end up in job and media error messages.
+const maxErrorLineLen = 300
+
+// runFFmpeg runs ffmpeg with args, killing it when ctx is cancelled or the
+// configured timeout passes. On failure the last error line ffmpeg printed
+// is added to the error, so stored failures say why it failed rather than
+// just "exit status 1".
+func (c *Converter) runFFmpeg(ctx context.Context, args []string) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	stderr := &tailBuffer{max: maxStderrBytes}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %w", err, ctxErr)
+		}
+		if line := lastErrorLine(string(stderr.buf)); line != "" {
+			return fmt.Errorf("%w: %s", err, line)
+		}
+		return err
+	}
+	return nil
+}
+
+// lastErrorLine picks the most useful line of ffmpeg's stderr: the last one
+// mentioning an error, else the last line that is not progress output or the
+// generic "Conversion failed!". The result is truncated and sanitized.
+func lastErrorLine(stderr string) string {
+	lines := strings.FieldsFunc(stderr, func(r rune) bool { return r == '\n' || r == '\r' })
+	var fallback string
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line == "Conversion failed!" ||
+			strings.HasPrefix(line, "frame=") || strings.HasPrefix(line, "size=") {
+			continue
+		}
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "error") || strings.Contains(lower, "invalid") {
+			return truncateErrorLine(line)
+		}
+		if fallback == "" {
+			fallback = line
+		}
+	}
+	return truncateErrorLine(fallback)
+}
+
+func truncateErrorLine(line string) string {
+	if runes := []rune(line); len(runes) > maxErrorLineLen {
+		line = string(runes[:maxErrorLineLen]) + "…"
+	}
+	return logger.SanitizeForLog(line)
+}
+
+// tailBuffer is an io.Writer keeping only the last max bytes written.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
 }
 
 func (c *Converter) Probe(inputPath string) (*domain.ProbeResult, error) {
@@ -1,8 +1,17 @@
 package ffmpeg
 
 import (
+	"context"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
 )
 
 func TestValidatePath(t *testing.T) {
@@ -110,7 +119,7 @@ func TestConverter_Convert_PathValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, err := c.Convert(tt.inputPath, tt.outputDir, tt.id)
+			_, _, err := c.Convert(context.Background(), tt.inputPath, tt.outputDir, tt.id)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("Convert() expected error containing %q, got nil", tt.errMsg)
@@ -166,7 +175,7 @@ func TestConverter_Thumbnail_PathValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := c.Thumbnail(tt.inputPath, tt.outputPath)
+			err := c.Thumbnail(context.Background(), tt.inputPath, tt.outputPath)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("Thumbnail() expected error containing %q, got nil", tt.errMsg)
@@ -232,3 +241,304 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConverter_AV1Args_UsesConfiguredSettings(t *testing.T) {
+	c := &Converter{av1Preset: 4, av1CRF: 24}
+
+	args := strings.Join(c.av1Args("/in.mp4", "/out.webm", 30, nil), " ")
+
+	for _, want := range []string{"-c:v libsvtav1", "-crf 24", "-preset 4", "-r 30", "-y /out.webm"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("av1Args() = %q, missing %q", args, want)
+		}
+	}
+}
+
+func TestConverter_AV1Args_PreservesHDR(t *testing.T) {
+	c := &Converter{av1Preset: 8, av1CRF: 30}
+	hdr := &domain.ProbeStream{ColorSpace: "bt2020nc", ColorTransfer: "arib-std-b67", PixFmt: "yuv420p10le"}
+
+	args := strings.Join(c.av1Args("/in.mov", "/out.webm", 0, hdr), " ")
+
+	for _, want := range []string{"-pix_fmt yuv420p10le", "-color_primaries bt2020", "-color_trc arib-std-b67", "-colorspace bt2020nc"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("av1Args() = %q, missing %q", args, want)
+		}
+	}
+	if strings.Contains(args, "tonemap") {
+		t.Errorf("av1Args() = %q, should not tone-map", args)
+	}
+}
+
+func TestH264Args_ToneMapsHDR(t *testing.T) {
+	hdr := &domain.ProbeStream{ColorSpace: "bt2020nc", ColorTransfer: "smpte2084", PixFmt: "yuv420p10le"}
+
+	args := strings.Join(h264Args("/in.mov", "/out.mp4", 0, hdr, "yuv420p"), " ")
+	for _, want := range []string{"tonemap=tonemap=hable", "format=yuv420p", "-color_trc bt709", "-c:v libx264"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("h264Args() = %q, missing %q", args, want)
+		}
+	}
+
+	sdr := strings.Join(h264Args("/in.mov", "/out.mp4", 0, nil, ""), " ")
+	if strings.Contains(sdr, "-vf") {
+		t.Errorf("h264Args() = %q, SDR input should not be filtered", sdr)
+	}
+}
+
+func TestH264Args_NormalizesPixelFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    *domain.ProbeStream
+		pixFmt string
+		want   bool
+	}{
+		{"10-bit source", &domain.ProbeStream{PixFmt: "yuv420p10le"}, "yuv420p", true},
+		{"4:4:4 source", &domain.ProbeStream{PixFmt: "yuv444p"}, "yuv420p", true},
+		{"unknown source", nil, "yuv420p", true},
+		{"already compatible", &domain.ProbeStream{PixFmt: "yuv420p"}, "yuv420p", false},
+		{"normalization disabled", &domain.ProbeStream{PixFmt: "yuv444p"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.Join(h264Args("/in.mov", "/out.mp4", 0, tt.src, tt.pixFmt), " ")
+			if got := strings.Contains(args, "-pix_fmt yuv420p"); got != tt.want {
+				t.Errorf("h264Args() = %q, want -pix_fmt: %v", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestH264Args_GIF(t *testing.T) {
+	gif := &domain.ProbeStream{CodecType: "video", CodecName: "gif", PixFmt: "bgra", Width: 99, Height: 75}
+	args := strings.Join(h264Args("/in.gif", "/out.mp4", 0, gif, ""), " ")
+	for _, want := range []string{"-vf " + evenDimensionsFilter, "-pix_fmt yuv420p", "-movflags +faststart"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("h264Args() = %q, want %q", args, want)
+		}
+	}
+}
+
+func TestOpusArgs_Bitrate(t *testing.T) {
+	tests := []struct {
+		name string
+		src  *domain.ProbeStream
+		want string
+	}{
+		{"flac", &domain.ProbeStream{CodecType: "audio", CodecName: "flac"}, "-b:a 192k"},
+		{"wav", &domain.ProbeStream{CodecType: "audio", CodecName: "pcm_s16le"}, "-b:a 192k"},
+		{"lossy", &domain.ProbeStream{CodecType: "audio", CodecName: "wmav2"}, "-b:a 128k"},
+		{"unknown", nil, "-b:a 128k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.Join(opusArgs("/in.flac", "/out.ogg", tt.src), " ")
+			if !strings.Contains(args, tt.want) {
+				t.Errorf("opusArgs() = %q, want %q", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailArgs_Seek(t *testing.T) {
+	args := strings.Join(thumbnailArgs("/in.mp4", "/thumb.jpg", 2500*time.Millisecond), " ")
+	if !strings.Contains(args, "-ss 2.500") {
+		t.Errorf("thumbnailArgs() = %q, want -ss 2.500", args)
+	}
+}
+
+func TestVerifyArgs_DecodesStartToNull(t *testing.T) {
+	args := strings.Join(verifyArgs("/in.mp4"), " ")
+	for _, want := range []string{"-xerror", "-t 5", "-f null -"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("verifyArgs() = %q, want %s", args, want)
+		}
+	}
+}
+
+func TestStoryboardArgs(t *testing.T) {
+	args := strings.Join(storyboardArgs("/in.mp4", "/sprites.jpg", 10*time.Second), " ")
+	if !strings.Contains(args, "-vf fps=1/10,scale=160:-2,tile=10x10") {
+		t.Errorf("storyboardArgs() = %q, want the fps/scale/tile filter", args)
+	}
+	if !strings.Contains(args, "-q:v 5 -f image2 -y /sprites.jpg") {
+		t.Errorf("storyboardArgs() = %q, want a JPEG sprite", args)
+	}
+}
+
+func TestStoryboardIntervalFor(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     time.Duration
+	}{
+		{30 * time.Second, 10 * time.Second},
+		{1000 * time.Second, 10 * time.Second},
+		{1001 * time.Second, 11 * time.Second},
+		{2 * time.Hour, 72 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := storyboardIntervalFor(tt.duration); got != tt.want {
+			t.Errorf("storyboardIntervalFor(%v) = %v, want %v", tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestStoryboardVTT(t *testing.T) {
+	got := storyboardVTT("sprites.jpg", 25500*time.Millisecond, 10*time.Second, 160, 90)
+	want := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:10.000\nsprites.jpg#xywh=0,0,160,90\n" +
+		"\n00:00:10.000 --> 00:00:20.000\nsprites.jpg#xywh=160,0,160,90\n" +
+		"\n00:00:20.000 --> 00:00:25.500\nsprites.jpg#xywh=320,0,160,90\n"
+	if got != want {
+		t.Errorf("storyboardVTT() = %q, want %q", got, want)
+	}
+
+	long := storyboardVTT("s.png", 2*time.Hour, 72*time.Second, 160, 90)
+	if !strings.Contains(long, "01:58:48.000 --> 02:00:00.000\ns.png#xywh=1440,810,160,90") {
+		t.Errorf("storyboardVTT() last cue should be the bottom-right tile, got %q", long[len(long)-80:])
+	}
+}
+
+func TestLastErrorLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   string
+	}{
+		{
+			"encoder error",
+			"Input #0, mov,mp4\nframe=  10 fps=0.0 q=0.0 size=0kB\r[libsvtav1 @ 0x1] Error: unsupported pixel format\n" +
+				"[out#0/webm @ 0x2] Nothing was written into output file\nConversion failed!\n",
+			"[libsvtav1 @ 0x1] Error: unsupported pixel format",
+		},
+		{"missing input", "/in.mp4: No such file or directory\n", "/in.mp4: No such file or directory"},
+		{"control characters", "Invalid data found \x1b[31mhere\n", `Invalid data found \x1b[31mhere`},
+		{"nothing useful", "Conversion failed!\n\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastErrorLine(tt.stderr); got != tt.want {
+				t.Errorf("lastErrorLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastErrorLine_Truncates(t *testing.T) {
+	got := lastErrorLine("Error " + strings.Repeat("x", 1000))
+	if n := len([]rune(got)); n != maxErrorLineLen+1 {
+		t.Errorf("lastErrorLine() length = %d, want %d", n, maxErrorLineLen+1)
+	}
+}
+
+func TestTailBuffer_KeepsLastBytes(t *testing.T) {
+	b := &tailBuffer{max: 4}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("defg"))
+	if got := string(b.buf); got != "defg" {
+		t.Errorf("tailBuffer = %q, want %q", got, "defg")
+	}
+}
+
+func TestConvertCodec_Cancelled(t *testing.T) {
+	c := &Converter{timeout: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.ConvertCodec(ctx, "/in.mp4", t.TempDir(), "abc", domain.CodecOpus, 0, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ConvertCodec() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestEncodeTo_ReplacesOutputOnlyOnSuccess(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "abc_h264.mp4")
+	if err := os.WriteFile(outputPath, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A download already serving the old file
+	serving, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = serving.Close() }()
+
+	err = encodeTo(outputPath, func(tmpPath string) error {
+		if filepath.Ext(tmpPath) != ".mp4" || filepath.Dir(tmpPath) != filepath.Dir(outputPath) {
+			t.Errorf("tmpPath = %q, want a .mp4 next to the output", tmpPath)
+		}
+		if err := os.WriteFile(tmpPath, []byte("partial"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("encode failed")
+	})
+	if err == nil {
+		t.Fatal("encodeTo() error = nil, want the encode error")
+	}
+	if data, _ := os.ReadFile(outputPath); string(data) != "old" {
+		t.Errorf("after a failed encode the output = %q, want it untouched", data)
+	}
+
+	err = encodeTo(outputPath, func(tmpPath string) error {
+		return os.WriteFile(tmpPath, []byte("new"), 0o600)
+	})
+	if err != nil {
+		t.Fatalf("encodeTo() error = %v", err)
+	}
+	if data, _ := os.ReadFile(outputPath); string(data) != "new" {
+		t.Errorf("output = %q, want the new encode", data)
+	}
+	if data, _ := io.ReadAll(serving); string(data) != "old" {
+		t.Errorf("in-flight download read %q, want the old file", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(outputPath))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the output", len(entries))
+	}
+}
+
+// fakeBinaries puts shell scripts named after binaries on an otherwise empty
+// PATH; each prints output.
+func fakeBinaries(t *testing.T, output string, binaries ...string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script fakes need a unix shell")
+	}
+	dir := t.TempDir()
+	for _, name := range binaries {
+		script := "#!/bin/sh\necho '" + output + "'\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil { //nolint:gosec // must be executable
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestConverter_Available(t *testing.T) {
+	c := &Converter{}
+
+	fakeBinaries(t, "", "ffmpeg", "ffprobe")
+	if err := c.Available(); err != nil {
+		t.Errorf("Available() error = %v, want nil", err)
+	}
+
+	fakeBinaries(t, "", "ffmpeg")
+	err := c.Available()
+	if err == nil || !strings.Contains(err.Error(), "ffprobe") {
+		t.Errorf("Available() error = %v, want ffprobe not found", err)
+	}
+}
+
+func TestConverter_Version(t *testing.T) {
+	fakeBinaries(t, "ffmpeg version 7.1 Copyright (c) 2000-2024 the FFmpeg developers", "ffmpeg")
+
+	got, err := (&Converter{}).Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ffmpeg version 7.1 Copyright (c) 2000-2024 the FFmpeg developers"; got != want {
+		t.Errorf("Version() = %q, want %q", got, want)
+	}
+}
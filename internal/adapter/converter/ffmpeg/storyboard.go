@@ -0,0 +1,115 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"image/jpeg"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+// Storyboard sprites hold up to storyboardColumns x storyboardRows tiles
+// storyboardTileWidth pixels wide, one every storyboardInterval. Videos too
+// long to fit at that rate get a longer interval instead of more sprites.
+const (
+	storyboardInterval  = 10 * time.Second
+	storyboardColumns   = 10
+	storyboardRows      = 10
+	storyboardTileWidth = 160
+)
+
+// storyboardQuality is the JPEG quality of sprites (2 best, 31 worst). Tiles
+// are shown small, so a lower quality keeps long videos' sprites light.
+const storyboardQuality = "5"
+
+// Storyboard renders the scrubbing preview of a video: a JPEG sprite of
+// frames and a WebVTT file whose cues point at the sprite's tiles through
+// spriteURL, resolved by players relative to the VTT file's URL.
+func (c *Converter) Storyboard(ctx context.Context, inputPath, spritePath, vttPath, spriteURL string) error {
+	for _, path := range []string{inputPath, spritePath, vttPath} {
+		if err := validatePath(path); err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+	}
+
+	probe, err := c.Probe(inputPath)
+	if err != nil {
+		return fmt.Errorf("probe: %w", err)
+	}
+	duration := time.Duration(domain.ParseDuration(probe.Format.Duration) * float64(time.Second))
+	if duration <= 0 {
+		return fmt.Errorf("storyboard needs a known duration")
+	}
+
+	interval := storyboardIntervalFor(duration)
+	if err := c.runFFmpeg(ctx, storyboardArgs(inputPath, spritePath, interval)); err != nil {
+		return err
+	}
+
+	f, err := os.Open(spritePath)
+	if err != nil {
+		return fmt.Errorf("open sprite: %w", err)
+	}
+	sprite, err := jpeg.DecodeConfig(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("read sprite: %w", err)
+	}
+
+	vtt := storyboardVTT(spriteURL, duration, interval, sprite.Width/storyboardColumns, sprite.Height/storyboardRows)
+	if err := os.WriteFile(vttPath, []byte(vtt), 0600); err != nil {
+		return fmt.Errorf("write storyboard vtt: %w", err)
+	}
+	return nil
+}
+
+// storyboardIntervalFor returns the time between tiles, stretched past
+// storyboardInterval in whole seconds when the video has more frames than
+// the sprite has tiles.
+func storyboardIntervalFor(duration time.Duration) time.Duration {
+	perTile := time.Duration(math.Ceil(duration.Seconds()/(storyboardColumns*storyboardRows))) * time.Second
+	return max(storyboardInterval, perTile)
+}
+
+func storyboardArgs(inputPath, spritePath string, interval time.Duration) []string {
+	return []string{
+		"-nostdin", // Security: prevent stdin-based attacks
+		"-i", inputPath,
+		"-an",
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:-2,tile=%dx%d",
+			int(interval.Seconds()), storyboardTileWidth, storyboardColumns, storyboardRows),
+		"-frames:v", "1",
+		"-q:v", storyboardQuality,
+		"-f", "image2",
+		"-y",
+		spritePath,
+	}
+}
+
+// storyboardVTT maps each interval of the video to its tile, in the order
+// ffmpeg's tile filter lays frames out: left to right, then top to bottom.
+func storyboardVTT(spriteURL string, duration, interval time.Duration, tileWidth, tileHeight int) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := 0; i < storyboardColumns*storyboardRows; i++ {
+		start := time.Duration(i) * interval
+		if start >= duration {
+			break
+		}
+		end := min(start+interval, duration)
+		x := (i % storyboardColumns) * tileWidth
+		y := (i / storyboardColumns) * tileHeight
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), spriteURL, x, y, tileWidth, tileHeight)
+	}
+	return b.String()
+}
+
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
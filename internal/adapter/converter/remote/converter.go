@@ -0,0 +1,323 @@
+// Package remote offloads encoding to an external transcoding service over
+// HTTP. Probes, thumbnails and storyboards are cheap next to encodes and
+// stay with a local converter.
+//
+// The service is expected to implement:
+//
+//	POST   {url}/jobs              multipart form: input (file), codec, fps
+//	                               -> 2xx {"id": "..."}
+//	GET    {url}/jobs/{id}         -> {"status": "pending|running|done|failed", "error": "..."}
+//	GET    {url}/jobs/{id}/output  -> the encoded file
+//	DELETE {url}/jobs/{id}         cancels the job
+//
+// Every request carries "Authorization: Bearer {token}" when a token is set.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// Job states reported by the service.
+const (
+	statusDone   = "done"
+	statusFailed = "failed"
+)
+
+// maxErrorBody bounds how much of an error response is read into errors.
+const maxErrorBody = 4 * 1024
+
+type Converter struct {
+	baseURL      string
+	token        string
+	pollInterval time.Duration
+	// timeout bounds each job, from upload to downloaded output.
+	timeout time.Duration
+	// local runs everything but encodes.
+	local  port.MediaConverter
+	client *http.Client
+}
+
+func NewConverter(baseURL, token string, pollInterval, timeout time.Duration, local port.MediaConverter) *Converter {
+	return &Converter{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		token:        token,
+		pollInterval: pollInterval,
+		timeout:      timeout,
+		local:        local,
+		client:       &http.Client{},
+	}
+}
+
+// Convert encodes to AV1, falling back to H264, like the local converter.
+func (c *Converter) Convert(ctx context.Context, inputPath, outputDir, id string) (outputPath, codec string, err error) {
+	basePath := filepath.Join(outputDir, id)
+
+	webmPath := basePath + ".webm"
+	if err := c.transcode(ctx, inputPath, webmPath, domain.CodecAV1, 0); err != nil {
+		mp4Path := basePath + ".mp4"
+		if err := c.transcode(ctx, inputPath, mp4Path, domain.CodecH264, 0); err != nil {
+			return "", "", fmt.Errorf("both AV1 and H264 conversion failed: %w", err)
+		}
+		return mp4Path, string(domain.CodecH264), nil
+	}
+	return webmPath, string(domain.CodecAV1), nil
+}
+
+func (c *Converter) ConvertCodec(ctx context.Context, inputPath, outputDir, id string, codec domain.Codec, fps int, _ *domain.ProbeResult) (outputPath string, err error) {
+	basePath := filepath.Join(outputDir, id)
+
+	switch codec {
+	case domain.CodecAV1:
+		outputPath = basePath + "_av1.webm"
+	case domain.CodecH264:
+		outputPath = basePath + "_h264.mp4"
+	case domain.CodecOpus:
+		outputPath = basePath + "_opus.ogg"
+	default:
+		return "", fmt.Errorf("unsupported codec: %s", codec)
+	}
+
+	if err := c.transcode(ctx, inputPath, outputPath, codec, fps); err != nil {
+		return "", fmt.Errorf("convert to %s: %w", codec, err)
+	}
+	return outputPath, nil
+}
+
+func (c *Converter) Thumbnail(ctx context.Context, inputPath, outputPath string) error {
+	return c.local.Thumbnail(ctx, inputPath, outputPath)
+}
+
+func (c *Converter) Storyboard(ctx context.Context, inputPath, spritePath, vttPath, spriteURL string) error {
+	return c.local.Storyboard(ctx, inputPath, spritePath, vttPath, spriteURL)
+}
+
+func (c *Converter) Probe(inputPath string) (*domain.ProbeResult, error) {
+	return c.local.Probe(inputPath)
+}
+
+func (c *Converter) Verify(ctx context.Context, inputPath string) error {
+	return c.local.Verify(ctx, inputPath)
+}
+
+// transcode runs one job on the service: it uploads the input, waits for
+// the job to finish and downloads the result to outputPath. A job still
+// running when ctx is cancelled is cancelled on the service too.
+func (c *Converter) transcode(ctx context.Context, inputPath, outputPath string, codec domain.Codec, fps int) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	jobID, err := c.submit(ctx, inputPath, codec, fps)
+	if err != nil {
+		return fmt.Errorf("submit transcode job: %w", err)
+	}
+
+	if err := c.wait(ctx, jobID); err != nil {
+		if ctx.Err() != nil {
+			c.cancel(jobID)
+		}
+		return err
+	}
+
+	if err := c.download(ctx, jobID, outputPath); err != nil {
+		return fmt.Errorf("download transcode output: %w", err)
+	}
+	return nil
+}
+
+// submit streams the input file to the service and returns the job ID.
+func (c *Converter) submit(ctx context.Context, inputPath string, codec domain.Codec, fps int) (string, error) {
+	f, err := os.Open(inputPath) //nolint:gosec // path comes from the media store
+	if err != nil {
+		return "", fmt.Errorf("open input: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	body, w := io.Pipe()
+	form := multipart.NewWriter(w)
+	go func() {
+		_ = w.CloseWithError(writeJobForm(form, f, codec, fps))
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/jobs", body)
+	if err != nil {
+		_ = body.Close()
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var job struct {
+		ID string `json:"id"`
+	}
+	if err := c.doJSON(req, &job); err != nil {
+		return "", err
+	}
+	if job.ID == "" {
+		return "", errors.New("service returned no job id")
+	}
+	return job.ID, nil
+}
+
+func writeJobForm(form *multipart.Writer, input *os.File, codec domain.Codec, fps int) error {
+	if err := form.WriteField("codec", string(codec)); err != nil {
+		return err
+	}
+	if err := form.WriteField("fps", strconv.Itoa(fps)); err != nil {
+		return err
+	}
+	part, err := form.CreateFormFile("input", filepath.Base(input.Name()))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, input); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// wait polls the job until it is done, failed or ctx is done.
+func (c *Converter) wait(ctx context.Context, jobID string) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		req, err := c.newRequest(ctx, http.MethodGet, "/jobs/"+url.PathEscape(jobID), nil)
+		if err != nil {
+			return err
+		}
+		var job struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := c.doJSON(req, &job); err != nil {
+			return fmt.Errorf("poll transcode job %s: %w", jobID, err)
+		}
+
+		switch job.Status {
+		case statusDone:
+			return nil
+		case statusFailed:
+			if job.Error == "" {
+				job.Error = "no reason given"
+			}
+			return fmt.Errorf("transcode job %s failed: %s", jobID, job.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// download writes the job output next to outputPath and renames it into
+// place, so a failed download never leaves a partial file at outputPath.
+func (c *Converter) download(ctx context.Context, jobID, outputPath string) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/jobs/"+url.PathEscape(jobID)+"/output", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	tmpPath := outputPath + ".part"
+	out, err := os.Create(tmpPath) //nolint:gosec // path is built from the data dir
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	_, err = io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write output: %w", err)
+	}
+	return os.Rename(tmpPath, outputPath)
+}
+
+// cancel asks the service to drop a job; failures are only logged since
+// the job is abandoned either way.
+func (c *Converter) cancel(jobID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(jobID), nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.client.Do(req)
+	if err == nil {
+		err = checkStatus(resp)
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		logger.Warn.Printf("failed to cancel transcode job %s: %v", jobID, err)
+	}
+}
+
+func (c *Converter) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("create transcoder request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Sharm")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+func (c *Converter) doJSON(req *http.Request, v any) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode transcoder response: %w", err)
+	}
+	return nil
+}
+
+// checkStatus turns a non-2xx response into an error carrying the start of
+// its body, which usually says what went wrong.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, logger.SanitizeForLog(msg))
+	}
+	return fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+var _ port.MediaConverter = (*Converter)(nil)
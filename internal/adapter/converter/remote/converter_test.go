@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeService is a transcoding service running a single job that reports
+// status. It records what was uploaded and whether the job was deleted.
+type fakeService struct {
+	mu      sync.Mutex
+	status  string
+	error   string
+	polls   int
+	codec   string
+	fps     string
+	input   string
+	auth    string
+	deleted bool
+	polled  chan struct{}
+}
+
+func newFakeService(t *testing.T, status, errMsg string) (*fakeService, *httptest.Server) {
+	t.Helper()
+	f := &fakeService{status: status, error: errMsg, polled: make(chan struct{}, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("input")
+		require.NoError(t, err)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+
+		f.mu.Lock()
+		f.codec, f.fps, f.input = r.FormValue("codec"), r.FormValue("fps"), string(data)
+		f.auth = r.Header.Get("Authorization")
+		f.mu.Unlock()
+		_, _ = w.Write([]byte(`{"id":"job1"}`))
+	})
+	mux.HandleFunc("GET /jobs/job1", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.polls++
+		select {
+		case f.polled <- struct{}{}:
+		default:
+		}
+		status := f.status
+		if status == statusDone && f.polls < 2 {
+			status = "running"
+		}
+		_, _ = w.Write([]byte(`{"status":"` + status + `","error":"` + f.error + `"}`))
+	})
+	mux.HandleFunc("GET /jobs/job1/output", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("encoded"))
+	})
+	mux.HandleFunc("DELETE /jobs/job1", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.deleted = true
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func writeInput(t *testing.T) (dir, path string) {
+	t.Helper()
+	dir = t.TempDir()
+	path = filepath.Join(dir, "input.mov")
+	require.NoError(t, os.WriteFile(path, []byte("source"), 0600))
+	return dir, path
+}
+
+func TestConverter_ConvertCodec(t *testing.T) {
+	svc, srv := newFakeService(t, statusDone, "")
+	dir, input := writeInput(t)
+	c := NewConverter(srv.URL+"/", "secret", time.Millisecond, time.Minute, nil)
+
+	out, err := c.ConvertCodec(context.Background(), input, dir, "AB12CD34", domain.CodecH264, 30, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, "AB12CD34_h264.mp4"), out)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "encoded", string(data))
+	assert.NoFileExists(t, out+".part")
+
+	assert.Equal(t, "h264", svc.codec)
+	assert.Equal(t, "30", svc.fps)
+	assert.Equal(t, "source", svc.input)
+	assert.Equal(t, "Bearer secret", svc.auth)
+	assert.Equal(t, 2, svc.polls)
+}
+
+func TestConverter_ConvertCodec_Failed(t *testing.T) {
+	_, srv := newFakeService(t, statusFailed, "unsupported input")
+	dir, input := writeInput(t)
+	c := NewConverter(srv.URL, "", time.Millisecond, time.Minute, nil)
+
+	_, err := c.ConvertCodec(context.Background(), input, dir, "AB12CD34", domain.CodecAV1, 0, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported input")
+	assert.NoFileExists(t, filepath.Join(dir, "AB12CD34_av1.webm"))
+}
+
+func TestConverter_ConvertCodec_CancelsJob(t *testing.T) {
+	svc, srv := newFakeService(t, "running", "")
+	dir, input := writeInput(t)
+	c := NewConverter(srv.URL, "", time.Millisecond, time.Minute, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-svc.polled
+		cancel()
+	}()
+
+	_, err := c.ConvertCodec(ctx, input, dir, "AB12CD34", domain.CodecAV1, 0, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.True(t, svc.deleted)
+}
+
+func TestConverter_Convert(t *testing.T) {
+	_, srv := newFakeService(t, statusDone, "")
+	dir, input := writeInput(t)
+	c := NewConverter(srv.URL, "", time.Millisecond, time.Minute, nil)
+
+	out, codec, err := c.Convert(context.Background(), input, dir, "AB12CD34")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "AB12CD34.webm"), out)
+	assert.Equal(t, string(domain.CodecAV1), codec)
+}
+
+func TestConverter_DelegatesToLocal(t *testing.T) {
+	local := mocks.NewMediaConverterMock(t)
+	local.EXPECT().Probe("in.mp4").Return(&domain.ProbeResult{}, nil)
+	local.EXPECT().Thumbnail(mock.Anything, "in.mp4", "thumb.jpg").Return(nil)
+	local.EXPECT().Verify(mock.Anything, "in.mp4").Return(nil)
+	c := NewConverter("http://transcoder.invalid", "", time.Second, time.Minute, local)
+
+	_, err := c.Probe("in.mp4")
+	require.NoError(t, err)
+	require.NoError(t, c.Thumbnail(context.Background(), "in.mp4", "thumb.jpg"))
+	require.NoError(t, c.Verify(context.Background(), "in.mp4"))
+}
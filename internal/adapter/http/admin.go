@@ -0,0 +1,130 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+type jobLogEntry struct {
+	JobID       int64            `json:"job_id"`
+	Type        domain.JobType   `json:"type"`
+	Codec       domain.Codec     `json:"codec,omitempty"`
+	Status      domain.JobStatus `json:"status"`
+	Attempts    int64            `json:"attempts"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+type mediaLogsResponse struct {
+	MediaID string             `json:"media_id"`
+	Status  domain.MediaStatus `json:"status"`
+	Error   string             `json:"error,omitempty"`
+	Jobs    []jobLogEntry      `json:"jobs"`
+}
+
+// AdminMediaLogs returns the jobs run for a media with their captured error
+// output, so failed conversions can be diagnosed without shell access.
+// Admin only.
+func (h *Handlers) AdminMediaLogs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := currentUser(r); user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+
+		id := r.PathValue("id")
+		media, jobs, err := h.mediaSvc.JobLogs(id)
+		if errors.Is(err, domain.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "media not found")
+			return
+		}
+		if err != nil {
+			logger.Error.Printf("admin logs error for %s: %v", logger.SanitizeForLog(id), err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load logs")
+			return
+		}
+
+		resp := mediaLogsResponse{
+			MediaID: media.ID,
+			Status:  media.Status,
+			Error:   media.ErrorMessage,
+			Jobs:    make([]jobLogEntry, 0, len(jobs)),
+		}
+		for _, job := range jobs {
+			entry := jobLogEntry{
+				JobID:     job.ID,
+				Type:      job.Type,
+				Codec:     job.Codec,
+				Status:    job.Status,
+				Attempts:  job.Attempts,
+				Error:     job.ErrorMessage,
+				CreatedAt: job.CreatedAt,
+			}
+			if job.StartedAt.Valid {
+				entry.StartedAt = &job.StartedAt.Time
+			}
+			if job.CompletedAt.Valid {
+				entry.CompletedAt = &job.CompletedAt.Time
+			}
+			resp.Jobs = append(resp.Jobs, entry)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+type reconvertFailedResponse struct {
+	Requeued int `json:"requeued"`
+	Skipped  int `json:"skipped"`
+}
+
+// AdminReconvertFailed queues every failed media for conversion again, for
+// use after fixing whatever made them fail. Media whose original was deleted
+// are reported as skipped. Admin only.
+func (h *Handlers) AdminReconvertFailed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := currentUser(r); user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+
+		requeued, skipped, err := h.mediaSvc.ReconvertFailed()
+		if err != nil {
+			logger.Error.Printf("bulk reconvert failed after %d requeued: %v", requeued, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to reconvert media")
+			return
+		}
+		logger.Info.Printf("bulk reconvert: requeued=%d, skipped=%d", requeued, skipped)
+		writeJSON(w, http.StatusOK, reconvertFailedResponse{Requeued: requeued, Skipped: skipped})
+	}
+}
+
+type regenerateThumbnailsResponse struct {
+	Queued  int `json:"queued"`
+	Skipped int `json:"skipped"`
+}
+
+// AdminRegenerateThumbnails queues a new thumbnail for every converted
+// video, to apply changed thumbnail settings retroactively. Admin only.
+func (h *Handlers) AdminRegenerateThumbnails() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := currentUser(r); user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+
+		queued, skipped, err := h.mediaSvc.RegenerateThumbnails()
+		if err != nil {
+			logger.Error.Printf("thumbnail regeneration failed after %d queued: %v", queued, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to regenerate thumbnails")
+			return
+		}
+		logger.Info.Printf("thumbnail regeneration: queued=%d, skipped=%d", queued, skipped)
+		writeJSON(w, http.StatusOK, regenerateThumbnailsResponse{Queued: queued, Skipped: skipped})
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jobLogsStub knows a single media with one failed conversion.
+type jobLogsStub struct {
+	MediaService
+}
+
+func (jobLogsStub) JobLogs(id string) (*domain.Media, []domain.Job, error) {
+	if id != "abc" {
+		return nil, nil, domain.ErrNotFound
+	}
+	media := &domain.Media{ID: "abc", Status: domain.MediaStatusFailed, ErrorMessage: "conversion failed"}
+	jobs := []domain.Job{{ID: 1, Type: domain.JobTypeConvert, Codec: domain.CodecAV1, Status: domain.JobStatusFailed, Attempts: 1, ErrorMessage: "exit status 1"}}
+	return media, jobs, nil
+}
+
+func TestAdminMediaLogs(t *testing.T) {
+	h := NewHandlers(jobLogsStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	request := func(id string, user *domain.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/media/"+id+"/logs", nil)
+		req.SetPathValue("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+		rec := httptest.NewRecorder()
+		h.AdminMediaLogs()(rec, req)
+		return rec
+	}
+
+	rec := request("abc", &domain.User{ID: 1, IsAdmin: true})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp mediaLogsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "conversion failed", resp.Error)
+	require.Len(t, resp.Jobs, 1)
+	assert.Equal(t, "exit status 1", resp.Jobs[0].Error)
+
+	assert.Equal(t, http.StatusForbidden, request("abc", &domain.User{ID: 2}).Code)
+	assert.Equal(t, http.StatusNotFound, request("missing", &domain.User{ID: 1, IsAdmin: true}).Code)
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/adapter/http/validation"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// AnonymousUpload limits the public upload form offered to visitors who are
+// not signed in. Their media has no owner, so it shows up on no dashboard
+// and is only reachable through its share link until it expires.
+type AnonymousUpload struct {
+	MaxSizeMB     int
+	Retention     time.Duration
+	RatePerMinute int
+
+	// Challenge must be solved before each upload; nil asks for none.
+	Challenge ChallengeVerifier
+}
+
+// ChallengeVerifier checks an anti-abuse challenge, such as a captcha,
+// solved on the public upload form.
+type ChallengeVerifier interface {
+	// Widget describes the client-side widget producing the token.
+	Widget() domain.ChallengeWidget
+	// Verify returns domain.ErrChallengeFailed when token is not a valid
+	// solution, and other errors when it could not be checked.
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// maxBytes is the largest body an anonymous upload may send.
+func (a AnonymousUpload) maxBytes() int64 {
+	return int64(a.MaxSizeMB) * 1024 * 1024
+}
+
+// LandingOr serves the public upload page to visitors without a session
+// cookie or API key and hands everyone else to next.
+func LandingOr(page, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); ok {
+			next(w, r)
+			return
+		}
+		if _, err := r.Cookie(CookieName); err == nil {
+			next(w, r)
+			return
+		}
+		page(w, r)
+	}
+}
+
+func (h *Handlers) AnonymousUploadPage(limits AnonymousUpload) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		var widget *domain.ChallengeWidget
+		if limits.Challenge != nil {
+			cw := limits.Challenge.Widget()
+			widget = &cw
+		}
+		_ = templates.AnonymousUpload(h.version, limits.MaxSizeMB, limits.Retention, widget).Render(r.Context(), w)
+	}
+}
+
+// AnonymousUpload stores a single file sent through the public upload form
+// as ownerless media with the anonymous retention. Codecs, tags and custom
+// links are left to signed-in users. The form is parsed with a small memory
+// budget, spilling the file to disk, so an unverified request cannot hold
+// the whole size limit in memory; when a challenge is configured, its token
+// is checked before the file is validated or stored.
+func (h *Handlers) AnonymousUpload(limits AnonymousUpload, behindProxy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.uploadsPaused() {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInsufficientStorage)
+			_ = templates.ErrorInline(errDiskLow).Render(r.Context(), w)
+			return
+		}
+
+		tooLarge := fmt.Sprintf("File too large: uploads are limited to %d MB", limits.MaxSizeMB)
+		if declaresTooLarge(w, r, limits.maxBytes()) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = templates.ErrorInline(tooLarge).Render(r.Context(), w)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limits.maxBytes())
+
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = templates.ErrorInline(tooLarge).Render(r.Context(), w)
+			return
+		}
+
+		if limits.Challenge != nil {
+			token := r.FormValue(limits.Challenge.Widget().ResponseField)
+			if err := limits.Challenge.Verify(r.Context(), token, getClientID(r, behindProxy)); err != nil {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				if errors.Is(err, domain.ErrChallengeFailed) {
+					logger.Warn.Printf("anonymous upload from %s refused: %v", getClientID(r, behindProxy), err)
+					w.WriteHeader(http.StatusForbidden)
+					_ = templates.ErrorInline("Verification failed, please try again").Render(r.Context(), w)
+					return
+				}
+				logger.Error.Printf("anonymous upload challenge check failed: %v", err)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = templates.ErrorInline("Verification is unavailable, please try again later").Render(r.Context(), w)
+				return
+			}
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = templates.ErrorInline("Invalid file upload").Render(r.Context(), w)
+			return
+		}
+		defer file.Close() //nolint:errcheck
+
+		mime, allowed, err := validation.ValidateMagicBytes(file, h.mimeAllowlist)
+		if err != nil {
+			logger.Error.Printf("magic bytes validation error for %s: %v", logger.SanitizeForLog(header.Filename), err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorInline("Failed to validate file type").Render(r.Context(), w)
+			return
+		}
+		if !allowed {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = templates.ErrorInline("File type not allowed").Render(r.Context(), w)
+			return
+		}
+
+		tmpFile, err := os.CreateTemp("", "upload-*.tmp")
+		if err != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorInline("Failed to process upload").Render(r.Context(), w)
+			return
+		}
+		defer func() {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpFile.Name()) // may already be moved by service
+		}()
+
+		size, err := io.Copy(tmpFile, file)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorInline("Failed to save file").Render(r.Context(), w)
+			return
+		}
+
+		mediaType, ok := h.uploadMediaType(w, r, header.Filename, mime)
+		if !ok {
+			return
+		}
+		if h.exceedsTypeLimit(w, r, mediaType, size) {
+			return
+		}
+
+		media, err := h.mediaSvc.Upload(0, header.Filename, tmpFile, limits.Retention, mediaType, nil, 0, nil, "")
+		if err != nil {
+			renderUploadError(w, r, header.Filename, err)
+			return
+		}
+		logger.Info.Printf("anonymous upload %s stored as %s", logger.SanitizeForLog(header.Filename), media.ID)
+
+		// There is no dashboard to go back to, so the uploader lands on
+		// the share page and keeps its link.
+		w.Header().Set("HX-Redirect", "/v/"+media.ID)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLandingOr(t *testing.T) {
+	handler := LandingOr(
+		func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("landing")) },
+		func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("dashboard")) },
+	)
+
+	tests := []struct {
+		name    string
+		prepare func(*http.Request)
+		want    string
+	}{
+		{"visitor", func(*http.Request) {}, "landing"},
+		{"session", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: CookieName, Value: "token"}) }, "dashboard"},
+		{"api key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer key") }, "dashboard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.prepare(req)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			assert.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+type recordingUploads struct {
+	MediaService
+	ownerID   int64
+	retention time.Duration
+	codecs    []domain.Codec
+}
+
+func (s *recordingUploads) Upload(
+	ownerID int64, _ string, _ *os.File, retention time.Duration, mediaType domain.MediaType, codecs []domain.Codec, _ int,
+	_ []string, _ string,
+) (*domain.Media, error) {
+	s.ownerID, s.retention, s.codecs = ownerID, retention, codecs
+	return &domain.Media{ID: "ANON1234", Type: mediaType}, nil
+}
+
+func TestHandlers_AnonymousUpload(t *testing.T) {
+	uploads := &recordingUploads{ownerID: -1}
+	h := NewHandlers(uploads, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+	handler := h.AnonymousUpload(AnonymousUpload{MaxSizeMB: 1, Retention: time.Hour, RatePerMinute: 5}, false)
+
+	upload := func(size int) *httptest.ResponseRecorder {
+		png := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, size)...)
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile("file", "shot.png")
+		require.NoError(t, err)
+		_, err = part.Write(png)
+		require.NoError(t, err)
+		require.NoError(t, mw.WriteField("codecs", "av1"))
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/public/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := upload(2 * 1024 * 1024)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "limited to 1 MB")
+	assert.Equal(t, int64(-1), uploads.ownerID, "nothing should be stored")
+
+	rec = upload(1024)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "/v/ANON1234", rec.Header().Get("HX-Redirect"))
+	assert.Equal(t, int64(0), uploads.ownerID)
+	assert.Equal(t, time.Hour, uploads.retention)
+	assert.Empty(t, uploads.codecs, "anonymous uploads get the default codecs")
+}
+
+// stubChallenge accepts the token "solved" and fails to reach the provider
+// for "offline".
+type stubChallenge struct{}
+
+func (stubChallenge) Widget() domain.ChallengeWidget {
+	return domain.ChallengeWidget{ScriptURL: "https://captcha.example/api.js", Class: "captcha", SiteKey: "site", ResponseField: "captcha-response"}
+}
+
+func (stubChallenge) Verify(_ context.Context, token, _ string) error {
+	switch token {
+	case "solved":
+		return nil
+	case "offline":
+		return errors.New("provider unreachable")
+	default:
+		return domain.ErrChallengeFailed
+	}
+}
+
+func TestHandlers_AnonymousUpload_Challenge(t *testing.T) {
+	uploads := &recordingUploads{ownerID: -1}
+	h := NewHandlers(uploads, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+	limits := AnonymousUpload{MaxSizeMB: 1, Retention: time.Hour, RatePerMinute: 5, Challenge: stubChallenge{}}
+	handler := h.AnonymousUpload(limits, false)
+
+	rec := httptest.NewRecorder()
+	h.AnonymousUploadPage(limits)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Contains(t, rec.Body.String(), `data-sitekey="site"`)
+	assert.Contains(t, rec.Body.String(), "https://captcha.example/api.js")
+
+	upload := func(token string) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if token != "" {
+			require.NoError(t, mw.WriteField("captcha-response", token))
+		}
+		part, err := mw.CreateFormFile("file", "shot.png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0, 0, 0, 0x0D})
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/public/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, upload(""))
+	assert.Equal(t, http.StatusForbidden, upload("forged"))
+	assert.Equal(t, http.StatusServiceUnavailable, upload("offline"))
+	assert.Equal(t, int64(-1), uploads.ownerID, "nothing should be stored without a solved challenge")
+
+	assert.Equal(t, http.StatusOK, upload("solved"))
+	assert.Equal(t, int64(0), uploads.ownerID)
+}
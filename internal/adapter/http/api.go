@@ -0,0 +1,225 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// writeJSON encodes v as the response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error.Printf("json encode error: %v", err)
+	}
+}
+
+// writeJSONError writes {"error": msg} with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+type statsResponse struct {
+	domain.StorageStats
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// APIStats returns storage usage as JSON: of the whole instance for admins,
+// of the user's own media otherwise.
+func (h *Handlers) APIStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := h.stats(r)
+		if err != nil {
+			logger.Error.Printf("stats error: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load stats")
+			return
+		}
+		writeJSON(w, http.StatusOK, statsResponse{StorageStats: stats, TotalBytes: stats.TotalBytes()})
+	}
+}
+
+type apiVariant struct {
+	Codec    domain.Codec         `json:"codec"`
+	Status   domain.VariantStatus `json:"status"`
+	FileSize int64                `json:"file_size"`
+	Error    string               `json:"error,omitempty"`
+}
+
+// apiMedia is the public view of a media; storage paths and probe output
+// stay internal.
+type apiMedia struct {
+	ID           string             `json:"id"`
+	URL          string             `json:"url"`
+	Type         domain.MediaType   `json:"type"`
+	OriginalName string             `json:"original_name"`
+	Status       domain.MediaStatus `json:"status"`
+	Error        string             `json:"error,omitempty"`
+	FileSize     int64              `json:"file_size"`
+	Width        int                `json:"width,omitempty"`
+	Height       int                `json:"height,omitempty"`
+	Tags         []string           `json:"tags"`
+	Variants     []apiVariant       `json:"variants"`
+	CreatedAt    time.Time          `json:"created_at"`
+	// ExpiresAt is null for media that never expire.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// OriginalAvailable reports whether the upload itself can still be
+	// downloaded, at OriginalURL; the legacy conversion path deletes it.
+	OriginalAvailable bool   `json:"original_available"`
+	OriginalURL       string `json:"original_url,omitempty"`
+}
+
+func (h *Handlers) toAPIMedia(ctx context.Context, m *domain.Media) apiMedia {
+	am := apiMedia{
+		ID:           m.ID,
+		URL:          fmt.Sprintf("https://%s/v/%s", h.domain, m.ID),
+		Type:         m.Type,
+		OriginalName: m.OriginalName,
+		Status:       m.Status,
+		Error:        m.ErrorMessage,
+		FileSize:     m.FileSize,
+		Width:        m.Width,
+		Height:       m.Height,
+		Tags:         m.Tags,
+		Variants:     make([]apiVariant, 0, len(m.Variants)),
+		CreatedAt:    m.CreatedAt,
+	}
+	if !m.NeverExpires() {
+		am.ExpiresAt = &m.ExpiresAt
+	}
+	if am.Tags == nil {
+		am.Tags = []string{}
+	}
+	if h.originalAvailable(ctx, m) {
+		am.OriginalAvailable = true
+		am.OriginalURL = am.URL + "/original"
+	}
+	for _, v := range m.Variants {
+		am.Variants = append(am.Variants, apiVariant{Codec: v.Codec, Status: v.Status, FileSize: v.FileSize, Error: v.ErrorMessage})
+	}
+	return am
+}
+
+// originalAvailable reports whether /v/{id}/original can serve the upload:
+// it is still on disk, or in object storage when links to it are served.
+func (h *Handlers) originalAvailable(ctx context.Context, m *domain.Media) bool {
+	if m.OriginalPath == "" {
+		return false
+	}
+	if _, err := os.Stat(m.OriginalPath); err == nil {
+		return true
+	}
+	if h.blobLinks != nil {
+		_, ok := h.blobLinks.BlobURL(ctx, m.OriginalPath, "", "")
+		return ok
+	}
+	return false
+}
+
+// APIListMedia returns the caller's media in the status given by the status
+// query parameter, newest first, so integrations can poll for failures or
+// track the conversion backlog.
+func (h *Handlers) APIListMedia() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := domain.MediaStatus(r.URL.Query().Get("status"))
+		switch status {
+		case domain.MediaStatusPending, domain.MediaStatusProcessing, domain.MediaStatusDone, domain.MediaStatusFailed:
+		default:
+			writeJSONError(w, http.StatusBadRequest, "status must be one of pending, processing, done, failed")
+			return
+		}
+
+		media, err := h.mediaSvc.ListByStatus(currentUserID(r), status)
+		if err != nil {
+			logger.Error.Printf("list media by status %s: %v", status, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to list media")
+			return
+		}
+
+		resp := make([]apiMedia, 0, len(media))
+		for _, m := range media {
+			resp = append(resp, h.toAPIMedia(r.Context(), m))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// APIMediaExists looks up the caller's media by the SHA-256 of its original
+// upload, given as the sha256 query parameter, so sync clients can skip
+// sending content that is already there. It answers 200 with the media or
+// 404; HEAD requests get the status alone.
+func (h *Handlers) APIMediaExists() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sum := r.URL.Query().Get("sha256")
+		if raw, err := hex.DecodeString(sum); err != nil || len(raw) != sha256.Size {
+			writeJSONError(w, http.StatusBadRequest, "sha256 must be a hex-encoded SHA-256 digest")
+			return
+		}
+
+		media, err := h.mediaSvc.FindByChecksum(currentUserID(r), sum)
+		switch {
+		case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrExpired):
+			writeJSONError(w, http.StatusNotFound, "no media with this checksum")
+			return
+		case err != nil:
+			logger.Error.Printf("find media by checksum: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to look up media")
+			return
+		}
+		writeJSON(w, http.StatusOK, h.toAPIMedia(r.Context(), media))
+	}
+}
+
+// apiProbe is the ffprobe output for a file along with the values clients
+// usually derive from it.
+type apiProbe struct {
+	*domain.ProbeResult
+	Filename        string  `json:"filename"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Bitrate is the overall bitrate in bits per second, 0 when unknown.
+	Bitrate      int64   `json:"bitrate"`
+	Width        int     `json:"width,omitempty"`
+	Height       int     `json:"height,omitempty"`
+	FrameRate    float64 `json:"frame_rate,omitempty"`
+	HDR          bool    `json:"hdr"`
+	WebOptimized bool    `json:"web_optimized"`
+}
+
+func toAPIProbe(probe *domain.ProbeResult, filename string) apiProbe {
+	ap := apiProbe{
+		ProbeResult:     probe,
+		Filename:        filename,
+		DurationSeconds: domain.ParseDuration(probe.Format.Duration),
+		Bitrate:         domain.ParseSize(probe.Format.BitRate),
+		WebOptimized:    probe.IsWebOptimized(),
+	}
+	if vs := probe.VideoStream(); vs != nil {
+		ap.Width, ap.Height = vs.Width, vs.Height
+		ap.FrameRate = domain.ParseFrameRate(vs.RFrameRate)
+		ap.HDR = vs.IsHDR()
+	}
+	return ap
+}
+
+// APIProbe probes the file sent as the "file" field of a multipart request
+// and returns what Sharm would see in it, so clients can check a file
+// before uploading it. Nothing is stored.
+func (h *Handlers) APIProbe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		probe, filename, err := h.probeRequest(w, r)
+		if err != nil {
+			writeJSONError(w, err.status, err.msg)
+			return
+		}
+		writeJSON(w, http.StatusOK, toAPIProbe(probe, filename))
+	}
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusListStub holds one failed media for user 1.
+type statusListStub struct {
+	MediaService
+}
+
+func (statusListStub) ListByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error) {
+	if ownerID != 1 || status != domain.MediaStatusFailed {
+		return nil, nil
+	}
+	return []*domain.Media{{
+		ID:           "abc",
+		Type:         domain.MediaTypeVideo,
+		OriginalName: "clip.mp4",
+		OriginalPath: "/data/uploads/abc_clip.mp4",
+		Status:       domain.MediaStatusFailed,
+		ErrorMessage: "conversion failed",
+		Variants:     []domain.Variant{{Codec: domain.CodecAV1, Status: domain.VariantStatusFailed}},
+	}}, nil
+}
+
+// statsStub reports one media for the instance and none per owner.
+type statsStub struct {
+	MediaService
+}
+
+func (statsStub) Stats() (domain.StorageStats, error) {
+	return domain.StorageStats{TotalCount: 1, MediaBytes: 100}, nil
+}
+
+func (statsStub) OwnerStats(ownerID int64) (domain.StorageStats, error) {
+	return domain.StorageStats{}, nil
+}
+
+func TestAPIStats_ScopedToOwnerUnlessAdmin(t *testing.T) {
+	h := NewHandlers(statsStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	request := func(user *domain.User) statsResponse {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+		rec := httptest.NewRecorder()
+		h.APIStats()(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp statsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Zero(t, request(&domain.User{ID: 2}).TotalCount, "users only see their own media")
+	assert.Equal(t, 1, request(&domain.User{ID: 1, IsAdmin: true}).TotalCount)
+}
+
+func TestAPIListMedia(t *testing.T) {
+	h := NewHandlers(statusListStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	request := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/media?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: 1}))
+		rec := httptest.NewRecorder()
+		h.APIListMedia()(rec, req)
+		return rec
+	}
+
+	rec := request("status=failed")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "/data/uploads", "storage paths must not leak")
+	var resp []apiMedia
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	assert.Equal(t, "https://example.com/v/abc", resp[0].URL)
+	assert.Equal(t, "conversion failed", resp[0].Error)
+	assert.False(t, resp[0].OriginalAvailable, "the original is not on disk")
+	assert.Empty(t, resp[0].OriginalURL)
+	require.Len(t, resp[0].Variants, 1)
+	assert.Equal(t, domain.VariantStatusFailed, resp[0].Variants[0].Status)
+
+	rec = request("status=done")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, request("status=bogus").Code)
+	assert.Equal(t, http.StatusBadRequest, request("").Code)
+}
+
+func TestToAPIMedia_OriginalAvailable(t *testing.T) {
+	original := filepath.Join(t.TempDir(), "abc_clip.mp4")
+	require.NoError(t, os.WriteFile(original, []byte("video"), 0600))
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	am := h.toAPIMedia(context.Background(), &domain.Media{ID: "abc", OriginalPath: original})
+	assert.True(t, am.OriginalAvailable)
+	assert.Equal(t, "https://example.com/v/abc/original", am.OriginalURL)
+
+	am = h.toAPIMedia(context.Background(), &domain.Media{ID: "abc"})
+	assert.False(t, am.OriginalAvailable, "deleted after conversion")
+
+	// Originals pruned from disk are still served from object storage
+	h = NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", BlobLinks: presignedLinks{}})
+	am = h.toAPIMedia(context.Background(), &domain.Media{ID: "abc", OriginalPath: filepath.Join(t.TempDir(), "pruned.mp4")})
+	assert.True(t, am.OriginalAvailable)
+
+	// Legacy conversions delete the original and its blob, and its path
+	am = h.toAPIMedia(context.Background(), &domain.Media{ID: "abc", ConvertedPath: original})
+	assert.False(t, am.OriginalAvailable)
+}
+
+// checksumStub knows one media of user 1 by the checksum of "hello".
+type checksumStub struct {
+	MediaService
+}
+
+func (checksumStub) FindByChecksum(ownerID int64, sum string) (*domain.Media, error) {
+	if ownerID != 1 || sum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Media{ID: "abc", Status: domain.MediaStatusDone, ExpiresAt: domain.NeverExpiresAt}, nil
+}
+
+func TestAPIMediaExists(t *testing.T) {
+	h := NewHandlers(checksumStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	tests := []struct {
+		name string
+		sum  string
+		want int
+	}{
+		{"found", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", http.StatusOK},
+		{"unknown", "0000000000000000000000000000000000000000000000000000000000000000", http.StatusNotFound},
+		{"not hex", "hello", http.StatusBadRequest},
+		{"too short", "2cf24dba", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/media/exists?sha256="+tt.sum, nil)
+			req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: 1}))
+			rec := httptest.NewRecorder()
+
+			h.APIMediaExists()(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				var resp apiMedia
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, "abc", resp.ID)
+			}
+		})
+	}
+}
+
+// probeStub reports an 8-bit H264 MP4 for every file and checks that the
+// probed file is still there.
+type probeStub struct {
+	MediaService
+}
+
+func (probeStub) ProbeFile(path string) (*domain.ProbeResult, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return &domain.ProbeResult{
+		Format: domain.ProbeFormat{FormatName: "mov,mp4,m4a,3gp,3g2,mj2", Duration: "12.5", BitRate: "2500000"},
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p", Width: 1920, Height: 1080, RFrameRate: "30/1"},
+		},
+	}, nil
+}
+
+func TestAPIProbe(t *testing.T) {
+	h := NewHandlers(probeStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 1, Version: "test"})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "clip.mp4")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("video"))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/probe", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.APIProbe()(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "clip.mp4", resp["filename"])
+	assert.InDelta(t, 12.5, resp["duration_seconds"], 0.001)
+	assert.InDelta(t, 2500000, resp["bitrate"], 0.001)
+	assert.InDelta(t, 1920, resp["width"], 0.001)
+	assert.InDelta(t, 30, resp["frame_rate"], 0.001)
+	assert.Equal(t, true, resp["web_optimized"])
+	assert.Contains(t, resp, "streams")
+	assert.Contains(t, resp, "format")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/probe", bytes.NewReader(make([]byte, 2*1024*1024)))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	h.APIProbe()(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error"`)
+}
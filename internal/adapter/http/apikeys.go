@@ -0,0 +1,89 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
+)
+
+// APIKeysHandler lists the user's API keys on GET and creates one on POST.
+// The raw key is rendered only in the POST response.
+func APIKeysHandler(authSvc AuthService, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		props := templates.APIKeysProps{IsAdmin: user.IsAdmin, Version: version}
+		status := http.StatusOK
+
+		if r.Method == http.MethodPost {
+			raw, key, err := authSvc.CreateAPIKey(user.ID, r.FormValue("label"))
+			switch {
+			case errors.Is(err, service.ErrInvalidLabel):
+				props.Error = "Label is required and must be at most 100 characters"
+				status = http.StatusBadRequest
+			case err != nil:
+				logger.Error.Printf("api keys: failed to create key for user %s: %v", user.Username, err)
+				props.Error = "Failed to create API key"
+				status = http.StatusInternalServerError
+			default:
+				logger.Info.Printf("api keys: created key %d (%s) for user %s", key.ID, key.Label, user.Username)
+				props.NewKey = raw
+				w.Header().Set("Cache-Control", "no-store")
+			}
+		}
+
+		keys, err := authSvc.ListAPIKeys(user.ID)
+		if err != nil {
+			logger.Error.Printf("api keys: failed to list keys for user %s: %v", user.Username, err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorPage("500", "Failed to load API keys", version).Render(r.Context(), w)
+			return
+		}
+		props.Keys = keys
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = templates.APIKeysPage(props).Render(r.Context(), w)
+	}
+}
+
+// RevokeAPIKeyHandler deletes one of the user's API keys and returns to the
+// key list.
+func RevokeAPIKeyHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		keyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := authSvc.RevokeAPIKey(user.ID, keyID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				http.Error(w, "API key not found", http.StatusNotFound)
+				return
+			}
+			logger.Error.Printf("api keys: failed to revoke key %d for user %s: %v", keyID, user.Username, err)
+			http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info.Printf("api keys: revoked key %d for user %s", keyID, user.Username)
+		http.Redirect(w, r, "/settings/api-keys", http.StatusSeeOther)
+	}
+}
@@ -2,30 +2,46 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
 	"github.com/bnema/sharm/internal/adapter/http/templates"
 	"github.com/bnema/sharm/internal/domain"
 	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
 )
 
 const (
 	CookieName     = "auth_token"
-	CookieMaxAge   = 7 * 24 * 60 * 60
 	CookiePath     = "/"
 	CookieSameSite = http.SameSiteStrictMode
 	HXRequestTrue  = "true"
 )
 
-func getClientID(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
+// getClientID identifies the client for rate limiting and logging. Behind a
+// reverse proxy it is the last X-Forwarded-For hop, the address the proxy
+// itself saw; earlier hops are supplied by the client and can be forged.
+// Otherwise the header is ignored and the connection's address is used.
+func getClientID(r *http.Request, behindProxy bool) string {
+	if behindProxy {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if ip := net.ParseIP(strings.TrimSpace(hops[i])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	return r.RemoteAddr
+	return host
 }
 
 func formatDuration(d time.Duration) string {
@@ -47,11 +63,34 @@ type AuthService interface {
 	ValidatePassword(username, password string) error
 	GenerateToken(username string) (string, error)
 	ValidateToken(token string) (*domain.User, error)
+	TokenTTL() time.Duration
+	TokenAge(token string) (time.Duration, error)
 	CreateUser(username, password string) error
+	AddUser(admin *domain.User, username, password string) error
+	ListUsers() ([]domain.User, error)
 	ChangePassword(username, oldPassword, newPassword string) error
+	CreateAPIKey(userID int64, label string) (string, *domain.APIKey, error)
+	ListAPIKeys(userID int64) ([]domain.APIKey, error)
+	RevokeAPIKey(userID, keyID int64) error
+	ValidateAPIKey(raw string) (*domain.User, error)
+	GenerateRecoveryCode(userID int64) (string, error)
+	HasRecoveryCode(userID int64) (bool, error)
+	ResetPasswordWithCode(code, newPassword string) error
+	TOTPStatus(userID int64) (*domain.TOTPStatus, error)
+	BeginTOTPEnrollment(userID int64) (string, error)
+	EnableTOTP(userID int64, code string) ([]string, error)
+	DisableTOTP(userID int64, code string) error
+	RequiresTOTP(username string) (bool, error)
+	GenerateTOTPChallenge(username string) (string, error)
+	VerifyTOTPChallenge(challenge, code string) (*domain.User, error)
+	LoginWithOIDC(identity *domain.OIDCIdentity) (*domain.User, error)
 }
 
-func AuthMiddleware(authSvc AuthService, next http.HandlerFunc) http.HandlerFunc {
+// maxSessionRenewWindow caps how close to expiry a session must be before
+// AuthMiddleware renews it.
+const maxSessionRenewWindow = 24 * time.Hour
+
+func AuthMiddleware(authSvc AuthService, behindProxy bool, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		hasUser, err := authSvc.HasUser()
 		if err != nil {
@@ -64,6 +103,21 @@ func AuthMiddleware(authSvc AuthService, next http.HandlerFunc) http.HandlerFunc
 			return
 		}
 
+		// An API key never falls back to the session cookie, so a bad key
+		// cannot ride along on a browser session.
+		if raw, ok := bearerToken(r); ok {
+			user, keyErr := authSvc.ValidateAPIKey(raw)
+			if keyErr != nil {
+				logger.Warn.Printf("auth middleware: invalid api key, error=%v, path=%s", keyErr, r.URL.Path)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="sharm"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), userKey, user)
+			next(w, r.WithContext(ctx))
+			return
+		}
+
 		cookie, err := r.Cookie(CookieName)
 		if err != nil {
 			logger.Debug.Printf("auth middleware: no cookie found, path=%s", r.URL.Path)
@@ -78,21 +132,91 @@ func AuthMiddleware(authSvc AuthService, next http.HandlerFunc) http.HandlerFunc
 			return
 		}
 
+		renewSession(w, r, authSvc, user, cookie.Value, behindProxy)
+
 		ctx := context.WithValue(r.Context(), userKey, user)
 		next(w, r.WithContext(ctx))
 	}
 }
 
-func LoginHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter, tracker *ratelimit.LoginAttemptTracker, backoff *ratelimit.Backoff, version string, behindProxy bool) http.HandlerFunc {
+// renewSession issues a fresh session cookie once token enters the last part
+// of its lifetime, so active users stay logged in. Tokens are only renewed
+// near expiry to avoid a Set-Cookie on every request.
+func renewSession(w http.ResponseWriter, r *http.Request, authSvc AuthService, user *domain.User, token string, behindProxy bool) {
+	age, err := authSvc.TokenAge(token)
+	if err != nil {
+		return
+	}
+	ttl := authSvc.TokenTTL()
+	if age < ttl-min(maxSessionRenewWindow, ttl/2) {
+		return
+	}
+
+	fresh, err := authSvc.GenerateToken(user.Username)
+	if err != nil {
+		logger.Error.Printf("auth middleware: failed to renew session for %s: %v", user.Username, err)
+		return
+	}
+	setAuthCookie(w, r, fresh, ttl, behindProxy)
+	logger.Debug.Printf("auth middleware: renewed session for %s", user.Username)
+}
+
+// currentUser returns the user set by AuthMiddleware, or nil on public routes.
+func currentUser(r *http.Request) *domain.User {
+	user, _ := r.Context().Value(userKey).(*domain.User)
+	return user
+}
+
+// currentUserID returns the authenticated user's ID, or 0 when there is none.
+func currentUserID(r *http.Request) int64 {
+	if user := currentUser(r); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// ownsMedia reports whether the authenticated user may manage media: they
+// uploaded it, or it is an ownerless anonymous upload and they are an
+// admin, who must be able to take down abusive uploads before they expire.
+func ownsMedia(r *http.Request, media *domain.Media) bool {
+	user := currentUser(r)
+	if user == nil {
+		return false
+	}
+	return media.OwnerID == user.ID || (media.OwnerID == 0 && user.IsAdmin)
+}
+
+// bearerToken extracts the credential from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// MetricsAuth guards next with a static bearer token. An empty token leaves
+// the endpoint open, for scrapers on a private network.
+func MetricsAuth(token string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got, ok := bearerToken(r)
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func LoginHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter, tracker *ratelimit.LoginAttemptTracker, backoff *ratelimit.Backoff, loginOpts templates.LoginOptions, version string, behindProxy bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		clientID := getClientID(r)
+		clientID := getClientID(r, behindProxy)
 
 		if r.Method == http.MethodGet {
-			renderLogin(w, r, version)
+			renderLogin(w, r, "", loginOpts, version, http.StatusOK)
 			return
 		}
 
-		if r.Method == http.MethodPost {
+		if r.Method == http.MethodPost && loginOpts.Password {
 			username := r.FormValue("username")
 			password := r.FormValue("password")
 
@@ -126,25 +250,29 @@ func LoginHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter,
 				return
 			}
 
-			tracker.RecordSuccess(clientID)
-			rateLimiter.Reset(clientID)
-
-			token, err := authSvc.GenerateToken(username)
+			requiresTOTP, err := authSvc.RequiresTOTP(username)
 			if err != nil {
-				logger.Error.Printf("login: failed to generate token for %s: %v", username, err)
+				logger.Error.Printf("login: failed to check 2fa for %s: %v", username, err)
 				renderFormError(w, r, "Internal error, please try again", http.StatusInternalServerError)
 				return
 			}
-
-			setAuthCookie(w, r, token, behindProxy)
-			logger.Info.Printf("login successful for %s from %s", username, clientID)
-
-			if r.Header.Get("HX-Request") == HXRequestTrue {
-				w.Header().Set("HX-Redirect", "/")
+			if requiresTOTP {
+				// The rate limiter is only reset once the second factor
+				// passes, so a known password does not unlock code guessing.
+				challenge, err := authSvc.GenerateTOTPChallenge(username)
+				if err != nil {
+					logger.Error.Printf("login: failed to create 2fa challenge for %s: %v", username, err)
+					renderFormError(w, r, "Internal error, please try again", http.StatusInternalServerError)
+					return
+				}
+				logger.Info.Printf("login: password accepted for %s from %s, awaiting 2fa", username, clientID)
+				renderLoginTOTP(w, r, challenge, version)
 				return
 			}
 
-			http.Redirect(w, r, "/", http.StatusSeeOther)
+			tracker.RecordSuccess(clientID)
+			rateLimiter.Reset(clientID)
+			completeLogin(w, r, authSvc, username, clientID, behindProxy)
 			return
 		}
 
@@ -152,10 +280,96 @@ func LoginHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter,
 	}
 }
 
-func renderLogin(w http.ResponseWriter, r *http.Request, version string) {
+// LoginTOTPHandler is the second login step for users with 2FA enabled. It
+// takes the challenge issued after the password check and a TOTP or backup
+// code, sharing the login rate limiter and backoff.
+func LoginTOTPHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter, tracker *ratelimit.LoginAttemptTracker, backoff *ratelimit.Backoff, behindProxy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID := getClientID(r, behindProxy)
+
+		challenge := r.FormValue("challenge")
+		code := r.FormValue("code")
+		if challenge == "" || code == "" {
+			renderFormError(w, r, "Authentication code is required", http.StatusBadRequest)
+			return
+		}
+
+		allowed, blockDuration := rateLimiter.Check(clientID)
+		if !allowed {
+			logger.Warn.Printf("2fa attempt: rate limit exceeded from %s, blocked for %v", clientID, blockDuration)
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockDuration.Seconds()))
+			renderFormError(w, r, fmt.Sprintf("Too many attempts. Try again in %s", formatDuration(blockDuration)), http.StatusTooManyRequests)
+			return
+		}
+
+		user, err := authSvc.VerifyTOTPChallenge(challenge, code)
+		switch {
+		case errors.Is(err, service.ErrInvalidChallenge):
+			renderFormError(w, r, "Login expired, please sign in again", http.StatusUnauthorized)
+			return
+		case errors.Is(err, service.ErrInvalidTOTP):
+			tracker.RecordFailure(clientID)
+			failedAttempts := tracker.GetFailedAttempts(clientID)
+			if backoffDuration := backoff.Duration(failedAttempts); backoffDuration > 0 {
+				logger.Info.Printf("2fa attempt: invalid code from %s (attempt %d), backing off for %v", clientID, failedAttempts, backoffDuration)
+				time.Sleep(backoffDuration)
+			} else {
+				logger.Info.Printf("2fa attempt: invalid code from %s (attempt %d)", clientID, failedAttempts)
+			}
+			renderFormError(w, r, "Invalid authentication code", http.StatusUnauthorized)
+			return
+		case err != nil:
+			logger.Error.Printf("2fa: failed to verify code: %v", err)
+			renderFormError(w, r, "Internal error, please try again", http.StatusInternalServerError)
+			return
+		}
+
+		tracker.RecordSuccess(clientID)
+		rateLimiter.Reset(clientID)
+		completeLogin(w, r, authSvc, user.Username, clientID, behindProxy)
+	}
+}
+
+// completeLogin issues the session cookie and sends the browser home.
+func completeLogin(w http.ResponseWriter, r *http.Request, authSvc AuthService, username, clientID string, behindProxy bool) {
+	token, err := authSvc.GenerateToken(username)
+	if err != nil {
+		logger.Error.Printf("login: failed to generate token for %s: %v", username, err)
+		renderFormError(w, r, "Internal error, please try again", http.StatusInternalServerError)
+		return
+	}
+
+	setAuthCookie(w, r, token, authSvc.TokenTTL(), behindProxy)
+	logger.Info.Printf("login successful for %s from %s", username, clientID)
+
+	if r.Header.Get("HX-Request") == HXRequestTrue {
+		w.Header().Set("HX-Redirect", "/")
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// renderLoginTOTP replaces the password form with the authentication code
+// form. HTMX requests swap it into the login card.
+func renderLoginTOTP(w http.ResponseWriter, r *http.Request, challenge, version string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Header.Get("HX-Request") == HXRequestTrue {
+		w.Header().Set("HX-Retarget", "#login-card")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		_ = templates.LoginTOTPStep(challenge).Render(r.Context(), w)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
-	_ = templates.Login("", version).Render(r.Context(), w)
+	_ = templates.LoginTOTP(challenge, version).Render(r.Context(), w)
+}
+
+func renderLogin(w http.ResponseWriter, r *http.Request, errorMsg string, loginOpts templates.LoginOptions, version string, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = templates.Login(errorMsg, loginOpts, version).Render(r.Context(), w)
 }
 
 func LogoutHandler(behindProxy bool) http.HandlerFunc {
@@ -223,7 +437,22 @@ func SetupHandler(authSvc AuthService, version string, behindProxy bool) http.Ha
 				return
 			}
 
-			setAuthCookie(w, r, token, behindProxy)
+			setAuthCookie(w, r, token, authSvc.TokenTTL(), behindProxy)
+
+			// Resolve the new account through its token to issue the
+			// one-time recovery code shown in place of the redirect.
+			user, err := authSvc.ValidateToken(token)
+			if err == nil {
+				var code string
+				code, err = authSvc.GenerateRecoveryCode(user.ID)
+				if err == nil {
+					renderSetupComplete(w, r, code, version)
+					return
+				}
+			}
+			// The account is usable without a recovery code; one can be
+			// generated later from settings.
+			logger.Error.Printf("setup: failed to generate recovery code for %s: %v", username, err)
 
 			if r.Header.Get("HX-Request") == HXRequestTrue {
 				w.Header().Set("HX-Redirect", "/")
@@ -238,6 +467,22 @@ func SetupHandler(authSvc AuthService, version string, behindProxy bool) http.Ha
 	}
 }
 
+// renderSetupComplete shows the one-time recovery code after setup. HTMX
+// requests swap it into the setup card in place of the form.
+func renderSetupComplete(w http.ResponseWriter, r *http.Request, code, version string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Header.Get("HX-Request") == HXRequestTrue {
+		w.Header().Set("HX-Retarget", "#setup-card")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		_ = templates.RecoveryCodeNotice(code).Render(r.Context(), w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = templates.SetupComplete(code, version).Render(r.Context(), w)
+}
+
 func ChangePasswordHandler(authSvc AuthService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, ok := r.Context().Value(userKey).(*domain.User)
@@ -284,12 +529,13 @@ func renderFormError(w http.ResponseWriter, r *http.Request, msg string, status
 	_ = templates.FormError(msg).Render(r.Context(), w)
 }
 
-func setAuthCookie(w http.ResponseWriter, r *http.Request, token string, behindProxy bool) {
+// setAuthCookie stores the session token in a cookie that expires with it.
+func setAuthCookie(w http.ResponseWriter, r *http.Request, token string, ttl time.Duration, behindProxy bool) {
 	secure := r.TLS != nil || behindProxy
 	http.SetCookie(w, &http.Cookie{
 		Name:     CookieName,
 		Value:    token,
-		MaxAge:   CookieMaxAge,
+		MaxAge:   int(ttl.Seconds()),
 		Path:     CookiePath,
 		Secure:   secure,
 		HttpOnly: true,
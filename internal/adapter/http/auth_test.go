@@ -0,0 +1,246 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAuthService accepts a single API key and no session tokens.
+type stubAuthService struct {
+	AuthService
+	apiKey string
+}
+
+func (s stubAuthService) HasUser() (bool, error) { return true, nil }
+
+func (s stubAuthService) ValidateToken(string) (*domain.User, error) {
+	return nil, service.ErrInvalidToken
+}
+
+func (s stubAuthService) ValidateAPIKey(raw string) (*domain.User, error) {
+	if raw != s.apiKey {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &domain.User{ID: 1, Username: "admin"}, nil
+}
+
+func (s stubAuthService) ResetPasswordWithCode(code, _ string) error {
+	if code != s.apiKey {
+		return service.ErrInvalidRecovery
+	}
+	return nil
+}
+
+func TestRecoverHandler(t *testing.T) {
+	svc := stubAuthService{apiKey: "ABCD-EFGH"}
+	limiter := ratelimit.NewLoginRateLimiter(5, time.Minute, time.Minute)
+	tracker := ratelimit.NewLoginAttemptTracker()
+	backoff := ratelimit.NewBackoff(0, 0, 1)
+	h := RecoverHandler(svc, limiter, tracker, backoff, "test", false)
+
+	post := func(code string) *httptest.ResponseRecorder {
+		form := url.Values{"code": {code}, "new_password": {"N3w-P@ssword"}, "confirm_password": {"N3w-P@ssword"}}
+		req := httptest.NewRequest(http.MethodPost, "/recover", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", HXRequestTrue)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	rec := post("WRONG")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, 1, tracker.GetFailedAttempts(getClientID(httptest.NewRequest(http.MethodPost, "/recover", nil), false)))
+
+	rec = post("ABCD-EFGH")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "#recover-card", rec.Header().Get("HX-Retarget"))
+	assert.Contains(t, rec.Body.String(), "Password reset")
+}
+
+func TestMetricsAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			MetricsAuth(tt.token, ok)(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(userKey).(*domain.User)
+		assert.NotNil(t, user)
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := AuthMiddleware(stubAuthService{apiKey: "sharm_1_secret"}, false, next)
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid key", "Bearer sharm_1_secret", http.StatusOK},
+		{"invalid key", "Bearer sharm_1_nope", http.StatusUnauthorized},
+		{"no header falls back to login", "", http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+// sessionStub accepts any session token issued age ago.
+type sessionStub struct {
+	AuthService
+	age time.Duration
+}
+
+func (sessionStub) HasUser() (bool, error) { return true, nil }
+func (sessionStub) ValidateToken(string) (*domain.User, error) {
+	return &domain.User{ID: 1, Username: "admin"}, nil
+}
+func (s sessionStub) TokenAge(string) (time.Duration, error) { return s.age, nil }
+func (sessionStub) TokenTTL() time.Duration                  { return 7 * 24 * time.Hour }
+func (sessionStub) GenerateToken(string) (string, error)     { return "fresh", nil }
+
+func TestAuthMiddleware_RenewsSessionNearExpiry(t *testing.T) {
+	next := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name  string
+		age   time.Duration
+		renew bool
+	}{
+		{"fresh session", time.Hour, false},
+		{"outside renewal window", 5 * 24 * time.Hour, false},
+		{"within renewal window", 6*24*time.Hour + time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AuthMiddleware(sessionStub{age: tt.age}, false, next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: "old"})
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			cookies := rec.Result().Cookies()
+			if !tt.renew {
+				assert.Empty(t, cookies)
+				return
+			}
+			require.Len(t, cookies, 1)
+			assert.Equal(t, "fresh", cookies[0].Value)
+			assert.Equal(t, int((7 * 24 * time.Hour).Seconds()), cookies[0].MaxAge)
+		})
+	}
+}
+
+// totpLoginStub accepts any password for a user with 2FA enabled.
+type totpLoginStub struct {
+	AuthService
+}
+
+func (totpLoginStub) ValidatePassword(string, string) error        { return nil }
+func (totpLoginStub) RequiresTOTP(string) (bool, error)            { return true, nil }
+func (totpLoginStub) GenerateTOTPChallenge(string) (string, error) { return "challenge", nil }
+
+func TestLoginHandler_AsksForSecondFactor(t *testing.T) {
+	limiter := ratelimit.NewLoginRateLimiter(5, time.Minute, time.Minute)
+	h := LoginHandler(totpLoginStub{}, limiter, ratelimit.NewLoginAttemptTracker(), ratelimit.NewBackoff(0, 0, 1), templates.LoginOptions{Password: true}, "test", false)
+
+	form := url.Values{"username": {"admin"}, "password": {"secret"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", HXRequestTrue)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Result().Cookies(), "no session before the second factor")
+	assert.Equal(t, "#login-card", rec.Header().Get("HX-Retarget"))
+	assert.Contains(t, rec.Body.String(), `name="challenge" value="challenge"`)
+}
+
+func TestTOTPQRCode(t *testing.T) {
+	qr, secret, err := totpQRCode("otpauth://totp/Sharm:admin?issuer=Sharm&secret=JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+	assert.True(t, strings.HasPrefix(qr, "data:image/png;base64,"))
+}
+
+func TestGetClientID(t *testing.T) {
+	tests := []struct {
+		name        string
+		behindProxy bool
+		forwarded   []string
+		want        string
+	}{
+		{"direct ignores header", false, []string{"203.0.113.9"}, "192.0.2.1"},
+		{"direct without header", false, nil, "192.0.2.1"},
+		{"proxy uses last hop", true, []string{"203.0.113.9"}, "203.0.113.9"},
+		{"proxy ignores spoofed hops", true, []string{"198.51.100.7, 203.0.113.9"}, "203.0.113.9"},
+		{"proxy reads last header", true, []string{"198.51.100.7", "203.0.113.9"}, "203.0.113.9"},
+		{"proxy skips garbage", true, []string{"203.0.113.9, not-an-ip"}, "203.0.113.9"},
+		{"proxy without header", true, nil, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:54321"
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, tt.want, getClientID(req, tt.behindProxy))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// MetadataBackup snapshots and restores the metadata store.
+type MetadataBackup interface {
+	// BackupName is the file name the snapshot is saved as.
+	BackupName() string
+	Backup(ctx context.Context, w io.Writer) error
+	// Restore replaces the store's contents with the snapshot saved at
+	// snapshotPath.
+	Restore(ctx context.Context, snapshotPath string) error
+}
+
+// backupMediaDirs are the directories of the data dir holding media files.
+// The secret key is left out on purpose: a leaked backup must not let
+// anyone forge sessions.
+var backupMediaDirs = []string{"uploads", "converted"}
+
+// AdminBackupHandler streams a consistent snapshot of the metadata store
+// as a download. With ?media=true it streams a .tar.gz holding the snapshot
+// and every media file instead. Admin only.
+func AdminBackupHandler(backup MetadataBackup, dataDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+
+		// Archives with all media take longer to send than the server's
+		// write timeout allows.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		withMedia := r.URL.Query().Get("media") == "true"
+		logger.Info.Printf("backup requested by %s, media=%t", user.Username, withMedia)
+
+		// The snapshot is taken to a temporary file before anything is
+		// sent, so a failure still gets a proper error response and the
+		// archive knows its size for the tar header.
+		snapshot, err := os.CreateTemp(dataDir, ".backup-*")
+		if err != nil {
+			logger.Error.Printf("backup failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "backup failed")
+			return
+		}
+		defer func() {
+			_ = snapshot.Close()
+			_ = os.Remove(snapshot.Name())
+		}()
+		if err := backup.Backup(r.Context(), snapshot); err != nil {
+			logger.Error.Printf("backup failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "backup failed")
+			return
+		}
+		if _, err := snapshot.Seek(0, io.SeekStart); err != nil {
+			logger.Error.Printf("backup failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "backup failed")
+			return
+		}
+
+		stamp := time.Now().UTC().Format("20060102-150405")
+		if !withMedia {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "sharm-"+stamp+"-"+backup.BackupName()))
+			if _, err := io.Copy(w, snapshot); err != nil {
+				logger.Error.Printf("backup download failed: %v", err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "sharm-"+stamp+".tar.gz"))
+		if err := writeBackupArchive(w, snapshot, backup.BackupName(), dataDir); err != nil {
+			logger.Error.Printf("backup archive failed: %v", err)
+		}
+	}
+}
+
+// writeBackupArchive writes a gzipped tarball of the metadata snapshot,
+// saved as name, followed by the media files under dataDir.
+func writeBackupArchive(w io.Writer, snapshot *os.File, name, dataDir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := addToArchive(tw, snapshot, name); err != nil {
+		return err
+	}
+
+	for _, dir := range backupMediaDirs {
+		err := filepath.WalkDir(filepath.Join(dataDir, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			// Skip directories and files still being written, which are
+			// either hidden or end in .part.
+			if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") || filepath.Ext(path) == ".part" {
+				return nil
+			}
+			rel, err := filepath.Rel(dataDir, path)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(path) //nolint:gosec // path is walked from the data dir
+			if err != nil {
+				// Media deleted since the walk listed it.
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			return addToArchive(tw, f, filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addToArchive(tw *tar.Writer, f *os.File, name string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubBackup struct {
+	restored   string
+	restoreErr error
+}
+
+func (*stubBackup) BackupName() string { return "sharm.db" }
+
+func (*stubBackup) Backup(_ context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, "snapshot")
+	return err
+}
+
+func (s *stubBackup) Restore(_ context.Context, snapshotPath string) error {
+	if s.restoreErr != nil {
+		return s.restoreErr
+	}
+	data, err := os.ReadFile(snapshotPath)
+	s.restored = string(data)
+	return err
+}
+
+func TestAdminBackupHandler(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "uploads"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "uploads", "AB12CD34_clip.mp4"), []byte("video"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "uploads", ".x.mp4.123.part"), []byte("partial"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "converted"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "converted", ".AB12CD34_av1.part.webm"), []byte("partial"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, ".secret_key"), []byte("secret"), 0600))
+	handler := AdminBackupHandler(&stubBackup{}, dataDir)
+	admin := &domain.User{ID: 1, Username: "admin", IsAdmin: true}
+
+	request := func(target string, user *domain.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("/admin/backup", &domain.User{ID: 2}).Code)
+
+	rec := request("/admin/backup", admin)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "snapshot", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "sharm.db")
+
+	rec = request("/admin/backup?media=true", admin)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"sharm.db":                  "snapshot",
+		"uploads/AB12CD34_clip.mp4": "video",
+	}, files)
+
+	entries, err := os.ReadDir(dataDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "the temporary snapshot should be removed")
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// chunkBaseDir returns the directory holding in-progress chunked uploads.
+func chunkBaseDir() string {
+	return filepath.Join(os.TempDir(), "sharm-chunks")
+}
+
+// chunkStorageSize returns the total number of bytes stored under dir.
+// A missing directory counts as empty.
+func chunkStorageSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			// Chunk removed concurrently (sweeper or completed upload)
+			if os.IsNotExist(infoErr) {
+				return nil
+			}
+			return infoErr
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// sweepStaleChunks removes upload directories under dir that have not been
+// modified within ttl. It returns the number of directories removed.
+func sweepStaleChunks(dir string, ttl time.Duration) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if removeErr := os.RemoveAll(path); removeErr != nil {
+			logger.Error.Printf("failed to remove stale chunk dir %s: %v", path, removeErr)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// SweepStaleChunks removes chunked uploads abandoned for longer than ttl.
+func SweepStaleChunks(ttl time.Duration) (int, error) {
+	return sweepStaleChunks(chunkBaseDir(), ttl)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkStorageSize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "upload-a"), 0750))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "upload-b"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "upload-a", "0"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "upload-a", "1"), make([]byte, 50), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "upload-b", "0"), make([]byte, 25), 0600))
+
+	size, err := chunkStorageSize(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(175), size)
+}
+
+func TestChunkStorageSize_MissingDir(t *testing.T) {
+	size, err := chunkStorageSize(filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+}
+
+func TestSweepStaleChunks(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale")
+	fresh := filepath.Join(dir, "fresh")
+	require.NoError(t, os.MkdirAll(stale, 0750))
+	require.NoError(t, os.MkdirAll(fresh, 0750))
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	removed, err := sweepStaleChunks(dir, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err), "stale upload dir should be removed")
+	_, err = os.Stat(fresh)
+	assert.NoError(t, err, "fresh upload dir should be kept")
+}
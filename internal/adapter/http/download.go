@@ -0,0 +1,142 @@
+package http
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/validation"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// zipFilename returns the archive name for a media's bundled download.
+func zipFilename(originalName string) string {
+	return strings.TrimSuffix(originalName, filepath.Ext(originalName)) + ".zip"
+}
+
+// serveFile serves a media file like http.ServeFile, with ranges and
+// conditional requests, but stops reading it as soon as the client goes
+// away. With a stall timeout, each write to the client must finish within
+// it, which replaces the server's write timeout for the rest of the serve.
+func (h *Handlers) serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	f, err := os.Open(path) //nolint:gosec // path comes from the media store
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	http.ServeContent(h.stallGuard(w), r, info.Name(), info.ModTime(), contextReadSeeker{ctx: r.Context(), ReadSeeker: f})
+}
+
+// serveBlob redirects to a direct URL for path when its blob store hands
+// them out, and serves it like serveFile otherwise. The Content-Type and
+// Content-Disposition already set for the file are signed into the URL for
+// object storage to answer with; the other file headers are dropped.
+func (h *Handlers) serveBlob(w http.ResponseWriter, r *http.Request, path string) {
+	if h.blobLinks != nil {
+		if url, ok := h.blobLinks.BlobURL(r.Context(), path, w.Header().Get("Content-Type"), w.Header().Get("Content-Disposition")); ok {
+			for _, name := range []string{"Content-Type", "Content-Disposition", "Digest", "Content-Digest"} {
+				w.Header().Del(name)
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+	}
+	h.serveFile(w, r, path)
+}
+
+// stallGuard wraps w to enforce the stall timeout, if one is set.
+func (h *Handlers) stallGuard(w http.ResponseWriter) http.ResponseWriter {
+	if h.serveStallTimeout <= 0 {
+		return w
+	}
+	return &stallWriter{ResponseWriter: w, rc: http.NewResponseController(w), timeout: h.serveStallTimeout}
+}
+
+// contextReadSeeker fails reads once ctx is done, so a serve whose client
+// disconnected stops at the next read instead of finishing the file.
+type contextReadSeeker struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+func (c contextReadSeeker) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.ReadSeeker.Read(p)
+}
+
+// stallWriter pushes the connection's write deadline forward before every
+// write, so a download is only cut off once the client stops accepting data.
+type stallWriter struct {
+	http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (s *stallWriter) Write(p []byte) (int, error) {
+	_ = s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *stallWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// writeMediaZip streams the original and every finished variant of media into
+// a ZIP archive written to w. Files missing from disk are skipped. Entries are
+// stored uncompressed since the media is already compressed.
+func writeMediaZip(ctx context.Context, w io.Writer, media *domain.Media) error {
+	zw := zip.NewWriter(w)
+
+	if media.OriginalPath != "" {
+		if err := addZipEntry(ctx, zw, validation.SanitizeFilename(media.OriginalName), media.OriginalPath); err != nil {
+			return err
+		}
+	}
+	for _, v := range media.Variants {
+		if v.Status != domain.VariantStatusDone || v.Path == "" {
+			continue
+		}
+		name := validation.SanitizeFilename(variantFilename(media.OriginalName, v.Codec))
+		if err := addZipEntry(ctx, zw, name, v.Path); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// addZipEntry copies the file at path into zw under name. A file that cannot
+// be opened is logged and skipped; only write errors are returned, or ctx's
+// error once it is done.
+func addZipEntry(ctx context.Context, zw *zip.Writer, name, path string) error {
+	f, err := os.Open(path) //nolint:gosec // path comes from the media store
+	if err != nil {
+		logger.Error.Printf("skipping %s in zip download: %v", path, err)
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, contextReadSeeker{ctx: ctx, ReadSeeker: f})
+	return err
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMediaZip_SkipsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.mov")
+	h264 := filepath.Join(dir, "h264.mp4")
+	require.NoError(t, os.WriteFile(original, []byte("original-bytes"), 0600))
+	require.NoError(t, os.WriteFile(h264, []byte("h264-bytes"), 0600))
+
+	media := &domain.Media{
+		OriginalName: "clip.mov",
+		OriginalPath: original,
+		Variants: []domain.Variant{
+			{Codec: domain.CodecH264, Status: domain.VariantStatusDone, Path: h264},
+			{Codec: domain.CodecAV1, Status: domain.VariantStatusDone, Path: filepath.Join(dir, "missing.webm")},
+			{Codec: domain.CodecOpus, Status: domain.VariantStatusPending},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeMediaZip(context.Background(), &buf, media))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		contents[f.Name] = string(data)
+	}
+
+	assert.Equal(t, map[string]string{
+		"clip.mov":      "original-bytes",
+		"clip.h264.mp4": "h264-bytes",
+	}, contents)
+}
+
+func TestZipFilename(t *testing.T) {
+	assert.Equal(t, "clip.zip", zipFilename("clip.mov"))
+	assert.Equal(t, "archive.tar.zip", zipFilename("archive.tar.gz"))
+}
+
+func TestServeFile_Ranges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0600))
+	h := &Handlers{serveStallTimeout: time.Minute}
+
+	req := httptest.NewRequest(http.MethodGet, "/v/abc/raw", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec := httptest.NewRecorder()
+	h.serveFile(rec, req, path)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "2345", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.serveFile(rec, httptest.NewRequest(http.MethodGet, "/v/abc/raw", nil), filepath.Join(t.TempDir(), "gone.mp4"))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServeFile_StopsWhenClientIsGone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), 1<<20), 0600))
+	h := &Handlers{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	h.serveFile(rec, httptest.NewRequest(http.MethodGet, "/v/abc/raw", nil).WithContext(ctx), path)
+
+	assert.Zero(t, rec.Body.Len(), "nothing should be read for a gone client")
+}
+
+func TestWriteMediaZip_StopsWhenClientIsGone(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.mov")
+	require.NoError(t, os.WriteFile(original, []byte("original-bytes"), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := writeMediaZip(ctx, io.Discard, &domain.Media{OriginalName: "clip.mov", OriginalPath: original})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,18 +1,26 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bnema/sharm/internal/adapter/http/templates"
 	"github.com/bnema/sharm/internal/adapter/http/validation"
 	"github.com/bnema/sharm/internal/domain"
 	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/static"
 )
 
 const (
@@ -24,54 +32,318 @@ const (
 )
 
 type MediaService interface {
-	Upload(filename string, file *os.File, retentionDays int, mediaType domain.MediaType, codecs []domain.Codec, fps int) (*domain.Media, error)
+	Upload(
+		ownerID int64, filename string, file *os.File, retention time.Duration, mediaType domain.MediaType, codecs []domain.Codec, fps int,
+		tags []string, slug string,
+	) (*domain.Media, error)
 	Get(id string) (*domain.Media, error)
-	ListAll() ([]*domain.Media, error)
+	Lookup(id string) (*domain.Media, error)
+	ListAll(ownerID int64) ([]*domain.Media, error)
+	ListPaged(ownerID int64, sort domain.SortBy, limit, offset int) ([]*domain.Media, int, error)
+	ListByTag(ownerID int64, tag string) ([]*domain.Media, error)
+	Search(ownerID int64, query string) ([]*domain.Media, error)
+	ListByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error)
 	Delete(id string) error
+	DeleteMany(ownerID int64, ids []string) (int, map[string]error)
 	ProbeFile(filePath string) (*domain.ProbeResult, error)
+	Checksum(mediaID, file, path string) (string, error)
+	FindByChecksum(ownerID int64, sum string) (*domain.Media, error)
+	RequestVariant(media *domain.Media, codec domain.Codec) (*domain.Variant, error)
+	Cancel(media *domain.Media) error
+	JobLogs(id string) (*domain.Media, []domain.Job, error)
+	ReconvertFailed() (requeued, skipped int, err error)
+	RegenerateThumbnails() (queued, skipped int, err error)
+	Stats() (domain.StorageStats, error)
+	OwnerStats(ownerID int64) (domain.StorageStats, error)
+}
+
+// DiskStatus reports whether the data volume is too full to accept uploads.
+type DiskStatus interface {
+	Low() bool
+}
+
+// BlobLinker hands out direct URLs to media files kept in object storage.
+type BlobLinker interface {
+	BlobURL(ctx context.Context, path, contentType, disposition string) (string, bool)
 }
 
 type Handlers struct {
-	mediaSvc  MediaService
-	domain    string
-	maxSizeMB int
-	version   string
+	mediaSvc      MediaService
+	domain        string
+	maxSizeMB     int
+	typeMaxSizeMB map[domain.MediaType]int
+	version       string
+	chunkMaxBytes int64
+	ogImagePath   string
+	diskStatus    DiskStatus
+	mimeAllowlist validation.MIMEAllowlist
+
+	// rejectTypeMismatch refuses uploads whose content is not the kind of
+	// media their extension claims instead of correcting the type.
+	rejectTypeMismatch bool
+
+	// retention bounds the retention uploads may ask for.
+	retention domain.RetentionPolicy
+
+	// location is the time zone dates are shown in.
+	location *time.Location
+
+	// serveStallTimeout aborts a media serve once the client has accepted
+	// no data for this long; 0 leaves it to the server's write timeout.
+	serveStallTimeout time.Duration
+
+	// blobLinks redirects media downloads to object storage; nil serves
+	// every file from local disk.
+	blobLinks BlobLinker
+
+	// botCodec is served from /v/{id}/raw to link-preview bots that accept
+	// any format; empty leaves them to Accept negotiation.
+	botCodec domain.Codec
+
+	// processingPlaceholder is a file served from /v/{id}/raw while the
+	// media has no finished output yet; empty serves the original.
+	processingPlaceholder string
+
+	// dashboardCache holds rendered dashboard pages; nil when caching is off.
+	dashboardCache *pageCache
 }
 
-func NewHandlers(mediaSvc MediaService, domainName string, maxSizeMB int, version string) *Handlers {
+// HandlerConfig holds the settings of the page and API handlers; zero values
+// disable the optional behaviour.
+type HandlerConfig struct {
+	Domain        string
+	Version       string
+	MaxSizeMB     int
+	TypeMaxSizeMB map[domain.MediaType]int
+	ChunkMaxBytes int64
+	OGImagePath   string
+	DiskStatus    DiskStatus
+
+	// DashboardCacheTTL keeps rendered dashboard pages this long; 0 turns
+	// the cache off.
+	DashboardCacheTTL time.Duration
+
+	// AllowedMIMETypes narrows the accepted uploads; empty accepts every
+	// supported type.
+	AllowedMIMETypes   []string
+	RejectTypeMismatch bool
+	Retention          domain.RetentionPolicy
+	Location           *time.Location
+	ServeStallTimeout  time.Duration
+	BlobLinks          BlobLinker
+
+	BotCodec              domain.Codec
+	ProcessingPlaceholder string
+}
+
+func NewHandlers(mediaSvc MediaService, cfg HandlerConfig) *Handlers {
 	return &Handlers{
-		mediaSvc:  mediaSvc,
-		domain:    domainName,
-		maxSizeMB: maxSizeMB,
-		version:   version,
+		mediaSvc:      mediaSvc,
+		domain:        cfg.Domain,
+		maxSizeMB:     cfg.MaxSizeMB,
+		typeMaxSizeMB: cfg.TypeMaxSizeMB,
+		version:       cfg.Version,
+		chunkMaxBytes: cfg.ChunkMaxBytes,
+		ogImagePath:   cfg.OGImagePath,
+		diskStatus:    cfg.DiskStatus,
+		mimeAllowlist: validation.NewMIMEAllowlist(cfg.AllowedMIMETypes),
+
+		rejectTypeMismatch: cfg.RejectTypeMismatch,
+		retention:          cfg.Retention,
+		location:           cfg.Location,
+		serveStallTimeout:  cfg.ServeStallTimeout,
+		blobLinks:          cfg.BlobLinks,
+		botCodec:           cfg.BotCodec,
+
+		processingPlaceholder: cfg.ProcessingPlaceholder,
+
+		dashboardCache: newPageCache(cfg.DashboardCacheTTL),
 	}
 }
 
+// uploadsPaused reports whether uploads are refused because disk space is low.
+func (h *Handlers) uploadsPaused() bool {
+	return h.diskStatus != nil && h.diskStatus.Low()
+}
+
+// errDiskLow is shown when uploads are paused for low disk space.
+const errDiskLow = "Server storage is almost full, uploads are paused"
+
+// dashboardPageSize is the number of media rows shown per dashboard page.
+const dashboardPageSize = 50
+
 func (h *Handlers) Dashboard() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		media, err := h.mediaSvc.ListAll()
+		tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+		props := templates.DashboardProps{
+			DomainName: h.domain,
+			Version:    h.version,
+			ActiveTag:  tag,
+			Sort:       domain.ParseSortBy(r.URL.Query().Get("sort")),
+			Page:       1,
+			TotalPages: 1,
+			Location:   h.location,
+		}
+
+		ownerID := currentUserID(r)
+		cacheKey := fmt.Sprintf("%d|%s|%s|%s", ownerID, tag, props.Sort, r.URL.Query().Get("page"))
+		if body, ok := h.dashboardCache.Get(cacheKey); ok {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(body)
+			return
+		}
+
+		var err error
+		if tag != "" {
+			props.Media, err = h.mediaSvc.ListByTag(ownerID, tag)
+		} else {
+			props.Page = parsePage(r.URL.Query().Get("page"))
+			var total int
+			props.Media, total, err = h.mediaSvc.ListPaged(ownerID, props.Sort, dashboardPageSize, (props.Page-1)*dashboardPageSize)
+			props.TotalPages = max(1, (total+dashboardPageSize-1)/dashboardPageSize)
+		}
 		if err != nil {
 			logger.Error.Printf("dashboard list error: %v", err)
+			props.Media = []*domain.Media{}
+		}
+
+		var buf bytes.Buffer
+		if renderErr := templates.Dashboard(props).Render(r.Context(), &buf); renderErr != nil {
+			logger.Error.Printf("dashboard render error: %v", renderErr)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		// Don't cache an empty page produced by a failed query
+		if err == nil {
+			h.dashboardCache.Set(cacheKey, buf.Bytes())
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// parsePage reads a 1-based page number, falling back to the first page.
+func parsePage(value string) int {
+	page, err := strconv.Atoi(value)
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// Search filters the library by filename or tag. HTMX requests get only the
+// results fragment so the search box can update the list as the user types.
+func (h *Handlers) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+		media, err := h.mediaSvc.Search(currentUserID(r), query)
+		if err != nil {
+			logger.Error.Printf("search error for %q: %v", query, err)
 			media = []*domain.Media{}
 		}
 
+		props := templates.DashboardProps{
+			Media:      media,
+			DomainName: h.domain,
+			Version:    h.version,
+			Query:      query,
+			Location:   h.location,
+		}
+
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_ = templates.Dashboard(media, h.domain, h.version).Render(r.Context(), w)
+		if r.Header.Get("HX-Request") == "true" {
+			_ = templates.DashboardResults(media, h.domain, templates.DashboardEmptyMessage(props), h.location).Render(r.Context(), w)
+			return
+		}
+		_ = templates.Dashboard(props).Render(r.Context(), w)
 	}
 }
 
 func (h *Handlers) UploadPage() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_ = templates.Upload(h.version).Render(r.Context(), w)
+		_ = templates.Upload(h.version, h.retention).Render(r.Context(), w)
+	}
+}
+
+// maxUploadMB returns the size cap for mediaType, falling back to the global limit.
+func (h *Handlers) maxUploadMB(mediaType domain.MediaType) int {
+	if mb := h.typeMaxSizeMB[mediaType]; mb > 0 {
+		return mb
+	}
+	return h.maxSizeMB
+}
+
+// requestMaxBytes bounds an upload request body before its media type is
+// known, so it is the largest of the global and per-type limits.
+func (h *Handlers) requestMaxBytes() int64 {
+	mb := h.maxSizeMB
+	for _, typeMB := range h.typeMaxSizeMB {
+		mb = max(mb, typeMB)
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// declaresTooLarge reports whether the request announces a body longer than
+// limit, so it can be refused before any of it is read. Requests without a
+// Content-Length are still cut off by http.MaxBytesReader. The connection is
+// closed rather than drained of the unread body.
+func declaresTooLarge(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	if r.ContentLength <= limit {
+		return false
+	}
+	w.Header().Set("Connection", "close")
+	return true
+}
+
+// exceedsTypeLimit renders a 413 and returns true when size is over the cap for mediaType.
+func (h *Handlers) exceedsTypeLimit(w http.ResponseWriter, r *http.Request, mediaType domain.MediaType, size int64) bool {
+	limitMB := h.maxUploadMB(mediaType)
+	if size <= int64(limitMB)*1024*1024 {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_ = templates.ErrorInline(fmt.Sprintf("File too large: %s uploads are limited to %d MB", mediaType, limitMB)).Render(r.Context(), w)
+	return true
+}
+
+// uploadMediaType resolves the media type of an upload from its extension
+// and sniffed mime. When they disagree and mismatches are rejected, it
+// renders a 400 and returns false.
+func (h *Handlers) uploadMediaType(w http.ResponseWriter, r *http.Request, filename, mime string) (domain.MediaType, bool) {
+	detected := domain.DetectMediaType(filename)
+	mediaType := domain.MediaTypeFromMIME(detected, mime)
+	if mediaType != detected && h.rejectTypeMismatch {
+		logger.Warn.Printf("rejected upload %s: %s content with a %s extension", logger.SanitizeForLog(filename), mime, detected)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = templates.ErrorInline("File content does not match its extension").Render(r.Context(), w)
+		return "", false
 	}
+	return mediaType, true
 }
 
 func (h *Handlers) Upload() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxSizeMB)*1024*1024)
+		if h.uploadsPaused() {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInsufficientStorage)
+			_ = templates.ErrorInline(errDiskLow).Render(r.Context(), w)
+			return
+		}
+
+		if declaresTooLarge(w, r, h.requestMaxBytes()) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = templates.ErrorInline("File too large").Render(r.Context(), w)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, h.requestMaxBytes())
 
-		if err := r.ParseMultipartForm(int64(h.maxSizeMB) * 1024 * 1024); err != nil {
+		if err := r.ParseMultipartForm(h.requestMaxBytes()); err != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			w.WriteHeader(http.StatusRequestEntityTooLarge)
 			_ = templates.ErrorInline("File too large").Render(r.Context(), w)
@@ -88,7 +360,7 @@ func (h *Handlers) Upload() http.HandlerFunc {
 		defer file.Close() //nolint:errcheck
 
 		// Validate file type using magic bytes
-		_, allowed, err := validation.ValidateMagicBytes(file)
+		mime, allowed, err := validation.ValidateMagicBytes(file, h.mimeAllowlist)
 		if err != nil {
 			logger.Error.Printf("magic bytes validation error for %s: %v", logger.SanitizeForLog(header.Filename), err)
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -115,46 +387,40 @@ func (h *Handlers) Upload() http.HandlerFunc {
 			_ = os.Remove(tmpFile.Name()) // may already be moved by service
 		}()
 
-		if _, copyErr := io.Copy(tmpFile, file); copyErr != nil {
+		size, copyErr := io.Copy(tmpFile, file)
+		if copyErr != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			w.WriteHeader(http.StatusInternalServerError)
 			_ = templates.ErrorInline("Failed to save file").Render(r.Context(), w)
 			return
 		}
 
-		retentionStr := r.FormValue("retention")
-		retentionDays, parseErr := strconv.Atoi(retentionStr)
-		if parseErr != nil {
-			retentionDays = 7
+		mediaType, ok := h.uploadMediaType(w, r, header.Filename, mime)
+		if !ok {
+			return
 		}
-		if err != nil {
-			retentionDays = 7
+		if h.exceedsTypeLimit(w, r, mediaType, size) {
+			return
 		}
 
+		retention := h.uploadRetention(r.FormValue("retention"))
+
 		// Parse selected codecs from form
 		var codecs []domain.Codec
 		for _, c := range r.Form["codecs"] {
 			switch domain.Codec(c) {
-			case domain.CodecAV1, domain.CodecH264, domain.CodecOpus:
+			case domain.CodecAV1, domain.CodecH264, domain.CodecOpus, domain.CodecAuto:
 				codecs = append(codecs, domain.Codec(c))
 			}
 		}
 
 		fps, _ := strconv.Atoi(r.FormValue("fps"))
 
-		mediaType := domain.DetectMediaType(header.Filename)
-		_, err = h.mediaSvc.Upload(header.Filename, tmpFile, retentionDays, mediaType, codecs, fps)
+		tags := parseTags(r.FormValue("tags"))
+		slug := r.FormValue("slug")
+		_, err = h.mediaSvc.Upload(currentUserID(r), header.Filename, tmpFile, retention, mediaType, codecs, fps, tags, slug)
 		if err != nil {
-			logger.Error.Printf("upload error for %s: %v", logger.SanitizeForLog(header.Filename), err)
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			msg := "Upload failed"
-			if strings.Contains(err.Error(), "no space left") {
-				msg = "Upload failed: disk full"
-			} else if strings.Contains(err.Error(), "permission denied") {
-				msg = "Upload failed: permission error"
-			}
-			_ = templates.ErrorInline(msg).Render(r.Context(), w)
+			renderUploadError(w, r, header.Filename, err)
 			return
 		}
 
@@ -164,8 +430,63 @@ func (h *Handlers) Upload() http.HandlerFunc {
 	}
 }
 
+// uploadRetention parses the retention form value and clamps it into the
+// configured bounds, using the default when it is missing or malformed.
+func (h *Handlers) uploadRetention(value string) time.Duration {
+	retention, err := domain.ParseRetention(value)
+	if value == "" || err != nil {
+		return h.retention.Default
+	}
+	return h.retention.Clamp(retention)
+}
+
+// renderUploadError reports a failed MediaService.Upload. Rejected content
+// is the client's fault and is shown as is; anything else is a server error.
+func renderUploadError(w http.ResponseWriter, r *http.Request, filename string, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	switch {
+	case errors.Is(err, domain.ErrAnimationTooLarge), errors.Is(err, domain.ErrTooManyPixels):
+		logger.Warn.Printf("upload rejected for %s: %v", logger.SanitizeForLog(filename), err)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_ = templates.ErrorInline("Upload rejected: "+err.Error()).Render(r.Context(), w)
+		return
+	case errors.Is(err, domain.ErrUndecodable):
+		logger.Warn.Printf("upload rejected for %s: %v", logger.SanitizeForLog(filename), err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = templates.ErrorInline("Upload rejected: the file is empty or corrupt, no playable audio or video was found").Render(r.Context(), w)
+		return
+	case errors.Is(err, domain.ErrInvalidSlug):
+		w.WriteHeader(http.StatusBadRequest)
+		_ = templates.ErrorInline(fmt.Sprintf("Custom link must be %d to %d letters, digits or dashes",
+			domain.SlugMinLength, domain.SlugMaxLength)).Render(r.Context(), w)
+		return
+	case errors.Is(err, domain.ErrSlugTaken):
+		w.WriteHeader(http.StatusConflict)
+		_ = templates.ErrorInline("This custom link is already taken").Render(r.Context(), w)
+		return
+	}
+
+	logger.Error.Printf("upload error for %s: %v", logger.SanitizeForLog(filename), err)
+	w.WriteHeader(http.StatusInternalServerError)
+	msg := "Upload failed"
+	if strings.Contains(err.Error(), "no space left") {
+		msg = "Upload failed: disk full"
+	} else if strings.Contains(err.Error(), "permission denied") {
+		msg = "Upload failed: permission error"
+	}
+	_ = templates.ErrorInline(msg).Render(r.Context(), w)
+}
+
 const chunkSize = 5 * 1024 * 1024 // 5MB
 
+// parseTags splits a comma-separated tags form value. Normalization happens in the service.
+func parseTags(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 // validateUploadID checks that uploadID is a valid UUID-like string (alphanumeric with dashes).
 func validateUploadID(uploadID string) bool {
 	if uploadID == "" || len(uploadID) > 64 {
@@ -181,6 +502,15 @@ func validateUploadID(uploadID string) bool {
 
 func (h *Handlers) ChunkUpload() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if h.uploadsPaused() {
+			http.Error(w, errDiskLow, http.StatusInsufficientStorage)
+			return
+		}
+
+		if declaresTooLarge(w, r, chunkSize+1024*1024) {
+			http.Error(w, "Chunk too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		r.Body = http.MaxBytesReader(w, r.Body, chunkSize+1024*1024) // chunk + overhead
 
 		if err := r.ParseMultipartForm(chunkSize + 1024*1024); err != nil {
@@ -207,7 +537,7 @@ func (h *Handlers) ChunkUpload() http.HandlerFunc {
 			return
 		}
 
-		file, _, err := r.FormFile("chunk")
+		file, header, err := r.FormFile("chunk")
 		if err != nil {
 			http.Error(w, "Invalid chunk data", http.StatusBadRequest)
 			return
@@ -218,7 +548,22 @@ func (h *Handlers) ChunkUpload() http.HandlerFunc {
 			}
 		}()
 
-		chunkDir := filepath.Join(os.TempDir(), "sharm-chunks", uploadID)
+		// Refuse new chunks once the global chunk quota is exhausted
+		if h.chunkMaxBytes > 0 {
+			used, sizeErr := chunkStorageSize(chunkBaseDir())
+			if sizeErr != nil {
+				logger.Error.Printf("failed to compute chunk storage size: %v", sizeErr)
+				http.Error(w, "Server error", http.StatusInternalServerError)
+				return
+			}
+			if used+header.Size > h.chunkMaxBytes {
+				logger.Warn.Printf("chunk storage quota exceeded: used=%d, incoming=%d, max=%d", used, header.Size, h.chunkMaxBytes)
+				http.Error(w, "Chunk storage full, try again later", http.StatusInsufficientStorage)
+				return
+			}
+		}
+
+		chunkDir := filepath.Join(chunkBaseDir(), uploadID)
 		if mkdirErr := os.MkdirAll(chunkDir, 0750); mkdirErr != nil {
 			logger.Error.Printf("failed to create chunk dir: %v", mkdirErr)
 			http.Error(w, "Server error", http.StatusInternalServerError)
@@ -253,6 +598,11 @@ func (h *Handlers) ChunkUpload() http.HandlerFunc {
 
 func (h *Handlers) CompleteUpload() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if h.uploadsPaused() {
+			http.Error(w, errDiskLow, http.StatusInsufficientStorage)
+			return
+		}
+
 		if err := r.ParseMultipartForm(1024 * 1024); err != nil {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
@@ -261,7 +611,6 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 		uploadID := r.FormValue("uploadId")
 		filename := r.FormValue("filename")
 		totalChunksStr := r.FormValue("totalChunks")
-		retentionStr := r.FormValue("retention")
 
 		if uploadID == "" || filename == "" || totalChunksStr == "" {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
@@ -284,23 +633,20 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 			return
 		}
 
-		retentionDays, err := strconv.Atoi(retentionStr)
-		if err != nil {
-			retentionDays = 7
-		}
+		retention := h.uploadRetention(r.FormValue("retention"))
 
 		// Parse codecs
 		var codecs []domain.Codec
 		for _, c := range r.Form["codecs"] {
 			switch domain.Codec(c) {
-			case domain.CodecAV1, domain.CodecH264, domain.CodecOpus:
+			case domain.CodecAV1, domain.CodecH264, domain.CodecOpus, domain.CodecAuto:
 				codecs = append(codecs, domain.Codec(c))
 			}
 		}
 
 		fps, _ := strconv.Atoi(r.FormValue("fps"))
 
-		chunkDir := filepath.Join(os.TempDir(), "sharm-chunks", uploadID)
+		chunkDir := filepath.Join(chunkBaseDir(), uploadID)
 		defer func() {
 			if removeErr := os.RemoveAll(chunkDir); removeErr != nil {
 				logger.Error.Printf("failed to cleanup chunk dir %s: %v", chunkDir, removeErr)
@@ -323,6 +669,7 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 			}
 		}()
 
+		var size int64
 		for i := range totalChunks {
 			chunkPath := filepath.Join(chunkDir, strconv.Itoa(i))
 			chunk, openErr := os.Open(chunkPath)
@@ -331,7 +678,8 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 				http.Error(w, fmt.Sprintf("Missing chunk %d", i), http.StatusBadRequest)
 				return
 			}
-			_, copyErr := io.Copy(assembled, chunk)
+			n, copyErr := io.Copy(assembled, chunk)
+			size += n
 			if closeErr := chunk.Close(); closeErr != nil {
 				logger.Error.Printf("failed to close chunk %d for upload %s: %v", i, uploadID, closeErr)
 			}
@@ -350,7 +698,7 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 		}
 
 		// Validate assembled file type using magic bytes
-		_, allowed, err := validation.ValidateMagicBytes(assembled)
+		mime, allowed, err := validation.ValidateMagicBytes(assembled, h.mimeAllowlist)
 		if err != nil {
 			logger.Error.Printf("magic bytes validation error for %s: %v", logger.SanitizeForLog(filename), err)
 			http.Error(w, "Failed to validate file type", http.StatusInternalServerError)
@@ -363,19 +711,19 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 			return
 		}
 
-		mediaType := domain.DetectMediaType(filename)
-		_, err = h.mediaSvc.Upload(filename, assembled, retentionDays, mediaType, codecs, fps)
+		mediaType, ok := h.uploadMediaType(w, r, filename, mime)
+		if !ok {
+			return
+		}
+		if h.exceedsTypeLimit(w, r, mediaType, size) {
+			return
+		}
+
+		tags := parseTags(r.FormValue("tags"))
+		slug := r.FormValue("slug")
+		_, err = h.mediaSvc.Upload(currentUserID(r), filename, assembled, retention, mediaType, codecs, fps, tags, slug)
 		if err != nil {
-			logger.Error.Printf("upload error for %s: %v", logger.SanitizeForLog(filename), err)
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			msg := "Upload failed"
-			if strings.Contains(err.Error(), "no space left") {
-				msg = "Upload failed: disk full"
-			} else if strings.Contains(err.Error(), "permission denied") {
-				msg = "Upload failed: permission error"
-			}
-			_ = templates.ErrorInline(msg).Render(r.Context(), w)
+			renderUploadError(w, r, filename, err)
 			return
 		}
 
@@ -384,12 +732,29 @@ func (h *Handlers) CompleteUpload() http.HandlerFunc {
 	}
 }
 
+// renderStatusFragment renders the status page fragment for the current
+// state of media.
+func (h *Handlers) renderStatusFragment(w http.ResponseWriter, r *http.Request, media *domain.Media) {
+	switch media.Status {
+	case domain.MediaStatusPending, domain.MediaStatusProcessing:
+		_ = templates.StatusPolling(media.ID).Render(r.Context(), w)
+	case domain.MediaStatusDone:
+		shareURL := fmt.Sprintf("https://%s/v/%s", h.domain, media.ID)
+		_ = templates.StatusDone(media, shareURL, h.location).Render(r.Context(), w)
+	case domain.MediaStatusFailed:
+		_ = templates.StatusFailed(media.ErrorMessage).Render(r.Context(), w)
+	}
+}
+
 func (h *Handlers) StatusPage() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := strings.TrimPrefix(r.URL.Path, "/status/")
 		id = strings.TrimSuffix(id, "/")
 
 		media, err := h.mediaSvc.Get(id)
+		if err == nil && !ownsMedia(r, media) {
+			err = domain.ErrNotFound
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			w.WriteHeader(http.StatusNotFound)
@@ -405,15 +770,7 @@ func (h *Handlers) StatusPage() http.HandlerFunc {
 
 		// HTMX polling request — return fragment
 		if r.Header.Get("HX-Request") == hxRequestTrue {
-			switch media.Status {
-			case domain.MediaStatusPending, domain.MediaStatusProcessing:
-				_ = templates.StatusPolling(media.ID).Render(r.Context(), w)
-			case domain.MediaStatusDone:
-				shareURL := fmt.Sprintf("https://%s/v/%s", h.domain, media.ID)
-				_ = templates.StatusDone(media, shareURL).Render(r.Context(), w)
-			case domain.MediaStatusFailed:
-				_ = templates.StatusFailed(media.ErrorMessage).Render(r.Context(), w)
-			}
+			h.renderStatusFragment(w, r, media)
 			return
 		}
 
@@ -432,6 +789,22 @@ func (h *Handlers) DeleteMedia() http.HandlerFunc {
 		id := strings.TrimPrefix(r.URL.Path, "/media/")
 		id = strings.TrimSuffix(id, "/")
 
+		// Expired media are looked up too: they stay deletable by their
+		// owner until the sweeper removes them.
+		media, err := h.mediaSvc.Lookup(id)
+		if err == nil && !ownsMedia(r, media) {
+			err = domain.ErrNotFound
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.Error.Printf("delete lookup error for %s: %v", logger.SanitizeForLog(id), err)
+			http.Error(w, "Delete failed", http.StatusInternalServerError)
+			return
+		}
+
 		if err := h.mediaSvc.Delete(id); err != nil {
 			logger.Error.Printf("delete error for %s: %v", logger.SanitizeForLog(id), err)
 			http.Error(w, "Delete failed", http.StatusInternalServerError)
@@ -442,61 +815,161 @@ func (h *Handlers) DeleteMedia() http.HandlerFunc {
 	}
 }
 
-func (h *Handlers) ProbeUpload() http.HandlerFunc {
+// CancelMedia stops the conversions of a media still being processed. HTMX
+// requests from the status page get its updated fragment back.
+func (h *Handlers) CancelMedia() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxSizeMB)*1024*1024)
+		id := r.PathValue("id")
 
-		if err := r.ParseMultipartForm(32 << 20); err != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = templates.ErrorInline("Invalid file upload").Render(r.Context(), w)
+		media, err := h.mediaSvc.Get(id)
+		if err == nil && !ownsMedia(r, media) {
+			err = domain.ErrNotFound
+		}
+		if err != nil {
+			http.Error(w, "Media not found", http.StatusNotFound)
 			return
 		}
 
-		file, header, err := r.FormFile("file")
-		if err != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = templates.ErrorInline("Invalid file upload").Render(r.Context(), w)
+		if err := h.mediaSvc.Cancel(media); err != nil {
+			if errors.Is(err, domain.ErrNotCancellable) {
+				http.Error(w, "Nothing left to cancel", http.StatusConflict)
+				return
+			}
+			logger.Error.Printf("cancel error for %s: %v", logger.SanitizeForLog(id), err)
+			http.Error(w, "Cancel failed", http.StatusInternalServerError)
 			return
 		}
-		defer func() {
-			if closeErr := file.Close(); closeErr != nil {
-				logger.Error.Printf("failed to close uploaded file: %v", closeErr)
+
+		if r.Header.Get("HX-Request") == hxRequestTrue {
+			if media, err = h.mediaSvc.Get(id); err == nil {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				h.renderStatusFragment(w, r, media)
+				return
 			}
-		}()
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
 
-		tmpFile, createErr := os.CreateTemp("", "probe-*.tmp")
-		if createErr != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = templates.ErrorInline("Failed to process file").Render(r.Context(), w)
+// maxBulkDelete caps how many media one bulk delete request may name.
+const maxBulkDelete = 500
+
+// BulkDeleteMedia deletes the media named by the repeated ids form field and
+// renders a toast summarizing the outcome, listing the IDs that failed.
+func (h *Handlers) BulkDeleteMedia() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = templates.Toast("Invalid request", templates.ToastError).Render(r.Context(), w)
+			return
+		}
+		ids := r.PostForm["ids"]
+		if len(ids) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = templates.Toast("No media selected", templates.ToastError).Render(r.Context(), w)
+			return
+		}
+		if len(ids) > maxBulkDelete {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = templates.Toast(fmt.Sprintf("At most %d media can be deleted at once", maxBulkDelete), templates.ToastError).Render(r.Context(), w)
 			return
 		}
-		defer func() {
-			_ = tmpFile.Close()
-			_ = os.Remove(tmpFile.Name()) // may already be moved by service
-		}()
 
-		if _, copyErr := io.Copy(tmpFile, file); copyErr != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = templates.ErrorInline("Failed to read file").Render(r.Context(), w)
+		deleted, failed := h.mediaSvc.DeleteMany(currentUserID(r), ids)
+		if len(failed) == 0 {
+			_ = templates.Toast(fmt.Sprintf("Deleted %d media", deleted), templates.ToastSuccess).Render(r.Context(), w)
 			return
 		}
 
-		probeResult, probeErr := h.mediaSvc.ProbeFile(tmpFile.Name())
-		if probeErr != nil {
-			logger.Error.Printf("probe error for %s: %v", logger.SanitizeForLog(header.Filename), probeErr)
+		failedIDs := make([]string, 0, len(failed))
+		for id, err := range failed {
+			if !errors.Is(err, domain.ErrNotFound) {
+				logger.Error.Printf("bulk delete error for %s: %v", logger.SanitizeForLog(id), err)
+			}
+			failedIDs = append(failedIDs, id)
+		}
+		slices.Sort(failedIDs)
+		_ = templates.Toast(
+			fmt.Sprintf("Deleted %d media, %d failed: %s", deleted, len(failedIDs), strings.Join(failedIDs, ", ")),
+			templates.ToastError,
+		).Render(r.Context(), w)
+	}
+}
+
+func (h *Handlers) ProbeUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		probe, filename, err := h.probeRequest(w, r)
+		if err != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = templates.ErrorInline("Failed to probe file").Render(r.Context(), w)
+			w.WriteHeader(err.status)
+			_ = templates.ErrorInline(err.msg).Render(r.Context(), w)
 			return
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_ = templates.ProbeResult(probeResult, header.Filename).Render(r.Context(), w)
+		_ = templates.ProbeResult(probe, filename).Render(r.Context(), w)
+	}
+}
+
+// probeError is a failed probeRequest: the status and message to answer with.
+type probeError struct {
+	status int
+	msg    string
+}
+
+// probeRequest copies the file sent as the "file" field of a multipart
+// request to a temporary file, probes it and removes it again. Nothing is
+// stored. The upload size limits apply.
+func (h *Handlers) probeRequest(w http.ResponseWriter, r *http.Request) (*domain.ProbeResult, string, *probeError) {
+	if declaresTooLarge(w, r, h.requestMaxBytes()) {
+		return nil, "", &probeError{http.StatusRequestEntityTooLarge, "File too large"}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.requestMaxBytes())
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, "", &probeError{http.StatusRequestEntityTooLarge, "File too large"}
+		}
+		return nil, "", &probeError{http.StatusBadRequest, "Invalid file upload"}
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", &probeError{http.StatusBadRequest, "Invalid file upload"}
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			logger.Error.Printf("failed to close uploaded file: %v", closeErr)
+		}
+	}()
+
+	mediaType := domain.DetectMediaType(header.Filename)
+	if header.Size > int64(h.maxUploadMB(mediaType))*1024*1024 {
+		return nil, "", &probeError{http.StatusRequestEntityTooLarge, fmt.Sprintf("File too large: %s uploads are limited to %d MB", mediaType, h.maxUploadMB(mediaType))}
 	}
+
+	tmpFile, err := os.CreateTemp("", "probe-*.tmp")
+	if err != nil {
+		return nil, "", &probeError{http.StatusInternalServerError, "Failed to process file"}
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := io.Copy(tmpFile, file); err != nil {
+		return nil, "", &probeError{http.StatusInternalServerError, "Failed to read file"}
+	}
+
+	probe, err := h.mediaSvc.ProbeFile(tmpFile.Name())
+	if err != nil {
+		logger.Error.Printf("probe error for %s: %v", logger.SanitizeForLog(header.Filename), err)
+		return nil, "", &probeError{http.StatusUnprocessableEntity, "Failed to probe file"}
+	}
+	return probe, header.Filename, nil
 }
 
 func (h *Handlers) MediaInfo() http.HandlerFunc {
@@ -506,6 +979,9 @@ func (h *Handlers) MediaInfo() http.HandlerFunc {
 		id = strings.TrimSuffix(id, "/")
 
 		media, err := h.mediaSvc.Get(id)
+		if err == nil && !ownsMedia(r, media) {
+			err = domain.ErrNotFound
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			w.WriteHeader(http.StatusNotFound)
@@ -523,43 +999,73 @@ func (h *Handlers) MediaInfo() http.HandlerFunc {
 	}
 }
 
-func (h *Handlers) Media() http.HandlerFunc {
+// Stats renders storage usage, scoped like APIStats.
+func (h *Handlers) Stats() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-
-		// Extract the media ID and suffix
-		trimmed := strings.TrimPrefix(path, "/v/")
-		parts := strings.SplitN(trimmed, "/", 2)
-		id := parts[0]
-		suffix := ""
-		if len(parts) > 1 {
-			suffix = parts[1]
-		}
-
-		switch suffix {
-		case "raw", "raw.mp4":
-			h.ServeRaw()(w, r)
-		case "thumb":
-			h.ServeThumb()(w, r)
-		case "original":
-			h.ServeOriginal(id)(w, r)
-		case "av1":
-			h.ServeVariant(id, domain.CodecAV1)(w, r)
-		case "h264":
-			h.ServeVariant(id, domain.CodecH264)(w, r)
-		case "opus":
-			h.ServeVariant(id, domain.CodecOpus)(w, r)
-		default:
-			h.SharePage()(w, r)
+		stats, err := h.stats(r)
+		if err != nil {
+			logger.Error.Printf("stats error: %v", err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorPage("500", "Failed to load stats", h.version).Render(r.Context(), w)
+			return
 		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = templates.StatsPage(stats, h.version).Render(r.Context(), w)
 	}
 }
 
-func (h *Handlers) SharePage() http.HandlerFunc {
+// stats returns the storage usage of the whole instance to admins and of
+// their own media to other users.
+func (h *Handlers) stats(r *http.Request) (domain.StorageStats, error) {
+	user := currentUser(r)
+	if user == nil {
+		return domain.StorageStats{}, errors.New("no user in request")
+	}
+	if user.IsAdmin {
+		return h.mediaSvc.Stats()
+	}
+	return h.mediaSvc.OwnerStats(user.ID)
+}
+
+// MediaFiles maps the files served under /v/{id}/ to their handlers.
+func (h *Handlers) MediaFiles() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"raw":          h.ServeRaw(false),
+		"raw.mp4":      h.ServeRaw(true),
+		"embed":        h.ServeRaw(true),
+		"thumb":        h.ServeThumb(),
+		"original":     h.ServeOriginal(),
+		"av1":          h.ServeVariant(domain.CodecAV1),
+		"h264":         h.ServeVariant(domain.CodecH264),
+		"opus":         h.ServeVariant(domain.CodecOpus),
+		"download.zip": h.ServeZip(),
+		"sprites.vtt":  h.ServeStoryboard(false),
+		"sprites.jpg":  h.ServeStoryboard(true),
+		// Previews rendered before sprites moved to JPEG link these names.
+		"storyboard.vtt": h.ServeStoryboard(false),
+		"storyboard.png": h.ServeStoryboard(true),
+	}
+}
+
+// MediaFile serves /v/{id}/{file} paths no exact pattern matched: files
+// named in another case, such as /RAW, or with a trailing slash.
+func (h *Handlers) MediaFile() http.HandlerFunc {
+	files := h.MediaFiles()
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := strings.TrimPrefix(r.URL.Path, "/v/")
-		id = strings.TrimSuffix(id, "/")
+		handler, ok := files[strings.ToLower(r.PathValue("file"))]
+		if !ok {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}
+}
 
+func (h *Handlers) SharePage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 		media, err := h.mediaSvc.Get(id)
 		if err != nil {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -569,12 +1075,13 @@ func (h *Handlers) SharePage() http.HandlerFunc {
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_ = templates.Share(media, h.domain).Render(r.Context(), w)
+		_ = templates.Share(media, h.domain, h.location).Render(r.Context(), w)
 	}
 }
 
-func (h *Handlers) ServeOriginal(id string) http.HandlerFunc {
+func (h *Handlers) ServeOriginal() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 		media, err := h.mediaSvc.Get(id)
 		if err != nil {
 			http.Error(w, "Media not found", http.StatusNotFound)
@@ -589,38 +1096,92 @@ func (h *Handlers) ServeOriginal(id string) http.HandlerFunc {
 		mimeType := detectOriginalMIMEType(media)
 		w.Header().Set("Content-Type", mimeType)
 		w.Header().Set("Content-Disposition", validation.ContentDisposition(media.OriginalName, true))
-		http.ServeFile(w, r, media.OriginalPath)
+		h.setDigestHeaders(w, r, media.ID, domain.ChecksumOriginal, media.OriginalPath)
+		h.serveBlob(w, r, media.OriginalPath)
 	}
 }
 
-func (h *Handlers) ServeVariant(id string, codec domain.Codec) http.HandlerFunc {
+func (h *Handlers) ServeVariant(codec domain.Codec) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 		media, err := h.mediaSvc.Get(id)
 		if err != nil {
 			http.Error(w, "Media not found", http.StatusNotFound)
 			return
 		}
 
-		v := media.VariantByCodec(codec)
-		if v == nil || v.Status != domain.VariantStatusDone || v.Path == "" {
+		v, err := h.mediaSvc.RequestVariant(media, codec)
+		if err != nil || v.Status == domain.VariantStatusFailed {
 			http.Error(w, "Variant not available", http.StatusNotFound)
 			return
 		}
+		if v.Status != domain.VariantStatusDone || v.Path == "" {
+			h.serveEncoding(w, r)
+			return
+		}
 
 		mimeType := codecMIMEType(codec, media.Type)
 		w.Header().Set("Content-Type", mimeType)
 		w.Header().Set("Content-Disposition", validation.ContentDisposition(variantFilename(media.OriginalName, codec), true))
-		http.ServeFile(w, r, v.Path)
+		h.setDigestHeaders(w, r, media.ID, string(codec), v.Path)
+		h.serveBlob(w, r, v.Path)
 	}
 }
 
-func (h *Handlers) ServeRaw() http.HandlerFunc {
+// ServeZip streams the original and all finished variants as one ZIP download.
+func (h *Handlers) ServeZip() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := strings.TrimPrefix(r.URL.Path, "/v/")
-		id = strings.TrimSuffix(id, "/raw")
-		id = strings.TrimSuffix(id, "/raw.mp4")
-		id = strings.TrimSuffix(id, "/")
+		id := r.PathValue("id")
+		media, err := h.mediaSvc.Get(id)
+		if err != nil {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", validation.ContentDisposition(zipFilename(media.OriginalName), false))
+		if err := writeMediaZip(r.Context(), h.stallGuard(w), media); err != nil {
+			// Headers are already sent; the client sees a truncated archive.
+			logger.Error.Printf("zip download error for %s: %v", media.ID, err)
+		}
+	}
+}
+
+// unfurlBots are User-Agent fragments of the link-preview crawlers of chat
+// apps and social networks, matched case-insensitively.
+var unfurlBots = []string{
+	"discordbot",
+	"slackbot",
+	"slack-imgproxy",
+	"whatsapp",
+	"telegrambot",
+	"twitterbot",
+	"facebookexternalhit",
+	"linkedinbot",
+	"mattermost",
+	"skypeuripreview",
+	"redditbot",
+}
+
+// isUnfurlBot reports whether userAgent belongs to a link-preview crawler.
+func isUnfurlBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, bot := range unfurlBots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
 
+// ServeRaw serves the best available file for the request's Accept header.
+// With preferH264, as for /v/{id}/embed and /v/{id}/raw.mp4, the H264
+// variant is served whenever it is done: chat apps send Accept: */* and
+// would otherwise get AV1, which many of them cannot play inline. Known
+// link-preview bots that accept anything get the configured bot codec.
+func (h *Handlers) ServeRaw(preferH264 bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 		media, err := h.mediaSvc.Get(id)
 		if err != nil {
 			http.Error(w, "Media not found", http.StatusNotFound)
@@ -628,18 +1189,34 @@ func (h *Handlers) ServeRaw() http.HandlerFunc {
 		}
 
 		// Serve best available: first done variant, then converted path, then original
-		if v := media.BestVariantForAccept(r.Header.Get("Accept")); v != nil && v.Path != "" {
+		accept := strings.TrimSpace(r.Header.Get("Accept"))
+		v := media.BestVariantForAccept(accept)
+		switch {
+		case preferH264:
+			if h264 := media.H264Variant(); h264 != nil {
+				v = h264
+			}
+		case h.botCodec != "" && (accept == "" || accept == "*/*") && isUnfurlBot(r.UserAgent()):
+			if bot := media.VariantByCodec(h.botCodec); bot != nil && bot.Status == domain.VariantStatusDone {
+				v = bot
+			}
+		}
+		if v == nil && media.ConvertedPath == "" && h.servePlaceholder(w, r, media) {
+			return
+		}
+		if v != nil && v.Path != "" {
 			mimeType := codecMIMEType(v.Codec, media.Type)
 			w.Header().Set("Content-Type", mimeType)
 			w.Header().Set("Content-Disposition", validation.ContentDisposition(media.OriginalName, true))
-			http.ServeFile(w, r, v.Path)
+			h.setDigestHeaders(w, r, media.ID, string(v.Codec), v.Path)
+			h.serveBlob(w, r, v.Path)
 			return
 		}
 
 		// Fall back to legacy converted path or original
-		servePath := media.ConvertedPath
+		servePath, checksumFile := media.ConvertedPath, domain.ChecksumConverted
 		if servePath == "" {
-			servePath = media.OriginalPath
+			servePath, checksumFile = media.OriginalPath, domain.ChecksumOriginal
 		}
 
 		if servePath == "" {
@@ -650,7 +1227,73 @@ func (h *Handlers) ServeRaw() http.HandlerFunc {
 		mimeType := detectMIMEType(media)
 		w.Header().Set("Content-Type", mimeType)
 		w.Header().Set("Content-Disposition", validation.ContentDisposition(media.OriginalName, true))
-		http.ServeFile(w, r, servePath)
+		h.setDigestHeaders(w, r, media.ID, checksumFile, servePath)
+		h.serveBlob(w, r, servePath)
+	}
+}
+
+// servePlaceholder answers for a media that is still being converted, when
+// a processing placeholder is configured. Browsers navigating to the file
+// are sent to the share page; embeds and link-preview bots, which fetch as
+// soon as a link is posted, get the placeholder and are asked to come back.
+// It reports whether it answered.
+func (h *Handlers) servePlaceholder(w http.ResponseWriter, r *http.Request, media *domain.Media) bool {
+	if h.processingPlaceholder == "" ||
+		(media.Status != domain.MediaStatusPending && media.Status != domain.MediaStatusProcessing) {
+		return false
+	}
+	if _, err := os.Stat(h.processingPlaceholder); err != nil {
+		logger.Warn.Printf("configured processing placeholder %s not found, serving the original", h.processingPlaceholder)
+		return false
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", encodingRetrySeconds)
+	navigation := r.Header.Get("Sec-Fetch-Mode") == "navigate" || strings.Contains(r.Header.Get("Accept"), "text/html")
+	if navigation && !isUnfurlBot(r.UserAgent()) {
+		http.Redirect(w, r, "/v/"+media.ID, http.StatusFound)
+		return true
+	}
+	http.ServeFile(w, r, h.processingPlaceholder)
+	return true
+}
+
+// encodingRetrySeconds is how long clients are asked to wait for an in-progress variant.
+const encodingRetrySeconds = "10"
+
+// serveEncoding answers 202 for a variant that is still being encoded. Browsers
+// get a page that refreshes itself; other clients can honor Retry-After.
+func (h *Handlers) serveEncoding(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", encodingRetrySeconds)
+	w.Header().Set("Cache-Control", "no-store")
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Error(w, "Variant is being encoded, retry later", http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Refresh", encodingRetrySeconds)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	_ = templates.EncodingPage(h.version).Render(r.Context(), w)
+}
+
+// setDigestHeaders advertises the SHA-256 of the served file so clients can
+// verify downloads. Content-Digest describes the response body, so it is only
+// sent for full (non-range) responses. Failures are logged and never block serving.
+func (h *Handlers) setDigestHeaders(w http.ResponseWriter, r *http.Request, mediaID, file, path string) {
+	sum, err := h.mediaSvc.Checksum(mediaID, file, path)
+	if err != nil {
+		logger.Error.Printf("checksum error for %s/%s: %v", mediaID, file, err)
+		return
+	}
+	raw, err := hex.DecodeString(sum)
+	if err != nil {
+		logger.Error.Printf("invalid stored checksum for %s/%s: %v", mediaID, file, err)
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	w.Header().Set("Digest", "sha-256="+encoded)
+	if r.Header.Get("Range") == "" {
+		w.Header().Set("Content-Digest", "sha-256=:"+encoded+":")
 	}
 }
 
@@ -753,10 +1396,7 @@ func variantFilename(originalName string, codec domain.Codec) string {
 
 func (h *Handlers) ServeThumb() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := strings.TrimPrefix(r.URL.Path, "/v/")
-		id = strings.TrimSuffix(id, "/thumb")
-		id = strings.TrimSuffix(id, "/")
-
+		id := r.PathValue("id")
 		media, err := h.mediaSvc.Get(id)
 		if err != nil {
 			http.Error(w, "Media not found", http.StatusNotFound)
@@ -769,6 +1409,58 @@ func (h *Handlers) ServeThumb() http.HandlerFunc {
 		}
 
 		w.Header().Set("Content-Type", "image/jpeg")
-		http.ServeFile(w, r, media.ThumbPath)
+		h.serveBlob(w, r, media.ThumbPath)
+	}
+}
+
+// ServeStoryboard serves the scrubbing preview of a video: the WebVTT
+// mapping playback times to tiles, or with sprite the image they point at.
+func (h *Handlers) ServeStoryboard(sprite bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		media, err := h.mediaSvc.Get(id)
+		if err != nil {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+
+		if !media.HasStoryboard() {
+			http.Error(w, "Storyboard not available", http.StatusNotFound)
+			return
+		}
+
+		if sprite {
+			w.Header().Set("Content-Type", "image/jpeg")
+			if strings.HasSuffix(media.StoryboardPath, ".png") {
+				w.Header().Set("Content-Type", "image/png")
+			}
+			h.serveBlob(w, r, media.StoryboardPath)
+			return
+		}
+		// The VTT links the sprite relative to its own URL, so it is always
+		// served here for that link to land on the sprite route.
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		h.serveFile(w, r, media.StoryboardVTTPath)
+	}
+}
+
+// defaultOGImage is the bundled fallback used when no instance image is configured.
+const defaultOGImage = "icon-512x512.png"
+
+// OGImage serves the instance-wide default Open Graph image, used in link
+// previews for shares without a usable thumbnail.
+func (h *Handlers) OGImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+
+		if h.ogImagePath != "" {
+			if _, err := os.Stat(h.ogImagePath); err == nil {
+				http.ServeFile(w, r, h.ogImagePath)
+				return
+			}
+			logger.Warn.Printf("configured OG image %s not found, using default", h.ogImagePath)
+		}
+
+		http.ServeFileFS(w, r, static.FS, defaultOGImage)
 	}
 }
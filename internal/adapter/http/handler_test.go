@@ -0,0 +1,602 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOGImage_DefaultsToBundledIcon(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	rec := httptest.NewRecorder()
+	h.OGImage()(rec, httptest.NewRequest(http.MethodGet, "/og-image", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.NotEmpty(t, rec.Body.Bytes())
+}
+
+func TestOGImage_ServesConfiguredFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "og.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("custom-og-image"), 0600))
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", OGImagePath: path})
+
+	rec := httptest.NewRecorder()
+	h.OGImage()(rec, httptest.NewRequest(http.MethodGet, "/og-image", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "custom-og-image", rec.Body.String())
+}
+
+func TestOGImage_MissingConfiguredFileFallsBack(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", OGImagePath: filepath.Join(t.TempDir(), "missing.png")})
+
+	rec := httptest.NewRecorder()
+	h.OGImage()(rec, httptest.NewRequest(http.MethodGet, "/og-image", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+}
+
+func TestParsePage(t *testing.T) {
+	assert.Equal(t, 1, parsePage(""))
+	assert.Equal(t, 1, parsePage("abc"))
+	assert.Equal(t, 1, parsePage("0"))
+	assert.Equal(t, 1, parsePage("-3"))
+	assert.Equal(t, 4, parsePage("4"))
+}
+
+type stubDiskStatus struct{ low bool }
+
+func (s stubDiskStatus) Low() bool { return s.low }
+
+func TestChunkUpload_RefusedWhenDiskLow(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", DiskStatus: stubDiskStatus{low: true}})
+
+	rec := httptest.NewRecorder()
+	h.ChunkUpload()(rec, httptest.NewRequest(http.MethodPost, "/upload/chunk", nil))
+
+	assert.Equal(t, http.StatusInsufficientStorage, rec.Code)
+}
+
+func TestMaxUploadMB_FallsBackToGlobalLimit(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", TypeMaxSizeMB: map[domain.MediaType]int{
+		domain.MediaTypeImage: 10,
+		domain.MediaTypeVideo: 2000,
+	}})
+
+	assert.Equal(t, 10, h.maxUploadMB(domain.MediaTypeImage))
+	assert.Equal(t, 100, h.maxUploadMB(domain.MediaTypeAudio))
+	assert.Equal(t, 2000, h.maxUploadMB(domain.MediaTypeVideo))
+	assert.Equal(t, int64(2000*1024*1024), h.requestMaxBytes())
+}
+
+func TestUpload_RejectsFileOverTypeLimit(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 10, Version: "test", TypeMaxSizeMB: map[domain.MediaType]int{
+		domain.MediaTypeImage: 1,
+	}})
+
+	png := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 1024*1024)...)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "big.png")
+	require.NoError(t, err)
+	_, err = part.Write(png)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.Upload()(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "image uploads are limited to 1 MB")
+}
+
+func TestUpload_RejectsTypeMismatch(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 10, Version: "test", RejectTypeMismatch: true})
+
+	png := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0, 0, 0, 0x0D}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "clip.mp4")
+	require.NoError(t, err)
+	_, err = part.Write(png)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.Upload()(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "does not match its extension")
+}
+
+func TestRenderUploadError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+		msg  string
+	}{
+		{domain.ErrSlugTaken, http.StatusConflict, "already taken"},
+		{fmt.Errorf("%w: too short", domain.ErrInvalidSlug), http.StatusBadRequest, "Custom link must be"},
+		{domain.ErrUndecodable, http.StatusUnprocessableEntity, "empty or corrupt"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		renderUploadError(rec, httptest.NewRequest(http.MethodPost, "/upload", nil), "shot.png", tt.err)
+
+		assert.Equal(t, tt.code, rec.Code)
+		assert.Contains(t, rec.Body.String(), tt.msg)
+	}
+}
+
+func TestHandlers_UploadRetention(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", Retention: domain.RetentionPolicy{Default: 7 * domain.Day, Min: 2 * domain.Day, Max: 30 * domain.Day}})
+
+	assert.Equal(t, 7*domain.Day, h.uploadRetention(""))
+	assert.Equal(t, 7*domain.Day, h.uploadRetention("forever"))
+	assert.Equal(t, 14*domain.Day, h.uploadRetention("14"), "bare numbers are days")
+	assert.Equal(t, 14*domain.Day, h.uploadRetention("2w"))
+	assert.Equal(t, 2*domain.Day, h.uploadRetention("1"))
+	assert.Equal(t, 2*domain.Day, h.uploadRetention("1h"))
+	assert.Equal(t, 30*domain.Day, h.uploadRetention("100000"))
+	assert.Equal(t, 30*domain.Day, h.uploadRetention("never"), "never expiring is not allowed with a maximum")
+
+	h.retention.Max = 0
+	h.retention.Min = time.Hour
+	assert.Equal(t, time.Hour, h.uploadRetention("1h"))
+	assert.Equal(t, domain.RetentionNever, h.uploadRetention("never"))
+	assert.Equal(t, domain.RetentionNever, h.uploadRetention("-1"))
+}
+
+// bulkDeleteStub deletes the media of user 1 and fails on "bad".
+type bulkDeleteStub struct {
+	MediaService
+}
+
+func (bulkDeleteStub) DeleteMany(ownerID int64, ids []string) (int, map[string]error) {
+	failed := map[string]error{}
+	for _, id := range ids {
+		if id == "bad" || ownerID != 1 {
+			failed[id] = domain.ErrNotFound
+		}
+	}
+	return len(ids) - len(failed), failed
+}
+
+func TestBulkDeleteMedia(t *testing.T) {
+	h := NewHandlers(bulkDeleteStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	tests := []struct {
+		name string
+		form url.Values
+		want int
+		body string
+	}{
+		{"deletes all", url.Values{"ids": {"a", "b"}}, http.StatusOK, "Deleted 2 media"},
+		{"reports failures", url.Values{"ids": {"a", "bad"}}, http.StatusOK, "Deleted 1 media, 1 failed: bad"},
+		{"nothing selected", url.Values{}, http.StatusBadRequest, "No media selected"},
+		{"too many", url.Values{"ids": make([]string, maxBulkDelete+1)}, http.StatusBadRequest, "At most 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/media/bulk-delete", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: 1}))
+			rec := httptest.NewRecorder()
+
+			h.BulkDeleteMedia()(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
+
+// deleteStub holds "anon", an anonymous upload, "owned", media of user 3,
+// and "expired", expired media of user 3; "broken" fails to load.
+type deleteStub struct {
+	MediaService
+	deleted []string
+}
+
+func (s *deleteStub) Lookup(id string) (*domain.Media, error) {
+	switch id {
+	case "anon":
+		return &domain.Media{ID: id}, nil
+	case "owned":
+		return &domain.Media{ID: id, OwnerID: 3}, nil
+	case "expired":
+		return &domain.Media{ID: id, OwnerID: 3, ExpiresAt: time.Now().Add(-time.Hour)}, nil
+	case "broken":
+		return nil, errors.New("database is locked")
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *deleteStub) Delete(id string) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func TestDeleteMedia_Ownership(t *testing.T) {
+	tests := []struct {
+		name string
+		user *domain.User
+		id   string
+		want int
+	}{
+		{"owner", &domain.User{ID: 3}, "owned", http.StatusOK},
+		{"other user", &domain.User{ID: 4}, "owned", http.StatusNotFound},
+		{"admin on another user's media", &domain.User{ID: 1, IsAdmin: true}, "owned", http.StatusNotFound},
+		{"admin on anonymous media", &domain.User{ID: 1, IsAdmin: true}, "anon", http.StatusOK},
+		{"user on anonymous media", &domain.User{ID: 4}, "anon", http.StatusNotFound},
+		{"owner on expired media", &domain.User{ID: 3}, "expired", http.StatusOK},
+		{"other user on expired media", &domain.User{ID: 4}, "expired", http.StatusNotFound},
+		{"unknown media", &domain.User{ID: 3}, "missing", http.StatusNotFound},
+		{"store error", &domain.User{ID: 3}, "broken", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &deleteStub{}
+			h := NewHandlers(stub, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+			req := httptest.NewRequest(http.MethodDelete, "/media/"+tt.id, nil)
+			req = req.WithContext(context.WithValue(req.Context(), userKey, tt.user))
+			rec := httptest.NewRecorder()
+
+			h.DeleteMedia()(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, []string{tt.id}, stub.deleted)
+			} else {
+				assert.Empty(t, stub.deleted)
+			}
+		})
+	}
+}
+
+// cancelStub holds media of user 1: "busy" is converting, "done" has
+// nothing left to cancel.
+type cancelStub struct {
+	MediaService
+	cancelled bool
+}
+
+func (s *cancelStub) Get(id string) (*domain.Media, error) {
+	switch {
+	case id == "busy" && s.cancelled:
+		return &domain.Media{ID: id, OwnerID: 1, Status: domain.MediaStatusFailed, ErrorMessage: "cancelled"}, nil
+	case id == "busy":
+		return &domain.Media{ID: id, OwnerID: 1, Status: domain.MediaStatusProcessing}, nil
+	case id == "done":
+		return &domain.Media{ID: id, OwnerID: 1, Status: domain.MediaStatusDone}, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *cancelStub) Cancel(media *domain.Media) error {
+	if media.Status == domain.MediaStatusDone {
+		return domain.ErrNotCancellable
+	}
+	s.cancelled = true
+	return nil
+}
+
+func TestCancelMedia(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		userID int64
+		want   int
+		body   string
+	}{
+		{"cancels", "busy", 1, http.StatusOK, "cancelled"},
+		{"other user", "busy", 2, http.StatusNotFound, "Media not found"},
+		{"finished", "done", 1, http.StatusConflict, "Nothing left to cancel"},
+		{"unknown", "gone", 1, http.StatusNotFound, "Media not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandlers(&cancelStub{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+			req := httptest.NewRequest(http.MethodPost, "/media/"+tt.id+"/cancel", nil)
+			req.SetPathValue("id", tt.id)
+			req.Header.Set("HX-Request", "true")
+			req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: tt.userID}))
+			rec := httptest.NewRecorder()
+
+			h.CancelMedia()(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
+
+// unreadBody fails the test if the handler reads from it.
+type unreadBody struct{ t *testing.T }
+
+func (b unreadBody) Read([]byte) (int, error) {
+	b.t.Error("body of an oversized upload was read")
+	return 0, io.EOF
+}
+
+func TestUploads_RejectDeclaredOversizeBeforeReading(t *testing.T) {
+	h := NewHandlers(nil, HandlerConfig{Domain: "example.com", MaxSizeMB: 1, Version: "test"})
+
+	tests := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+		length  int64
+	}{
+		{"upload", "/upload", h.Upload(), 2 * 1024 * 1024},
+		{"chunk", "/upload/chunk", h.ChunkUpload(), chunkSize + 2*1024*1024},
+		{"probe", "/upload/probe", h.ProbeUpload(), 2 * 1024 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, unreadBody{t})
+			req.ContentLength = tt.length
+			req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+			rec := httptest.NewRecorder()
+
+			tt.handler(rec, req)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+			assert.Equal(t, "close", rec.Header().Get("Connection"))
+		})
+	}
+}
+
+type thumbless struct {
+	MediaService
+}
+
+func (thumbless) Get(id string) (*domain.Media, error) {
+	if id != "ABC" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Media{ID: id, Status: domain.MediaStatusDone, ExpiresAt: domain.NeverExpiresAt}, nil
+}
+
+func TestMediaRoutes(t *testing.T) {
+	h := NewHandlers(thumbless{}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+	mux := http.NewServeMux()
+	registerMediaRoutes(mux, h)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/v/ABC", http.StatusOK, "<html"},
+		{"/v/ABC/", http.StatusOK, "<html"},
+		{"/v/NOPE", http.StatusNotFound, "Media not found"},
+		{"/v/ABC/raw", http.StatusServiceUnavailable, "Media not ready"},
+		{"/v/ABC/raw.mp4", http.StatusServiceUnavailable, "Media not ready"},
+		{"/v/ABC/embed", http.StatusServiceUnavailable, "Media not ready"},
+		{"/v/ABC/thumb", http.StatusNotFound, "Thumbnail not available"},
+		{"/v/ABC/original", http.StatusNotFound, "Original not available"},
+		{"/v/ABC/sprites.vtt", http.StatusNotFound, "Storyboard not available"},
+		{"/v/ABC/sprites.jpg", http.StatusNotFound, "Storyboard not available"},
+		{"/v/ABC/storyboard.vtt", http.StatusNotFound, "Storyboard not available"},
+		{"/v/ABC/storyboard.png", http.StatusNotFound, "Storyboard not available"},
+		{"/v/NOPE/h264", http.StatusNotFound, "Media not found"},
+		{"/v/NOPE/download.zip", http.StatusNotFound, "Media not found"},
+		{"/v/ABC/THUMB", http.StatusNotFound, "Thumbnail not available"},
+		{"/v/ABC/thumb/", http.StatusNotFound, "Thumbnail not available"},
+		{"/v/ABC/bogus", http.StatusNotFound, "Not found"},
+		{"/v/ABC/thumb/extra", http.StatusNotFound, "404 page not found"},
+		{"/v/ABC//thumb", http.StatusTemporaryRedirect, "/v/ABC/thumb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
+
+type storyboardStub struct {
+	MediaService
+	media *domain.Media
+}
+
+func (s storyboardStub) Get(id string) (*domain.Media, error) {
+	return s.media, nil
+}
+
+func TestHandlers_ServeStoryboard(t *testing.T) {
+	dir := t.TempDir()
+	vtt := filepath.Join(dir, "abc_sprites.vtt")
+	sprite := filepath.Join(dir, "abc_sprites.jpg")
+	require.NoError(t, os.WriteFile(vtt, []byte("WEBVTT\n"), 0600))
+	require.NoError(t, os.WriteFile(sprite, []byte{0xFF, 0xD8, 0xFF}, 0600))
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, StoryboardPath: sprite, StoryboardVTTPath: vtt}
+	h := NewHandlers(storyboardStub{media: media}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test"})
+
+	mux := http.NewServeMux()
+	registerMediaRoutes(mux, h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v/abc/sprites.vtt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/vtt; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "WEBVTT\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v/abc/sprites.jpg", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+
+	media.StoryboardPath, media.StoryboardVTTPath = "", ""
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v/abc/storyboard.png", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+type presignedLinks struct{}
+
+func (presignedLinks) BlobURL(ctx context.Context, path, contentType, disposition string) (string, bool) {
+	query := url.Values{"X-Amz-Signature": {"abc"}}
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+	return "https://bucket.example.com/" + filepath.Base(path) + "?" + query.Encode(), true
+}
+
+type checksumless struct {
+	storyboardStub
+}
+
+func (checksumless) Checksum(mediaID, file, path string) (string, error) {
+	return "", domain.ErrNotFound
+}
+
+func TestHandlers_ServeRaw_EmbedPrefersH264(t *testing.T) {
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, Status: domain.MediaStatusDone, Variants: []domain.Variant{
+		{Codec: domain.CodecAV1, Status: domain.VariantStatusDone, Path: "/data/converted/abc_av1.webm"},
+		{Codec: domain.CodecH264, Status: domain.VariantStatusDone, Path: "/data/converted/abc_h264.mp4"},
+	}}
+	h := NewHandlers(checksumless{storyboardStub{media: media}}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", BlobLinks: presignedLinks{}})
+	mux := http.NewServeMux()
+	registerMediaRoutes(mux, h)
+
+	served := func(path string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "*/*")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusFound, rec.Code)
+		return rec.Header().Get("Location")
+	}
+
+	assert.Contains(t, served("/v/abc/raw"), "abc_av1.webm")
+	assert.Contains(t, served("/v/abc/raw.mp4"), "abc_h264.mp4")
+	assert.Contains(t, served("/v/abc/embed"), "abc_h264.mp4")
+
+	// Without a finished H264 variant the embed falls back to the best one
+	media.Variants[1].Status = domain.VariantStatusProcessing
+	assert.Contains(t, served("/v/abc/embed"), "abc_av1.webm")
+}
+
+func TestHandlers_ServeRaw_BotPreferredCodec(t *testing.T) {
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, Status: domain.MediaStatusDone, Variants: []domain.Variant{
+		{Codec: domain.CodecAV1, Status: domain.VariantStatusDone, Path: "/data/converted/abc_av1.webm"},
+		{Codec: domain.CodecH264, Status: domain.VariantStatusDone, Path: "/data/converted/abc_h264.mp4"},
+	}}
+	served := func(botCodec domain.Codec, userAgent, accept string) string {
+		h := NewHandlers(checksumless{storyboardStub{media: media}}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", BlobLinks: presignedLinks{}, BotCodec: botCodec})
+		req := httptest.NewRequest(http.MethodGet, "/v/abc/raw", nil)
+		req.SetPathValue("id", "abc")
+		req.Header.Set("User-Agent", userAgent)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeRaw(false)(rec, req)
+		require.Equal(t, http.StatusFound, rec.Code)
+		return rec.Header().Get("Location")
+	}
+	const discord = "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)"
+
+	assert.Contains(t, served(domain.CodecH264, discord, ""), "abc_h264.mp4")
+	assert.Contains(t, served(domain.CodecH264, "WhatsApp/2.23.20.0", "*/*"), "abc_h264.mp4")
+	assert.Contains(t, served(domain.CodecH264, discord, "video/webm"), "abc_av1.webm", "an explicit Accept wins")
+	assert.Contains(t, served(domain.CodecH264, "Mozilla/5.0 Firefox/130.0", "*/*"), "abc_av1.webm", "browsers negotiate")
+	assert.Contains(t, served("", discord, "*/*"), "abc_av1.webm", "disabled")
+}
+
+func TestHandlers_ServeRaw_ProcessingPlaceholder(t *testing.T) {
+	dir := t.TempDir()
+	placeholder := filepath.Join(dir, "processing.png")
+	require.NoError(t, os.WriteFile(placeholder, []byte("placeholder"), 0600))
+	original := filepath.Join(dir, "abc_clip.mkv")
+	require.NoError(t, os.WriteFile(original, []byte("original"), 0600))
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, Status: domain.MediaStatusProcessing, OriginalPath: original, OriginalName: "clip.mkv",
+		Variants: []domain.Variant{{Codec: domain.CodecH264, Status: domain.VariantStatusProcessing}},
+	}
+
+	serve := func(placeholderPath, userAgent, accept string) *httptest.ResponseRecorder {
+		h := NewHandlers(checksumless{storyboardStub{media: media}}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", ProcessingPlaceholder: placeholderPath})
+		req := httptest.NewRequest(http.MethodGet, "/v/abc/embed", nil)
+		req.SetPathValue("id", "abc")
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeRaw(true)(rec, req)
+		return rec
+	}
+	const discord = "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)"
+	const browser = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+	rec := serve(placeholder, discord, browser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "placeholder", rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	rec = serve(placeholder, "Mozilla/5.0 Firefox/130.0", browser)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/v/abc", rec.Header().Get("Location"))
+
+	assert.Equal(t, "original", serve("", discord, "*/*").Body.String(), "disabled")
+	assert.Equal(t, "original", serve(filepath.Join(dir, "missing.png"), discord, "*/*").Body.String())
+
+	media.Status = domain.MediaStatusFailed
+	assert.Equal(t, "original", serve(placeholder, discord, "*/*").Body.String(), "only while converting")
+}
+
+func TestHandlers_ServeBlobRedirect(t *testing.T) {
+	dir := t.TempDir()
+	vtt := filepath.Join(dir, "abc_sprites.vtt")
+	sprite := filepath.Join(dir, "abc_sprites.jpg")
+	require.NoError(t, os.WriteFile(vtt, []byte("WEBVTT\n"), 0600))
+	require.NoError(t, os.WriteFile(sprite, []byte{0xFF, 0xD8, 0xFF}, 0600))
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, StoryboardPath: sprite, StoryboardVTTPath: vtt}
+	h := NewHandlers(storyboardStub{media: media}, HandlerConfig{Domain: "example.com", MaxSizeMB: 100, Version: "test", BlobLinks: presignedLinks{}})
+
+	mux := http.NewServeMux()
+	registerMediaRoutes(mux, h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v/abc/sprites.jpg", nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://bucket.example.com/abc_sprites.jpg?X-Amz-Signature=abc&response-content-type=image%2Fjpeg", rec.Header().Get("Location"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	// The VTT links the sprite relative to its own URL.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v/abc/sprites.vtt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "WEBVTT\n", rec.Body.String())
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// readinessTimeout bounds how long /readyz waits on all checks together.
+const readinessTimeout = 5 * time.Second
+
+// ReadinessCheck is a dependency the server needs to serve traffic, such as
+// the database or the ffmpeg binaries.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type readinessResponse struct {
+	Status string   `json:"status"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+// HealthzHandler reports that the process is up. It checks nothing else so
+// a slow dependency never gets a live server restarted.
+func HealthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, readinessResponse{Status: "ok"})
+	}
+}
+
+// ReadyzHandler runs every check and answers 503 with the names of those
+// that failed. Error details are only logged: the endpoint is public.
+func ReadyzHandler(checks []ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		var failed []string
+		for _, c := range checks {
+			if err := c.Check(ctx); err != nil {
+				logger.Warn.Printf("readiness check %s failed: %v", c.Name, err)
+				failed = append(failed, c.Name)
+			}
+		}
+		if len(failed) > 0 {
+			writeJSON(w, http.StatusServiceUnavailable, readinessResponse{Status: "unavailable", Failed: failed})
+			return
+		}
+		writeJSON(w, http.StatusOK, readinessResponse{Status: "ok"})
+	}
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HealthzHandler()(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestReadyzHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	broken := func(context.Context) error { return errors.New("exec: \"ffmpeg\": executable file not found in $PATH") }
+
+	rec := httptest.NewRecorder()
+	ReadyzHandler([]ReadinessCheck{{"database", ok}, {"ffmpeg", ok}})(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ReadyzHandler([]ReadinessCheck{{"database", ok}, {"ffmpeg", broken}})(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"unavailable","failed":["ffmpeg"]}`, rec.Body.String())
+}
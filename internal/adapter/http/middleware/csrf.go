@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -41,8 +42,9 @@ func (c *CSRFProtection) Middleware(next http.Handler) http.Handler {
 			c.setCSRFCookie(w, r, token)
 		}
 
-		// Safe methods don't require token validation
-		if isSafeMethod(r.Method) {
+		// Safe methods don't require token validation. Bearer-authenticated
+		// requests carry no ambient credentials, so CSRF does not apply.
+		if isSafeMethod(r.Method) || hasBearerAuth(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -147,6 +149,12 @@ func (c *CSRFProtection) setCSRFCookie(w http.ResponseWriter, r *http.Request, t
 	})
 }
 
+// hasBearerAuth reports whether the request authenticates with an
+// Authorization bearer header rather than cookies.
+func hasBearerAuth(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
 // isSafeMethod returns true for HTTP methods that don't require CSRF protection.
 func isSafeMethod(method string) bool {
 	switch method {
@@ -106,6 +106,21 @@ func TestCSRFMiddleware_POSTRequiresToken(t *testing.T) {
 	assert.Equal(t, http.StatusForbidden, rec.Code)
 }
 
+func TestCSRFMiddleware_POSTWithBearerSkipsToken(t *testing.T) {
+	csrf := NewCSRFProtection(testSecretKey)
+	handler := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer sharm_1_secret")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
 func TestCSRFMiddleware_PUTRequiresToken(t *testing.T) {
 	csrf := NewCSRFProtection(testSecretKey)
 	handler := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
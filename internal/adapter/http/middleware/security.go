@@ -1,15 +1,60 @@
 package middleware
 
 import (
+	"cmp"
 	"net/http"
 	"strings"
 )
 
+// Default sources for the configurable CSP directives. They allow the CDN
+// and Google Fonts assets the templates load.
+const (
+	DefaultCSPScriptSrc  = "'self' 'unsafe-inline' https://cdn.jsdelivr.net"
+	DefaultCSPStyleSrc   = "'self' 'unsafe-inline' https://fonts.googleapis.com"
+	DefaultCSPFontSrc    = "'self' https://fonts.gstatic.com"
+	DefaultCSPConnectSrc = "'self'"
+	DefaultCSPImgSrc     = "'self' data: blob:"
+	DefaultCSPMediaSrc   = "'self' blob:"
+)
+
+// CSPConfig holds the source lists of the configurable Content-Security-Policy
+// directives. An empty field keeps the default for that directive.
+type CSPConfig struct {
+	ScriptSrc  string
+	StyleSrc   string
+	FontSrc    string
+	ConnectSrc string
+	// FrameSrc lists the origins pages may embed frames from; empty
+	// leaves it to default-src.
+	FrameSrc string
+	ImgSrc   string
+	MediaSrc string
+}
+
+// Allow adds origins to the script, style, connect and frame sources, for
+// third-party widgets such as captchas.
+func (c CSPConfig) Allow(origins string) CSPConfig {
+	c.ScriptSrc = cmp.Or(c.ScriptSrc, DefaultCSPScriptSrc) + " " + origins
+	c.StyleSrc = cmp.Or(c.StyleSrc, DefaultCSPStyleSrc) + " " + origins
+	c.ConnectSrc = cmp.Or(c.ConnectSrc, DefaultCSPConnectSrc) + " " + origins
+	c.FrameSrc = strings.TrimSpace(c.FrameSrc + " " + origins)
+	return c
+}
+
+// AllowMedia adds origins to the image and media sources, for media files
+// served from object storage.
+func (c CSPConfig) AllowMedia(origins string) CSPConfig {
+	c.ImgSrc = cmp.Or(c.ImgSrc, DefaultCSPImgSrc) + " " + origins
+	c.MediaSrc = cmp.Or(c.MediaSrc, DefaultCSPMediaSrc) + " " + origins
+	return c
+}
+
 // SecurityHeaders adds security-related HTTP headers to all responses.
 // It sets X-Content-Type-Options, X-Frame-Options, Referrer-Policy,
 // Permissions-Policy, Content-Security-Policy, and conditionally
 // Strict-Transport-Security when behind TLS.
-func SecurityHeaders(next http.Handler) http.Handler {
+func SecurityHeaders(cspConfig CSPConfig, next http.Handler) http.Handler {
+	csp := buildCSP(cspConfig)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Prevent MIME type sniffing
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -24,7 +69,6 @@ func SecurityHeaders(next http.Handler) http.Handler {
 		w.Header().Set("Permissions-Policy", "camera=(), microphone=(), geolocation=()")
 
 		// Content Security Policy
-		csp := buildCSP()
 		w.Header().Set("Content-Security-Policy", csp)
 
 		// HTTP Strict Transport Security (only when behind TLS)
@@ -37,17 +81,20 @@ func SecurityHeaders(next http.Handler) http.Handler {
 }
 
 // buildCSP constructs the Content-Security-Policy header value.
-func buildCSP() string {
+func buildCSP(cfg CSPConfig) string {
 	directives := []string{
 		"default-src 'self'",
-		"script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net",
-		"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com",
-		"font-src 'self' https://fonts.gstatic.com",
-		"img-src 'self' data: blob:",
-		"media-src 'self' blob:",
-		"connect-src 'self'",
+		"script-src " + cmp.Or(cfg.ScriptSrc, DefaultCSPScriptSrc),
+		"style-src " + cmp.Or(cfg.StyleSrc, DefaultCSPStyleSrc),
+		"font-src " + cmp.Or(cfg.FontSrc, DefaultCSPFontSrc),
+		"img-src " + cmp.Or(cfg.ImgSrc, DefaultCSPImgSrc),
+		"media-src " + cmp.Or(cfg.MediaSrc, DefaultCSPMediaSrc),
+		"connect-src " + cmp.Or(cfg.ConnectSrc, DefaultCSPConnectSrc),
 		"frame-ancestors 'none'",
 	}
+	if cfg.FrameSrc != "" {
+		directives = append(directives, "frame-src "+cfg.FrameSrc)
+	}
 	return strings.Join(directives, "; ")
 }
 
@@ -10,7 +10,7 @@ import (
 )
 
 func TestSecurityHeaders_StaticHeaders(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -54,7 +54,7 @@ func TestSecurityHeaders_StaticHeaders(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_Present(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -68,7 +68,7 @@ func TestSecurityHeaders_CSP_Present(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_DefaultSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -82,7 +82,7 @@ func TestSecurityHeaders_CSP_DefaultSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_ScriptSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -96,7 +96,7 @@ func TestSecurityHeaders_CSP_ScriptSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_StyleSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -110,7 +110,7 @@ func TestSecurityHeaders_CSP_StyleSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_FrameAncestors(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -124,7 +124,7 @@ func TestSecurityHeaders_CSP_FrameAncestors(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_FontSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -138,7 +138,7 @@ func TestSecurityHeaders_CSP_FontSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_ImgSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -152,7 +152,7 @@ func TestSecurityHeaders_CSP_ImgSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_MediaSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -166,7 +166,7 @@ func TestSecurityHeaders_CSP_MediaSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_ConnectSrc(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -180,7 +180,7 @@ func TestSecurityHeaders_CSP_ConnectSrc(t *testing.T) {
 }
 
 func TestSecurityHeaders_HSTS_NotSetWithoutTLS(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -193,7 +193,7 @@ func TestSecurityHeaders_HSTS_NotSetWithoutTLS(t *testing.T) {
 }
 
 func TestSecurityHeaders_HSTS_SetWithXForwardedProtoHTTPS(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -209,7 +209,7 @@ func TestSecurityHeaders_HSTS_SetWithXForwardedProtoHTTPS(t *testing.T) {
 }
 
 func TestSecurityHeaders_HSTS_NotSetWithXForwardedProtoHTTP(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -223,7 +223,7 @@ func TestSecurityHeaders_HSTS_NotSetWithXForwardedProtoHTTP(t *testing.T) {
 }
 
 func TestSecurityHeaders_HSTS_SetWithTLS(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -239,7 +239,7 @@ func TestSecurityHeaders_HSTS_SetWithTLS(t *testing.T) {
 }
 
 func TestSecurityHeaders_HSTS_IncludesSubdomains(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -255,7 +255,7 @@ func TestSecurityHeaders_HSTS_IncludesSubdomains(t *testing.T) {
 
 func TestSecurityHeaders_CallsNextHandler(t *testing.T) {
 	called := false
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -269,7 +269,7 @@ func TestSecurityHeaders_CallsNextHandler(t *testing.T) {
 }
 
 func TestSecurityHeaders_PreservesResponseStatus(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 	}))
 
@@ -282,7 +282,7 @@ func TestSecurityHeaders_PreservesResponseStatus(t *testing.T) {
 }
 
 func TestSecurityHeaders_PreservesResponseBody(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.Write([]byte("test response"))
 	}))
 
@@ -295,7 +295,7 @@ func TestSecurityHeaders_PreservesResponseBody(t *testing.T) {
 }
 
 func TestSecurityHeaders_AllHeadersSet(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -318,7 +318,7 @@ func TestSecurityHeaders_AllHeadersSet(t *testing.T) {
 }
 
 func TestSecurityHeaders_CSP_AllDirectives(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := SecurityHeaders(CSPConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -344,3 +344,42 @@ func TestSecurityHeaders_CSP_AllDirectives(t *testing.T) {
 		assert.Contains(t, csp, directive, "CSP should contain %s directive", directive)
 	}
 }
+
+func TestSecurityHeaders_CSP_Overrides(t *testing.T) {
+	handler := SecurityHeaders(CSPConfig{
+		ScriptSrc:  "'self'",
+		ConnectSrc: "'self' https://cdn.example.com",
+	}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	csp := rec.Header().Get("Content-Security-Policy")
+	assert.Contains(t, csp, "script-src 'self';")
+	assert.NotContains(t, csp, "cdn.jsdelivr.net")
+	assert.Contains(t, csp, "connect-src 'self' https://cdn.example.com;")
+	assert.Contains(t, csp, "style-src "+DefaultCSPStyleSrc+";", "unset directives keep their default")
+	assert.Contains(t, csp, "font-src "+DefaultCSPFontSrc+";")
+}
+
+func TestCSPConfig_Allow(t *testing.T) {
+	csp := buildCSP(CSPConfig{ScriptSrc: "'self'"}.Allow("https://challenges.cloudflare.com"))
+
+	assert.Contains(t, csp, "script-src 'self' https://challenges.cloudflare.com;")
+	assert.Contains(t, csp, "style-src "+DefaultCSPStyleSrc+" https://challenges.cloudflare.com;")
+	assert.Contains(t, csp, "connect-src "+DefaultCSPConnectSrc+" https://challenges.cloudflare.com;")
+	assert.Contains(t, csp, "frame-src https://challenges.cloudflare.com")
+	assert.NotContains(t, buildCSP(CSPConfig{}), "frame-src", "frames stay under default-src")
+}
+
+func TestCSPConfig_AllowMedia(t *testing.T) {
+	csp := buildCSP(CSPConfig{}.AllowMedia("https://media.s3.example.com"))
+
+	assert.Contains(t, csp, "img-src "+DefaultCSPImgSrc+" https://media.s3.example.com;")
+	assert.Contains(t, csp, "media-src "+DefaultCSPMediaSrc+" https://media.s3.example.com;")
+	assert.Contains(t, csp, "script-src "+DefaultCSPScriptSrc+";", "scripts still only load from the usual sources")
+}
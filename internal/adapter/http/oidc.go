@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
+)
+
+// IdentityProvider is an OpenID Connect provider users can sign in through.
+type IdentityProvider interface {
+	AuthCodeURL(state, nonce string) string
+	Exchange(ctx context.Context, code, nonce string) (*domain.OIDCIdentity, error)
+}
+
+// The state and nonce of a pending OIDC login live in a short-lived cookie
+// scoped to the callback. It is SameSite=Lax because the provider redirects
+// back with a cross-site navigation.
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStatePath   = "/auth/oidc"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+// OIDCLoginHandler starts an OIDC login by redirecting to the provider.
+func OIDCLoginHandler(provider IdentityProvider, behindProxy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := randomToken()
+		if err != nil {
+			logger.Error.Printf("oidc: failed to generate state: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		nonce, err := randomToken()
+		if err != nil {
+			logger.Error.Printf("oidc: failed to generate nonce: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state + ":" + nonce,
+			MaxAge:   int(oidcStateTTL.Seconds()),
+			Path:     oidcStatePath,
+			Secure:   r.TLS != nil || behindProxy,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, provider.AuthCodeURL(state, nonce), http.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler completes an OIDC login: it checks the state, redeems
+// the code, maps the identity to a local user and issues the session cookie.
+func OIDCCallbackHandler(authSvc AuthService, provider IdentityProvider, loginOpts templates.LoginOptions, version string, behindProxy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID := getClientID(r, behindProxy)
+		query := r.URL.Query()
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    "",
+			MaxAge:   -1,
+			Path:     oidcStatePath,
+			Secure:   r.TLS != nil || behindProxy,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		if err != nil {
+			renderLogin(w, r, "Login expired, please try again", loginOpts, version, http.StatusBadRequest)
+			return
+		}
+		state, nonce, ok := strings.Cut(cookie.Value, ":")
+		if !ok || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+			logger.Warn.Printf("oidc: state mismatch from %s", clientID)
+			renderLogin(w, r, "Login expired, please try again", loginOpts, version, http.StatusBadRequest)
+			return
+		}
+
+		if providerErr := query.Get("error"); providerErr != "" {
+			logger.Info.Printf("oidc: provider returned %s for %s", providerErr, clientID)
+			renderLogin(w, r, "Sign-in was cancelled or denied", loginOpts, version, http.StatusUnauthorized)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			renderLogin(w, r, "Missing authorization code", loginOpts, version, http.StatusBadRequest)
+			return
+		}
+
+		identity, err := provider.Exchange(r.Context(), code, nonce)
+		if err != nil {
+			logger.Error.Printf("oidc: failed to verify login from %s: %v", clientID, err)
+			renderLogin(w, r, "Could not verify sign-in with the identity provider", loginOpts, version, http.StatusBadGateway)
+			return
+		}
+
+		user, err := authSvc.LoginWithOIDC(identity)
+		switch {
+		case errors.Is(err, service.ErrUserExists):
+			logger.Warn.Printf("oidc: subject %s clashes with an existing local account", identity.Subject)
+			renderLogin(w, r, "An account with this username already exists", loginOpts, version, http.StatusConflict)
+			return
+		case errors.Is(err, service.ErrInvalidUsername):
+			logger.Warn.Printf("oidc: no usable username for subject %s: %v", identity.Subject, err)
+			renderLogin(w, r, "Your identity provider did not supply a usable username", loginOpts, version, http.StatusBadRequest)
+			return
+		case err != nil:
+			logger.Error.Printf("oidc: failed to resolve user for subject %s: %v", identity.Subject, err)
+			renderLogin(w, r, "Internal error, please try again", loginOpts, version, http.StatusInternalServerError)
+			return
+		}
+
+		token, err := authSvc.GenerateToken(user.Username)
+		if err != nil {
+			logger.Error.Printf("oidc: failed to generate token for %s: %v", user.Username, err)
+			renderLogin(w, r, "Internal error, please try again", loginOpts, version, http.StatusInternalServerError)
+			return
+		}
+
+		setAuthCookie(w, r, token, authSvc.TokenTTL(), behindProxy)
+		logger.Info.Printf("login successful for %s via oidc from %s", user.Username, clientID)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_ = templates.SignedIn().Render(r.Context(), w)
+	}
+}
+
+// randomToken returns 128 random bits, base64url encoded.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
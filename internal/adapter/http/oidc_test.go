@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdentityProvider accepts the code "good" with the nonce it handed out.
+type stubIdentityProvider struct{}
+
+func (stubIdentityProvider) AuthCodeURL(state, nonce string) string {
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode()
+}
+
+func (stubIdentityProvider) Exchange(_ context.Context, code, nonce string) (*domain.OIDCIdentity, error) {
+	if code != "good" || nonce != "nonce" {
+		return nil, assert.AnError
+	}
+	return &domain.OIDCIdentity{Subject: "abc-123", Username: "jane"}, nil
+}
+
+// oidcAuthStub maps every identity to jane.
+type oidcAuthStub struct {
+	AuthService
+}
+
+func (oidcAuthStub) LoginWithOIDC(*domain.OIDCIdentity) (*domain.User, error) {
+	return &domain.User{ID: 2, Username: "jane"}, nil
+}
+func (oidcAuthStub) GenerateToken(string) (string, error) { return "session", nil }
+func (oidcAuthStub) TokenTTL() time.Duration              { return time.Hour }
+
+func TestOIDCLoginHandler_RedirectsWithState(t *testing.T) {
+	rec := httptest.NewRecorder()
+	OIDCLoginHandler(stubIdentityProvider{}, false)(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, oidcStateCookie, cookies[0].Name)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, cookies[0].Value, location.Query().Get("state")+":"+location.Query().Get("nonce"))
+}
+
+func TestOIDCCallbackHandler(t *testing.T) {
+	handler := OIDCCallbackHandler(oidcAuthStub{}, stubIdentityProvider{}, templates.LoginOptions{Password: true, SSO: true}, "test", false)
+
+	tests := []struct {
+		name    string
+		cookie  string
+		query   string
+		want    int
+		session bool
+	}{
+		{"signs in", "state:nonce", "state=state&code=good", http.StatusOK, true},
+		{"missing state cookie", "", "state=state&code=good", http.StatusBadRequest, false},
+		{"state mismatch", "state:nonce", "state=forged&code=good", http.StatusBadRequest, false},
+		{"provider error", "state:nonce", "state=state&error=access_denied", http.StatusUnauthorized, false},
+		{"rejected code", "state:nonce", "state=state&code=bad", http.StatusBadGateway, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			var session *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == CookieName {
+					session = c
+				}
+			}
+			if tt.session {
+				require.NotNil(t, session)
+				assert.Equal(t, "session", session.Value)
+			} else {
+				assert.Nil(t, session)
+			}
+		})
+	}
+}
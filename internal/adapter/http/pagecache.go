@@ -0,0 +1,72 @@
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+// maxPageCacheEntries bounds the cache so arbitrary query strings can't grow it forever.
+const maxPageCacheEntries = 128
+
+type cachedPage struct {
+	body    []byte
+	expires time.Time
+}
+
+// pageCache holds rendered pages for a short TTL. A nil *pageCache is a
+// valid, disabled cache: Get always misses and Set/Invalidate are no-ops.
+type pageCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedPage
+}
+
+// newPageCache returns a cache with the given TTL, or nil when ttl is not positive.
+func newPageCache(ttl time.Duration) *pageCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &pageCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedPage),
+	}
+}
+
+func (c *pageCache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page, ok := c.entries[key]
+	if !ok || time.Now().After(page.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return page.body, true
+}
+
+func (c *pageCache) Set(key string, body []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxPageCacheEntries {
+		clear(c.entries)
+	}
+	c.entries[key] = cachedPage{body: body, expires: time.Now().Add(c.ttl)}
+}
+
+// Invalidate drops every cached page.
+func (c *pageCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+}
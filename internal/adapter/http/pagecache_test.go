@@ -0,0 +1,39 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageCache_DisabledWhenTTLIsZero(t *testing.T) {
+	c := newPageCache(0)
+	assert.Nil(t, c)
+
+	c.Set("k", []byte("body"))
+	_, ok := c.Get("k")
+	assert.False(t, ok)
+	c.Invalidate()
+}
+
+func TestPageCache_GetSetInvalidate(t *testing.T) {
+	c := newPageCache(time.Minute)
+
+	c.Set("k", []byte("body"))
+	body, ok := c.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "body", string(body))
+
+	c.Invalidate()
+	_, ok = c.Get("k")
+	assert.False(t, ok)
+}
+
+func TestPageCache_Expires(t *testing.T) {
+	c := newPageCache(time.Minute)
+	c.entries["k"] = cachedPage{body: []byte("stale"), expires: time.Now().Add(-time.Second)}
+
+	_, ok := c.Get("k")
+	assert.False(t, ok)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits each client to a steady request rate while allowing
+// short bursts up to the bucket size.
+type TokenBucket struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rate    float64 // tokens per second
+	burst   float64
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket allows perMinute requests per client on average, with at
+// most burst in a row from a full bucket.
+func NewTokenBucket(perMinute, burst int) *TokenBucket {
+	limiter := &TokenBucket{
+		buckets: make(map[string]*bucket),
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+	}
+
+	go limiter.cleanup()
+
+	return limiter
+}
+
+// Allow takes a token for clientID. When the bucket is empty it returns false
+// and how long until the next token is available.
+func (b *TokenBucket) Allow(clientID string) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	bk, exists := b.buckets[clientID]
+	if !exists {
+		bk = &bucket{tokens: b.burst, last: now}
+		b.buckets[clientID] = bk
+	}
+
+	bk.tokens = min(b.burst, bk.tokens+now.Sub(bk.last).Seconds()*b.rate)
+	bk.last = now
+
+	if bk.tokens < 1 {
+		wait := math.Ceil((1 - bk.tokens) / b.rate)
+		return false, time.Duration(wait) * time.Second
+	}
+
+	bk.tokens--
+	return true, 0
+}
+
+func (b *TokenBucket) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.mu.Lock()
+		now := time.Now()
+		refill := time.Duration(b.burst / b.rate * float64(time.Second))
+
+		// A bucket idle long enough to be full again is the same as none.
+		for clientID, bk := range b.buckets {
+			if now.Sub(bk.last) > refill {
+				delete(b.buckets, clientID)
+			}
+		}
+
+		b.mu.Unlock()
+	}
+}
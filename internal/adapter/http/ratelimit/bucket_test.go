@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Allow_BurstThenBlocks(t *testing.T) {
+	limiter := NewTokenBucket(6, 3)
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("client1")
+		assert.True(t, allowed)
+	}
+
+	allowed, wait := limiter.Allow("client1")
+
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, wait)
+}
+
+func TestTokenBucket_Allow_Refills(t *testing.T) {
+	limiter := NewTokenBucket(6, 1)
+
+	allowed, _ := limiter.Allow("client1")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("client1")
+	assert.False(t, allowed)
+
+	limiter.mu.Lock()
+	limiter.buckets["client1"].last = time.Now().Add(-10 * time.Second)
+	limiter.mu.Unlock()
+
+	allowed, _ = limiter.Allow("client1")
+	assert.True(t, allowed)
+}
+
+func TestTokenBucket_Allow_PerClient(t *testing.T) {
+	limiter := NewTokenBucket(6, 1)
+
+	limiter.Allow("client1")
+	allowed, _ := limiter.Allow("client2")
+
+	assert.True(t, allowed)
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
+)
+
+// RecoverHandler lets a user who forgot their password set a new one with
+// their recovery code. Attempts share the login rate limiter and backoff.
+func RecoverHandler(authSvc AuthService, rateLimiter *ratelimit.LoginRateLimiter, tracker *ratelimit.LoginAttemptTracker, backoff *ratelimit.Backoff, version string, behindProxy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID := getClientID(r, behindProxy)
+
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_ = templates.Recover(version).Render(r.Context(), w)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		code := r.FormValue("code")
+		newPassword := r.FormValue("new_password")
+		confirmPassword := r.FormValue("confirm_password")
+
+		if code == "" || newPassword == "" {
+			renderFormError(w, r, "Recovery code and new password are required", http.StatusBadRequest)
+			return
+		}
+
+		if newPassword != confirmPassword {
+			renderFormError(w, r, "Passwords do not match", http.StatusBadRequest)
+			return
+		}
+
+		allowed, blockDuration := rateLimiter.Check(clientID)
+		if !allowed {
+			logger.Warn.Printf("recover attempt: rate limit exceeded from %s, blocked for %v", clientID, blockDuration)
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", blockDuration.Seconds()))
+			renderFormError(w, r, fmt.Sprintf("Too many attempts. Try again in %s", formatDuration(blockDuration)), http.StatusTooManyRequests)
+			return
+		}
+
+		if err := authSvc.ResetPasswordWithCode(code, newPassword); err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidRecovery):
+				tracker.RecordFailure(clientID)
+				failedAttempts := tracker.GetFailedAttempts(clientID)
+				if backoffDuration := backoff.Duration(failedAttempts); backoffDuration > 0 {
+					logger.Info.Printf("recover attempt: invalid code from %s (attempt %d), backing off for %v", clientID, failedAttempts, backoffDuration)
+					time.Sleep(backoffDuration)
+				} else {
+					logger.Info.Printf("recover attempt: invalid code from %s (attempt %d)", clientID, failedAttempts)
+				}
+				renderFormError(w, r, "Invalid recovery code", http.StatusUnauthorized)
+			case errors.Is(err, service.ErrWeakPassword):
+				renderFormError(w, r, "Could not reset password: "+err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error.Printf("recover: failed to reset password: %v", err)
+				renderFormError(w, r, "Internal error, please try again", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		tracker.RecordSuccess(clientID)
+		rateLimiter.Reset(clientID)
+		logger.Info.Printf("recover: password reset with recovery code from %s", clientID)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.Header.Get("HX-Request") == HXRequestTrue {
+			w.Header().Set("HX-Retarget", "#recover-card")
+			w.Header().Set("HX-Reswap", "innerHTML")
+			w.WriteHeader(http.StatusOK)
+			_ = templates.RecoverSuccess().Render(r.Context(), w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = templates.RecoverComplete(version).Render(r.Context(), w)
+	}
+}
+
+// RecoveryCodeHandler shows the user's recovery code status on GET and
+// replaces the code on POST. The new code is rendered only in the POST
+// response.
+func RecoveryCodeHandler(authSvc AuthService, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		props := templates.RecoveryProps{IsAdmin: user.IsAdmin, Version: version}
+
+		if r.Method == http.MethodPost {
+			code, err := authSvc.GenerateRecoveryCode(user.ID)
+			if err != nil {
+				logger.Error.Printf("recovery: failed to generate code for user %s: %v", user.Username, err)
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = templates.ErrorPage("500", "Failed to generate recovery code", version).Render(r.Context(), w)
+				return
+			}
+			logger.Info.Printf("recovery: generated new code for user %s", user.Username)
+			props.NewCode = code
+			w.Header().Set("Cache-Control", "no-store")
+		}
+
+		hasCode, err := authSvc.HasRecoveryCode(user.ID)
+		if err != nil {
+			logger.Error.Printf("recovery: failed to load code status for user %s: %v", user.Username, err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorPage("500", "Failed to load recovery code", version).Render(r.Context(), w)
+			return
+		}
+		props.HasCode = hasCode
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_ = templates.RecoveryPage(props).Render(r.Context(), w)
+	}
+}
@@ -0,0 +1,296 @@
+package http
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// Maintenance turns requests away while a restore swaps out the data the
+// server reads. The liveness probe still answers so an orchestrator does
+// not restart the process in the middle of the swap.
+type Maintenance struct {
+	active atomic.Bool
+	// writes counts the requests in flight that may change data: any but
+	// GET and HEAD, which only read.
+	writes atomic.Int64
+}
+
+// countedWriteKey marks requests counted in Maintenance.writes.
+type countedWriteKey struct{}
+
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Counted before the check, so enter cannot miss a request that
+		// got past it.
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			m.writes.Add(1)
+			defer m.writes.Add(-1)
+			r = r.WithContext(context.WithValue(r.Context(), countedWriteKey{}, true))
+		}
+		if m.active.Load() && r.URL.Path != "/healthz" {
+			w.Header().Set("Retry-After", "30")
+			writeJSONError(w, http.StatusServiceUnavailable, "down for maintenance, try again shortly")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enter turns new requests away, then waits until the requests changing
+// data that were already running, other than r itself, are done. If ctx
+// ends first, maintenance is left again and ctx's error returned.
+func (m *Maintenance) enter(ctx context.Context, r *http.Request) error {
+	m.active.Store(true)
+	var own int64
+	if counted, _ := r.Context().Value(countedWriteKey{}).(bool); counted {
+		own = 1
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for m.writes.Load() > own {
+		select {
+		case <-ctx.Done():
+			m.active.Store(false)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (m *Maintenance) leave() {
+	m.active.Store(false)
+}
+
+// restoreWaitTimeout bounds how long a restore waits for running jobs and
+// requests before giving up with the data untouched.
+var restoreWaitTimeout = 2 * time.Minute
+
+type restoreResponse struct {
+	Status string `json:"status"`
+	Files  int    `json:"files"`
+}
+
+// errInvalidArchive marks archives rejected before anything was changed.
+var errInvalidArchive = errors.New("invalid backup archive")
+
+// AdminRestoreHandler replaces the metadata store and media files with the
+// contents of a backup archive sent as the request body, in the format
+// written by AdminBackupHandler with ?media=true. The archive is unpacked
+// and checked next to the live data first; the server then waits for
+// running jobs, enters maintenance mode, waits for running requests that
+// change data, swaps the media directories and the metadata in, and
+// resumes. A failed restore puts the previous media back, and one that
+// waits longer than restoreWaitTimeout is aborted first. Admin only.
+func AdminRestoreHandler(backup MetadataBackup, workers WorkerControl, maintenance *Maintenance, dataDir string) http.HandlerFunc {
+	var running sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+		if !running.TryLock() {
+			writeJSONError(w, http.StatusConflict, "a restore is already running")
+			return
+		}
+		defer running.Unlock()
+
+		// Archives with all media take longer to upload than the server's
+		// read timeout allows.
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		logger.Info.Printf("restore requested by %s", user.Username)
+
+		// Staged inside the data dir so the swap is a rename on one
+		// filesystem. Removing it afterwards also removes the old media.
+		staging, err := os.MkdirTemp(dataDir, ".restore-*")
+		if err != nil {
+			logger.Error.Printf("restore failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "restore failed")
+			return
+		}
+		defer func() { _ = os.RemoveAll(staging) }()
+
+		files, err := extractBackupArchive(r.Body, staging, backup.BackupName())
+		if err != nil {
+			logger.Error.Printf("restore rejected: %v", err)
+			if errors.Is(err, errInvalidArchive) {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+			} else {
+				writeJSONError(w, http.StatusInternalServerError, "restore failed")
+			}
+			return
+		}
+
+		waitCtx, cancel := context.WithTimeout(r.Context(), restoreWaitTimeout)
+		defer cancel()
+		wasPaused := workers.Paused()
+		if err := workers.Drain(waitCtx); err != nil {
+			if !wasPaused {
+				workers.Resume()
+			}
+			logger.Error.Printf("restore aborted waiting for running jobs: %v", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "timed out waiting for running jobs")
+			return
+		}
+		// Uploads already running must not save into the restored
+		// metadata or write into the swapped directories.
+		if err := maintenance.enter(waitCtx, r); err != nil {
+			if !wasPaused {
+				workers.Resume()
+			}
+			logger.Error.Printf("restore aborted waiting for running requests: %v", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "timed out waiting for running requests")
+			return
+		}
+		logger.Info.Printf("maintenance mode on for restore")
+
+		// The client going away must not stop the swap half way.
+		err = swapInBackup(context.WithoutCancel(r.Context()), backup, staging, dataDir)
+
+		maintenance.leave()
+		if !wasPaused {
+			workers.Resume()
+		}
+		logger.Info.Printf("maintenance mode off")
+
+		if err != nil {
+			logger.Error.Printf("restore failed: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "restore failed, the previous data was kept")
+			return
+		}
+		logger.Info.Printf("restored backup with %d media files", files)
+		writeJSON(w, http.StatusOK, restoreResponse{Status: "restored", Files: files})
+	}
+}
+
+// extractBackupArchive unpacks a gzipped tarball into dir and returns the
+// number of media files in it. Only the metadata snapshot, saved as name,
+// and files under the media directories are accepted.
+func extractBackupArchive(r io.Reader, dir, name string) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidArchive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	var files int
+	var hasMetadata bool
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", errInvalidArchive, err)
+		}
+
+		entry := path.Clean(header.Name)
+		if !filepath.IsLocal(entry) {
+			return 0, fmt.Errorf("%w: unsafe path %q", errInvalidArchive, header.Name)
+		}
+		root, _, nested := strings.Cut(entry, "/")
+		switch {
+		case header.Typeflag == tar.TypeDir:
+			continue
+		case header.Typeflag != tar.TypeReg:
+			return 0, fmt.Errorf("%w: %q is not a regular file", errInvalidArchive, header.Name)
+		case entry == name:
+			hasMetadata = true
+		case nested && slices.Contains(backupMediaDirs, root):
+			files++
+		default:
+			return 0, fmt.Errorf("%w: unexpected entry %q", errInvalidArchive, header.Name)
+		}
+
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(entry))); err != nil {
+			return 0, err
+		}
+	}
+	if !hasMetadata {
+		return 0, fmt.Errorf("%w: %s is missing", errInvalidArchive, name)
+	}
+	return files, nil
+}
+
+func extractFile(r io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) //nolint:gosec // entry paths are checked to be local
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: duplicate entry %q", errInvalidArchive, filepath.Base(dst))
+		}
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// swapInBackup moves the staged media directories into the data dir,
+// keeping the live ones in staging, and restores the metadata snapshot.
+// If anything fails the live directories are moved back.
+func swapInBackup(ctx context.Context, backup MetadataBackup, staging, dataDir string) error {
+	var swapped []string
+	rollback := func() {
+		for _, dir := range swapped {
+			live := filepath.Join(dataDir, dir)
+			if err := os.RemoveAll(live); err != nil {
+				logger.Error.Printf("restore rollback: remove %s: %v", live, err)
+				continue
+			}
+			if err := os.Rename(filepath.Join(staging, dir+".old"), live); err != nil && !os.IsNotExist(err) {
+				logger.Error.Printf("restore rollback: put back %s: %v", live, err)
+			}
+		}
+	}
+
+	for _, dir := range backupMediaDirs {
+		live := filepath.Join(dataDir, dir)
+		if err := os.Rename(live, filepath.Join(staging, dir+".old")); err != nil && !os.IsNotExist(err) {
+			rollback()
+			return fmt.Errorf("move aside %s: %w", live, err)
+		}
+		swapped = append(swapped, dir)
+		if err := os.Rename(filepath.Join(staging, dir), live); err != nil {
+			if !os.IsNotExist(err) {
+				rollback()
+				return fmt.Errorf("move in %s: %w", live, err)
+			}
+			// The backup has no files here
+			if err := os.MkdirAll(live, 0750); err != nil {
+				rollback()
+				return fmt.Errorf("create %s: %w", live, err)
+			}
+		}
+	}
+
+	if err := backup.Restore(ctx, filepath.Join(staging, backup.BackupName())); err != nil {
+		rollback()
+		return fmt.Errorf("restore metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRestoreHandler(t *testing.T) {
+	// A backup archive as AdminBackupHandler writes it
+	srcDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "uploads"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "uploads", "NEW12345_clip.mp4"), []byte("new video"), 0600))
+	snapshot, err := os.CreateTemp(t.TempDir(), "snapshot")
+	require.NoError(t, err)
+	_, err = snapshot.WriteString("snapshot")
+	require.NoError(t, err)
+	_, err = snapshot.Seek(0, 0)
+	require.NoError(t, err)
+	var archive bytes.Buffer
+	require.NoError(t, writeBackupArchive(&archive, snapshot, "sharm.db", srcDir))
+	require.NoError(t, snapshot.Close())
+
+	newDataDir := func() string {
+		dataDir := t.TempDir()
+		for _, dir := range backupMediaDirs {
+			require.NoError(t, os.MkdirAll(filepath.Join(dataDir, dir), 0750))
+			require.NoError(t, os.WriteFile(filepath.Join(dataDir, dir, "OLD12345_old"), []byte("old"), 0600))
+		}
+		return dataDir
+	}
+	admin := &domain.User{ID: 1, Username: "admin", IsAdmin: true}
+	request := func(handler http.HandlerFunc, body []byte, user *domain.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	assertNoStaging := func(dataDir string) {
+		entries, err := os.ReadDir(dataDir)
+		require.NoError(t, err)
+		for _, e := range entries {
+			assert.False(t, strings.HasPrefix(e.Name(), ".restore-"), "staging dir should be removed")
+		}
+	}
+
+	t.Run("requires admin", func(t *testing.T) {
+		handler := AdminRestoreHandler(&stubBackup{}, &stubWorkers{}, &Maintenance{}, newDataDir())
+		assert.Equal(t, http.StatusForbidden, request(handler, archive.Bytes(), &domain.User{ID: 2}).Code)
+	})
+
+	t.Run("restores metadata and media", func(t *testing.T) {
+		dataDir := newDataDir()
+		backup := &stubBackup{}
+		workers := &stubWorkers{}
+		handler := AdminRestoreHandler(backup, workers, &Maintenance{}, dataDir)
+
+		rec := request(handler, archive.Bytes(), admin)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{"status":"restored","files":1}`, rec.Body.String())
+		assert.Equal(t, "snapshot", backup.restored)
+		assert.False(t, workers.paused, "workers resume after the restore")
+
+		data, err := os.ReadFile(filepath.Join(dataDir, "uploads", "NEW12345_clip.mp4"))
+		require.NoError(t, err)
+		assert.Equal(t, "new video", string(data))
+		assert.NoFileExists(t, filepath.Join(dataDir, "uploads", "OLD12345_old"))
+		assert.DirExists(t, filepath.Join(dataDir, "converted"), "directories missing from the backup start empty")
+		assert.NoFileExists(t, filepath.Join(dataDir, "converted", "OLD12345_old"))
+		assertNoStaging(dataDir)
+	})
+
+	t.Run("keeps workers paused by an admin", func(t *testing.T) {
+		workers := &stubWorkers{paused: true}
+		handler := AdminRestoreHandler(&stubBackup{}, workers, &Maintenance{}, newDataDir())
+		require.Equal(t, http.StatusOK, request(handler, archive.Bytes(), admin).Code)
+		assert.True(t, workers.paused)
+	})
+
+	t.Run("rejects invalid archives", func(t *testing.T) {
+		for name, entry := range map[string]string{
+			"path traversal":   "../escape",
+			"unexpected entry": ".secret_key",
+			"media dir itself": "uploads",
+		} {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, n := range []string{"sharm.db", entry} {
+				require.NoError(t, tw.WriteHeader(&tar.Header{Name: n, Mode: 0600, Size: 1, Typeflag: tar.TypeReg}))
+				_, err := tw.Write([]byte("x"))
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+			require.NoError(t, gz.Close())
+
+			dataDir := newDataDir()
+			backup := &stubBackup{}
+			handler := AdminRestoreHandler(backup, &stubWorkers{}, &Maintenance{}, dataDir)
+			rec := request(handler, buf.Bytes(), admin)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+			assert.Empty(t, backup.restored, name)
+			assert.FileExists(t, filepath.Join(dataDir, "uploads", "OLD12345_old"), name)
+			assertNoStaging(dataDir)
+		}
+
+		handler := AdminRestoreHandler(&stubBackup{}, &stubWorkers{}, &Maintenance{}, newDataDir())
+		assert.Equal(t, http.StatusBadRequest, request(handler, []byte("not gzip"), admin).Code)
+	})
+
+	t.Run("requires the metadata snapshot", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeBackupArchive(&buf, emptyFile(t), "other.json", srcDir))
+		handler := AdminRestoreHandler(&stubBackup{}, &stubWorkers{}, &Maintenance{}, newDataDir())
+		rec := request(handler, buf.Bytes(), admin)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("gives up on jobs that keep running", func(t *testing.T) {
+		setRestoreWaitTimeout(t, 50*time.Millisecond)
+		dataDir := newDataDir()
+		backup := &stubBackup{}
+		workers := &stubWorkers{busy: true}
+		handler := AdminRestoreHandler(backup, workers, &Maintenance{}, dataDir)
+
+		rec := request(handler, archive.Bytes(), admin)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Empty(t, backup.restored)
+		assert.FileExists(t, filepath.Join(dataDir, "uploads", "OLD12345_old"))
+		assert.False(t, workers.paused)
+		assertNoStaging(dataDir)
+	})
+
+	t.Run("waits for running uploads", func(t *testing.T) {
+		setRestoreWaitTimeout(t, time.Second)
+		maintenance := &Maintenance{}
+		maintenance.writes.Store(1)
+		backup := &stubBackup{}
+		handler := AdminRestoreHandler(backup, &stubWorkers{}, maintenance, newDataDir())
+
+		time.AfterFunc(100*time.Millisecond, func() {
+			assert.True(t, maintenance.active.Load(), "new requests are turned away while waiting")
+			maintenance.writes.Add(-1)
+		})
+		rec := request(handler, archive.Bytes(), admin)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "snapshot", backup.restored)
+		assert.False(t, maintenance.active.Load())
+	})
+
+	t.Run("gives up on uploads that keep running", func(t *testing.T) {
+		setRestoreWaitTimeout(t, 50*time.Millisecond)
+		dataDir := newDataDir()
+		maintenance := &Maintenance{}
+		maintenance.writes.Store(1)
+		backup := &stubBackup{}
+		workers := &stubWorkers{}
+		handler := AdminRestoreHandler(backup, workers, maintenance, dataDir)
+
+		rec := request(handler, archive.Bytes(), admin)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Empty(t, backup.restored)
+		assert.FileExists(t, filepath.Join(dataDir, "uploads", "OLD12345_old"))
+		assert.False(t, maintenance.active.Load(), "requests are served again")
+		assert.False(t, workers.paused)
+		assertNoStaging(dataDir)
+	})
+
+	t.Run("puts the old media back when the metadata restore fails", func(t *testing.T) {
+		dataDir := newDataDir()
+		workers := &stubWorkers{}
+		handler := AdminRestoreHandler(&stubBackup{restoreErr: errors.New("corrupt")}, workers, &Maintenance{}, dataDir)
+
+		rec := request(handler, archive.Bytes(), admin)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.FileExists(t, filepath.Join(dataDir, "uploads", "OLD12345_old"))
+		assert.FileExists(t, filepath.Join(dataDir, "converted", "OLD12345_old"))
+		assert.NoFileExists(t, filepath.Join(dataDir, "uploads", "NEW12345_clip.mp4"))
+		assert.False(t, workers.paused)
+		assertNoStaging(dataDir)
+	})
+}
+
+func setRestoreWaitTimeout(t *testing.T, d time.Duration) {
+	t.Helper()
+	prev := restoreWaitTimeout
+	restoreWaitTimeout = d
+	t.Cleanup(func() { restoreWaitTimeout = prev })
+}
+
+func emptyFile(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "empty")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestMaintenance_Middleware(t *testing.T) {
+	m := &Maintenance{}
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("/").Code)
+
+	m.active.Store(true)
+	rec := serve("/")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusNoContent, serve("/healthz").Code, "liveness answers during maintenance")
+}
+
+func TestMaintenance_CountsWritingRequests(t *testing.T) {
+	m := &Maintenance{}
+	var during int64
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = m.writes.Load()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Zero(t, during, "reads are not counted")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", nil))
+	assert.Equal(t, int64(1), during)
+	assert.Zero(t, m.writes.Load(), "done requests are no longer counted")
+
+	m.active.Store(true)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Zero(t, m.writes.Load(), "turned away requests are no longer counted")
+}
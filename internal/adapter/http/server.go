@@ -6,6 +6,8 @@ import (
 
 	"github.com/bnema/sharm/internal/adapter/http/middleware"
 	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/infrastructure/metrics"
 	"github.com/bnema/sharm/internal/service"
 	"github.com/bnema/sharm/static"
 )
@@ -19,15 +21,58 @@ type Server struct {
 	rateLimiter    *ratelimit.LoginRateLimiter
 	backoffTracker *ratelimit.LoginAttemptTracker
 	backoff        *ratelimit.Backoff
+	uploadLimiter  *ratelimit.TokenBucket
 	csrf           *middleware.CSRFProtection
+	csp            middleware.CSPConfig
 	behindProxy    bool
 	version        string
+	metricsEnabled bool
+	metricsToken   string
+	identity       IdentityProvider
+	loginOpts      templates.LoginOptions
+	readiness      []ReadinessCheck
+	workers        WorkerControl
+	backup         MetadataBackup
+	dataDir        string
+	maintenance    *Maintenance
+
+	// anonymous enables the public upload form; nil keeps / behind login.
+	anonymous        *AnonymousUpload
+	anonymousLimiter *ratelimit.TokenBucket
 }
 
-func NewServer(authSvc AuthService, mediaSvc MediaService, eventBus *service.EventBus, domain string, maxSizeMB int, version string, behindProxy bool, secretKey string) *Server {
+// ServerConfig holds the handler settings plus those of sign-in, rate
+// limiting and the operational endpoints.
+type ServerConfig struct {
+	HandlerConfig
+
+	BehindProxy         bool
+	SecretKey           string
+	MetricsEnabled      bool
+	MetricsToken        string
+	Identity            IdentityProvider
+	PasswordLogin       bool
+	UploadRatePerMinute int
+	CSP                 middleware.CSPConfig
+	Readiness           []ReadinessCheck
+	Workers             WorkerControl
+	SSEJSONEvents       bool
+	Backup              MetadataBackup
+	DataDir             string
+
+	// Anonymous enables the public upload form; nil keeps / behind login.
+	Anonymous *AnonymousUpload
+}
+
+func NewServer(authSvc AuthService, mediaSvc MediaService, eventBus *service.EventBus, cfg ServerConfig) *Server {
 	mux := http.NewServeMux()
-	handlers := NewHandlers(mediaSvc, domain, maxSizeMB, version)
-	sseHandler := NewSSEHandler(eventBus, mediaSvc, domain)
+	handlers := NewHandlers(mediaSvc, cfg.HandlerConfig)
+	if handlers.dashboardCache != nil {
+		eventBus.Listen(func(string, service.Event) {
+			handlers.dashboardCache.Invalidate()
+		})
+	}
+	sseHandler := NewSSEHandler(eventBus, mediaSvc, cfg.Domain, cfg.Location, cfg.SSEJSONEvents)
 
 	rateLimiter := ratelimit.NewLoginRateLimiter(
 		5,
@@ -43,7 +88,12 @@ func NewServer(authSvc AuthService, mediaSvc MediaService, eventBus *service.Eve
 		2.0,
 	)
 
-	csrf := middleware.NewCSRFProtection(secretKey)
+	var uploadLimiter *ratelimit.TokenBucket
+	if cfg.UploadRatePerMinute > 0 {
+		uploadLimiter = ratelimit.NewTokenBucket(cfg.UploadRatePerMinute, cfg.UploadRatePerMinute)
+	}
+
+	csrf := middleware.NewCSRFProtection(cfg.SecretKey)
 
 	s := &Server{
 		mux:            mux,
@@ -54,9 +104,24 @@ func NewServer(authSvc AuthService, mediaSvc MediaService, eventBus *service.Eve
 		rateLimiter:    rateLimiter,
 		backoffTracker: backoffTracker,
 		backoff:        backoff,
+		uploadLimiter:  uploadLimiter,
 		csrf:           csrf,
-		behindProxy:    behindProxy,
-		version:        version,
+		csp:            cfg.CSP,
+		behindProxy:    cfg.BehindProxy,
+		version:        cfg.Version,
+		metricsEnabled: cfg.MetricsEnabled,
+		metricsToken:   cfg.MetricsToken,
+		identity:       cfg.Identity,
+		loginOpts:      templates.LoginOptions{Password: cfg.PasswordLogin, SSO: cfg.Identity != nil},
+		readiness:      cfg.Readiness,
+		workers:        cfg.Workers,
+		backup:         cfg.Backup,
+		dataDir:        cfg.DataDir,
+		maintenance:    &Maintenance{},
+		anonymous:      cfg.Anonymous,
+	}
+	if cfg.Anonymous != nil {
+		s.anonymousLimiter = ratelimit.NewTokenBucket(cfg.Anonymous.RatePerMinute, cfg.Anonymous.RatePerMinute)
 	}
 
 	s.registerRoutes()
@@ -66,35 +131,105 @@ func NewServer(authSvc AuthService, mediaSvc MediaService, eventBus *service.Eve
 }
 
 func (s *Server) registerRoutes() {
-	setupHandler := SetupHandler(s.authSvc, s.version, s.behindProxy)
-	s.mux.HandleFunc("GET /setup", setupHandler)
-	s.mux.HandleFunc("POST /setup", setupHandler)
+	// Probes for container orchestration; unauthenticated by design
+	s.mux.HandleFunc("GET /healthz", HealthzHandler())
+	s.mux.HandleFunc("GET /readyz", ReadyzHandler(s.readiness))
 
-	loginHandler := LoginHandler(s.authSvc, s.rateLimiter, s.backoffTracker, s.backoff, s.version, s.behindProxy)
+	loginHandler := LoginHandler(s.authSvc, s.rateLimiter, s.backoffTracker, s.backoff, s.loginOpts, s.version, s.behindProxy)
 	s.mux.HandleFunc("GET /login", loginHandler)
 	s.mux.HandleFunc("POST /login", loginHandler)
 
-	s.mux.HandleFunc("POST /logout", AuthMiddleware(s.authSvc, LogoutHandler(s.behindProxy)))
+	if s.loginOpts.Password {
+		setupHandler := SetupHandler(s.authSvc, s.version, s.behindProxy)
+		s.mux.HandleFunc("GET /setup", setupHandler)
+		s.mux.HandleFunc("POST /setup", setupHandler)
+
+		s.mux.HandleFunc("POST /login/totp", LoginTOTPHandler(s.authSvc, s.rateLimiter, s.backoffTracker, s.backoff, s.behindProxy))
+
+		recoverHandler := RecoverHandler(s.authSvc, s.rateLimiter, s.backoffTracker, s.backoff, s.version, s.behindProxy)
+		s.mux.HandleFunc("GET /recover", recoverHandler)
+		s.mux.HandleFunc("POST /recover", recoverHandler)
+	} else {
+		// With SSO as the only sign-in method the first provider login
+		// becomes the admin, so there is nothing to set up.
+		s.mux.HandleFunc("GET /setup", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		})
+	}
+
+	if s.identity != nil {
+		s.mux.HandleFunc("GET /auth/oidc/login", OIDCLoginHandler(s.identity, s.behindProxy))
+		s.mux.HandleFunc("GET /auth/oidc/callback", OIDCCallbackHandler(s.authSvc, s.identity, s.loginOpts, s.version, s.behindProxy))
+	}
 
-	s.mux.HandleFunc("POST /change-password", AuthMiddleware(s.authSvc, ChangePasswordHandler(s.authSvc)))
+	s.mux.HandleFunc("POST /logout", AuthMiddleware(s.authSvc, s.behindProxy, LogoutHandler(s.behindProxy)))
 
-	s.mux.HandleFunc("GET /{$}", AuthMiddleware(s.authSvc, s.handlers.Dashboard()))
+	s.mux.HandleFunc("POST /change-password", AuthMiddleware(s.authSvc, s.behindProxy, ChangePasswordHandler(s.authSvc)))
 
-	s.mux.HandleFunc("GET /upload", AuthMiddleware(s.authSvc, s.handlers.UploadPage()))
+	apiKeysHandler := APIKeysHandler(s.authSvc, s.version)
+	s.mux.HandleFunc("GET /settings/api-keys", AuthMiddleware(s.authSvc, s.behindProxy, apiKeysHandler))
+	s.mux.HandleFunc("POST /settings/api-keys", AuthMiddleware(s.authSvc, s.behindProxy, apiKeysHandler))
+	s.mux.HandleFunc("POST /settings/api-keys/{id}/revoke", AuthMiddleware(s.authSvc, s.behindProxy, RevokeAPIKeyHandler(s.authSvc)))
 
-	s.mux.HandleFunc("POST /upload", AuthMiddleware(s.authSvc, s.handlers.Upload()))
-	s.mux.HandleFunc("POST /upload/chunk", AuthMiddleware(s.authSvc, s.handlers.ChunkUpload()))
-	s.mux.HandleFunc("POST /upload/complete", AuthMiddleware(s.authSvc, s.handlers.CompleteUpload()))
+	recoveryCodeHandler := RecoveryCodeHandler(s.authSvc, s.version)
+	s.mux.HandleFunc("GET /settings/recovery", AuthMiddleware(s.authSvc, s.behindProxy, recoveryCodeHandler))
+	s.mux.HandleFunc("POST /settings/recovery", AuthMiddleware(s.authSvc, s.behindProxy, recoveryCodeHandler))
 
-	s.mux.HandleFunc("GET /status/", AuthMiddleware(s.authSvc, s.handlers.StatusPage()))
+	twoFactorHandler := TwoFactorHandler(s.authSvc, s.version)
+	s.mux.HandleFunc("GET /settings/2fa", AuthMiddleware(s.authSvc, s.behindProxy, twoFactorHandler))
+	s.mux.HandleFunc("POST /settings/2fa", AuthMiddleware(s.authSvc, s.behindProxy, twoFactorHandler))
+
+	usersHandler := UsersHandler(s.authSvc, s.version)
+	s.mux.HandleFunc("GET /settings/users", AuthMiddleware(s.authSvc, s.behindProxy, usersHandler))
+	s.mux.HandleFunc("POST /settings/users", AuthMiddleware(s.authSvc, s.behindProxy, usersHandler))
+
+	dashboard := AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.Dashboard())
+	if s.anonymous != nil {
+		s.mux.HandleFunc("GET /{$}", LandingOr(s.handlers.AnonymousUploadPage(*s.anonymous), dashboard))
+		s.mux.HandleFunc("POST /public/upload", UploadRateLimit(s.anonymousLimiter, s.behindProxy, s.handlers.AnonymousUpload(*s.anonymous, s.behindProxy)))
+	} else {
+		s.mux.HandleFunc("GET /{$}", dashboard)
+	}
+	s.mux.HandleFunc("GET /search", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.Search()))
 
-	s.mux.HandleFunc("GET /events/", AuthMiddleware(s.authSvc, s.sseHandler.Events()))
+	s.mux.HandleFunc("GET /upload", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.UploadPage()))
 
-	s.mux.HandleFunc("DELETE /media/", AuthMiddleware(s.authSvc, s.handlers.DeleteMedia()))
+	s.mux.HandleFunc("POST /upload", AuthMiddleware(s.authSvc, s.behindProxy, UploadRateLimit(s.uploadLimiter, s.behindProxy, s.handlers.Upload())))
+	s.mux.HandleFunc("POST /upload/chunk", AuthMiddleware(s.authSvc, s.behindProxy, UploadRateLimit(s.uploadLimiter, s.behindProxy, s.handlers.ChunkUpload())))
+	s.mux.HandleFunc("POST /upload/complete", AuthMiddleware(s.authSvc, s.behindProxy, UploadRateLimit(s.uploadLimiter, s.behindProxy, s.handlers.CompleteUpload())))
 
-	s.mux.HandleFunc("GET /media/", AuthMiddleware(s.authSvc, s.handlers.MediaInfo()))
+	s.mux.HandleFunc("GET /status/", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.StatusPage()))
 
-	s.mux.HandleFunc("GET /v/", s.handlers.Media())
+	s.mux.HandleFunc("GET /events/", AuthMiddleware(s.authSvc, s.behindProxy, s.sseHandler.Events()))
+
+	s.mux.HandleFunc("DELETE /media/", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.DeleteMedia()))
+	s.mux.HandleFunc("POST /media/bulk-delete", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.BulkDeleteMedia()))
+	s.mux.HandleFunc("POST /media/{id}/cancel", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.CancelMedia()))
+
+	s.mux.HandleFunc("GET /media/", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.MediaInfo()))
+
+	s.mux.HandleFunc("GET /stats", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.Stats()))
+	s.mux.HandleFunc("GET /api/v1/stats", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.APIStats()))
+	s.mux.HandleFunc("GET /api/v1/media", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.APIListMedia()))
+	s.mux.HandleFunc("GET /api/v1/media/exists", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.APIMediaExists()))
+	s.mux.HandleFunc("POST /api/v1/probe", AuthMiddleware(s.authSvc, s.behindProxy, UploadRateLimit(s.uploadLimiter, s.behindProxy, s.handlers.APIProbe())))
+
+	s.mux.HandleFunc("GET /admin/media/{id}/logs", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.AdminMediaLogs()))
+	s.mux.HandleFunc("POST /admin/reconvert-failed", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.AdminReconvertFailed()))
+	s.mux.HandleFunc("POST /admin/regenerate-thumbnails", AuthMiddleware(s.authSvc, s.behindProxy, s.handlers.AdminRegenerateThumbnails()))
+	s.mux.HandleFunc("GET /admin/workers", AuthMiddleware(s.authSvc, s.behindProxy, AdminWorkersHandler(s.workers)))
+	s.mux.HandleFunc("POST /admin/workers/pause", AuthMiddleware(s.authSvc, s.behindProxy, AdminPauseWorkersHandler(s.workers, true)))
+	s.mux.HandleFunc("POST /admin/workers/resume", AuthMiddleware(s.authSvc, s.behindProxy, AdminPauseWorkersHandler(s.workers, false)))
+	s.mux.HandleFunc("GET /admin/backup", AuthMiddleware(s.authSvc, s.behindProxy, AdminBackupHandler(s.backup, s.dataDir)))
+	s.mux.HandleFunc("POST /admin/restore", AuthMiddleware(s.authSvc, s.behindProxy, AdminRestoreHandler(s.backup, s.workers, s.maintenance, s.dataDir)))
+
+	registerMediaRoutes(s.mux, s.handlers)
+
+	s.mux.HandleFunc("GET /og-image", s.handlers.OGImage())
+
+	if s.metricsEnabled {
+		s.mux.Handle("GET /metrics", MetricsAuth(s.metricsToken, metrics.Handler()))
+	}
 }
 
 func (s *Server) registerStatic() {
@@ -102,6 +237,19 @@ func (s *Server) registerStatic() {
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Chain: SecurityHeaders -> CSRF -> mux
-	middleware.SecurityHeaders(s.csrf.Middleware(s.mux)).ServeHTTP(w, r)
+	// Chain: SecurityHeaders -> Maintenance -> CSRF -> mux
+	middleware.SecurityHeaders(s.csp, s.maintenance.Middleware(s.csrf.Middleware(s.mux))).ServeHTTP(w, r)
+}
+
+// registerMediaRoutes adds the public share routes: the share page at
+// /v/{id} and the files under it. Doubled slashes are already redirected
+// away by the mux; other spellings of a file fall back to MediaFile.
+func registerMediaRoutes(mux *http.ServeMux, h *Handlers) {
+	mux.HandleFunc("GET /v/{id}", h.SharePage())
+	mux.HandleFunc("GET /v/{id}/{$}", h.SharePage())
+	for file, handler := range h.MediaFiles() {
+		mux.HandleFunc("GET /v/{id}/"+file, handler)
+	}
+	mux.HandleFunc("GET /v/{id}/{file}", h.MediaFile())
+	mux.HandleFunc("GET /v/{id}/{file}/{$}", h.MediaFile())
 }
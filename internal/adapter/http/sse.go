@@ -3,9 +3,11 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/bnema/sharm/internal/adapter/http/templates"
 	"github.com/bnema/sharm/internal/domain"
@@ -17,21 +19,67 @@ type SSEHandler struct {
 	eventBus *service.EventBus
 	mediaSvc MediaService
 	domain   string
+	location *time.Location
+	// jsonEvents adds a machine-readable "done" event for custom clients.
+	jsonEvents bool
 }
 
 type renderedFragments struct {
-	statusHTML string
-	rowHTML    string
+	statusHTML   string
+	rowHTML      string
+	variantsHTML string
 }
 
-func NewSSEHandler(eventBus *service.EventBus, mediaSvc MediaService, domainName string) *SSEHandler {
+func NewSSEHandler(eventBus *service.EventBus, mediaSvc MediaService, domainName string, location *time.Location, jsonEvents bool) *SSEHandler {
 	return &SSEHandler{
-		eventBus: eventBus,
-		mediaSvc: mediaSvc,
-		domain:   domainName,
+		eventBus:   eventBus,
+		mediaSvc:   mediaSvc,
+		domain:     domainName,
+		location:   location,
+		jsonEvents: jsonEvents,
 	}
 }
 
+// doneEvent is the data of the JSON "done" event.
+type doneEvent struct {
+	ID       string           `json:"id"`
+	URL      string           `json:"url"`
+	Type     domain.MediaType `json:"type"`
+	Codecs   []domain.Codec   `json:"codecs"`
+	Width    int              `json:"width,omitempty"`
+	Height   int              `json:"height,omitempty"`
+	Duration float64          `json:"duration,omitempty"`
+	FileSize int64            `json:"file_size"`
+}
+
+// renderDoneJSON describes finished media for the JSON "done" event. Codecs
+// lists the variants ready to play.
+func (h *SSEHandler) renderDoneJSON(media *domain.Media) (string, error) {
+	event := doneEvent{
+		ID:       media.ID,
+		URL:      fmt.Sprintf("https://%s/v/%s", h.domain, media.ID),
+		Type:     media.Type,
+		Codecs:   []domain.Codec{},
+		Width:    media.Width,
+		Height:   media.Height,
+		FileSize: media.FileSize,
+	}
+	for _, v := range media.Variants {
+		if v.Status == domain.VariantStatusDone {
+			event.Codecs = append(event.Codecs, v.Codec)
+		}
+	}
+	if probe, err := media.ParseProbe(); err == nil {
+		event.Duration = domain.ParseDuration(probe.Format.Duration)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // renderStatusHTML renders the status page fragment for a media item.
 func (h *SSEHandler) renderStatusHTML(media *domain.Media) (string, error) {
 	var buf bytes.Buffer
@@ -40,7 +88,7 @@ func (h *SSEHandler) renderStatusHTML(media *domain.Media) (string, error) {
 	switch media.Status {
 	case domain.MediaStatusDone:
 		shareURL := fmt.Sprintf("https://%s/v/%s", h.domain, media.ID)
-		err = templates.StatusDone(media, shareURL).Render(context.Background(), &buf)
+		err = templates.StatusDone(media, shareURL, h.location).Render(context.Background(), &buf)
 	case domain.MediaStatusFailed:
 		err = templates.StatusFailed(media.ErrorMessage).Render(context.Background(), &buf)
 	default:
@@ -53,10 +101,20 @@ func (h *SSEHandler) renderStatusHTML(media *domain.Media) (string, error) {
 	return buf.String(), nil
 }
 
+// renderVariantsHTML renders the per-codec progress shown while a media is
+// converting.
+func (h *SSEHandler) renderVariantsHTML(media *domain.Media) (string, error) {
+	var buf bytes.Buffer
+	if err := templates.VariantProgress(media.Variants).Render(context.Background(), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // renderRowHTML renders the inner content of a dashboard row for SSE innerHTML swap.
 func (h *SSEHandler) renderRowHTML(media *domain.Media) (string, error) {
 	var buf bytes.Buffer
-	err := templates.DashboardRowContent(media, h.domain).Render(context.Background(), &buf)
+	err := templates.DashboardRowContent(media, h.domain, h.location).Render(context.Background(), &buf)
 	if err != nil {
 		return "", err
 	}
@@ -75,7 +133,9 @@ func sseWrite(w http.ResponseWriter, eventName, data string) {
 	}
 }
 
-// sendAllEvents sends changed "status" and "row" SSE fragments for a media item.
+// sendAllEvents sends changed "status", "variants" and "row" SSE fragments
+// for a media item. Variants are only sent while it converts; once done, the
+// status fragment replaces them.
 func (h *SSEHandler) sendAllEvents(w http.ResponseWriter, media *domain.Media, previous *renderedFragments) (*renderedFragments, error) {
 	statusHTML, err := h.renderStatusHTML(media)
 	if err != nil {
@@ -87,16 +147,33 @@ func (h *SSEHandler) sendAllEvents(w http.ResponseWriter, media *domain.Media, p
 		return nil, err
 	}
 
+	variantsHTML, err := h.renderVariantsHTML(media)
+	if err != nil {
+		return nil, err
+	}
+
+	terminal := media.Status == domain.MediaStatusDone || media.Status == domain.MediaStatusFailed
 	if previous == nil || previous.statusHTML != statusHTML {
 		sseWrite(w, "status", statusHTML)
 	}
+	if !terminal && (previous == nil || previous.variantsHTML != variantsHTML) {
+		sseWrite(w, "variants", variantsHTML)
+	}
 	if previous == nil || previous.rowHTML != rowHTML {
 		sseWrite(w, "row", rowHTML)
 	}
+	if h.jsonEvents && media.Status == domain.MediaStatusDone {
+		doneJSON, err := h.renderDoneJSON(media)
+		if err != nil {
+			return nil, err
+		}
+		sseWrite(w, "done", doneJSON)
+	}
 
 	return &renderedFragments{
-		statusHTML: statusHTML,
-		rowHTML:    rowHTML,
+		statusHTML:   statusHTML,
+		rowHTML:      rowHTML,
+		variantsHTML: variantsHTML,
 	}, nil
 }
 
@@ -111,18 +188,34 @@ func (h *SSEHandler) Events() http.HandlerFunc {
 		}
 
 		media, err := h.mediaSvc.Get(id)
-		if err != nil {
+		if err != nil || !ownsMedia(r, media) {
 			http.Error(w, "Media not found", http.StatusNotFound)
 			return
 		}
 
+		terminal := media.Status == domain.MediaStatusDone || media.Status == domain.MediaStatusFailed
+
+		// Subscribe to events before any response is written, so a full
+		// bus can still be answered with an error status
+		var ch chan service.Event
+		if !terminal {
+			ch, err = h.eventBus.Subscribe(id)
+			if err != nil {
+				logger.Warn.Printf("SSE subscription refused for media %s: %v", id, err)
+				w.Header().Set("Retry-After", "10")
+				http.Error(w, "Too many open event streams", http.StatusServiceUnavailable)
+				return
+			}
+			defer h.eventBus.Unsubscribe(id, ch)
+		}
+
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
 		w.Header().Set("X-Accel-Buffering", "no")
 
 		// If already terminal, send final events and close
-		if media.Status == domain.MediaStatusDone || media.Status == domain.MediaStatusFailed {
+		if terminal {
 			if _, err := h.sendAllEvents(w, media, nil); err != nil {
 				logger.Error.Printf("SSE render error for terminal media %s: %v", id, err)
 			}
@@ -136,16 +229,12 @@ func (h *SSEHandler) Events() http.HandlerFunc {
 			return
 		}
 
-		// Subscribe to events
-		ch := h.eventBus.Subscribe(id)
-		defer h.eventBus.Unsubscribe(id, ch)
-
 		ctx := r.Context()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case event, ok := <-ch:
+			case _, ok := <-ch:
 				if !ok {
 					return
 				}
@@ -156,8 +245,9 @@ func (h *SSEHandler) Events() http.HandlerFunc {
 				}
 				state, _ = h.sendAllEvents(w, media, state)
 
-				// Close on terminal states
-				if event.Status == string(domain.MediaStatusDone) || event.Status == string(domain.MediaStatusFailed) {
+				// Close on terminal states. The first event may be a replay
+				// of an older status, so trust the stored media over it.
+				if media.Status == domain.MediaStatusDone || media.Status == domain.MediaStatusFailed {
 					return
 				}
 			}
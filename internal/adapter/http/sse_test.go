@@ -1,16 +1,37 @@
 package http
 
 import (
+	"context"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// finishingStub reports media as processing on the first Get and done after,
+// as if the conversion finished right before the client subscribed.
+type finishingStub struct {
+	MediaService
+	calls int
+}
+
+func (s *finishingStub) Get(id string) (*domain.Media, error) {
+	s.calls++
+	status := domain.MediaStatusProcessing
+	if s.calls > 1 {
+		status = domain.MediaStatusDone
+	}
+	return &domain.Media{ID: id, OwnerID: 1, Type: domain.MediaTypeImage, OriginalName: "a.png", Status: status, RetentionDays: 7}, nil
+}
+
 func TestSendAllEvents_SkipsUnchangedFragments(t *testing.T) {
-	h := NewSSEHandler(nil, nil, "example.com")
+	h := NewSSEHandler(nil, nil, "example.com", nil, false)
 	media := &domain.Media{
 		ID:            "abc12345",
 		Type:          domain.MediaTypeVideo,
@@ -37,7 +58,7 @@ func TestSendAllEvents_SkipsUnchangedFragments(t *testing.T) {
 }
 
 func TestSendAllEvents_EmitsUpdatedFragments(t *testing.T) {
-	h := NewSSEHandler(nil, nil, "example.com")
+	h := NewSSEHandler(nil, nil, "example.com", nil, false)
 	processing := &domain.Media{
 		ID:            "abc12345",
 		Type:          domain.MediaTypeVideo,
@@ -73,3 +94,125 @@ func TestSendAllEvents_EmitsUpdatedFragments(t *testing.T) {
 	assert.Equal(t, 1, strings.Count(second.Body.String(), "event: status"))
 	assert.Equal(t, 1, strings.Count(second.Body.String(), "event: row"))
 }
+
+func TestEvents_DeliversStatusPublishedBeforeSubscribe(t *testing.T) {
+	bus := service.NewEventBus(0, 0)
+	bus.Publish("abc12345", service.Event{Type: "status", Status: string(domain.MediaStatusDone)})
+	h := NewSSEHandler(bus, &finishingStub{}, "example.com", nil, false)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events/abc12345", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: 1}))
+	// Returns only once the terminal state has been sent.
+	h.Events()(rec, req)
+
+	assert.Equal(t, 2, strings.Count(rec.Body.String(), "event: status"))
+	assert.Contains(t, rec.Body.String(), "/v/abc12345")
+}
+
+func TestEvents_OtherUsersMediaNotFound(t *testing.T) {
+	bus := service.NewEventBus(1, 0)
+	h := NewSSEHandler(bus, &finishingStub{}, "example.com", nil, false)
+	owner, err := bus.Subscribe("abc12345")
+	require.NoError(t, err)
+	defer bus.Unsubscribe("abc12345", owner)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events/abc12345", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userKey, &domain.User{ID: 2}))
+	h.Events()(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "event:")
+
+	select {
+	case _, ok := <-owner:
+		assert.True(t, ok, "the owner's stream should not be evicted")
+	default:
+	}
+}
+
+func TestRenderStatusHTML_ShowsExpiryInLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	h := NewSSEHandler(nil, nil, "example.com", paris, false)
+	media := &domain.Media{
+		ID:            "abc12345",
+		Type:          domain.MediaTypeImage,
+		Status:        domain.MediaStatusDone,
+		RetentionDays: 7,
+		ExpiresAt:     time.Date(2027, 1, 9, 12, 30, 0, 0, time.UTC),
+	}
+
+	html, err := h.renderStatusHTML(media)
+
+	require.NoError(t, err)
+	assert.Contains(t, html, "Jan 9, 2027 13:30 CET")
+}
+
+func TestSendAllEvents_JSONDoneEvent(t *testing.T) {
+	media := &domain.Media{
+		ID:        "abc12345",
+		Type:      domain.MediaTypeVideo,
+		Status:    domain.MediaStatusDone,
+		Width:     1920,
+		Height:    1080,
+		FileSize:  2048,
+		ProbeJSON: `{"format":{"duration":"12.500000"}}`,
+		Variants: []domain.Variant{
+			{Codec: domain.CodecH264, Status: domain.VariantStatusDone},
+			{Codec: domain.CodecAV1, Status: domain.VariantStatusFailed},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	_, err := NewSSEHandler(nil, nil, "example.com", nil, true).sendAllEvents(rec, media, nil)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "event: done\n"+
+		`data: {"id":"abc12345","url":"https://example.com/v/abc12345","type":"video","codecs":["h264"],"width":1920,"height":1080,"duration":12.5,"file_size":2048}`)
+
+	rec = httptest.NewRecorder()
+	_, err = NewSSEHandler(nil, nil, "example.com", nil, false).sendAllEvents(rec, media, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, rec.Body.String(), "event: done")
+}
+
+func TestSendAllEvents_VariantProgress(t *testing.T) {
+	h := NewSSEHandler(nil, nil, "example.com", nil, false)
+	media := &domain.Media{
+		ID:            "abc12345",
+		Type:          domain.MediaTypeVideo,
+		OriginalName:  "demo.mp4",
+		Status:        domain.MediaStatusProcessing,
+		RetentionDays: 7,
+		Variants: []domain.Variant{
+			{Codec: domain.CodecAV1, Status: domain.VariantStatusProcessing},
+			{Codec: domain.CodecH264, Status: domain.VariantStatusPending},
+		},
+	}
+
+	first := httptest.NewRecorder()
+	state, err := h.sendAllEvents(first, media, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(first.Body.String(), "event: variants"))
+	assert.Contains(t, first.Body.String(), "converting")
+	assert.Contains(t, first.Body.String(), "queued")
+
+	// One codec finishing only changes the variants and the row, so the
+	// status fragment holding the SSE connection is left alone
+	media.Variants[0].Status = domain.VariantStatusDone
+	media.Variants[0].FileSize = 2048
+	second := httptest.NewRecorder()
+	_, err = h.sendAllEvents(second, media, state)
+	require.NoError(t, err)
+	assert.Equal(t, 0, strings.Count(second.Body.String(), "event: status"))
+	assert.Equal(t, 1, strings.Count(second.Body.String(), "event: variants"))
+	assert.Contains(t, second.Body.String(), "done, 2.0 KB")
+
+	// A single codec has nothing to break down
+	single := httptest.NewRecorder()
+	media.Variants = media.Variants[:1]
+	_, err = h.sendAllEvents(single, media, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, single.Body.String(), "done, 2.0 KB")
+}
@@ -8,7 +8,37 @@ package templates
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func Upload(version string) templ.Component {
+import (
+	"github.com/bnema/sharm/internal/domain"
+	"slices"
+	"time"
+)
+
+// retentionChoices returns the configured presets that fit the retention
+// bounds, plus the default when it is not one of them. Never expiring, if
+// allowed, stays last.
+func retentionChoices(policy domain.RetentionPolicy) []time.Duration {
+	presets := policy.Presets
+	if len(presets) == 0 {
+		presets = domain.DefaultRetentionPresets
+	}
+	var choices []time.Duration
+	for _, preset := range presets {
+		if preset != domain.RetentionNever && policy.Clamp(preset) == preset && !slices.Contains(choices, preset) {
+			choices = append(choices, preset)
+		}
+	}
+	if policy.Default != domain.RetentionNever && !slices.Contains(choices, policy.Default) {
+		choices = append(choices, policy.Default)
+	}
+	slices.Sort(choices)
+	if policy.AllowsNever() && (slices.Contains(presets, domain.RetentionNever) || policy.Default == domain.RetentionNever) {
+		choices = append(choices, domain.RetentionNever)
+	}
+	return choices
+}
+
+func Upload(version string, retention domain.RetentionPolicy) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -83,7 +113,53 @@ func Upload(version string) templ.Component {
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Codec selection (shown dynamically based on file type) --><div id=\"codec-options\" style=\"display:none;margin-top:var(--s-md);\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Conversion formats</label><div style=\"display:flex;flex-direction:column;gap:var(--s-xs);\"><label style=\"display:flex;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-muted);cursor:default;\"><input type=\"checkbox\" checked disabled> <span>Original (always kept)</span></label> <label id=\"codec-av1\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"av1\"> <span>WebM (AV1)</span></label> <label id=\"codec-h264\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"h264\"> <span>MP4 (H264)</span></label> <label id=\"codec-opus\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"opus\"> <span>OGG (Opus)</span></label></div><div id=\"fps-options\" style=\"display:none;margin-top:var(--s-sm);\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Frame rate</label><div style=\"display:flex;gap:var(--s-md);\"><label style=\"display:flex;align-items:center;gap:var(--s-xs);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"radio\" name=\"fps\" value=\"30\" checked> <span>30 FPS</span></label> <label style=\"display:flex;align-items:center;gap:var(--s-xs);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"radio\" name=\"fps\" value=\"60\"> <span>60 FPS</span></label></div></div></div><div class=\"mt-md\" style=\"display:flex;align-items:flex-end;gap:var(--s-sm);\"><div style=\"flex:1;\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Retention</label> <select name=\"retention\" class=\"input\"><option value=\"1\">1 day</option> <option value=\"3\">3 days</option> <option value=\"7\" selected>7 days</option> <option value=\"14\">14 days</option> <option value=\"30\">30 days</option></select></div><button type=\"submit\" class=\"button\">Upload</button></div></form>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Codec selection (shown dynamically based on file type) --><div id=\"codec-options\" style=\"display:none;margin-top:var(--s-md);\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Conversion formats</label><div style=\"display:flex;flex-direction:column;gap:var(--s-xs);\"><label style=\"display:flex;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-muted);cursor:default;\"><input type=\"checkbox\" checked disabled> <span>Original (always kept)</span></label> <label id=\"codec-auto\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"auto\"> <span>Auto (chosen from the source)</span></label> <label id=\"codec-av1\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"av1\"> <span>WebM (AV1)</span></label> <label id=\"codec-h264\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"h264\"> <span>MP4 (H264)</span></label> <label id=\"codec-opus\" style=\"display:none;align-items:center;gap:var(--s-sm);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"checkbox\" name=\"codecs\" value=\"opus\"> <span>OGG (Opus)</span></label></div><div id=\"fps-options\" style=\"display:none;margin-top:var(--s-sm);\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Frame rate</label><div style=\"display:flex;gap:var(--s-md);\"><label style=\"display:flex;align-items:center;gap:var(--s-xs);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"radio\" name=\"fps\" value=\"30\" checked> <span>30 FPS</span></label> <label style=\"display:flex;align-items:center;gap:var(--s-xs);font-size:var(--text-sm);color:var(--text-primary);cursor:pointer;\"><input type=\"radio\" name=\"fps\" value=\"60\"> <span>60 FPS</span></label></div></div></div><div class=\"mt-md\" style=\"display:flex;align-items:flex-end;gap:var(--s-sm);\"><div style=\"flex:1;\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Retention</label> <select name=\"retention\" class=\"input\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				for _, choice := range retentionChoices(retention) {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<option value=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var5 string
+					templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(domain.FormatRetention(choice))
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/adapter/http/templates/upload.templ`, Line: 85, Col: 54}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					if choice == retention.Default {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " selected")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, ">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var6 string
+					templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(domain.RetentionLabel(choice))
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/adapter/http/templates/upload.templ`, Line: 85, Col: 130}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</option>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</select></div><div style=\"flex:1;\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Tags</label> <input type=\"text\" name=\"tags\" class=\"input\" placeholder=\"comma, separated\"></div><div style=\"flex:1;\"><label class=\"text-muted\" style=\"display:block;font-size:var(--text-xs);margin-bottom:var(--s-xs);\">Custom link</label> <input type=\"text\" name=\"slug\" class=\"input\" placeholder=\"optional, e.g. my-demo\" minlength=\"3\" maxlength=\"64\" pattern=\"[A-Za-z0-9]([A-Za-z0-9\\-]*[A-Za-z0-9])?\"></div><button type=\"submit\" class=\"button\">Upload</button></div></form>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
@@ -91,7 +167,7 @@ func Upload(version string) templ.Component {
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " <div id=\"probe-result\" class=\"mt-md\"></div><div id=\"result\" class=\"mt-md\"></div>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, " <div id=\"probe-result\" class=\"mt-md\"></div><div id=\"result\" class=\"mt-md\"></div>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+
+	"github.com/pquerna/otp"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
+)
+
+// totpQRSize is the edge length in pixels of the enrollment QR code.
+const totpQRSize = 200
+
+// TwoFactorHandler shows the user's 2FA state on GET. POST performs the
+// form's action: "enroll" starts an enrollment, "enable" confirms it with a
+// code and reveals the backup codes once, and "disable" turns 2FA off.
+func TwoFactorHandler(authSvc AuthService, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		props := templates.TwoFactorProps{IsAdmin: user.IsAdmin, Version: version}
+		status := http.StatusOK
+
+		if r.Method == http.MethodPost {
+			var err error
+			action := r.FormValue("action")
+			switch action {
+			case "enroll":
+				_, err = authSvc.BeginTOTPEnrollment(user.ID)
+			case "enable":
+				props.BackupCodes, err = authSvc.EnableTOTP(user.ID, r.FormValue("code"))
+				w.Header().Set("Cache-Control", "no-store")
+			case "disable":
+				err = authSvc.DisableTOTP(user.ID, r.FormValue("code"))
+			default:
+				http.Error(w, "Unknown action", http.StatusBadRequest)
+				return
+			}
+
+			switch {
+			case errors.Is(err, service.ErrInvalidTOTP):
+				props.Error = "Invalid authentication code"
+				status = http.StatusBadRequest
+			case errors.Is(err, service.ErrTOTPNotEnrolled), errors.Is(err, service.ErrTOTPAlreadyEnabled):
+				props.Error = "Two-factor authentication changed in the meantime, please try again"
+				status = http.StatusConflict
+			case err != nil:
+				logger.Error.Printf("2fa: failed to %s for user %s: %v", action, user.Username, err)
+				props.Error = "Failed to update two-factor authentication"
+				status = http.StatusInternalServerError
+			default:
+				logger.Info.Printf("2fa: %s succeeded for user %s", action, user.Username)
+			}
+		}
+
+		totpStatus, err := authSvc.TOTPStatus(user.ID)
+		if err != nil {
+			logger.Error.Printf("2fa: failed to load status for user %s: %v", user.Username, err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorPage("500", "Failed to load two-factor settings", version).Render(r.Context(), w)
+			return
+		}
+		props.Enabled = totpStatus.Enabled
+		props.RemainingBackups = totpStatus.BackupCodes
+
+		if totpStatus.PendingURI != "" {
+			props.QRCode, props.Secret, err = totpQRCode(totpStatus.PendingURI)
+			if err != nil {
+				logger.Error.Printf("2fa: failed to render qr code for user %s: %v", user.Username, err)
+			}
+			w.Header().Set("Cache-Control", "no-store")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = templates.TwoFactorPage(props).Render(r.Context(), w)
+	}
+}
+
+// totpQRCode renders a provisioning URI as a PNG data URI and also returns
+// the secret for manual entry.
+func totpQRCode(uri string) (string, string, error) {
+	key, err := otp.NewKeyFromURL(uri)
+	if err != nil {
+		return "", "", fmt.Errorf("parse totp uri: %w", err)
+	}
+
+	img, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return "", key.Secret(), fmt.Errorf("encode qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", key.Secret(), fmt.Errorf("encode qr png: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), key.Secret(), nil
+}
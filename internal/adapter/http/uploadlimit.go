@@ -0,0 +1,31 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// UploadRateLimit throttles upload requests per client so one client cannot
+// saturate the worker pool and disk. Every chunk of a chunked upload counts
+// as a request. A nil limiter disables the limit.
+func UploadRateLimit(limiter *ratelimit.TokenBucket, behindProxy bool, next http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID := getClientID(r, behindProxy)
+		if allowed, wait := limiter.Allow(clientID); !allowed {
+			logger.Warn.Printf("upload rate limit exceeded from %s, path=%s", clientID, r.URL.Path)
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", wait.Seconds()))
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = templates.ErrorInline(fmt.Sprintf("Too many uploads. Try again in %s", formatDuration(wait))).Render(r.Context(), w)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnema/sharm/internal/adapter/http/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadRateLimit(t *testing.T) {
+	next := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	handler := UploadRateLimit(ratelimit.NewTokenBucket(60, 2), false, next)
+
+	codes := make([]int, 0, 3)
+	var rec *httptest.ResponseRecorder
+	for range 3 {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/upload", nil))
+		codes = append(codes, rec.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bnema/sharm/internal/adapter/http/templates"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/service"
+)
+
+// UsersHandler lists accounts on GET and adds one on POST. Admin only.
+func UsersHandler(authSvc AuthService, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin := currentUser(r)
+		if admin == nil || !admin.IsAdmin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		props := templates.UsersProps{Version: version}
+		status := http.StatusOK
+
+		if r.Method == http.MethodPost {
+			username := r.FormValue("username")
+			password := r.FormValue("password")
+
+			switch {
+			case username == "" || password == "":
+				props.Error = "Username and password are required"
+				status = http.StatusBadRequest
+			case password != r.FormValue("confirm_password"):
+				props.Error = "Passwords do not match"
+				status = http.StatusBadRequest
+			default:
+				if err := authSvc.AddUser(admin, username, password); err != nil {
+					logger.Warn.Printf("users: failed to add user %s: %v", logger.SanitizeForLog(username), err)
+					props.Error = addUserErrorMessage(err)
+					status = http.StatusBadRequest
+				} else {
+					logger.Info.Printf("users: %s added user %s", admin.Username, logger.SanitizeForLog(username))
+					props.Created = username
+				}
+			}
+		}
+
+		users, err := authSvc.ListUsers()
+		if err != nil {
+			logger.Error.Printf("users: failed to list users: %v", err)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = templates.ErrorPage("500", "Failed to load users", version).Render(r.Context(), w)
+			return
+		}
+		props.Users = users
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = templates.UsersPage(props).Render(r.Context(), w)
+	}
+}
+
+// addUserErrorMessage turns an AddUser error into a message for the form.
+func addUserErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrUserExists):
+		return "A user with that name already exists"
+	case errors.Is(err, service.ErrInvalidUsername), errors.Is(err, service.ErrWeakPassword):
+		return "Could not add user: " + err.Error()
+	case errors.Is(err, service.ErrNotAdmin):
+		return "Only admins can add users"
+	default:
+		return "Failed to create user. Please try again."
+	}
+}
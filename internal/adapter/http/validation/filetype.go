@@ -4,14 +4,19 @@ package validation
 import (
 	"errors"
 	"io"
+	"maps"
 	"net/http"
+	"strings"
 )
 
 // ErrDisallowedFileType is returned when a file type is not in the allowlist.
 var ErrDisallowedFileType = errors.New("file type not allowed")
 
-// allowedMIMETypes defines the allowlist of media MIME types accepted for upload.
-var allowedMIMETypes = map[string]bool{
+// MIMEAllowlist is the set of MIME types accepted for upload.
+type MIMEAllowlist map[string]bool
+
+// defaultMIMETypes defines the media MIME types accepted when no allowlist is configured.
+var defaultMIMETypes = MIMEAllowlist{
 	// Images
 	"image/jpeg": true,
 	"image/png":  true,
@@ -32,6 +37,21 @@ var allowedMIMETypes = map[string]bool{
 	"audio/x-flac":    true,
 }
 
+// NewMIMEAllowlist builds an allowlist from types, normalizing case and
+// whitespace. An empty list yields the default allowlist.
+func NewMIMEAllowlist(types []string) MIMEAllowlist {
+	allowlist := MIMEAllowlist{}
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			allowlist[t] = true
+		}
+	}
+	if len(allowlist) == 0 {
+		return maps.Clone(defaultMIMETypes)
+	}
+	return allowlist
+}
+
 // magicBytesBufferSize is the number of bytes to read for content type detection.
 const magicBytesBufferSize = 512
 
@@ -44,9 +64,9 @@ const magicBytesBufferSize = 512
 //
 // Returns:
 //   - mime: the detected MIME type
-//   - allowed: whether the file type is in the allowlist
+//   - allowed: whether the file type is in allowlist
 //   - err: any error encountered during reading or seeking
-func ValidateMagicBytes(reader io.ReadSeeker) (mime string, allowed bool, err error) {
+func ValidateMagicBytes(reader io.ReadSeeker, allowlist MIMEAllowlist) (mime string, allowed bool, err error) {
 	// Read up to 512 bytes for content type detection
 	buf := make([]byte, magicBytesBufferSize)
 	n, err := reader.Read(buf)
@@ -75,7 +95,7 @@ func ValidateMagicBytes(reader io.ReadSeeker) (mime string, allowed bool, err er
 	}
 
 	// Check if MIME type is allowed
-	allowed = allowedMIMETypes[mime]
+	allowed = allowlist[mime]
 
 	return mime, allowed, nil
 }
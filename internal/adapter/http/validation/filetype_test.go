@@ -46,7 +46,7 @@ func padBytes(magic []byte, size int) []byte {
 
 func TestValidateMagicBytes_JPEG_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(jpegMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "JPEG should be allowed")
@@ -55,7 +55,7 @@ func TestValidateMagicBytes_JPEG_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_PNG_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(pngMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "PNG should be allowed")
@@ -64,7 +64,7 @@ func TestValidateMagicBytes_PNG_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_GIF_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(gifMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "GIF should be allowed")
@@ -73,7 +73,7 @@ func TestValidateMagicBytes_GIF_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_WebP_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(webpMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "WebP should be allowed")
@@ -82,7 +82,7 @@ func TestValidateMagicBytes_WebP_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_MP4_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(mp4Magic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "MP4 should be allowed")
@@ -91,7 +91,7 @@ func TestValidateMagicBytes_MP4_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_WebM_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(webmMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "WebM should be allowed")
@@ -100,7 +100,7 @@ func TestValidateMagicBytes_WebM_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_MP3_WithoutID3_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(mp3Magic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "MP3 without ID3 should be allowed")
@@ -109,7 +109,7 @@ func TestValidateMagicBytes_MP3_WithoutID3_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_MP3_WithID3_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(mp3ID3, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "MP3 with ID3 should be allowed")
@@ -118,7 +118,7 @@ func TestValidateMagicBytes_MP3_WithID3_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_OGG_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(oggMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "OGG should be allowed")
@@ -127,7 +127,7 @@ func TestValidateMagicBytes_OGG_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_WAV_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(wavMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "WAV should be allowed")
@@ -136,7 +136,7 @@ func TestValidateMagicBytes_WAV_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_FLAC_Allowed(t *testing.T) {
 	reader := bytes.NewReader(padBytes(flacMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.True(t, allowed, "FLAC should be allowed")
@@ -147,7 +147,7 @@ func TestValidateMagicBytes_FLAC_Allowed(t *testing.T) {
 
 func TestValidateMagicBytes_PHP_Rejected(t *testing.T) {
 	reader := bytes.NewReader(padBytes(phpMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.False(t, allowed, "PHP should be rejected")
@@ -156,7 +156,7 @@ func TestValidateMagicBytes_PHP_Rejected(t *testing.T) {
 
 func TestValidateMagicBytes_HTML_Rejected(t *testing.T) {
 	reader := bytes.NewReader(padBytes(htmlMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.False(t, allowed, "HTML should be rejected")
@@ -165,7 +165,7 @@ func TestValidateMagicBytes_HTML_Rejected(t *testing.T) {
 
 func TestValidateMagicBytes_JavaScript_Rejected(t *testing.T) {
 	reader := bytes.NewReader(padBytes(jsMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.False(t, allowed, "JavaScript should be rejected")
@@ -174,7 +174,7 @@ func TestValidateMagicBytes_JavaScript_Rejected(t *testing.T) {
 
 func TestValidateMagicBytes_EXE_Rejected(t *testing.T) {
 	reader := bytes.NewReader(padBytes(exeMagic, 512))
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.False(t, allowed, "EXE should be rejected")
@@ -183,7 +183,7 @@ func TestValidateMagicBytes_EXE_Rejected(t *testing.T) {
 
 func TestValidateMagicBytes_Empty_Rejected(t *testing.T) {
 	reader := bytes.NewReader(emptyMagic)
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 	require.NoError(t, err)
 	assert.False(t, allowed, "Empty file should be rejected")
@@ -197,7 +197,7 @@ func TestValidateMagicBytes_ReaderPositionReset(t *testing.T) {
 	reader := bytes.NewReader(originalData)
 
 	// Validate should read and reset
-	_, _, err := ValidateMagicBytes(reader)
+	_, _, err := ValidateMagicBytes(reader, defaultMIMETypes)
 	require.NoError(t, err)
 
 	// Verify reader is at position 0
@@ -227,7 +227,7 @@ func TestValidateMagicBytes_ReaderPositionResetAfterPartialRead(t *testing.T) {
 	require.NoError(t, err)
 
 	// Now validate
-	_, _, err = ValidateMagicBytes(reader)
+	_, _, err = ValidateMagicBytes(reader, defaultMIMETypes)
 	require.NoError(t, err)
 
 	// Verify reader is at position 0
@@ -243,7 +243,7 @@ func TestValidateMagicBytes_SmallFile_NoError(t *testing.T) {
 	smallData := []byte{0xFF, 0xD8, 0xFF, 0xE0}
 	reader := bytes.NewReader(smallData)
 
-	mime, allowed, err := ValidateMagicBytes(reader)
+	mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 	require.NoError(t, err)
 	assert.True(t, allowed, "Small JPEG-like file should still be validated")
 	assert.NotEmpty(t, mime)
@@ -270,7 +270,7 @@ func TestValidateMagicBytes_AllowedTypes_TableDriven(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			reader := bytes.NewReader(padBytes(tt.magic, 512))
-			mime, allowed, err := ValidateMagicBytes(reader)
+			mime, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 			require.NoError(t, err)
 			assert.True(t, allowed, "%s should be allowed", tt.name)
@@ -298,7 +298,7 @@ func TestValidateMagicBytes_RejectedTypes_TableDriven(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			reader := bytes.NewReader(padBytes(tt.magic, 512))
-			_, allowed, err := ValidateMagicBytes(reader)
+			_, allowed, err := ValidateMagicBytes(reader, defaultMIMETypes)
 
 			require.NoError(t, err)
 			assert.False(t, allowed, "%s should be rejected", tt.name)
@@ -306,6 +306,45 @@ func TestValidateMagicBytes_RejectedTypes_TableDriven(t *testing.T) {
 	}
 }
 
+// --- Tests for configured allowlists ---
+
+func TestNewMIMEAllowlist_EmptyUsesDefaults(t *testing.T) {
+	assert.Equal(t, defaultMIMETypes, NewMIMEAllowlist(nil))
+	assert.Equal(t, defaultMIMETypes, NewMIMEAllowlist([]string{" ", ""}))
+}
+
+func TestNewMIMEAllowlist_Normalizes(t *testing.T) {
+	allowlist := NewMIMEAllowlist([]string{" Image/PNG ", "image/jpeg"})
+	assert.Equal(t, MIMEAllowlist{"image/png": true, "image/jpeg": true}, allowlist)
+}
+
+func TestValidateMagicBytes_RestrictedAllowlist(t *testing.T) {
+	imagesOnly := NewMIMEAllowlist([]string{"image/jpeg", "image/png"})
+
+	tests := []struct {
+		name    string
+		magic   []byte
+		allowed bool
+	}{
+		{"JPEG", jpegMagic, true},
+		{"PNG", pngMagic, true},
+		{"GIF", gifMagic, false},
+		{"MP4", mp4Magic, false},
+		{"WebM", webmMagic, false},
+		{"MP3", mp3ID3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bytes.NewReader(padBytes(tt.magic, 512))
+			_, allowed, err := ValidateMagicBytes(reader, imagesOnly)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, allowed)
+		})
+	}
+}
+
 // --- Test ErrDisallowedFileType ---
 
 func TestErrDisallowedFileType_Defined(t *testing.T) {
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// WorkerControl pauses and resumes background job processing.
+type WorkerControl interface {
+	Pause()
+	Resume()
+	Paused() bool
+	// Drain pauses processing and waits for running jobs to finish.
+	Drain(ctx context.Context) error
+}
+
+type workersResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// AdminWorkersHandler reports whether job processing is paused. Admin only.
+func AdminWorkersHandler(workers WorkerControl) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := currentUser(r); user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+		writeJSON(w, http.StatusOK, workersResponse{Paused: workers.Paused()})
+	}
+}
+
+// AdminPauseWorkersHandler pauses or resumes job processing. Running jobs
+// finish and queued jobs wait; nothing is lost across a pause. Admin only.
+func AdminPauseWorkersHandler(workers WorkerControl, pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if user == nil || !user.IsAdmin {
+			writeJSONError(w, http.StatusForbidden, "admin privileges required")
+			return
+		}
+		if pause {
+			workers.Pause()
+		} else {
+			workers.Resume()
+		}
+		logger.Info.Printf("workers paused=%t by %s", pause, user.Username)
+		writeJSON(w, http.StatusOK, workersResponse{Paused: workers.Paused()})
+	}
+}
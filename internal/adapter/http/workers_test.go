@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubWorkers struct {
+	paused bool
+	// busy makes Drain wait until its context ends.
+	busy bool
+}
+
+func (s *stubWorkers) Pause()       { s.paused = true }
+func (s *stubWorkers) Resume()      { s.paused = false }
+func (s *stubWorkers) Paused() bool { return s.paused }
+
+func (s *stubWorkers) Drain(ctx context.Context) error {
+	s.paused = true
+	if s.busy {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func TestAdminPauseWorkersHandler(t *testing.T) {
+	workers := &stubWorkers{}
+	admin := &domain.User{ID: 1, Username: "admin", IsAdmin: true}
+
+	request := func(handler http.HandlerFunc, user *domain.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/workers", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	paused := func(rec *httptest.ResponseRecorder) bool {
+		var resp workersResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Paused
+	}
+
+	rec := request(AdminPauseWorkersHandler(workers, true), admin)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, paused(rec))
+	assert.True(t, paused(request(AdminWorkersHandler(workers), admin)))
+
+	rec = request(AdminPauseWorkersHandler(workers, false), admin)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, paused(rec))
+
+	assert.Equal(t, http.StatusForbidden, request(AdminPauseWorkersHandler(workers, true), &domain.User{ID: 2}).Code)
+	assert.False(t, workers.Paused(), "non-admins cannot pause")
+}
@@ -0,0 +1,83 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+var (
+	ErrMissingIDToken = errors.New("token response has no id_token")
+	ErrNonceMismatch  = errors.New("id token nonce mismatch")
+)
+
+// Provider signs users in through an OpenID Connect provider using the
+// authorization code flow.
+type Provider struct {
+	oauth    oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// NewProvider discovers the provider's endpoints and signing keys from
+// issuerURL. It fails if the issuer cannot be reached.
+func NewProvider(ctx context.Context, issuerURL, clientID, clientSecret, redirectURL string) (*Provider, error) {
+	provider, err := gooidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc issuer %s: %w", issuerURL, err)
+	}
+
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{gooidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: provider.Verifier(&gooidc.Config{ClientID: clientID}),
+	}, nil
+}
+
+func (p *Provider) AuthCodeURL(state, nonce string) string {
+	return p.oauth.AuthCodeURL(state, gooidc.Nonce(nonce))
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (*domain.OIDCIdentity, error) {
+	token, err := p.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode id token claims: %w", err)
+	}
+
+	return &domain.OIDCIdentity{
+		Subject:  idToken.Subject,
+		Username: claims.PreferredUsername,
+		Email:    claims.Email,
+	}, nil
+}
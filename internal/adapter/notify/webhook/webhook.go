@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+// Format is the payload shape a webhook expects.
+type Format string
+
+const (
+	FormatAuto    Format = "auto"
+	FormatGeneric Format = "generic"
+	FormatDiscord Format = "discord"
+	FormatSlack   Format = "slack"
+)
+
+// Embed colours for Discord, as 0xRRGGBB.
+const (
+	discordColorDone   = 0x2ecc71
+	discordColorFailed = 0xe74c3c
+)
+
+// DetectFormat resolves FormatAuto from the webhook URL: Discord and Slack
+// incoming webhooks get their own payloads, anything else the generic one.
+func DetectFormat(format Format, rawURL string) Format {
+	if format != FormatAuto {
+		return format
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return FormatGeneric
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case (host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")) &&
+		strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return FormatDiscord
+	case host == "hooks.slack.com":
+		return FormatSlack
+	default:
+		return FormatGeneric
+	}
+}
+
+// Webhook posts a message to a URL when a media finishes converting.
+type Webhook struct {
+	url        string
+	format     Format
+	domainName string
+	client     *http.Client
+}
+
+// NewWebhook returns a webhook posting to rawURL. Links in the payload point
+// at domainName. Each delivery is abandoned after timeout.
+func NewWebhook(rawURL string, format Format, domainName string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		url:        rawURL,
+		format:     DetectFormat(format, rawURL),
+		domainName: domainName,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *Webhook) Format() Format {
+	return w.format
+}
+
+func (w *Webhook) Notify(ctx context.Context, media *domain.Media) error {
+	body, err := json.Marshal(w.payload(media))
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Sharm")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) payload(media *domain.Media) any {
+	switch w.format {
+	case FormatDiscord:
+		return w.discordPayload(media)
+	case FormatSlack:
+		return w.slackPayload(media)
+	default:
+		return w.genericPayload(media)
+	}
+}
+
+func (w *Webhook) shareURL(media *domain.Media) string {
+	return fmt.Sprintf("https://%s/v/%s", w.domainName, media.ID)
+}
+
+// thumbnailURL returns the preview image of media, or "" when it has none.
+func (w *Webhook) thumbnailURL(media *domain.Media) string {
+	switch {
+	case media.Type == domain.MediaTypeImage:
+		return w.shareURL(media) + "/raw"
+	case media.ThumbPath != "":
+		return w.shareURL(media) + "/thumb"
+	default:
+		return ""
+	}
+}
+
+// summary describes the outcome of the conversion in one line.
+func summary(media *domain.Media) string {
+	if media.Status == domain.MediaStatusFailed {
+		if media.ErrorMessage != "" {
+			return "Conversion failed: " + media.ErrorMessage
+		}
+		return "Conversion failed"
+	}
+	return fmt.Sprintf("%s ready to share", capitalize(string(media.Type)))
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+type genericPayload struct {
+	Event string       `json:"event"`
+	Media genericMedia `json:"media"`
+}
+
+type genericMedia struct {
+	ID           string             `json:"id"`
+	URL          string             `json:"url"`
+	Type         domain.MediaType   `json:"type"`
+	OriginalName string             `json:"original_name"`
+	Status       domain.MediaStatus `json:"status"`
+	Error        string             `json:"error,omitempty"`
+	FileSize     int64              `json:"file_size"`
+	ThumbnailURL string             `json:"thumbnail_url,omitempty"`
+}
+
+func (w *Webhook) genericPayload(media *domain.Media) genericPayload {
+	return genericPayload{
+		Event: "media." + string(media.Status),
+		Media: genericMedia{
+			ID:           media.ID,
+			URL:          w.shareURL(media),
+			Type:         media.Type,
+			OriginalName: media.OriginalName,
+			Status:       media.Status,
+			Error:        media.ErrorMessage,
+			FileSize:     media.FileSize,
+			ThumbnailURL: w.thumbnailURL(media),
+		},
+	}
+}
+
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Thumbnail   *discordImage  `json:"thumbnail,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func (w *Webhook) discordPayload(media *domain.Media) discordPayload {
+	embed := discordEmbed{
+		Title:       media.OriginalName,
+		URL:         w.shareURL(media),
+		Description: summary(media),
+		Color:       discordColorDone,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	if media.Status == domain.MediaStatusFailed {
+		embed.Color = discordColorFailed
+	} else {
+		embed.Fields = []discordField{{Name: "Size", Value: domain.FormatSize(media.FileSize), Inline: true}}
+		if media.Width > 0 && media.Height > 0 {
+			embed.Fields = append(embed.Fields, discordField{
+				Name: "Resolution", Value: fmt.Sprintf("%dx%d", media.Width, media.Height), Inline: true,
+			})
+		}
+	}
+	if thumb := w.thumbnailURL(media); thumb != "" {
+		embed.Thumbnail = &discordImage{URL: thumb}
+	}
+	return discordPayload{Embeds: []discordEmbed{embed}}
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type      string      `json:"type"`
+	Text      *slackText  `json:"text,omitempty"`
+	Accessory *slackImage `json:"accessory,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackImage struct {
+	Type     string `json:"type"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+// slackEscaper escapes the characters Slack mrkdwn treats as control
+// sequences.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (w *Webhook) slackPayload(media *domain.Media) slackPayload {
+	name := slackEscaper.Replace(media.OriginalName)
+	block := slackBlock{
+		Type: "section",
+		Text: &slackText{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("*<%s|%s>*\n%s", w.shareURL(media), name, slackEscaper.Replace(summary(media))),
+		},
+	}
+	if thumb := w.thumbnailURL(media); thumb != "" {
+		block.Accessory = &slackImage{Type: "image", ImageURL: thumb, AltText: media.OriginalName}
+	}
+	return slackPayload{
+		Text:   fmt.Sprintf("%s: %s %s", media.OriginalName, summary(media), w.shareURL(media)),
+		Blocks: []slackBlock{block},
+	}
+}
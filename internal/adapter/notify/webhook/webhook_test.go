@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, FormatDiscord, DetectFormat(FormatAuto, "https://discord.com/api/webhooks/1/abc"))
+	assert.Equal(t, FormatDiscord, DetectFormat(FormatAuto, "https://ptb.discord.com/api/webhooks/1/abc"))
+	assert.Equal(t, FormatSlack, DetectFormat(FormatAuto, "https://hooks.slack.com/services/T/B/X"))
+	assert.Equal(t, FormatGeneric, DetectFormat(FormatAuto, "https://example.com/hook"))
+	assert.Equal(t, FormatGeneric, DetectFormat(FormatAuto, "https://discord.com.evil.example/api/webhooks/1"))
+	assert.Equal(t, FormatSlack, DetectFormat(FormatSlack, "https://relay.example.com/hook"))
+}
+
+// capture starts a server recording the last JSON body posted to it.
+func capture(t *testing.T, status int) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &body
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	video := &domain.Media{
+		ID: "AB12CD34", Type: domain.MediaTypeVideo, OriginalName: "clip <1>.mp4",
+		Status: domain.MediaStatusDone, FileSize: 2048, Width: 1920, Height: 1080, ThumbPath: "/data/thumb.jpg",
+	}
+
+	t.Run("generic", func(t *testing.T) {
+		srv, body := capture(t, http.StatusNoContent)
+		require.NoError(t, NewWebhook(srv.URL, FormatGeneric, "sharm.example.com", time.Second).Notify(context.Background(), video))
+
+		assert.Equal(t, "media.done", (*body)["event"])
+		media := (*body)["media"].(map[string]any)
+		assert.Equal(t, "https://sharm.example.com/v/AB12CD34", media["url"])
+		assert.Equal(t, "https://sharm.example.com/v/AB12CD34/thumb", media["thumbnail_url"])
+	})
+
+	t.Run("discord", func(t *testing.T) {
+		srv, body := capture(t, http.StatusNoContent)
+		require.NoError(t, NewWebhook(srv.URL, FormatDiscord, "sharm.example.com", time.Second).Notify(context.Background(), video))
+
+		embed := (*body)["embeds"].([]any)[0].(map[string]any)
+		assert.Equal(t, "clip <1>.mp4", embed["title"])
+		assert.Equal(t, "https://sharm.example.com/v/AB12CD34", embed["url"])
+		assert.Equal(t, float64(discordColorDone), embed["color"])
+		assert.Equal(t, "https://sharm.example.com/v/AB12CD34/thumb", embed["thumbnail"].(map[string]any)["url"])
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		srv, body := capture(t, http.StatusOK)
+		require.NoError(t, NewWebhook(srv.URL, FormatSlack, "sharm.example.com", time.Second).Notify(context.Background(), video))
+
+		block := (*body)["blocks"].([]any)[0].(map[string]any)
+		assert.Contains(t, block["text"].(map[string]any)["text"], "<https://sharm.example.com/v/AB12CD34|clip &lt;1&gt;.mp4>")
+		assert.Equal(t, "https://sharm.example.com/v/AB12CD34/thumb", block["accessory"].(map[string]any)["image_url"])
+	})
+
+	t.Run("failed conversion", func(t *testing.T) {
+		srv, body := capture(t, http.StatusNoContent)
+		failed := &domain.Media{ID: "AB12CD34", Type: domain.MediaTypeVideo, Status: domain.MediaStatusFailed, ErrorMessage: "all conversions failed"}
+		require.NoError(t, NewWebhook(srv.URL, FormatDiscord, "sharm.example.com", time.Second).Notify(context.Background(), failed))
+
+		embed := (*body)["embeds"].([]any)[0].(map[string]any)
+		assert.Equal(t, float64(discordColorFailed), embed["color"])
+		assert.Equal(t, "Conversion failed: all conversions failed", embed["description"])
+		assert.Nil(t, embed["thumbnail"])
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		srv, _ := capture(t, http.StatusBadRequest)
+		err := NewWebhook(srv.URL, FormatGeneric, "sharm.example.com", time.Second).Notify(context.Background(), video)
+		assert.ErrorContains(t, err, "400")
+	})
+}
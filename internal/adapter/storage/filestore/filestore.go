@@ -0,0 +1,92 @@
+// Package filestore keeps blobs as plain files under the data directory,
+// where media are produced, so storing them costs nothing.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bnema/sharm/internal/port"
+)
+
+type Store struct {
+	root string
+}
+
+func NewStore(root string) *Store {
+	return &Store{root: root}
+}
+
+// Put copies localPath to key, unless it is already the file stored there.
+func (s *Store) Put(ctx context.Context, key, localPath string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(localPath) == path {
+		return nil
+	}
+
+	in, err := os.Open(localPath) //nolint:gosec // path comes from the media store
+	if err != nil {
+		return fmt.Errorf("open %s: %w", localPath, err)
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("create blob directory: %w", err)
+	}
+	tmpPath := path + ".part"
+	out, err := os.Create(tmpPath) //nolint:gosec // path is built from the data dir
+	if err != nil {
+		return fmt.Errorf("create blob: %w", err)
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write blob: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path) //nolint:gosec // path is built from the data dir
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Presign is unsupported: files on disk are served by Sharm.
+func (s *Store) Presign(ctx context.Context, key, contentType, disposition string) (string, error) {
+	return "", port.ErrPresignUnsupported
+}
+
+// path maps key to a file under the root, refusing keys that escape it.
+func (s *Store) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+var _ port.BlobStore = (*Store)(nil)
@@ -0,0 +1,46 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bnema/sharm/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	root := t.TempDir()
+	s := NewStore(root)
+	ctx := context.Background()
+
+	// Files produced under the root are already stored.
+	inPlace := filepath.Join(root, "uploads", "AB12CD34_clip.mp4")
+	require.NoError(t, os.MkdirAll(filepath.Dir(inPlace), 0750))
+	require.NoError(t, os.WriteFile(inPlace, []byte("clip"), 0600))
+	require.NoError(t, s.Put(ctx, "uploads/AB12CD34_clip.mp4", inPlace))
+
+	elsewhere := filepath.Join(t.TempDir(), "thumb.jpg")
+	require.NoError(t, os.WriteFile(elsewhere, []byte("thumb"), 0600))
+	require.NoError(t, s.Put(ctx, "converted/AB12CD34_thumb.jpg", elsewhere))
+
+	r, err := s.Get(ctx, "converted/AB12CD34_thumb.jpg")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	_ = r.Close()
+	assert.Equal(t, "thumb", string(data))
+
+	require.NoError(t, s.Delete(ctx, "converted/AB12CD34_thumb.jpg"))
+	require.NoError(t, s.Delete(ctx, "converted/AB12CD34_thumb.jpg"), "deleting a missing key")
+	assert.NoFileExists(t, filepath.Join(root, "converted", "AB12CD34_thumb.jpg"))
+
+	_, err = s.Get(ctx, "../secret")
+	assert.Error(t, err)
+
+	_, err = s.Presign(ctx, "uploads/AB12CD34_clip.mp4", "", "")
+	assert.ErrorIs(t, err, port.ErrPresignUnsupported)
+}
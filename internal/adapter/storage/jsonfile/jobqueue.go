@@ -0,0 +1,247 @@
+package jsonfile
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// JobQueue keeps jobs in the store's document, in insertion order, so
+// enqueuing a variant and its job is a single write.
+type JobQueue struct {
+	store *Store
+}
+
+func NewJobQueue(store *Store) *JobQueue {
+	return &JobQueue{store: store}
+}
+
+// Enqueue adds a job unless one for the same media, type and codec is
+// already pending or running, in which case that job is returned instead.
+// Two jobs writing the same output path would otherwise race. Pending jobs
+// are claimed by descending priority, then in insertion order.
+func (q *JobQueue) Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps, priority int) (*domain.Job, error) {
+	var job domain.Job
+	err := q.store.update(func(doc *document) error {
+		if active := activeJob(doc, mediaID, jobType, codec); active != nil {
+			job = *active
+			return nil
+		}
+		job = *insertJob(doc, mediaID, jobType, codec, fps, priority, q.store.now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// EnqueueVariant saves v as a pending variant and queues its convert job in
+// one write, so a crash in between cannot leave a variant that no job will
+// ever process.
+func (q *JobQueue) EnqueueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	var job domain.Job
+	err := q.store.update(func(doc *document) error {
+		if activeJob(doc, v.MediaID, domain.JobTypeConvert, v.Codec) != nil {
+			return errActiveJob(v)
+		}
+		now := q.store.now()
+		if err := insertVariant(doc, v, now); err != nil {
+			return err
+		}
+		job = *insertJob(doc, v.MediaID, domain.JobTypeConvert, v.Codec, fps, domain.JobPriorityNormal, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RequeueVariant puts a failed variant back to pending and queues a new
+// convert job for it in one write.
+func (q *JobQueue) RequeueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	var job domain.Job
+	err := q.store.update(func(doc *document) error {
+		if activeJob(doc, v.MediaID, domain.JobTypeConvert, v.Codec) != nil {
+			return errActiveJob(v)
+		}
+		if stored := variantWhere(doc, func(sv *domain.Variant) bool { return sv.ID == v.ID }); stored != nil {
+			stored.Status = domain.VariantStatusPending
+			stored.ErrorMessage = ""
+		}
+		job = *insertJob(doc, v.MediaID, domain.JobTypeConvert, v.Codec, fps, domain.JobPriorityNormal, q.store.now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v.Status = domain.VariantStatusPending
+	v.ErrorMessage = ""
+	return &job, nil
+}
+
+// activeJob returns the pending or running job for the media, type and
+// codec, or nil. At most one exists, as with SQLite's unique index.
+func activeJob(doc *document, mediaID string, jobType domain.JobType, codec domain.Codec) *domain.Job {
+	for _, j := range doc.Jobs {
+		if j.MediaID == mediaID && j.Type == jobType && j.Codec == codec &&
+			(j.Status == domain.JobStatusPending || j.Status == domain.JobStatusRunning) {
+			return j
+		}
+	}
+	return nil
+}
+
+func errActiveJob(v *domain.Variant) error {
+	return fmt.Errorf("insert job: %s job for media %s is already queued", v.Codec, v.MediaID)
+}
+
+func insertJob(doc *document, mediaID string, jobType domain.JobType, codec domain.Codec, fps, priority int, now time.Time) *domain.Job {
+	doc.LastIDs.Job++
+	job := &domain.Job{
+		ID:        doc.LastIDs.Job,
+		MediaID:   mediaID,
+		Type:      jobType,
+		Codec:     codec,
+		Fps:       fps,
+		Priority:  priority,
+		Status:    domain.JobStatusPending,
+		CreatedAt: now.UTC(),
+	}
+	doc.Jobs = append(doc.Jobs, job)
+	return job
+}
+
+// Claim marks the next pending job running and returns it, or nil when
+// none is waiting.
+func (q *JobQueue) Claim() (*domain.Job, error) {
+	var claimed *domain.Job
+	err := q.store.update(func(doc *document) error {
+		var next *domain.Job
+		for _, j := range doc.Jobs {
+			if j.Status != domain.JobStatusPending {
+				continue
+			}
+			if next == nil || j.Priority > next.Priority || (j.Priority == next.Priority && j.ID < next.ID) {
+				next = j
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		next.Status = domain.JobStatusRunning
+		next.StartedAt = sql.NullTime{Time: q.store.now().UTC(), Valid: true}
+		next.Attempts++
+		c := *next
+		claimed = &c
+		return nil
+	})
+	return claimed, err
+}
+
+func (q *JobQueue) Complete(jobID int64) error {
+	return q.finish(jobID, domain.JobStatusDone, "")
+}
+
+func (q *JobQueue) Fail(jobID int64, errMsg string) error {
+	return q.finish(jobID, domain.JobStatusFailed, errMsg)
+}
+
+func (q *JobQueue) finish(jobID int64, status domain.JobStatus, errMsg string) error {
+	return q.store.update(func(doc *document) error {
+		for _, j := range doc.Jobs {
+			if j.ID == jobID {
+				j.Status = status
+				j.ErrorMessage = errMsg
+				j.CompletedAt = sql.NullTime{Time: q.store.now().UTC(), Valid: true}
+			}
+		}
+		return nil
+	})
+}
+
+// Requeue puts a running job back to pending so it is claimed again, for
+// jobs interrupted by shutdown.
+func (q *JobQueue) Requeue(jobID int64) error {
+	return q.resetRunning(func(j *domain.Job) bool { return j.ID == jobID })
+}
+
+// Defer puts a running job back to pending and takes back the attempt its
+// claim counted, for jobs claimed but not run.
+func (q *JobQueue) Defer(jobID int64) error {
+	return q.resetRunning(func(j *domain.Job) bool {
+		if j.ID != jobID {
+			return false
+		}
+		j.Attempts--
+		return true
+	})
+}
+
+func (q *JobQueue) ResetStalled() error {
+	return q.resetRunning(func(*domain.Job) bool { return true })
+}
+
+func (q *JobQueue) resetRunning(match func(j *domain.Job) bool) error {
+	return q.store.update(func(doc *document) error {
+		for _, j := range doc.Jobs {
+			if j.Status == domain.JobStatusRunning && match(j) {
+				j.Status = domain.JobStatusPending
+				j.StartedAt = sql.NullTime{}
+			}
+		}
+		return nil
+	})
+}
+
+// CancelByMedia marks the pending and running jobs of a media cancelled so
+// they are never claimed, and returns how many there were.
+func (q *JobQueue) CancelByMedia(mediaID string) (int, error) {
+	var n int
+	err := q.store.update(func(doc *document) error {
+		for _, j := range doc.Jobs {
+			if j.MediaID == mediaID && (j.Status == domain.JobStatusPending || j.Status == domain.JobStatusRunning) {
+				j.Status = domain.JobStatusCancelled
+				j.ErrorMessage = "cancelled"
+				j.CompletedAt = sql.NullTime{Time: q.store.now().UTC(), Valid: true}
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// PendingCount returns the number of jobs waiting to be claimed.
+func (q *JobQueue) PendingCount() (int, error) {
+	var n int
+	q.store.view(func(doc *document) {
+		for _, j := range doc.Jobs {
+			if j.Status == domain.JobStatusPending {
+				n++
+			}
+		}
+	})
+	return n, nil
+}
+
+// ListByMedia returns every job run for a media, oldest first, including
+// failed attempts and their error output.
+func (q *JobQueue) ListByMedia(mediaID string) ([]domain.Job, error) {
+	jobs := []domain.Job{}
+	q.store.view(func(doc *document) {
+		for _, j := range doc.Jobs {
+			if j.MediaID == mediaID {
+				jobs = append(jobs, *j)
+			}
+		}
+	})
+	return jobs, nil
+}
+
+var _ port.JobQueue = (*JobQueue)(nil)
@@ -0,0 +1,170 @@
+package jsonfile
+
+import (
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue_Enqueue_OneActiveJobPerCodec(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+
+	first, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	dup, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, dup.ID, "pending job should be reused")
+
+	claimed, err := queue.Claim()
+	require.NoError(t, err)
+	require.Equal(t, first.ID, claimed.ID)
+	assert.Equal(t, int64(1), claimed.Attempts)
+	dup, err = queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, dup.ID, "running job should be reused")
+
+	require.NoError(t, queue.Complete(first.ID))
+	next, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, next.ID, "finished job should not block a new one")
+}
+
+func TestJobQueue_EnqueueVariant_Atomic(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+
+	v := &domain.Variant{MediaID: m.ID, Codec: domain.CodecH264}
+	job, err := queue.EnqueueVariant(v, 30)
+	require.NoError(t, err)
+	assert.NotZero(t, v.ID)
+	assert.Equal(t, domain.VariantStatusPending, v.Status)
+	assert.Equal(t, 30, job.Fps)
+
+	_, err = queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 0, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	_, err = queue.EnqueueVariant(&domain.Variant{MediaID: m.ID, Codec: domain.CodecAV1}, 0)
+	require.Error(t, err)
+
+	variants, err := store.ListVariantsByMedia(m.ID)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, domain.CodecH264, variants[0].Codec)
+}
+
+func TestJobQueue_Requeue(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+	job, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecH264, 0, domain.JobPriorityNormal)
+	require.NoError(t, err)
+
+	_, err = queue.Claim()
+	require.NoError(t, err)
+	require.NoError(t, queue.Requeue(job.ID))
+
+	again, err := queue.Claim()
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, job.ID, again.ID)
+	assert.Equal(t, int64(2), again.Attempts)
+
+	require.NoError(t, queue.Complete(job.ID))
+	require.NoError(t, queue.Requeue(job.ID))
+	none, err := queue.Claim()
+	require.NoError(t, err)
+	assert.Nil(t, none, "finished jobs are not requeued")
+}
+
+func TestJobQueue_Defer_KeepsAttempts(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+	job, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 0, domain.JobPriorityNormal)
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err = queue.Claim()
+		require.NoError(t, err)
+		require.NoError(t, queue.Defer(job.ID))
+	}
+
+	again, err := queue.Claim()
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, int64(1), again.Attempts, "deferred claims are not attempts")
+
+	require.NoError(t, queue.Complete(job.ID))
+	require.NoError(t, queue.Defer(job.ID))
+	none, err := queue.Claim()
+	require.NoError(t, err)
+	assert.Nil(t, none, "finished jobs are not deferred")
+}
+
+func TestJobQueue_Claim_HighPriorityFirst(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+	normal, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 0, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	high, err := queue.Enqueue(m.ID, domain.JobTypeThumbnail, "", 0, domain.JobPriorityHigh)
+	require.NoError(t, err)
+
+	claimed, err := queue.Claim()
+	require.NoError(t, err)
+	assert.Equal(t, high.ID, claimed.ID)
+	claimed, err = queue.Claim()
+	require.NoError(t, err)
+	assert.Equal(t, normal.ID, claimed.ID)
+}
+
+func TestJobQueue_CancelByMedia(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+	other := domain.NewMedia(domain.MediaTypeVideo, "other.mp4", "/tmp/other.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(other))
+
+	running, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	_, err = queue.Claim()
+	require.NoError(t, err)
+	_, err = queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecH264, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	kept, err := queue.Enqueue(other.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+
+	n, err := queue.CancelByMedia(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	jobs, err := queue.ListByMedia(m.ID)
+	require.NoError(t, err)
+	for _, j := range jobs {
+		assert.Equal(t, domain.JobStatusCancelled, j.Status, "job %d", j.ID)
+	}
+
+	claimed, err := queue.Claim()
+	require.NoError(t, err)
+	assert.Equal(t, kept.ID, claimed.ID, "cancelled jobs are never claimed")
+
+	again, err := queue.Enqueue(m.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	assert.NotEqual(t, running.ID, again.ID, "a cancelled job should not block a new one")
+}
@@ -0,0 +1,662 @@
+// Package jsonfile keeps all metadata (media, users, API keys and jobs) in
+// a single JSON document in the data directory. It needs no database and
+// the file can be read and backed up with any tool, at the cost of
+// rewriting the whole document on every change, which suits small
+// instances with up to a few thousand media.
+package jsonfile
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// fileName is the document's name in the data directory.
+const fileName = "sharm.json"
+
+// formatVersion is bumped when the document layout changes; migrate
+// upgrades documents written with an older version.
+const formatVersion = 1
+
+// timestampFormat matches the timestamps SQLite stores for users and API
+// keys, which the domain keeps as strings.
+const timestampFormat = "2006-01-02 15:04:05"
+
+type userRecord struct {
+	domain.User
+	BackupCodes []string `json:"backup_codes,omitempty"`
+}
+
+type document struct {
+	Version int                      `json:"version"`
+	Media   map[string]*domain.Media `json:"media"`
+	Users   []*userRecord            `json:"users"`
+	APIKeys []*domain.APIKey         `json:"api_keys"`
+	Jobs    []*domain.Job            `json:"jobs"`
+	// LastIDs hold the last ID handed out per kind of record, so IDs are
+	// never reused after deletions, as with SQLite's AUTOINCREMENT.
+	LastIDs struct {
+		Variant int64 `json:"variant"`
+		User    int64 `json:"user"`
+		APIKey  int64 `json:"api_key"`
+		Job     int64 `json:"job"`
+	} `json:"last_ids"`
+}
+
+// Store holds the document in memory and writes it back after every
+// change. Records handed out are copies, so callers never share state
+// with the store.
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu  sync.Mutex
+	doc *document
+	// saved is the document as last written, to fall back to when a
+	// change cannot be saved.
+	saved []byte
+}
+
+// NewStore loads the document from dataDir, starting an empty one when
+// there is none yet.
+func NewStore(dataDir string) (*Store, error) {
+	s := &Store{path: filepath.Join(dataDir, fileName), now: time.Now}
+
+	doc, err := readDocument(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.doc = &document{Version: formatVersion, Media: map[string]*domain.Media{}}
+	case err != nil:
+		return nil, err
+	default:
+		s.doc = doc
+	}
+	return s, s.write()
+}
+
+// readDocument parses and migrates the document saved at path.
+func readDocument(path string) (*document, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is built from the data dir
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := migrate(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeDocument parses a document as write encodes it.
+func decodeDocument(data []byte) (*document, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Media == nil {
+		doc.Media = map[string]*domain.Media{}
+	}
+	return &doc, nil
+}
+
+// migrate upgrades a document to formatVersion. There is only one version
+// so far; older layouts get a case here when the format changes.
+func migrate(doc *document) error {
+	if doc.Version > formatVersion {
+		return fmt.Errorf("metadata file version %d is newer than this sharm supports (%d)", doc.Version, formatVersion)
+	}
+	doc.Version = formatVersion
+	return nil
+}
+
+// write saves the document through a temporary file, so a crash leaves
+// either the old or the new version in place. Callers hold mu, except
+// NewStore before the store is shared.
+func (s *Store) write() error {
+	data, err := json.Marshal(s.doc)
+	if err != nil {
+		return fmt.Errorf("encode metadata: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) //nolint:gosec // path is built from the data dir
+	if err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.saved = data
+	return nil
+}
+
+// update applies fn to the document and writes it if fn succeeds. A failed
+// write undoes the change, so the store never reports what it could not
+// save.
+func (s *Store) update(fn func(doc *document) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := fn(s.doc); err != nil {
+		return err
+	}
+	if err := s.write(); err != nil {
+		if doc, decodeErr := decodeDocument(s.saved); decodeErr == nil {
+			s.doc = doc
+		}
+		return err
+	}
+	return nil
+}
+
+// view runs fn with the document locked for reading.
+func (s *Store) view(fn func(doc *document)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.doc)
+}
+
+func (s *Store) Close() error {
+	return nil
+}
+
+// Ping checks that the document is still in place.
+func (s *Store) Ping(ctx context.Context) error {
+	_, err := os.Stat(s.path)
+	return err
+}
+
+// Checkpoint is a no-op: every change is written in full.
+func (s *Store) Checkpoint(ctx context.Context) error {
+	return nil
+}
+
+// Vacuum is a no-op: deleted records take no space in the rewritten file.
+func (s *Store) Vacuum(ctx context.Context) error {
+	return nil
+}
+
+// BackupName is the file name a backup of the document is saved as.
+func (s *Store) BackupName() string {
+	return fileName
+}
+
+// Backup writes the current document to w. It is encoded under the lock,
+// so the snapshot is consistent, and written after releasing it.
+func (s *Store) Backup(ctx context.Context, w io.Writer) error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.doc)
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode metadata: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the document with the snapshot saved at snapshotPath.
+// Jobs that were running when the snapshot was taken are queued again.
+func (s *Store) Restore(ctx context.Context, snapshotPath string) error {
+	doc, err := readDocument(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("load snapshot: %w", err)
+	}
+	for _, j := range doc.Jobs {
+		if j.Status == domain.JobStatusRunning {
+			j.Status = domain.JobStatusPending
+			j.StartedAt = sql.NullTime{}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.doc
+	s.doc = doc
+	if err := s.write(); err != nil {
+		s.doc = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) timestamp() string {
+	return s.now().UTC().Format(timestampFormat)
+}
+
+func (s *Store) Save(m *domain.Media) error {
+	return s.update(func(doc *document) error {
+		if _, ok := doc.Media[m.ID]; ok {
+			return fmt.Errorf("media %s already exists", m.ID)
+		}
+		saved := cloneMedia(m)
+		// Variants and checksums are saved through their own methods.
+		saved.Variants = nil
+		saved.Checksums = nil
+		saved.Tags = nil
+		for _, tag := range m.Tags {
+			if !slices.Contains(saved.Tags, tag) {
+				saved.Tags = append(saved.Tags, tag)
+			}
+		}
+		slices.Sort(saved.Tags)
+		doc.Media[m.ID] = saved
+		return nil
+	})
+}
+
+func (s *Store) Get(id string) (*domain.Media, error) {
+	var media *domain.Media
+	s.view(func(doc *document) {
+		if m, ok := doc.Media[id]; ok {
+			media = cloneMedia(m)
+		}
+	})
+	if media == nil {
+		return nil, domain.ErrNotFound
+	}
+	return media, nil
+}
+
+func (s *Store) Delete(id string) error {
+	return s.update(func(doc *document) error {
+		deleteMedia(doc, id)
+		return nil
+	})
+}
+
+// DeleteMany deletes several media and their jobs in one write.
+func (s *Store) DeleteMany(ids []string) error {
+	return s.update(func(doc *document) error {
+		for _, id := range ids {
+			deleteMedia(doc, id)
+		}
+		return nil
+	})
+}
+
+func deleteMedia(doc *document, id string) {
+	delete(doc.Media, id)
+	doc.Jobs = slices.DeleteFunc(doc.Jobs, func(j *domain.Job) bool { return j.MediaID == id })
+}
+
+func (s *Store) ListExpired() ([]*domain.Media, error) {
+	now := s.now()
+	return s.filter(func(m *domain.Media) bool { return m.ExpiresAt.Before(now) }, newestFirst), nil
+}
+
+// ListFailedOlderThan returns failed media whose last job finished more
+// than age ago; media that never ran a job count from their upload.
+func (s *Store) ListFailedOlderThan(age time.Duration) ([]*domain.Media, error) {
+	cutoff := s.now().Add(-age)
+	var result []*domain.Media
+	s.view(func(doc *document) {
+		for _, m := range doc.Media {
+			if m.Status != domain.MediaStatusFailed {
+				continue
+			}
+			last := m.CreatedAt
+			foundJob := false
+			for _, j := range doc.Jobs {
+				if j.MediaID == m.ID && j.CompletedAt.Valid && (!foundJob || j.CompletedAt.Time.After(last)) {
+					last, foundJob = j.CompletedAt.Time, true
+				}
+			}
+			if last.Before(cutoff) {
+				result = append(result, cloneMedia(m))
+			}
+		}
+	})
+	slices.SortFunc(result, newestFirst)
+	return result, nil
+}
+
+// IterateByStatus calls fn for every media in the given status in ID
+// order. The store is not locked while fn runs, so fn may update or delete
+// the media it is given.
+func (s *Store) IterateByStatus(status domain.MediaStatus, fn func(*domain.Media) error) error {
+	var ids []string
+	s.view(func(doc *document) {
+		for id, m := range doc.Media {
+			if m.Status == status {
+				ids = append(ids, id)
+			}
+		}
+	})
+	slices.Sort(ids)
+	for _, id := range ids {
+		m, err := s.Get(id)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListAll(ownerID int64) ([]*domain.Media, error) {
+	return s.filter(func(m *domain.Media) bool { return m.OwnerID == ownerID }, newestFirst), nil
+}
+
+// ListPaged returns one page of the owner's media in the requested order and
+// their total media count.
+func (s *Store) ListPaged(ownerID int64, sort domain.SortBy, limit, offset int) ([]*domain.Media, int, error) {
+	order := newestFirst
+	switch sort {
+	case domain.SortOldest:
+		order = func(a, b *domain.Media) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case domain.SortLargest:
+		order = func(a, b *domain.Media) int { return cmp.Or(cmp.Compare(b.FileSize, a.FileSize), newestFirst(a, b)) }
+	case domain.SortExpiring:
+		order = func(a, b *domain.Media) int { return cmp.Or(a.ExpiresAt.Compare(b.ExpiresAt), newestFirst(a, b)) }
+	}
+	all := s.filter(func(m *domain.Media) bool { return m.OwnerID == ownerID }, order)
+	start := min(max(offset, 0), len(all))
+	end := min(start+max(limit, 0), len(all))
+	return all[start:end], len(all), nil
+}
+
+func (s *Store) ListByTag(ownerID int64, tag string) ([]*domain.Media, error) {
+	return s.filter(func(m *domain.Media) bool {
+		return m.OwnerID == ownerID && slices.Contains(m.Tags, tag)
+	}, newestFirst), nil
+}
+
+// Search returns the owner's media whose original name or tags contain
+// query, ignoring case.
+func (s *Store) Search(ownerID int64, query string) ([]*domain.Media, error) {
+	query = strings.ToLower(query)
+	return s.filter(func(m *domain.Media) bool {
+		if m.OwnerID != ownerID {
+			return false
+		}
+		if strings.Contains(strings.ToLower(m.OriginalName), query) {
+			return true
+		}
+		return slices.ContainsFunc(m.Tags, func(tag string) bool {
+			return strings.Contains(strings.ToLower(tag), query)
+		})
+	}, newestFirst), nil
+}
+
+// ListOwnedByStatus returns the owner's media in the given status, newest first.
+func (s *Store) ListOwnedByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error) {
+	return s.filter(func(m *domain.Media) bool {
+		return m.OwnerID == ownerID && m.Status == status
+	}, newestFirst), nil
+}
+
+// filter returns copies of the media matching keep, sorted by order.
+func (s *Store) filter(keep func(*domain.Media) bool, order func(a, b *domain.Media) int) []*domain.Media {
+	var result []*domain.Media
+	s.view(func(doc *document) {
+		for _, m := range doc.Media {
+			if keep(m) {
+				result = append(result, cloneMedia(m))
+			}
+		}
+	})
+	slices.SortFunc(result, order)
+	return result
+}
+
+// newestFirst orders media by upload time, newest first, then by ID so
+// media uploaded in the same instant keep a stable order.
+func newestFirst(a, b *domain.Media) int {
+	return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID, b.ID))
+}
+
+// updateMedia applies fn to media id; like an SQL UPDATE, a missing media
+// is not an error.
+func (s *Store) updateMedia(id string, fn func(m *domain.Media)) error {
+	return s.update(func(doc *document) error {
+		if m, ok := doc.Media[id]; ok {
+			fn(m)
+		}
+		return nil
+	})
+}
+
+func (s *Store) UpdateStatus(id string, status domain.MediaStatus, errMsg string) error {
+	return s.updateMedia(id, func(m *domain.Media) {
+		m.Status = status
+		m.ErrorMessage = errMsg
+	})
+}
+
+func (s *Store) UpdateDone(done *domain.Media) error {
+	return s.updateMedia(done.ID, func(m *domain.Media) {
+		m.Status = domain.MediaStatusDone
+		m.OriginalPath = done.OriginalPath
+		m.ConvertedPath = done.ConvertedPath
+		m.Codec = done.Codec
+		m.Width = done.Width
+		m.Height = done.Height
+		m.ThumbPath = done.ThumbPath
+		m.FileSize = done.FileSize
+	})
+}
+
+func (s *Store) UpdateProbeJSON(id string, probeJSON string) error {
+	return s.updateMedia(id, func(m *domain.Media) {
+		m.ProbeJSON = probeJSON
+	})
+}
+
+func (s *Store) UpdateStoryboard(id, spritePath, vttPath string) error {
+	return s.updateMedia(id, func(m *domain.Media) {
+		m.StoryboardPath = spritePath
+		m.StoryboardVTTPath = vttPath
+	})
+}
+
+// Stats aggregates media counts and on-disk sizes.
+func (s *Store) Stats() (domain.StorageStats, error) {
+	return s.stats(func(*domain.Media) bool { return true }), nil
+}
+
+// OwnerStats aggregates media counts and sizes of the owner's media.
+func (s *Store) OwnerStats(ownerID int64) (domain.StorageStats, error) {
+	return s.stats(func(m *domain.Media) bool { return m.OwnerID == ownerID }), nil
+}
+
+// stats aggregates media counts and sizes of the media keep accepts.
+func (s *Store) stats(keep func(*domain.Media) bool) domain.StorageStats {
+	stats := domain.StorageStats{ByStatus: map[domain.MediaStatus]int{}}
+	now := s.now()
+	s.view(func(doc *document) {
+		for _, m := range doc.Media {
+			if !keep(m) {
+				continue
+			}
+			stats.ByStatus[m.Status]++
+			stats.TotalCount++
+			if m.ExpiresAt.Before(now) {
+				stats.ExpiredCount++
+			}
+			stats.MediaBytes += m.FileSize
+			for _, v := range m.Variants {
+				stats.VariantBytes += v.FileSize
+			}
+		}
+	})
+	return stats
+}
+
+// Variant methods
+
+func (s *Store) SaveVariant(v *domain.Variant) error {
+	return s.update(func(doc *document) error {
+		return insertVariant(doc, v, s.now())
+	})
+}
+
+// insertVariant adds v to its media as a pending variant, filling in its
+// ID and creation time.
+func insertVariant(doc *document, v *domain.Variant, now time.Time) error {
+	m, ok := doc.Media[v.MediaID]
+	if !ok {
+		return fmt.Errorf("media %s: %w", v.MediaID, domain.ErrNotFound)
+	}
+	doc.LastIDs.Variant++
+	v.ID = doc.LastIDs.Variant
+	v.Status = domain.VariantStatusPending
+	v.CreatedAt = now.UTC()
+	m.Variants = append(m.Variants, domain.Variant{
+		ID:        v.ID,
+		MediaID:   v.MediaID,
+		Codec:     v.Codec,
+		Status:    v.Status,
+		CreatedAt: v.CreatedAt,
+	})
+	return nil
+}
+
+func (s *Store) GetVariant(id int64) (*domain.Variant, error) {
+	return s.findVariant(func(v *domain.Variant) bool { return v.ID == id })
+}
+
+func (s *Store) GetVariantByMediaAndCodec(mediaID string, codec domain.Codec) (*domain.Variant, error) {
+	return s.findVariant(func(v *domain.Variant) bool { return v.MediaID == mediaID && v.Codec == codec })
+}
+
+func (s *Store) findVariant(match func(v *domain.Variant) bool) (*domain.Variant, error) {
+	var found *domain.Variant
+	s.view(func(doc *document) {
+		if v := variantWhere(doc, match); v != nil {
+			c := *v
+			found = &c
+		}
+	})
+	if found == nil {
+		return nil, domain.ErrNotFound
+	}
+	return found, nil
+}
+
+// variantWhere returns the stored variant matching match, or nil.
+func variantWhere(doc *document, match func(v *domain.Variant) bool) *domain.Variant {
+	for _, m := range doc.Media {
+		for i := range m.Variants {
+			if match(&m.Variants[i]) {
+				return &m.Variants[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListVariantsByMedia(mediaID string) ([]domain.Variant, error) {
+	variants := []domain.Variant{}
+	s.view(func(doc *document) {
+		if m, ok := doc.Media[mediaID]; ok {
+			variants = append(variants, m.Variants...)
+		}
+	})
+	return variants, nil
+}
+
+func (s *Store) UpdateVariantStatus(id int64, status domain.VariantStatus, errMsg string) error {
+	return s.update(func(doc *document) error {
+		if v := variantWhere(doc, func(v *domain.Variant) bool { return v.ID == id }); v != nil {
+			v.Status = status
+			v.ErrorMessage = errMsg
+		}
+		return nil
+	})
+}
+
+func (s *Store) UpdateVariantDone(done *domain.Variant) error {
+	return s.update(func(doc *document) error {
+		if v := variantWhere(doc, func(v *domain.Variant) bool { return v.ID == done.ID }); v != nil {
+			v.Status = domain.VariantStatusDone
+			v.Path = done.Path
+			v.FileSize = done.FileSize
+			v.Width = done.Width
+			v.Height = done.Height
+		}
+		return nil
+	})
+}
+
+func (s *Store) DeleteVariantsByMedia(mediaID string) error {
+	return s.updateMedia(mediaID, func(m *domain.Media) {
+		m.Variants = nil
+	})
+}
+
+// Checksum methods
+
+func (s *Store) GetChecksum(mediaID, file string) (string, error) {
+	var sum string
+	s.view(func(doc *document) {
+		if m, ok := doc.Media[mediaID]; ok {
+			sum = m.Checksums[file]
+		}
+	})
+	if sum == "" {
+		return "", domain.ErrNotFound
+	}
+	return sum, nil
+}
+
+func (s *Store) SaveChecksum(mediaID, file, sha256 string) error {
+	return s.updateMedia(mediaID, func(m *domain.Media) {
+		if m.Checksums == nil {
+			m.Checksums = map[string]string{}
+		}
+		m.Checksums[file] = sha256
+	})
+}
+
+func (s *Store) FindByChecksum(ownerID int64, file, sha256 string) (string, error) {
+	matches := s.filter(func(m *domain.Media) bool {
+		return m.OwnerID == ownerID && m.Status != domain.MediaStatusFailed && m.Checksums[file] == sha256
+	}, newestFirst)
+	if len(matches) == 0 {
+		return "", domain.ErrNotFound
+	}
+	return matches[0].ID, nil
+}
+
+func cloneMedia(m *domain.Media) *domain.Media {
+	c := *m
+	c.Variants = slices.Clone(m.Variants)
+	if c.Variants == nil {
+		c.Variants = []domain.Variant{}
+	}
+	c.Tags = slices.Clone(m.Tags)
+	c.Checksums = maps.Clone(m.Checksums)
+	return &c
+}
+
+var _ port.MediaStore = (*Store)(nil)
@@ -0,0 +1,345 @@
+package jsonfile
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestStore_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	m.Tags = []string{"cats", "birds", "cats"}
+	require.NoError(t, store.Save(m))
+	v := &domain.Variant{MediaID: m.ID, Codec: domain.CodecH264}
+	require.NoError(t, store.SaveVariant(v))
+	require.NoError(t, store.UpdateVariantDone(&domain.Variant{ID: v.ID, Path: "/tmp/clip_h264.mp4", FileSize: 42}))
+	require.NoError(t, store.UpdateProbeJSON(m.ID, `{"format":{}}`))
+	require.NoError(t, store.SaveChecksum(m.ID, domain.ChecksumOriginal, "abc"))
+	require.NoError(t, store.CreateUser("admin", "hash", true))
+
+	reopened, err := NewStore(dir)
+	require.NoError(t, err)
+	got, err := reopened.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"birds", "cats"}, got.Tags)
+	assert.Equal(t, `{"format":{}}`, got.ProbeJSON)
+	assert.Equal(t, "abc", got.Checksums[domain.ChecksumOriginal])
+	require.Len(t, got.Variants, 1)
+	assert.Equal(t, domain.VariantStatusDone, got.Variants[0].Status)
+	assert.Equal(t, int64(42), got.Variants[0].FileSize)
+	assert.True(t, got.ExpiresAt.Equal(m.ExpiresAt))
+
+	user, err := reopened.GetUser("admin")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+}
+
+func TestStore_FailedWriteUndoesChange(t *testing.T) {
+	store := newTestStore(t)
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+
+	// A directory in the way of the temporary file makes writes fail.
+	require.NoError(t, os.Mkdir(store.path+".tmp", 0750))
+	require.Error(t, store.UpdateStatus(m.ID, domain.MediaStatusFailed, "boom"))
+	other := domain.NewMedia(domain.MediaTypeImage, "cat.png", "/tmp/cat.png", 7*domain.Day)
+	require.Error(t, store.Save(other))
+
+	got, err := store.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, m.Status, got.Status)
+	assert.Empty(t, got.ErrorMessage)
+	_, err = store.Get(other.ID)
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(store.path+".tmp"))
+	require.NoError(t, store.UpdateStatus(m.ID, domain.MediaStatusDone, ""))
+	reopened, err := NewStore(filepath.Dir(store.path))
+	require.NoError(t, err)
+	_, err = reopened.Get(other.ID)
+	assert.Error(t, err, "the undone change is not saved with the next one")
+}
+
+func TestStore_Backup(t *testing.T) {
+	store := newTestStore(t)
+	m := domain.NewMedia(domain.MediaTypeImage, "a.png", "/tmp/a.png", domain.Day)
+	require.NoError(t, store.Save(m))
+
+	restoreDir := t.TempDir()
+	var buf bytes.Buffer
+	require.NoError(t, store.Backup(context.Background(), &buf))
+	require.NoError(t, os.WriteFile(filepath.Join(restoreDir, store.BackupName()), buf.Bytes(), 0600))
+
+	restored, err := NewStore(restoreDir)
+	require.NoError(t, err)
+	_, err = restored.Get(m.ID)
+	assert.NoError(t, err)
+}
+
+func TestStore_Restore(t *testing.T) {
+	store := newTestStore(t)
+	kept := domain.NewMedia(domain.MediaTypeImage, "kept.png", "/tmp/kept.png", domain.Day)
+	require.NoError(t, store.Save(kept))
+	snapshot := filepath.Join(t.TempDir(), fileName)
+	var buf bytes.Buffer
+	require.NoError(t, store.Backup(context.Background(), &buf))
+	require.NoError(t, os.WriteFile(snapshot, buf.Bytes(), 0600))
+
+	later := domain.NewMedia(domain.MediaTypeImage, "later.png", "/tmp/later.png", domain.Day)
+	require.NoError(t, store.Save(later))
+
+	newer := filepath.Join(t.TempDir(), fileName)
+	require.NoError(t, os.WriteFile(newer, []byte(`{"version":99}`), 0600))
+	require.ErrorContains(t, store.Restore(context.Background(), newer), "newer")
+	_, err := store.Get(later.ID)
+	require.NoError(t, err, "a rejected snapshot leaves the document alone")
+
+	require.NoError(t, store.Restore(context.Background(), snapshot))
+	_, err = store.Get(kept.ID)
+	assert.NoError(t, err)
+	_, err = store.Get(later.ID)
+	assert.Error(t, err, "media saved after the snapshot is gone")
+
+	reopened, err := NewStore(filepath.Dir(store.path))
+	require.NoError(t, err)
+	_, err = reopened.Get(later.ID)
+	assert.Error(t, err, "the restored document is saved")
+}
+
+func TestNewStore_RejectsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName), []byte(`{"version":99}`), 0600))
+
+	_, err := NewStore(dir)
+	assert.ErrorContains(t, err, "newer")
+}
+
+func TestStore_GetReturnsCopies(t *testing.T) {
+	store := newTestStore(t)
+	m := domain.NewMedia(domain.MediaTypeImage, "a.png", "/tmp/a.png", domain.Day)
+	require.NoError(t, store.Save(m))
+	require.Error(t, store.Save(m), "duplicate IDs must be rejected")
+
+	got, err := store.Get(m.ID)
+	require.NoError(t, err)
+	got.Status = domain.MediaStatusFailed
+
+	again, err := store.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaStatusPending, again.Status)
+	assert.NotNil(t, again.Variants)
+
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestStore_ListPaged(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now()
+	save := func(name string, ownerID, size int64, age time.Duration) {
+		m := domain.NewMedia(domain.MediaTypeImage, name, "/tmp/"+name, 7*domain.Day)
+		m.OwnerID = ownerID
+		m.FileSize = size
+		m.CreatedAt = base.Add(-age)
+		require.NoError(t, store.Save(m))
+	}
+	save("old.png", 1, 300, 3*time.Hour)
+	save("mid.png", 1, 100, 2*time.Hour)
+	save("new.png", 1, 200, time.Hour)
+	save("other.png", 2, 999, 0)
+
+	names := func(ms []*domain.Media) []string {
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.OriginalName)
+		}
+		return out
+	}
+
+	page, total, err := store.ListPaged(1, domain.SortNewest, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []string{"new.png", "mid.png"}, names(page))
+
+	page, _, err = store.ListPaged(1, domain.SortOldest, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new.png"}, names(page))
+
+	page, _, err = store.ListPaged(1, domain.SortLargest, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old.png", "new.png", "mid.png"}, names(page))
+
+	page, _, err = store.ListPaged(1, domain.SortNewest, 10, 5)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestStore_Search_MatchesNameOrTag(t *testing.T) {
+	store := newTestStore(t)
+	named := domain.NewMedia(domain.MediaTypeImage, "Holiday.png", "/tmp/a.png", domain.Day)
+	tagged := domain.NewMedia(domain.MediaTypeImage, "b.png", "/tmp/b.png", domain.Day)
+	tagged.Tags = []string{"holidays"}
+	other := domain.NewMedia(domain.MediaTypeImage, "c.png", "/tmp/c.png", domain.Day)
+	for _, m := range []*domain.Media{named, tagged, other} {
+		require.NoError(t, store.Save(m))
+	}
+
+	found, err := store.Search(0, "holiday")
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = store.Search(1, "holiday")
+	require.NoError(t, err)
+	assert.Empty(t, found, "other owners' media must not match")
+}
+
+func TestStore_ListExpired_SkipsNeverExpiring(t *testing.T) {
+	store := newTestStore(t)
+	expired := domain.NewMedia(domain.MediaTypeImage, "old.png", "/tmp/old.png", domain.Day)
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	forever := domain.NewMedia(domain.MediaTypeImage, "keep.png", "/tmp/keep.png", domain.RetentionNever)
+	require.NoError(t, store.Save(expired))
+	require.NoError(t, store.Save(forever))
+
+	medias, err := store.ListExpired()
+	require.NoError(t, err)
+	require.Len(t, medias, 1)
+	assert.Equal(t, expired.ID, medias[0].ID)
+}
+
+func TestStore_ListFailedOlderThan(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+
+	recent := domain.NewMedia(domain.MediaTypeVideo, "a.mp4", "/tmp/a.mp4", domain.Day)
+	recent.CreatedAt = time.Now().Add(-48 * time.Hour)
+	recent.Status = domain.MediaStatusFailed
+	stale := domain.NewMedia(domain.MediaTypeVideo, "b.mp4", "/tmp/b.mp4", domain.Day)
+	stale.CreatedAt = time.Now().Add(-48 * time.Hour)
+	stale.Status = domain.MediaStatusFailed
+	require.NoError(t, store.Save(recent))
+	require.NoError(t, store.Save(stale))
+
+	// recent failed just now on a retry, so it is not old enough yet.
+	job, err := queue.Enqueue(recent.ID, domain.JobTypeConvert, domain.CodecH264, 0, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	require.NoError(t, queue.Fail(job.ID, "boom"))
+
+	medias, err := store.ListFailedOlderThan(24 * time.Hour)
+	require.NoError(t, err)
+	require.Len(t, medias, 1)
+	assert.Equal(t, stale.ID, medias[0].ID)
+}
+
+func TestStore_DeleteRemovesJobs(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewJobQueue(store)
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", domain.Day)
+	require.NoError(t, store.Save(m))
+	_, err := queue.EnqueueVariant(&domain.Variant{MediaID: m.ID, Codec: domain.CodecH264}, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteMany([]string{m.ID}))
+
+	_, err = store.Get(m.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	n, err := queue.PendingCount()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestStore_FindByChecksum(t *testing.T) {
+	store := newTestStore(t)
+	save := func(ownerID int64, status domain.MediaStatus, sum string) *domain.Media {
+		m := domain.NewMedia(domain.MediaTypeImage, "a.png", "/tmp/a.png", 7*domain.Day)
+		m.OwnerID = ownerID
+		m.Status = status
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.SaveChecksum(m.ID, domain.ChecksumOriginal, sum))
+		return m
+	}
+	mine := save(1, domain.MediaStatusDone, "aaa")
+	save(2, domain.MediaStatusDone, "bbb")
+	save(1, domain.MediaStatusFailed, "ccc")
+
+	id, err := store.FindByChecksum(1, domain.ChecksumOriginal, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, mine.ID, id)
+
+	_, err = store.FindByChecksum(1, domain.ChecksumOriginal, "bbb")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = store.FindByChecksum(1, domain.ChecksumOriginal, "ccc")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestStore_Users(t *testing.T) {
+	store := newTestStore(t)
+	has, err := store.HasUser()
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, store.CreateUser("admin", "hash", true))
+	require.NoError(t, store.CreateOIDCUser("alice", "sub-1", false))
+	assert.Error(t, store.CreateUser("admin", "other", false), "usernames are unique")
+	assert.Error(t, store.CreateOIDCUser("bob", "sub-1", false), "subjects are unique")
+
+	first, err := store.GetFirstUser()
+	require.NoError(t, err)
+	assert.Equal(t, "admin", first.Username)
+	alice, err := store.GetUserByOIDCSubject("sub-1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", alice.Username)
+	_, err = store.GetUserByOIDCSubject("")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	require.NoError(t, store.ReplaceBackupCodes(first.ID, []string{"a", "b"}))
+	ok, err := store.ConsumeBackupCode(first.ID, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.ConsumeBackupCode(first.ID, "a")
+	require.NoError(t, err)
+	assert.False(t, ok, "codes are single use")
+	n, err := store.CountBackupCodes(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestStore_APIKeys_ScopedToOwner(t *testing.T) {
+	store := newTestStore(t)
+	key, err := store.CreateAPIKey(1, "ci", "hash")
+	require.NoError(t, err)
+	assert.NotZero(t, key.ID)
+
+	keys, err := store.ListAPIKeys(2)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.ErrorIs(t, store.RevokeAPIKey(2, key.ID), domain.ErrNotFound)
+
+	require.NoError(t, store.TouchAPIKey(key.ID))
+	got, err := store.GetAPIKey(key.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, got.LastUsedAt)
+
+	require.NoError(t, store.RevokeAPIKey(1, key.ID))
+	_, err = store.GetAPIKey(key.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
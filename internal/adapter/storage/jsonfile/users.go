@@ -0,0 +1,250 @@
+package jsonfile
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port"
+)
+
+func (s *Store) HasUser() (bool, error) {
+	var has bool
+	s.view(func(doc *document) { has = len(doc.Users) > 0 })
+	return has, nil
+}
+
+func (s *Store) GetUser(username string) (*domain.User, error) {
+	return s.findUser(func(u *userRecord) bool { return u.Username == username })
+}
+
+func (s *Store) GetUserByID(id int64) (*domain.User, error) {
+	return s.findUser(func(u *userRecord) bool { return u.ID == id })
+}
+
+func (s *Store) GetUserByOIDCSubject(subject string) (*domain.User, error) {
+	return s.findUser(func(u *userRecord) bool { return u.OIDCSubject != "" && u.OIDCSubject == subject })
+}
+
+// GetFirstUser returns the user created first.
+func (s *Store) GetFirstUser() (*domain.User, error) {
+	return s.findUser(func(*userRecord) bool { return true })
+}
+
+// findUser returns a copy of the first user, in creation order, matching
+// match.
+func (s *Store) findUser(match func(u *userRecord) bool) (*domain.User, error) {
+	var found *domain.User
+	s.view(func(doc *document) {
+		if u := userWhere(doc, match); u != nil {
+			c := u.User
+			found = &c
+		}
+	})
+	if found == nil {
+		return nil, domain.ErrNotFound
+	}
+	return found, nil
+}
+
+func userWhere(doc *document, match func(u *userRecord) bool) *userRecord {
+	for _, u := range doc.Users {
+		if match(u) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListUsers() ([]domain.User, error) {
+	var users []domain.User
+	s.view(func(doc *document) {
+		users = make([]domain.User, 0, len(doc.Users))
+		for _, u := range doc.Users {
+			users = append(users, u.User)
+		}
+	})
+	return users, nil
+}
+
+func (s *Store) CreateUser(username, passwordHash string, isAdmin bool) error {
+	return s.insertUser(domain.User{Username: username, PasswordHash: passwordHash, IsAdmin: isAdmin})
+}
+
+// CreateOIDCUser adds a user linked to an OIDC subject. It has no password,
+// so it can only sign in through the provider.
+func (s *Store) CreateOIDCUser(username, subject string, isAdmin bool) error {
+	return s.insertUser(domain.User{Username: username, OIDCSubject: subject, IsAdmin: isAdmin})
+}
+
+// insertUser adds u, enforcing the unique usernames and OIDC subjects the
+// SQLite schema has constraints for.
+func (s *Store) insertUser(u domain.User) error {
+	return s.update(func(doc *document) error {
+		for _, existing := range doc.Users {
+			if existing.Username == u.Username {
+				return fmt.Errorf("username %q already exists", u.Username)
+			}
+			if u.OIDCSubject != "" && existing.OIDCSubject == u.OIDCSubject {
+				return fmt.Errorf("oidc subject %q is already linked to a user", u.OIDCSubject)
+			}
+		}
+		doc.LastIDs.User++
+		u.ID = doc.LastIDs.User
+		u.CreatedAt = s.timestamp()
+		u.UpdatedAt = u.CreatedAt
+		doc.Users = append(doc.Users, &userRecord{User: u})
+		return nil
+	})
+}
+
+// updateUser applies fn to user id and bumps its UpdatedAt; a missing user
+// is not an error.
+func (s *Store) updateUser(id int64, fn func(u *userRecord)) error {
+	return s.update(func(doc *document) error {
+		if u := userWhere(doc, func(u *userRecord) bool { return u.ID == id }); u != nil {
+			fn(u)
+			u.UpdatedAt = s.timestamp()
+		}
+		return nil
+	})
+}
+
+func (s *Store) UpdatePassword(id int64, passwordHash string) error {
+	return s.updateUser(id, func(u *userRecord) { u.PasswordHash = passwordHash })
+}
+
+// SetRecoveryCode stores the hash of the user's recovery code; an empty hash
+// clears it.
+func (s *Store) SetRecoveryCode(id int64, codeHash string) error {
+	return s.updateUser(id, func(u *userRecord) { u.RecoveryHash = codeHash })
+}
+
+// SetTOTP stores the user's TOTP secret and whether two-factor login is
+// enforced. An empty secret with enabled false turns 2FA off.
+func (s *Store) SetTOTP(id int64, secret string, enabled bool) error {
+	return s.updateUser(id, func(u *userRecord) {
+		u.TOTPSecret = secret
+		u.TOTPEnabled = enabled
+	})
+}
+
+// ReplaceBackupCodes swaps the user's backup code hashes for codeHashes.
+func (s *Store) ReplaceBackupCodes(userID int64, codeHashes []string) error {
+	return s.update(func(doc *document) error {
+		u := userWhere(doc, func(u *userRecord) bool { return u.ID == userID })
+		if u == nil {
+			return fmt.Errorf("user %d: %w", userID, domain.ErrNotFound)
+		}
+		u.BackupCodes = slices.Clone(codeHashes)
+		return nil
+	})
+}
+
+// ConsumeBackupCode deletes the matching backup code and reports whether one
+// existed, so each code is accepted at most once.
+func (s *Store) ConsumeBackupCode(userID int64, codeHash string) (bool, error) {
+	var consumed bool
+	err := s.update(func(doc *document) error {
+		u := userWhere(doc, func(u *userRecord) bool { return u.ID == userID })
+		if u == nil {
+			return nil
+		}
+		if i := slices.Index(u.BackupCodes, codeHash); i >= 0 {
+			u.BackupCodes = slices.Delete(u.BackupCodes, i, i+1)
+			consumed = true
+		}
+		return nil
+	})
+	return consumed, err
+}
+
+func (s *Store) CountBackupCodes(userID int64) (int, error) {
+	var n int
+	s.view(func(doc *document) {
+		if u := userWhere(doc, func(u *userRecord) bool { return u.ID == userID }); u != nil {
+			n = len(u.BackupCodes)
+		}
+	})
+	return n, nil
+}
+
+func (s *Store) CreateAPIKey(userID int64, label, keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := s.update(func(doc *document) error {
+		doc.LastIDs.APIKey++
+		key = domain.APIKey{
+			ID:        doc.LastIDs.APIKey,
+			UserID:    userID,
+			Label:     label,
+			KeyHash:   keyHash,
+			CreatedAt: s.timestamp(),
+		}
+		stored := key
+		doc.APIKeys = append(doc.APIKeys, &stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *Store) GetAPIKey(id int64) (*domain.APIKey, error) {
+	var found *domain.APIKey
+	s.view(func(doc *document) {
+		for _, k := range doc.APIKeys {
+			if k.ID == id {
+				c := *k
+				found = &c
+			}
+		}
+	})
+	if found == nil {
+		return nil, domain.ErrNotFound
+	}
+	return found, nil
+}
+
+// ListAPIKeys returns the user's keys, newest first.
+func (s *Store) ListAPIKeys(userID int64) ([]domain.APIKey, error) {
+	keys := []domain.APIKey{}
+	s.view(func(doc *document) {
+		for _, k := range doc.APIKeys {
+			if k.UserID == userID {
+				keys = append(keys, *k)
+			}
+		}
+	})
+	slices.SortFunc(keys, func(a, b domain.APIKey) int {
+		return cmp.Or(cmp.Compare(b.CreatedAt, a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return keys, nil
+}
+
+func (s *Store) RevokeAPIKey(userID, id int64) error {
+	return s.update(func(doc *document) error {
+		n := len(doc.APIKeys)
+		doc.APIKeys = slices.DeleteFunc(doc.APIKeys, func(k *domain.APIKey) bool {
+			return k.ID == id && k.UserID == userID
+		})
+		if len(doc.APIKeys) == n {
+			return domain.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Store) TouchAPIKey(id int64) error {
+	return s.update(func(doc *document) error {
+		for _, k := range doc.APIKeys {
+			if k.ID == id {
+				k.LastUsedAt = s.timestamp()
+			}
+		}
+		return nil
+	})
+}
+
+var _ port.UserStore = (*Store)(nil)
@@ -0,0 +1,302 @@
+// Package s3 stores blobs in an S3-compatible bucket (AWS S3, MinIO,
+// Garage...) using the REST API signed with AWS Signature Version 4.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/port"
+)
+
+const (
+	algorithm = "AWS4-HMAC-SHA256"
+	service   = "s3"
+	// unsignedPayload lets uploads stream without hashing the file first.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+
+	// maxErrorBody bounds how much of an error response is read into errors.
+	maxErrorBody = 4 * 1024
+)
+
+// contentTypes covers the files Sharm writes, for hosts whose MIME tables
+// lack them.
+var contentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".vtt":  "text/vtt; charset=utf-8",
+}
+
+// contentType guesses the type objects are stored with from their name.
+func contentType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+type Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	// pathStyle addresses the bucket as endpoint/bucket instead of as the
+	// bucket.endpoint subdomain; MinIO and most self-hosted stores need it.
+	pathStyle     bool
+	presignExpiry time.Duration
+	client        *http.Client
+	now           func() time.Time
+}
+
+func NewStore(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool, presignExpiry time.Duration) (*Store, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	return &Store{
+		endpoint:      u,
+		region:        region,
+		bucket:        bucket,
+		accessKey:     accessKey,
+		secretKey:     secretKey,
+		pathStyle:     pathStyle,
+		presignExpiry: presignExpiry,
+		client:        &http.Client{},
+		now:           time.Now,
+	}, nil
+}
+
+// Origin is the scheme and host presigned URLs point at, for the
+// Content-Security-Policy.
+func (s *Store) Origin() string {
+	u := s.objectURL("")
+	return u.Scheme + "://" + u.Host
+}
+
+// Put uploads the file at localPath in a single request, typed after its
+// extension so the bucket serves it playable.
+func (s *Store) Put(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath) //nolint:gosec // path comes from the media store
+	if err != nil {
+		return fmt.Errorf("open %s: %w", localPath, err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", localPath, err)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", contentType(localPath))
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes key; S3 answers deletes of missing keys with success too.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Presign returns a GET URL for key valid for the store's presign expiry.
+// The response headers are signed into the URL, overriding the ones the
+// object was stored with.
+func (s *Store) Presign(ctx context.Context, key, contentType, disposition string) (string, error) {
+	u := s.objectURL(key)
+	now := s.now().UTC()
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", algorithm)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(s.presignExpiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+	if disposition != "" {
+		query.Set("response-content-disposition", disposition)
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	signature := s.signature(now, canonicalRequest(http.MethodGet, u, http.Header{"Host": {u.Host}}, []string{"host"}, unsignedPayload))
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+func (s *Store) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 request: %w", err)
+	}
+	s.sign(req)
+	return req, nil
+}
+
+// sign adds the Authorization header to req, signing its host and the
+// x-amz-* headers it sets.
+func (s *Store) sign(req *http.Request) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := req.Header.Clone()
+	headers.Set("Host", req.URL.Host)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+
+	signature := s.signature(now, canonicalRequest(req.Method, req.URL, headers, signed, unsignedPayload))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.accessKey, s.scope(now), strings.Join(signed, ";"), signature))
+}
+
+func (s *Store) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close() //nolint:errcheck
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, logger.SanitizeForLog(strings.TrimSpace(string(body))))
+	}
+	return resp, nil
+}
+
+func (s *Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path += "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func (s *Store) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/" + service + "/aws4_request"
+}
+
+func (s *Store) signature(t time.Time, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{algorithm, t.Format(amzDateFormat), s.scope(t), hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// canonicalRequest builds the string SigV4 signs for a request; signed
+// lists the lowercase names of the headers covered, in sorted order.
+func canonicalRequest(method string, u *url.URL, headers http.Header, signed []string, payloadHash string) string {
+	var b strings.Builder
+	b.WriteString(method + "\n")
+	b.WriteString(uriEncode(u.Path, false) + "\n")
+	b.WriteString(u.RawQuery + "\n")
+	for _, name := range signed {
+		b.WriteString(name + ":" + strings.TrimSpace(headers.Get(name)) + "\n")
+	}
+	b.WriteString("\n" + strings.Join(signed, ";") + "\n")
+	b.WriteString(payloadHash)
+	return b.String()
+}
+
+// canonicalQuery encodes query sorted by key with SigV4's encoding, which
+// differs from url.Values.Encode for spaces and a few symbols.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+var _ port.BlobStore = (*Store)(nil)
@@ -0,0 +1,229 @@
+// Package sidecar mirrors each media record into a JSON file next to its
+// upload, so a file-level backup of the data directory can rebuild the
+// database without it.
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// fileSuffix names sidecars <mediaID>.json; uploads are <mediaID>_<name>.
+const fileSuffix = ".json"
+
+// formatVersion is bumped when the sidecar layout changes incompatibly.
+const formatVersion = 1
+
+type document struct {
+	Version int           `json:"version"`
+	Media   *domain.Media `json:"media"`
+}
+
+// Store wraps a MediaStore and rewrites a media's sidecar after every
+// change to its record. Sidecar failures are logged and never fail the
+// underlying write; the database stays the source of truth.
+type Store struct {
+	port.MediaStore
+	dir string
+}
+
+// NewStore returns a MediaStore that keeps sidecars for inner in dir.
+func NewStore(inner port.MediaStore, dir string) *Store {
+	return &Store{MediaStore: inner, dir: dir}
+}
+
+func (s *Store) Save(m *domain.Media) error {
+	if err := s.MediaStore.Save(m); err != nil {
+		return err
+	}
+	s.sync(m.ID)
+	return nil
+}
+
+func (s *Store) UpdateDone(m *domain.Media) error {
+	if err := s.MediaStore.UpdateDone(m); err != nil {
+		return err
+	}
+	s.sync(m.ID)
+	return nil
+}
+
+func (s *Store) UpdateStatus(id string, status domain.MediaStatus, errMsg string) error {
+	if err := s.MediaStore.UpdateStatus(id, status, errMsg); err != nil {
+		return err
+	}
+	s.sync(id)
+	return nil
+}
+
+func (s *Store) UpdateVariantDone(v *domain.Variant) error {
+	if err := s.MediaStore.UpdateVariantDone(v); err != nil {
+		return err
+	}
+	s.sync(v.MediaID)
+	return nil
+}
+
+func (s *Store) Delete(id string) error {
+	if err := s.MediaStore.Delete(id); err != nil {
+		return err
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *Store) DeleteMany(ids []string) error {
+	if err := s.MediaStore.DeleteMany(ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.remove(id)
+	}
+	return nil
+}
+
+// remove deletes the sidecar of id, so restoring metadata does not bring
+// deleted media back.
+func (s *Store) remove(id string) {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error.Printf("failed to remove sidecar for %s: %v", id, err)
+	}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+fileSuffix)
+}
+
+// sync reloads the full record, including variants, tags and checksums,
+// and writes it out.
+func (s *Store) sync(id string) {
+	m, err := s.MediaStore.Get(id)
+	if err != nil {
+		logger.Error.Printf("failed to load media %s for sidecar: %v", id, err)
+		return
+	}
+	if err := Write(s.dir, m); err != nil {
+		logger.Error.Printf("failed to write sidecar for %s: %v", id, err)
+	}
+}
+
+// Write atomically replaces the sidecar for m in dir.
+func Write(dir string, m *domain.Media) error {
+	data, err := json.MarshalIndent(document{Version: formatVersion, Media: m}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sidecar: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+m.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp sidecar: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write temp sidecar: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("close temp sidecar: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, m.ID+fileSuffix)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("rename sidecar: %w", err)
+	}
+	return nil
+}
+
+// Read parses a single sidecar file.
+func Read(path string) (*domain.Media, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from a directory listing of the data dir
+	if err != nil {
+		return nil, err
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse sidecar: %w", err)
+	}
+	if doc.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported sidecar version %d", doc.Version)
+	}
+	if doc.Media == nil || doc.Media.ID == "" {
+		return nil, errors.New("sidecar has no media record")
+	}
+	return doc.Media, nil
+}
+
+// Restore inserts every media described by a sidecar in dir that store does
+// not already know about. It returns the number of records restored.
+func Restore(dir string, store port.MediaStore) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("read sidecar dir: %w", err)
+	}
+
+	restored := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		m, err := Read(filepath.Join(dir, name))
+		if err != nil {
+			logger.Warn.Printf("skipping sidecar %s: %v", name, err)
+			continue
+		}
+
+		if _, err := store.Get(m.ID); err == nil {
+			continue
+		} else if !errors.Is(err, domain.ErrNotFound) {
+			return restored, fmt.Errorf("check media %s: %w", m.ID, err)
+		}
+
+		if err := restoreMedia(store, m); err != nil {
+			return restored, fmt.Errorf("restore media %s: %w", m.ID, err)
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+func restoreMedia(store port.MediaStore, m *domain.Media) error {
+	if err := store.Save(m); err != nil {
+		return fmt.Errorf("save media: %w", err)
+	}
+
+	for i := range m.Variants {
+		v := m.Variants[i]
+		v.MediaID = m.ID
+		if err := store.SaveVariant(&v); err != nil {
+			return fmt.Errorf("save variant %s: %w", v.Codec, err)
+		}
+		var err error
+		switch v.Status {
+		case domain.VariantStatusDone:
+			err = store.UpdateVariantDone(&v)
+		case domain.VariantStatusPending:
+		default:
+			err = store.UpdateVariantStatus(v.ID, v.Status, v.ErrorMessage)
+		}
+		if err != nil {
+			return fmt.Errorf("update variant %s: %w", v.Codec, err)
+		}
+	}
+
+	for file, sum := range m.Checksums {
+		if err := store.SaveChecksum(m.ID, file, sum); err != nil {
+			return fmt.Errorf("save checksum %s: %w", file, err)
+		}
+	}
+	return nil
+}
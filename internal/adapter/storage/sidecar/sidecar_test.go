@@ -0,0 +1,104 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bnema/sharm/internal/adapter/storage/sqlite"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestStore_WritesAndRemovesSidecar(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(newSQLiteStore(t), dir)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/data/uploads/clip.mp4", 7*domain.Day)
+	m.Tags = []string{"holiday"}
+	require.NoError(t, store.Save(m))
+
+	got, err := Read(filepath.Join(dir, m.ID+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, "clip.mp4", got.OriginalName)
+	assert.Equal(t, domain.MediaStatusPending, got.Status)
+	assert.Equal(t, []string{"holiday"}, got.Tags)
+
+	m.Codec = domain.CodecH264
+	m.Width, m.Height = 1920, 1080
+	require.NoError(t, store.UpdateDone(m))
+
+	got, err = Read(filepath.Join(dir, m.ID+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaStatusDone, got.Status)
+	assert.Equal(t, 1920, got.Width)
+
+	require.NoError(t, store.Delete(m.ID))
+	_, err = os.Stat(filepath.Join(dir, m.ID+".json"))
+	assert.True(t, os.IsNotExist(err), "sidecar should be removed with the media")
+}
+
+func TestStore_DeleteManyRemovesSidecars(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(newSQLiteStore(t), dir)
+
+	var ids []string
+	for _, name := range []string{"a.mp4", "b.mp4", "kept.mp4"} {
+		m := domain.NewMedia(domain.MediaTypeVideo, name, "/data/uploads/"+name, 7*domain.Day)
+		require.NoError(t, store.Save(m))
+		ids = append(ids, m.ID)
+	}
+
+	require.NoError(t, store.DeleteMany(ids[:2]))
+	for _, id := range ids[:2] {
+		assert.NoFileExists(t, filepath.Join(dir, id+".json"), "sidecar should be removed with the media")
+	}
+	assert.FileExists(t, filepath.Join(dir, ids[2]+".json"))
+
+	restored, err := Restore(dir, newSQLiteStore(t))
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored, "bulk deleted media should not come back")
+}
+
+func TestRestore_RebuildsMissingRecords(t *testing.T) {
+	dir := t.TempDir()
+	source := newSQLiteStore(t)
+	store := NewStore(source, dir)
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/data/uploads/clip.mp4", 7*domain.Day)
+	m.Tags = []string{"beach"}
+	require.NoError(t, store.Save(m))
+	v := &domain.Variant{MediaID: m.ID, Codec: domain.CodecAV1}
+	require.NoError(t, source.SaveVariant(v))
+	v.Path, v.FileSize = "/data/converted/clip_av1.mp4", 42
+	require.NoError(t, store.UpdateVariantDone(v))
+	require.NoError(t, source.SaveChecksum(m.ID, domain.ChecksumOriginal, "abc123"))
+	require.NoError(t, store.UpdateDone(m))
+
+	target := newSQLiteStore(t)
+	restored, err := Restore(dir, target)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+
+	got, err := target.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaStatusDone, got.Status)
+	assert.Equal(t, []string{"beach"}, got.Tags)
+	assert.Equal(t, "abc123", got.Checksums[domain.ChecksumOriginal])
+	require.Len(t, got.Variants, 1)
+	assert.Equal(t, domain.VariantStatusDone, got.Variants[0].Status)
+	assert.Equal(t, int64(42), got.Variants[0].FileSize)
+
+	restored, err = Restore(dir, target)
+	require.NoError(t, err)
+	assert.Zero(t, restored, "existing media should be skipped")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/bnema/sharm/internal/adapter/storage/sqlite/sqlitedb"
 	"github.com/bnema/sharm/internal/domain"
@@ -11,24 +12,127 @@ import (
 )
 
 type JobQueue struct {
+	store   *Store
 	queries *sqlitedb.Queries
 }
 
 func NewJobQueue(store *Store) *JobQueue {
 	return &JobQueue{
+		store:   store,
 		queries: store.queries,
 	}
 }
 
-func (q *JobQueue) Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps int) (*domain.Job, error) {
+// Enqueue adds a job unless one for the same media, type and codec is
+// already pending or running, in which case that job is returned instead.
+// Two jobs writing the same output path would otherwise race. Pending jobs
+// are claimed by descending priority, then in insertion order.
+func (q *JobQueue) Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps, priority int) (*domain.Job, error) {
 	ctx := context.Background()
+	if job, err := q.activeJob(ctx, mediaID, jobType, codec); err != nil || job != nil {
+		return job, err
+	}
+
 	row, err := q.queries.InsertJob(ctx, sqlitedb.InsertJobParams{
+		MediaID:  mediaID,
+		Type:     string(jobType),
+		Codec:    string(codec),
+		Fps:      int64(fps),
+		Priority: int64(priority),
+	})
+	if err != nil {
+		// Lost a race against a concurrent Enqueue; the unique index on
+		// active jobs rejected the insert.
+		if job, getErr := q.activeJob(ctx, mediaID, jobType, codec); getErr == nil && job != nil {
+			return job, nil
+		}
+		return nil, err
+	}
+	return jobFromRow(row), nil
+}
+
+// EnqueueVariant saves v as a pending variant and queues its convert job in
+// one transaction, so a crash in between cannot leave a variant that no job
+// will ever process.
+func (q *JobQueue) EnqueueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	ctx := context.Background()
+	var variant sqlitedb.MediaVariant
+	var row sqlitedb.Job
+	err := q.store.WithTx(func(qtx *sqlitedb.Queries) error {
+		var err error
+		variant, err = qtx.InsertVariant(ctx, sqlitedb.InsertVariantParams{
+			MediaID: v.MediaID,
+			Codec:   string(v.Codec),
+		})
+		if err != nil {
+			return fmt.Errorf("insert variant: %w", err)
+		}
+		row, err = qtx.InsertJob(ctx, sqlitedb.InsertJobParams{
+			MediaID:  v.MediaID,
+			Type:     string(domain.JobTypeConvert),
+			Codec:    string(v.Codec),
+			Fps:      int64(fps),
+			Priority: domain.JobPriorityNormal,
+		})
+		if err != nil {
+			return fmt.Errorf("insert job: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v.ID = variant.ID
+	v.Status = domain.VariantStatusPending
+	v.CreatedAt = variant.CreatedAt
+	return jobFromRow(row), nil
+}
+
+// RequeueVariant puts a failed variant back to pending and queues a new
+// convert job for it in one transaction.
+func (q *JobQueue) RequeueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	ctx := context.Background()
+	var row sqlitedb.Job
+	err := q.store.WithTx(func(qtx *sqlitedb.Queries) error {
+		if err := qtx.UpdateVariantStatus(ctx, sqlitedb.UpdateVariantStatusParams{
+			ID:     v.ID,
+			Status: string(domain.VariantStatusPending),
+		}); err != nil {
+			return fmt.Errorf("reset variant: %w", err)
+		}
+		var err error
+		row, err = qtx.InsertJob(ctx, sqlitedb.InsertJobParams{
+			MediaID:  v.MediaID,
+			Type:     string(domain.JobTypeConvert),
+			Codec:    string(v.Codec),
+			Fps:      int64(fps),
+			Priority: domain.JobPriorityNormal,
+		})
+		if err != nil {
+			return fmt.Errorf("insert job: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v.Status = domain.VariantStatusPending
+	v.ErrorMessage = ""
+	return jobFromRow(row), nil
+}
+
+func (q *JobQueue) activeJob(ctx context.Context, mediaID string, jobType domain.JobType, codec domain.Codec) (*domain.Job, error) {
+	row, err := q.queries.GetActiveJob(ctx, sqlitedb.GetActiveJobParams{
 		MediaID: mediaID,
 		Type:    string(jobType),
 		Codec:   string(codec),
-		Fps:     int64(fps),
 	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
 	return jobFromRow(row), nil
@@ -59,11 +163,55 @@ func (q *JobQueue) Fail(jobID int64, errMsg string) error {
 	})
 }
 
+// Requeue puts a running job back to pending so it is claimed again, for
+// jobs interrupted by shutdown.
+func (q *JobQueue) Requeue(jobID int64) error {
+	ctx := context.Background()
+	return q.queries.RequeueJob(ctx, jobID)
+}
+
+// Defer puts a running job back to pending and takes back the attempt its
+// claim counted, for jobs claimed but not run.
+func (q *JobQueue) Defer(jobID int64) error {
+	ctx := context.Background()
+	return q.queries.DeferJob(ctx, jobID)
+}
+
 func (q *JobQueue) ResetStalled() error {
 	ctx := context.Background()
 	return q.queries.ResetStalledJobs(ctx)
 }
 
+// CancelByMedia marks the pending and running jobs of a media cancelled so
+// they are never claimed, and returns how many there were.
+func (q *JobQueue) CancelByMedia(mediaID string) (int, error) {
+	ctx := context.Background()
+	n, err := q.queries.CancelMediaJobs(ctx, mediaID)
+	return int(n), err
+}
+
+// PendingCount returns the number of jobs waiting to be claimed.
+func (q *JobQueue) PendingCount() (int, error) {
+	ctx := context.Background()
+	count, err := q.queries.CountPendingJobs(ctx)
+	return int(count), err
+}
+
+// ListByMedia returns every job run for a media, oldest first, including
+// failed attempts and their error output.
+func (q *JobQueue) ListByMedia(mediaID string) ([]domain.Job, error) {
+	ctx := context.Background()
+	rows, err := q.queries.ListJobsByMedia(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]domain.Job, 0, len(rows))
+	for _, row := range rows {
+		jobs = append(jobs, *jobFromRow(row))
+	}
+	return jobs, nil
+}
+
 func jobFromRow(row sqlitedb.Job) *domain.Job {
 	return &domain.Job{
 		ID:           row.ID,
@@ -71,6 +219,7 @@ func jobFromRow(row sqlitedb.Job) *domain.Job {
 		Type:         domain.JobType(row.Type),
 		Codec:        domain.Codec(row.Codec),
 		Fps:          int(row.Fps),
+		Priority:     int(row.Priority),
 		Status:       domain.JobStatus(row.Status),
 		ErrorMessage: row.ErrorMessage,
 		Attempts:     row.Attempts,
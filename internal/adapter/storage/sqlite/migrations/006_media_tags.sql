@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE media_tags (
+    media_id TEXT NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (media_id, tag)
+);
+
+CREATE INDEX idx_media_tags_tag ON media_tags(tag);
+
+-- +goose Down
+DROP TABLE media_tags;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE media_checksums (
+    media_id TEXT NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    file TEXT NOT NULL,
+    sha256 TEXT NOT NULL,
+    PRIMARY KEY (media_id, file)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS media_checksums;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Keep only the oldest active job per media, type and codec
+UPDATE jobs SET
+    status = 'failed',
+    error_message = 'superseded by duplicate job',
+    completed_at = datetime('now')
+WHERE status IN ('pending', 'running')
+  AND id NOT IN (
+    SELECT MIN(id) FROM jobs
+    WHERE status IN ('pending', 'running')
+    GROUP BY media_id, type, codec
+  );
+
+CREATE UNIQUE INDEX idx_jobs_active_unique ON jobs(media_id, type, codec)
+WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_active_unique;
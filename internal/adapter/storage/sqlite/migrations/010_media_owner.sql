@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- Existing installs have a single account; it becomes the admin and owns
+-- all media uploaded so far
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT 0;
+UPDATE users SET is_admin = 1 WHERE id = (SELECT MIN(id) FROM users);
+
+ALTER TABLE media ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 0;
+UPDATE media SET owner_id = COALESCE((SELECT MIN(id) FROM users), 0);
+
+CREATE INDEX idx_media_owner_id ON media(owner_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_media_owner_id;
+ALTER TABLE media DROP COLUMN owner_id;
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN recovery_code_hash TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN recovery_code_hash;
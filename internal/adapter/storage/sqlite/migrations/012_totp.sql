@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE totp_backup_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL
+);
+
+CREATE INDEX idx_totp_backup_codes_user_id ON totp_backup_codes(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS totp_backup_codes;
+ALTER TABLE users DROP COLUMN totp_enabled;
+ALTER TABLE users DROP COLUMN totp_secret;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE jobs ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+UPDATE jobs SET priority = 10 WHERE type IN ('thumbnail', 'probe');
+
+CREATE INDEX idx_jobs_pending_priority ON jobs(priority DESC, id) WHERE status = 'pending';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_pending_priority;
+ALTER TABLE jobs DROP COLUMN priority;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Composite indexes let the owner, status and job listings read rows in the
+-- order they are returned instead of sorting every match in a temp b-tree.
+-- They supersede the single-column owner, status and job media indexes.
+CREATE INDEX idx_media_owner_created ON media(owner_id, created_at);
+CREATE INDEX idx_media_owner_status_created ON media(owner_id, status, created_at);
+CREATE INDEX idx_media_owner_expires ON media(owner_id, expires_at, created_at DESC);
+CREATE INDEX idx_media_owner_size ON media(owner_id, file_size, created_at);
+CREATE INDEX idx_media_status_created ON media(status, created_at);
+CREATE INDEX idx_jobs_media_created ON jobs(media_id, created_at);
+DROP INDEX IF EXISTS idx_media_owner_id;
+DROP INDEX IF EXISTS idx_media_status;
+DROP INDEX IF EXISTS idx_jobs_media_id;
+
+-- +goose Down
+CREATE INDEX idx_jobs_media_id ON jobs(media_id);
+CREATE INDEX idx_media_status ON media(status);
+CREATE INDEX idx_media_owner_id ON media(owner_id);
+DROP INDEX IF EXISTS idx_jobs_media_created;
+DROP INDEX IF EXISTS idx_media_status_created;
+DROP INDEX IF EXISTS idx_media_owner_size;
+DROP INDEX IF EXISTS idx_media_owner_expires;
+DROP INDEX IF EXISTS idx_media_owner_status_created;
+DROP INDEX IF EXISTS idx_media_owner_created;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Lets clients look up whether content is already uploaded by its digest.
+CREATE INDEX idx_media_checksums_sha256 ON media_checksums(sha256, file);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_media_checksums_sha256;
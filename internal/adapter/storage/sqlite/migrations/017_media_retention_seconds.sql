@@ -0,0 +1,9 @@
+-- +goose Up
+-- Retention becomes a duration so uploads can be kept for less than a day.
+-- retention_days stays for older rows and readers; 0 seconds means the row
+-- predates this column or never expires.
+ALTER TABLE media ADD COLUMN retention_seconds INTEGER NOT NULL DEFAULT 0;
+UPDATE media SET retention_seconds = retention_days * 86400;
+
+-- +goose Down
+ALTER TABLE media DROP COLUMN retention_seconds;
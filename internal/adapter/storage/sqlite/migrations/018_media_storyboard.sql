@@ -0,0 +1,9 @@
+-- +goose Up
+-- Scrubbing preview of videos: a sprite of frames and the WebVTT mapping
+-- playback times to its tiles.
+ALTER TABLE media ADD COLUMN storyboard_path TEXT NOT NULL DEFAULT '';
+ALTER TABLE media ADD COLUMN storyboard_vtt_path TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE media DROP COLUMN storyboard_vtt_path;
+ALTER TABLE media DROP COLUMN storyboard_path;
@@ -0,0 +1,14 @@
+-- name: InsertAPIKey :one
+INSERT INTO api_keys (user_id, label, key_hash) VALUES (?, ?, ?) RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys WHERE id = ? LIMIT 1;
+
+-- name: ListAPIKeysByUser :many
+SELECT * FROM api_keys WHERE user_id = ? ORDER BY created_at DESC, id DESC;
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys WHERE id = ? AND user_id = ?;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys SET last_used_at = datetime('now') WHERE id = ?;
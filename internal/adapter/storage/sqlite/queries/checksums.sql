@@ -0,0 +1,18 @@
+-- name: GetMediaChecksum :one
+SELECT sha256 FROM media_checksums WHERE media_id = ? AND file = ? LIMIT 1;
+
+-- name: ListChecksumsByMedia :many
+SELECT * FROM media_checksums WHERE media_id = ? ORDER BY file ASC;
+
+-- name: UpsertMediaChecksum :exec
+INSERT OR REPLACE INTO media_checksums (media_id, file, sha256) VALUES (?, ?, ?);
+
+-- name: DeleteChecksumsByMedia :exec
+DELETE FROM media_checksums WHERE media_id = ?;
+
+-- name: FindMediaIDByChecksum :one
+SELECT m.id FROM media_checksums c
+JOIN media m ON m.id = c.media_id
+WHERE c.sha256 = ? AND c.file = ? AND m.owner_id = ? AND m.status != 'failed'
+ORDER BY m.created_at DESC
+LIMIT 1;
@@ -7,9 +7,17 @@ SELECT * FROM jobs WHERE media_id = ? ORDER BY created_at ASC;
 -- name: ListPendingJobs :many
 SELECT * FROM jobs WHERE status = 'pending' ORDER BY created_at ASC;
 
+-- name: CountPendingJobs :one
+SELECT COUNT(*) FROM jobs WHERE status = 'pending';
+
+-- name: GetActiveJob :one
+SELECT * FROM jobs
+WHERE media_id = ? AND type = ? AND codec = ? AND status IN ('pending', 'running')
+LIMIT 1;
+
 -- name: InsertJob :one
-INSERT INTO jobs (media_id, type, codec, fps, status, created_at)
-VALUES (?, ?, ?, ?, 'pending', datetime('now'))
+INSERT INTO jobs (media_id, type, codec, fps, priority, status, created_at)
+VALUES (?, ?, ?, ?, ?, 'pending', datetime('now'))
 RETURNING *;
 
 -- name: ClaimNextJob :one
@@ -20,7 +28,7 @@ UPDATE jobs SET
 WHERE id = (
     SELECT id FROM jobs
     WHERE status = 'pending'
-    ORDER BY created_at ASC
+    ORDER BY priority DESC, id ASC
     LIMIT 1
 )
 RETURNING *;
@@ -43,3 +51,23 @@ UPDATE jobs SET
     status = 'pending',
     started_at = NULL
 WHERE status = 'running';
+
+-- name: RequeueJob :exec
+UPDATE jobs SET
+    status = 'pending',
+    started_at = NULL
+WHERE id = ? AND status = 'running';
+
+-- name: DeferJob :exec
+UPDATE jobs SET
+    status = 'pending',
+    started_at = NULL,
+    attempts = attempts - 1
+WHERE id = ? AND status = 'running';
+
+-- name: CancelMediaJobs :execrows
+UPDATE jobs SET
+    status = 'cancelled',
+    error_message = 'cancelled',
+    completed_at = datetime('now')
+WHERE media_id = ? AND status IN ('pending', 'running');
@@ -2,20 +2,31 @@
 SELECT * FROM media WHERE id = ? LIMIT 1;
 
 -- name: ListAllMedia :many
-SELECT * FROM media ORDER BY created_at DESC;
+SELECT * FROM media WHERE owner_id = ? ORDER BY created_at DESC;
 
 -- name: ListExpiredMedia :many
 SELECT * FROM media WHERE expires_at < datetime('now');
 
--- name: ListMediaByStatus :many
-SELECT * FROM media WHERE status = ? ORDER BY created_at DESC;
+-- name: ListFailedMediaOlderThan :many
+-- Failed media whose last job finished before the cutoff; media that never
+-- ran a job fall back to their upload time.
+SELECT * FROM media
+WHERE status = 'failed'
+  AND COALESCE(
+    (SELECT MAX(jobs.completed_at) FROM jobs WHERE jobs.media_id = media.id),
+    media.created_at
+  ) < datetime('now', sqlc.arg(age_modifier));
+
+-- name: ListOwnedMediaByStatus :many
+SELECT * FROM media WHERE owner_id = ? AND status = ? ORDER BY created_at DESC;
 
 -- name: InsertMedia :exec
 INSERT INTO media (
     id, type, original_name, original_path, converted_path,
     status, codec, error_message, retention_days, file_size,
-    width, height, thumb_path, created_at, expires_at, probe_json
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+    width, height, thumb_path, created_at, expires_at, probe_json, owner_id,
+    retention_seconds
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 
 -- name: UpdateMediaStatus :exec
 UPDATE media SET status = ?, error_message = ? WHERE id = ?;
@@ -23,6 +34,7 @@ UPDATE media SET status = ?, error_message = ? WHERE id = ?;
 -- name: UpdateMediaDone :exec
 UPDATE media SET
     status = 'done',
+    original_path = ?,
     converted_path = ?,
     codec = ?,
     width = ?,
@@ -39,3 +51,50 @@ DELETE FROM media WHERE id = ?;
 
 -- name: UpdateMediaProbeJSON :exec
 UPDATE media SET probe_json = ? WHERE id = ?;
+
+-- name: UpdateMediaStoryboard :exec
+UPDATE media SET storyboard_path = ?, storyboard_vtt_path = ? WHERE id = ?;
+
+-- name: SearchMedia :many
+SELECT DISTINCT media.* FROM media
+LEFT JOIN media_tags ON media_tags.media_id = media.id
+WHERE media.owner_id = sqlc.arg(owner_id)
+  AND ((media.original_name LIKE sqlc.arg(pattern) ESCAPE '!')
+   OR (media_tags.tag LIKE sqlc.arg(pattern) ESCAPE '!'))
+ORDER BY media.created_at DESC;
+
+-- name: CountMedia :one
+SELECT COUNT(*) FROM media WHERE owner_id = ?;
+
+-- name: CountExpiredMedia :one
+SELECT COUNT(*) FROM media WHERE expires_at < datetime('now');
+
+-- name: CountMediaByStatus :many
+SELECT status, COUNT(*) AS count FROM media GROUP BY status;
+
+-- name: SumMediaFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media;
+
+-- name: CountOwnedExpiredMedia :one
+SELECT COUNT(*) FROM media WHERE owner_id = ? AND expires_at < datetime('now');
+
+-- name: CountOwnedMediaByStatus :many
+SELECT status, COUNT(*) AS count FROM media WHERE owner_id = ? GROUP BY status;
+
+-- name: SumOwnedMediaFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media WHERE owner_id = ?;
+
+-- name: ListMediaPagedNewest :many
+SELECT * FROM media WHERE owner_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?;
+
+-- name: ListMediaPagedOldest :many
+SELECT * FROM media WHERE owner_id = ? ORDER BY created_at ASC LIMIT ? OFFSET ?;
+
+-- name: ListMediaPagedLargest :many
+SELECT * FROM media WHERE owner_id = ? ORDER BY file_size DESC, created_at DESC LIMIT ? OFFSET ?;
+
+-- name: ListMediaPagedExpiring :many
+SELECT * FROM media WHERE owner_id = ? ORDER BY expires_at ASC, created_at DESC LIMIT ? OFFSET ?;
+
+-- name: ListMediaByStatusAfterID :many
+SELECT * FROM media WHERE status = ? AND id > ? ORDER BY id LIMIT ?;
@@ -0,0 +1,16 @@
+-- name: InsertMediaTag :exec
+INSERT OR IGNORE INTO media_tags (media_id, tag) VALUES (?, ?);
+
+-- name: ListTagsByMedia :many
+SELECT tag FROM media_tags WHERE media_id = ? ORDER BY tag ASC;
+
+-- name: DeleteTagsByMedia :exec
+DELETE FROM media_tags WHERE media_id = ?;
+
+-- name: ListMediaByTag :many
+SELECT * FROM media
+WHERE owner_id = ? AND id IN (SELECT media_id FROM media_tags WHERE tag = ?)
+ORDER BY created_at DESC;
+
+-- name: ListTagsByMediaIDs :many
+SELECT * FROM media_tags WHERE media_id IN (sqlc.slice('media_ids')) ORDER BY tag ASC;
@@ -4,6 +4,9 @@ SELECT * FROM users WHERE username = ? LIMIT 1;
 -- name: GetUserByID :one
 SELECT * FROM users WHERE id = ? LIMIT 1;
 
+-- name: GetUserByOIDCSubject :one
+SELECT * FROM users WHERE oidc_subject = ? AND oidc_subject != '' LIMIT 1;
+
 -- name: GetFirstUser :one
 SELECT * FROM users LIMIT 1;
 
@@ -11,7 +14,31 @@ SELECT * FROM users LIMIT 1;
 SELECT COUNT(*) FROM users;
 
 -- name: InsertUser :exec
-INSERT INTO users (username, password_hash) VALUES (?, ?);
+INSERT INTO users (username, password_hash, is_admin) VALUES (?, ?, ?);
+
+-- name: InsertOIDCUser :exec
+INSERT INTO users (username, password_hash, is_admin, oidc_subject) VALUES (?, '', ?, ?);
+
+-- name: ListUsers :many
+SELECT * FROM users ORDER BY id ASC;
 
 -- name: UpdateUserPassword :exec
 UPDATE users SET password_hash = ?, updated_at = datetime('now') WHERE id = ?;
+
+-- name: UpdateUserRecoveryCode :exec
+UPDATE users SET recovery_code_hash = ?, updated_at = datetime('now') WHERE id = ?;
+
+-- name: UpdateUserTOTP :exec
+UPDATE users SET totp_secret = ?, totp_enabled = ?, updated_at = datetime('now') WHERE id = ?;
+
+-- name: InsertBackupCode :exec
+INSERT INTO totp_backup_codes (user_id, code_hash) VALUES (?, ?);
+
+-- name: DeleteBackupCodesByUser :exec
+DELETE FROM totp_backup_codes WHERE user_id = ?;
+
+-- name: DeleteBackupCode :execrows
+DELETE FROM totp_backup_codes WHERE user_id = ? AND code_hash = ?;
+
+-- name: CountBackupCodes :one
+SELECT COUNT(*) FROM totp_backup_codes WHERE user_id = ?;
@@ -26,3 +26,15 @@ WHERE id = ?;
 
 -- name: DeleteVariantsByMedia :exec
 DELETE FROM media_variants WHERE media_id = ?;
+
+-- name: ListVariantsByMediaIDs :many
+SELECT * FROM media_variants WHERE media_id IN (sqlc.slice('media_ids')) ORDER BY created_at ASC;
+
+-- name: SumVariantFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media_variants;
+
+-- name: SumOwnedVariantFileSize :one
+SELECT CAST(COALESCE(SUM(media_variants.file_size), 0) AS INTEGER) AS total_bytes
+FROM media_variants
+JOIN media ON media.id = media_variants.media_id
+WHERE media.owner_id = ?;
@@ -9,6 +9,22 @@ import (
 	"context"
 )
 
+const cancelMediaJobs = `-- name: CancelMediaJobs :execrows
+UPDATE jobs SET
+    status = 'cancelled',
+    error_message = 'cancelled',
+    completed_at = datetime('now')
+WHERE media_id = ? AND status IN ('pending', 'running')
+`
+
+func (q *Queries) CancelMediaJobs(ctx context.Context, mediaID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelMediaJobs, mediaID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const claimNextJob = `-- name: ClaimNextJob :one
 UPDATE jobs SET
     status = 'running',
@@ -17,10 +33,10 @@ UPDATE jobs SET
 WHERE id = (
     SELECT id FROM jobs
     WHERE status = 'pending'
-    ORDER BY created_at ASC
+    ORDER BY priority DESC, id ASC
     LIMIT 1
 )
-RETURNING id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps
+RETURNING id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority
 `
 
 func (q *Queries) ClaimNextJob(ctx context.Context) (Job, error) {
@@ -38,6 +54,7 @@ func (q *Queries) ClaimNextJob(ctx context.Context) (Job, error) {
 		&i.CompletedAt,
 		&i.Codec,
 		&i.Fps,
+		&i.Priority,
 	)
 	return i, err
 }
@@ -54,6 +71,30 @@ func (q *Queries) CompleteJob(ctx context.Context, id int64) error {
 	return err
 }
 
+const countPendingJobs = `-- name: CountPendingJobs :one
+SELECT COUNT(*) FROM jobs WHERE status = 'pending'
+`
+
+func (q *Queries) CountPendingJobs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPendingJobs)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deferJob = `-- name: DeferJob :exec
+UPDATE jobs SET
+    status = 'pending',
+    started_at = NULL,
+    attempts = attempts - 1
+WHERE id = ? AND status = 'running'
+`
+
+func (q *Queries) DeferJob(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deferJob, id)
+	return err
+}
+
 const failJob = `-- name: FailJob :exec
 UPDATE jobs SET
     status = 'failed',
@@ -72,8 +113,40 @@ func (q *Queries) FailJob(ctx context.Context, arg FailJobParams) error {
 	return err
 }
 
+const getActiveJob = `-- name: GetActiveJob :one
+SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority FROM jobs
+WHERE media_id = ? AND type = ? AND codec = ? AND status IN ('pending', 'running')
+LIMIT 1
+`
+
+type GetActiveJobParams struct {
+	MediaID string
+	Type    string
+	Codec   string
+}
+
+func (q *Queries) GetActiveJob(ctx context.Context, arg GetActiveJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, getActiveJob, arg.MediaID, arg.Type, arg.Codec)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Type,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.Attempts,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.Codec,
+		&i.Fps,
+		&i.Priority,
+	)
+	return i, err
+}
+
 const getJob = `-- name: GetJob :one
-SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps FROM jobs WHERE id = ? LIMIT 1
+SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority FROM jobs WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetJob(ctx context.Context, id int64) (Job, error) {
@@ -91,21 +164,23 @@ func (q *Queries) GetJob(ctx context.Context, id int64) (Job, error) {
 		&i.CompletedAt,
 		&i.Codec,
 		&i.Fps,
+		&i.Priority,
 	)
 	return i, err
 }
 
 const insertJob = `-- name: InsertJob :one
-INSERT INTO jobs (media_id, type, codec, fps, status, created_at)
-VALUES (?, ?, ?, ?, 'pending', datetime('now'))
-RETURNING id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps
+INSERT INTO jobs (media_id, type, codec, fps, priority, status, created_at)
+VALUES (?, ?, ?, ?, ?, 'pending', datetime('now'))
+RETURNING id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority
 `
 
 type InsertJobParams struct {
-	MediaID string
-	Type    string
-	Codec   string
-	Fps     int64
+	MediaID  string
+	Type     string
+	Codec    string
+	Fps      int64
+	Priority int64
 }
 
 func (q *Queries) InsertJob(ctx context.Context, arg InsertJobParams) (Job, error) {
@@ -114,6 +189,7 @@ func (q *Queries) InsertJob(ctx context.Context, arg InsertJobParams) (Job, erro
 		arg.Type,
 		arg.Codec,
 		arg.Fps,
+		arg.Priority,
 	)
 	var i Job
 	err := row.Scan(
@@ -128,12 +204,13 @@ func (q *Queries) InsertJob(ctx context.Context, arg InsertJobParams) (Job, erro
 		&i.CompletedAt,
 		&i.Codec,
 		&i.Fps,
+		&i.Priority,
 	)
 	return i, err
 }
 
 const listJobsByMedia = `-- name: ListJobsByMedia :many
-SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps FROM jobs WHERE media_id = ? ORDER BY created_at ASC
+SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority FROM jobs WHERE media_id = ? ORDER BY created_at ASC
 `
 
 func (q *Queries) ListJobsByMedia(ctx context.Context, mediaID string) ([]Job, error) {
@@ -157,6 +234,7 @@ func (q *Queries) ListJobsByMedia(ctx context.Context, mediaID string) ([]Job, e
 			&i.CompletedAt,
 			&i.Codec,
 			&i.Fps,
+			&i.Priority,
 		); err != nil {
 			return nil, err
 		}
@@ -172,7 +250,7 @@ func (q *Queries) ListJobsByMedia(ctx context.Context, mediaID string) ([]Job, e
 }
 
 const listPendingJobs = `-- name: ListPendingJobs :many
-SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps FROM jobs WHERE status = 'pending' ORDER BY created_at ASC
+SELECT id, media_id, type, status, error_message, attempts, created_at, started_at, completed_at, codec, fps, priority FROM jobs WHERE status = 'pending' ORDER BY created_at ASC
 `
 
 func (q *Queries) ListPendingJobs(ctx context.Context) ([]Job, error) {
@@ -196,6 +274,7 @@ func (q *Queries) ListPendingJobs(ctx context.Context) ([]Job, error) {
 			&i.CompletedAt,
 			&i.Codec,
 			&i.Fps,
+			&i.Priority,
 		); err != nil {
 			return nil, err
 		}
@@ -210,6 +289,18 @@ func (q *Queries) ListPendingJobs(ctx context.Context) ([]Job, error) {
 	return items, nil
 }
 
+const requeueJob = `-- name: RequeueJob :exec
+UPDATE jobs SET
+    status = 'pending',
+    started_at = NULL
+WHERE id = ? AND status = 'running'
+`
+
+func (q *Queries) RequeueJob(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, requeueJob, id)
+	return err
+}
+
 const resetStalledJobs = `-- name: ResetStalledJobs :exec
 UPDATE jobs SET
     status = 'pending',
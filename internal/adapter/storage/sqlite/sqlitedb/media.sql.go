@@ -10,6 +10,103 @@ import (
 	"time"
 )
 
+const countExpiredMedia = `-- name: CountExpiredMedia :one
+SELECT COUNT(*) FROM media WHERE expires_at < datetime('now')
+`
+
+func (q *Queries) CountExpiredMedia(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countExpiredMedia)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMedia = `-- name: CountMedia :one
+SELECT COUNT(*) FROM media WHERE owner_id = ?
+`
+
+func (q *Queries) CountMedia(ctx context.Context, ownerID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMedia, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMediaByStatus = `-- name: CountMediaByStatus :many
+SELECT status, COUNT(*) AS count FROM media GROUP BY status
+`
+
+type CountMediaByStatusRow struct {
+	Status string
+	Count  int64
+}
+
+func (q *Queries) CountMediaByStatus(ctx context.Context) ([]CountMediaByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countMediaByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountMediaByStatusRow
+	for rows.Next() {
+		var i CountMediaByStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countOwnedExpiredMedia = `-- name: CountOwnedExpiredMedia :one
+SELECT COUNT(*) FROM media WHERE owner_id = ? AND expires_at < datetime('now')
+`
+
+func (q *Queries) CountOwnedExpiredMedia(ctx context.Context, ownerID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOwnedExpiredMedia, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOwnedMediaByStatus = `-- name: CountOwnedMediaByStatus :many
+SELECT status, COUNT(*) AS count FROM media WHERE owner_id = ? GROUP BY status
+`
+
+type CountOwnedMediaByStatusRow struct {
+	Status string
+	Count  int64
+}
+
+func (q *Queries) CountOwnedMediaByStatus(ctx context.Context, ownerID int64) ([]CountOwnedMediaByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countOwnedMediaByStatus, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountOwnedMediaByStatusRow
+	for rows.Next() {
+		var i CountOwnedMediaByStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteJobsByMedia = `-- name: DeleteJobsByMedia :exec
 DELETE FROM jobs WHERE media_id = ?
 `
@@ -29,7 +126,7 @@ func (q *Queries) DeleteMedia(ctx context.Context, id string) error {
 }
 
 const getMedia = `-- name: GetMedia :one
-SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json FROM media WHERE id = ? LIMIT 1
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetMedia(ctx context.Context, id string) (Medium, error) {
@@ -52,6 +149,10 @@ func (q *Queries) GetMedia(ctx context.Context, id string) (Medium, error) {
 		&i.CreatedAt,
 		&i.ExpiresAt,
 		&i.ProbeJson,
+		&i.OwnerID,
+		&i.RetentionSeconds,
+		&i.StoryboardPath,
+		&i.StoryboardVttPath,
 	)
 	return i, err
 }
@@ -60,27 +161,30 @@ const insertMedia = `-- name: InsertMedia :exec
 INSERT INTO media (
     id, type, original_name, original_path, converted_path,
     status, codec, error_message, retention_days, file_size,
-    width, height, thumb_path, created_at, expires_at, probe_json
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    width, height, thumb_path, created_at, expires_at, probe_json, owner_id,
+    retention_seconds
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type InsertMediaParams struct {
-	ID            string
-	Type          string
-	OriginalName  string
-	OriginalPath  string
-	ConvertedPath string
-	Status        string
-	Codec         string
-	ErrorMessage  string
-	RetentionDays int64
-	FileSize      int64
-	Width         int64
-	Height        int64
-	ThumbPath     string
-	CreatedAt     time.Time
-	ExpiresAt     time.Time
-	ProbeJson     string
+	ID               string
+	Type             string
+	OriginalName     string
+	OriginalPath     string
+	ConvertedPath    string
+	Status           string
+	Codec            string
+	ErrorMessage     string
+	RetentionDays    int64
+	FileSize         int64
+	Width            int64
+	Height           int64
+	ThumbPath        string
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+	ProbeJson        string
+	OwnerID          int64
+	RetentionSeconds int64
 }
 
 func (q *Queries) InsertMedia(ctx context.Context, arg InsertMediaParams) error {
@@ -101,16 +205,18 @@ func (q *Queries) InsertMedia(ctx context.Context, arg InsertMediaParams) error
 		arg.CreatedAt,
 		arg.ExpiresAt,
 		arg.ProbeJson,
+		arg.OwnerID,
+		arg.RetentionSeconds,
 	)
 	return err
 }
 
 const listAllMedia = `-- name: ListAllMedia :many
-SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json FROM media ORDER BY created_at DESC
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? ORDER BY created_at DESC
 `
 
-func (q *Queries) ListAllMedia(ctx context.Context) ([]Medium, error) {
-	rows, err := q.db.QueryContext(ctx, listAllMedia)
+func (q *Queries) ListAllMedia(ctx context.Context, ownerID int64) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listAllMedia, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +241,10 @@ func (q *Queries) ListAllMedia(ctx context.Context) ([]Medium, error) {
 			&i.CreatedAt,
 			&i.ExpiresAt,
 			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
 		); err != nil {
 			return nil, err
 		}
@@ -150,7 +260,7 @@ func (q *Queries) ListAllMedia(ctx context.Context) ([]Medium, error) {
 }
 
 const listExpiredMedia = `-- name: ListExpiredMedia :many
-SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json FROM media WHERE expires_at < datetime('now')
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE expires_at < datetime('now')
 `
 
 func (q *Queries) ListExpiredMedia(ctx context.Context) ([]Medium, error) {
@@ -179,6 +289,119 @@ func (q *Queries) ListExpiredMedia(ctx context.Context) ([]Medium, error) {
 			&i.CreatedAt,
 			&i.ExpiresAt,
 			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFailedMediaOlderThan = `-- name: ListFailedMediaOlderThan :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media
+WHERE status = 'failed'
+  AND COALESCE(
+    (SELECT MAX(jobs.completed_at) FROM jobs WHERE jobs.media_id = media.id),
+    media.created_at
+  ) < datetime('now', ?1)
+`
+
+// Failed media whose last job finished before the cutoff; media that never
+// ran a job fall back to their upload time.
+func (q *Queries) ListFailedMediaOlderThan(ctx context.Context, ageModifier interface{}) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listFailedMediaOlderThan, ageModifier)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByStatusAfterID = `-- name: ListMediaByStatusAfterID :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE status = ? AND id > ? ORDER BY id LIMIT ?
+`
+
+type ListMediaByStatusAfterIDParams struct {
+	Status string
+	ID     string
+	Limit  int64
+}
+
+func (q *Queries) ListMediaByStatusAfterID(ctx context.Context, arg ListMediaByStatusAfterIDParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaByStatusAfterID, arg.Status, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
 		); err != nil {
 			return nil, err
 		}
@@ -193,12 +416,18 @@ func (q *Queries) ListExpiredMedia(ctx context.Context) ([]Medium, error) {
 	return items, nil
 }
 
-const listMediaByStatus = `-- name: ListMediaByStatus :many
-SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json FROM media WHERE status = ? ORDER BY created_at DESC
+const listMediaPagedExpiring = `-- name: ListMediaPagedExpiring :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? ORDER BY expires_at ASC, created_at DESC LIMIT ? OFFSET ?
 `
 
-func (q *Queries) ListMediaByStatus(ctx context.Context, status string) ([]Medium, error) {
-	rows, err := q.db.QueryContext(ctx, listMediaByStatus, status)
+type ListMediaPagedExpiringParams struct {
+	OwnerID int64
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) ListMediaPagedExpiring(ctx context.Context, arg ListMediaPagedExpiringParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaPagedExpiring, arg.OwnerID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -223,6 +452,10 @@ func (q *Queries) ListMediaByStatus(ctx context.Context, status string) ([]Mediu
 			&i.CreatedAt,
 			&i.ExpiresAt,
 			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
 		); err != nil {
 			return nil, err
 		}
@@ -237,9 +470,305 @@ func (q *Queries) ListMediaByStatus(ctx context.Context, status string) ([]Mediu
 	return items, nil
 }
 
+const listMediaPagedLargest = `-- name: ListMediaPagedLargest :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? ORDER BY file_size DESC, created_at DESC LIMIT ? OFFSET ?
+`
+
+type ListMediaPagedLargestParams struct {
+	OwnerID int64
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) ListMediaPagedLargest(ctx context.Context, arg ListMediaPagedLargestParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaPagedLargest, arg.OwnerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaPagedNewest = `-- name: ListMediaPagedNewest :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?
+`
+
+type ListMediaPagedNewestParams struct {
+	OwnerID int64
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) ListMediaPagedNewest(ctx context.Context, arg ListMediaPagedNewestParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaPagedNewest, arg.OwnerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaPagedOldest = `-- name: ListMediaPagedOldest :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? ORDER BY created_at ASC LIMIT ? OFFSET ?
+`
+
+type ListMediaPagedOldestParams struct {
+	OwnerID int64
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) ListMediaPagedOldest(ctx context.Context, arg ListMediaPagedOldestParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaPagedOldest, arg.OwnerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnedMediaByStatus = `-- name: ListOwnedMediaByStatus :many
+SELECT id, type, original_name, original_path, converted_path, status, codec, error_message, retention_days, file_size, width, height, thumb_path, created_at, expires_at, probe_json, owner_id, retention_seconds, storyboard_path, storyboard_vtt_path FROM media WHERE owner_id = ? AND status = ? ORDER BY created_at DESC
+`
+
+type ListOwnedMediaByStatusParams struct {
+	OwnerID int64
+	Status  string
+}
+
+func (q *Queries) ListOwnedMediaByStatus(ctx context.Context, arg ListOwnedMediaByStatusParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listOwnedMediaByStatus, arg.OwnerID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchMedia = `-- name: SearchMedia :many
+SELECT DISTINCT media.id, media.type, media.original_name, media.original_path, media.converted_path, media.status, media.codec, media.error_message, media.retention_days, media.file_size, media.width, media.height, media.thumb_path, media.created_at, media.expires_at, media.probe_json, media.owner_id, media.retention_seconds, media.storyboard_path, media.storyboard_vtt_path FROM media
+LEFT JOIN media_tags ON media_tags.media_id = media.id
+WHERE media.owner_id = ?1
+  AND ((media.original_name LIKE ?2 ESCAPE '!')
+   OR (media_tags.tag LIKE ?2 ESCAPE '!'))
+ORDER BY media.created_at DESC
+`
+
+type SearchMediaParams struct {
+	OwnerID int64
+	Pattern string
+}
+
+func (q *Queries) SearchMedia(ctx context.Context, arg SearchMediaParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, searchMedia, arg.OwnerID, arg.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalName,
+			&i.OriginalPath,
+			&i.ConvertedPath,
+			&i.Status,
+			&i.Codec,
+			&i.ErrorMessage,
+			&i.RetentionDays,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.ThumbPath,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ProbeJson,
+			&i.OwnerID,
+			&i.RetentionSeconds,
+			&i.StoryboardPath,
+			&i.StoryboardVttPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumMediaFileSize = `-- name: SumMediaFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media
+`
+
+func (q *Queries) SumMediaFileSize(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumMediaFileSize)
+	var total_bytes int64
+	err := row.Scan(&total_bytes)
+	return total_bytes, err
+}
+
+const sumOwnedMediaFileSize = `-- name: SumOwnedMediaFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media WHERE owner_id = ?
+`
+
+func (q *Queries) SumOwnedMediaFileSize(ctx context.Context, ownerID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumOwnedMediaFileSize, ownerID)
+	var total_bytes int64
+	err := row.Scan(&total_bytes)
+	return total_bytes, err
+}
+
 const updateMediaDone = `-- name: UpdateMediaDone :exec
 UPDATE media SET
     status = 'done',
+    original_path = ?,
     converted_path = ?,
     codec = ?,
     width = ?,
@@ -250,6 +779,7 @@ WHERE id = ?
 `
 
 type UpdateMediaDoneParams struct {
+	OriginalPath  string
 	ConvertedPath string
 	Codec         string
 	Width         int64
@@ -261,6 +791,7 @@ type UpdateMediaDoneParams struct {
 
 func (q *Queries) UpdateMediaDone(ctx context.Context, arg UpdateMediaDoneParams) error {
 	_, err := q.db.ExecContext(ctx, updateMediaDone,
+		arg.OriginalPath,
 		arg.ConvertedPath,
 		arg.Codec,
 		arg.Width,
@@ -300,3 +831,18 @@ func (q *Queries) UpdateMediaStatus(ctx context.Context, arg UpdateMediaStatusPa
 	_, err := q.db.ExecContext(ctx, updateMediaStatus, arg.Status, arg.ErrorMessage, arg.ID)
 	return err
 }
+
+const updateMediaStoryboard = `-- name: UpdateMediaStoryboard :exec
+UPDATE media SET storyboard_path = ?, storyboard_vtt_path = ? WHERE id = ?
+`
+
+type UpdateMediaStoryboardParams struct {
+	StoryboardPath    string
+	StoryboardVttPath string
+	ID                string
+}
+
+func (q *Queries) UpdateMediaStoryboard(ctx context.Context, arg UpdateMediaStoryboardParams) error {
+	_, err := q.db.ExecContext(ctx, updateMediaStoryboard, arg.StoryboardPath, arg.StoryboardVttPath, arg.ID)
+	return err
+}
@@ -9,6 +9,15 @@ import (
 	"time"
 )
 
+type ApiKey struct {
+	ID         int64
+	UserID     int64
+	Label      string
+	KeyHash    string
+	CreatedAt  string
+	LastUsedAt sql.NullString
+}
+
 type Job struct {
 	ID           int64
 	MediaID      string
@@ -21,6 +30,18 @@ type Job struct {
 	CompletedAt  sql.NullTime
 	Codec        string
 	Fps          int64
+	Priority     int64
+}
+
+type MediaChecksum struct {
+	MediaID string
+	File    string
+	Sha256  string
+}
+
+type MediaTag struct {
+	MediaID string
+	Tag     string
 }
 
 type MediaVariant struct {
@@ -37,28 +58,43 @@ type MediaVariant struct {
 }
 
 type Medium struct {
-	ID            string
-	Type          string
-	OriginalName  string
-	OriginalPath  string
-	ConvertedPath string
-	Status        string
-	Codec         string
-	ErrorMessage  string
-	RetentionDays int64
-	FileSize      int64
-	Width         int64
-	Height        int64
-	ThumbPath     string
-	CreatedAt     time.Time
-	ExpiresAt     time.Time
-	ProbeJson     string
+	ID                string
+	Type              string
+	OriginalName      string
+	OriginalPath      string
+	ConvertedPath     string
+	Status            string
+	Codec             string
+	ErrorMessage      string
+	RetentionDays     int64
+	FileSize          int64
+	Width             int64
+	Height            int64
+	ThumbPath         string
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	ProbeJson         string
+	OwnerID           int64
+	RetentionSeconds  int64
+	StoryboardPath    string
+	StoryboardVttPath string
+}
+
+type TotpBackupCode struct {
+	ID       int64
+	UserID   int64
+	CodeHash string
 }
 
 type User struct {
-	ID           int64
-	Username     string
-	PasswordHash string
-	CreatedAt    string
-	UpdatedAt    string
+	ID               int64
+	Username         string
+	PasswordHash     string
+	CreatedAt        string
+	UpdatedAt        string
+	IsAdmin          bool
+	RecoveryCodeHash string
+	TotpSecret       string
+	TotpEnabled      bool
+	OidcSubject      string
 }
@@ -9,6 +9,17 @@ import (
 	"context"
 )
 
+const countBackupCodes = `-- name: CountBackupCodes :one
+SELECT COUNT(*) FROM totp_backup_codes WHERE user_id = ?
+`
+
+func (q *Queries) CountBackupCodes(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBackupCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsers = `-- name: CountUsers :one
 SELECT COUNT(*) FROM users
 `
@@ -20,8 +31,34 @@ func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const deleteBackupCode = `-- name: DeleteBackupCode :execrows
+DELETE FROM totp_backup_codes WHERE user_id = ? AND code_hash = ?
+`
+
+type DeleteBackupCodeParams struct {
+	UserID   int64
+	CodeHash string
+}
+
+func (q *Queries) DeleteBackupCode(ctx context.Context, arg DeleteBackupCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBackupCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteBackupCodesByUser = `-- name: DeleteBackupCodesByUser :exec
+DELETE FROM totp_backup_codes WHERE user_id = ?
+`
+
+func (q *Queries) DeleteBackupCodesByUser(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteBackupCodesByUser, userID)
+	return err
+}
+
 const getFirstUser = `-- name: GetFirstUser :one
-SELECT id, username, password_hash, created_at, updated_at FROM users LIMIT 1
+SELECT id, username, password_hash, created_at, updated_at, is_admin, recovery_code_hash, totp_secret, totp_enabled, oidc_subject FROM users LIMIT 1
 `
 
 func (q *Queries) GetFirstUser(ctx context.Context) (User, error) {
@@ -33,12 +70,17 @@ func (q *Queries) GetFirstUser(ctx context.Context) (User, error) {
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.RecoveryCodeHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, username, password_hash, created_at, updated_at FROM users WHERE username = ? LIMIT 1
+SELECT id, username, password_hash, created_at, updated_at, is_admin, recovery_code_hash, totp_secret, totp_enabled, oidc_subject FROM users WHERE username = ? LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
@@ -50,12 +92,17 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.RecoveryCodeHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, password_hash, created_at, updated_at FROM users WHERE id = ? LIMIT 1
+SELECT id, username, password_hash, created_at, updated_at, is_admin, recovery_code_hash, totp_secret, totp_enabled, oidc_subject FROM users WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
@@ -67,24 +114,119 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.RecoveryCodeHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const getUserByOIDCSubject = `-- name: GetUserByOIDCSubject :one
+SELECT id, username, password_hash, created_at, updated_at, is_admin, recovery_code_hash, totp_secret, totp_enabled, oidc_subject FROM users WHERE oidc_subject = ? AND oidc_subject != '' LIMIT 1
+`
+
+func (q *Queries) GetUserByOIDCSubject(ctx context.Context, oidcSubject string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByOIDCSubject, oidcSubject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.RecoveryCodeHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
+const insertBackupCode = `-- name: InsertBackupCode :exec
+INSERT INTO totp_backup_codes (user_id, code_hash) VALUES (?, ?)
+`
+
+type InsertBackupCodeParams struct {
+	UserID   int64
+	CodeHash string
+}
+
+func (q *Queries) InsertBackupCode(ctx context.Context, arg InsertBackupCodeParams) error {
+	_, err := q.db.ExecContext(ctx, insertBackupCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const insertOIDCUser = `-- name: InsertOIDCUser :exec
+INSERT INTO users (username, password_hash, is_admin, oidc_subject) VALUES (?, '', ?, ?)
+`
+
+type InsertOIDCUserParams struct {
+	Username    string
+	IsAdmin     bool
+	OidcSubject string
+}
+
+func (q *Queries) InsertOIDCUser(ctx context.Context, arg InsertOIDCUserParams) error {
+	_, err := q.db.ExecContext(ctx, insertOIDCUser, arg.Username, arg.IsAdmin, arg.OidcSubject)
+	return err
+}
+
 const insertUser = `-- name: InsertUser :exec
-INSERT INTO users (username, password_hash) VALUES (?, ?)
+INSERT INTO users (username, password_hash, is_admin) VALUES (?, ?, ?)
 `
 
 type InsertUserParams struct {
 	Username     string
 	PasswordHash string
+	IsAdmin      bool
 }
 
 func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) error {
-	_, err := q.db.ExecContext(ctx, insertUser, arg.Username, arg.PasswordHash)
+	_, err := q.db.ExecContext(ctx, insertUser, arg.Username, arg.PasswordHash, arg.IsAdmin)
 	return err
 }
 
+const listUsers = `-- name: ListUsers :many
+SELECT id, username, password_hash, created_at, updated_at, is_admin, recovery_code_hash, totp_secret, totp_enabled, oidc_subject FROM users ORDER BY id ASC
+`
+
+func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.PasswordHash,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAdmin,
+			&i.RecoveryCodeHash,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.OidcSubject,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users SET password_hash = ?, updated_at = datetime('now') WHERE id = ?
 `
@@ -98,3 +240,32 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.ID)
 	return err
 }
+
+const updateUserRecoveryCode = `-- name: UpdateUserRecoveryCode :exec
+UPDATE users SET recovery_code_hash = ?, updated_at = datetime('now') WHERE id = ?
+`
+
+type UpdateUserRecoveryCodeParams struct {
+	RecoveryCodeHash string
+	ID               int64
+}
+
+func (q *Queries) UpdateUserRecoveryCode(ctx context.Context, arg UpdateUserRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserRecoveryCode, arg.RecoveryCodeHash, arg.ID)
+	return err
+}
+
+const updateUserTOTP = `-- name: UpdateUserTOTP :exec
+UPDATE users SET totp_secret = ?, totp_enabled = ?, updated_at = datetime('now') WHERE id = ?
+`
+
+type UpdateUserTOTPParams struct {
+	TotpSecret  string
+	TotpEnabled bool
+	ID          int64
+}
+
+func (q *Queries) UpdateUserTOTP(ctx context.Context, arg UpdateUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserTOTP, arg.TotpSecret, arg.TotpEnabled, arg.ID)
+	return err
+}
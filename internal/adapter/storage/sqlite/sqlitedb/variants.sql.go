@@ -7,6 +7,7 @@ package sqlitedb
 
 import (
 	"context"
+	"strings"
 )
 
 const deleteVariantsByMedia = `-- name: DeleteVariantsByMedia :exec
@@ -134,6 +135,79 @@ func (q *Queries) ListVariantsByMedia(ctx context.Context, mediaID string) ([]Me
 	return items, nil
 }
 
+const listVariantsByMediaIDs = `-- name: ListVariantsByMediaIDs :many
+SELECT id, media_id, codec, path, file_size, width, height, status, error_message, created_at FROM media_variants WHERE media_id IN (/*SLICE:media_ids*/?) ORDER BY created_at ASC
+`
+
+func (q *Queries) ListVariantsByMediaIDs(ctx context.Context, mediaIds []string) ([]MediaVariant, error) {
+	query := listVariantsByMediaIDs
+	var queryParams []interface{}
+	if len(mediaIds) > 0 {
+		for _, v := range mediaIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:media_ids*/?", strings.Repeat(",?", len(mediaIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:media_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaVariant
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Codec,
+			&i.Path,
+			&i.FileSize,
+			&i.Width,
+			&i.Height,
+			&i.Status,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumOwnedVariantFileSize = `-- name: SumOwnedVariantFileSize :one
+SELECT CAST(COALESCE(SUM(media_variants.file_size), 0) AS INTEGER) AS total_bytes
+FROM media_variants
+JOIN media ON media.id = media_variants.media_id
+WHERE media.owner_id = ?
+`
+
+func (q *Queries) SumOwnedVariantFileSize(ctx context.Context, ownerID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumOwnedVariantFileSize, ownerID)
+	var total_bytes int64
+	err := row.Scan(&total_bytes)
+	return total_bytes, err
+}
+
+const sumVariantFileSize = `-- name: SumVariantFileSize :one
+SELECT CAST(COALESCE(SUM(file_size), 0) AS INTEGER) AS total_bytes FROM media_variants
+`
+
+func (q *Queries) SumVariantFileSize(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumVariantFileSize)
+	var total_bytes int64
+	err := row.Scan(&total_bytes)
+	return total_bytes, err
+}
+
 const updateVariantDone = `-- name: UpdateVariantDone :exec
 UPDATE media_variants SET
     status = 'done',
@@ -6,7 +6,12 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bnema/sharm/internal/adapter/storage/sqlite/sqlitedb"
 	"github.com/bnema/sharm/internal/domain"
@@ -21,6 +26,7 @@ var migrations embed.FS
 type Store struct {
 	db      *sql.DB
 	queries *sqlitedb.Queries
+	dataDir string
 }
 
 var hookOnce sync.Once
@@ -72,6 +78,7 @@ func NewStore(dataDir string) (*Store, error) {
 	return &Store{
 		db:      db,
 		queries: sqlitedb.New(db),
+		dataDir: dataDir,
 	}, nil
 }
 
@@ -79,6 +86,135 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// Checkpoint copies the write-ahead log into the database and truncates it,
+// so the -wal file does not keep the size of its busiest period.
+func (s *Store) Checkpoint(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// Vacuum rebuilds the database file to release pages freed by deletions.
+// It blocks other queries while it runs.
+func (s *Store) Vacuum(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "VACUUM")
+	return err
+}
+
+// BackupName is the file name a backup of the database is saved as.
+func (s *Store) BackupName() string {
+	return "sharm.db"
+}
+
+// Backup writes a consistent snapshot of the database to w. VACUUM INTO
+// holds the single connection only while the snapshot is written to a
+// temporary file next to the database; streaming it to w, which may be a
+// slow client, happens after other queries have resumed.
+func (s *Store) Backup(ctx context.Context, w io.Writer) error {
+	tmpDir, err := os.MkdirTemp(s.dataDir, ".backup-*")
+	if err != nil {
+		return fmt.Errorf("create backup dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	snapshot := filepath.Join(tmpDir, "sharm.db")
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+	f, err := os.Open(snapshot) //nolint:gosec // path is built from the data dir
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the database with the snapshot saved at snapshotPath,
+// which must be a sharm database no newer than this build's schema. The
+// pages are copied over the single connection with SQLite's backup API, so
+// other queries wait for the swap and see either the old or the restored
+// data. Jobs that were running when the snapshot was taken are queued again.
+func (s *Store) Restore(ctx context.Context, snapshotPath string) error {
+	if err := s.checkSnapshot(ctx, snapshotPath); err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	err = conn.Raw(func(driverConn any) error {
+		restorer, ok := driverConn.(interface {
+			NewRestore(srcURI string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return errors.New("driver does not support restore")
+		}
+		b, err := restorer.NewRestore(snapshotPath)
+		if err != nil {
+			return err
+		}
+		for more := true; more; {
+			if more, err = b.Step(-1); err != nil {
+				_ = b.Finish()
+				return err
+			}
+		}
+		return b.Finish()
+	})
+	_ = conn.Close()
+	if err != nil {
+		return fmt.Errorf("restore database: %w", err)
+	}
+
+	if err := goose.Up(s.db, "migrations"); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	if err := s.queries.ResetStalledJobs(ctx); err != nil {
+		return fmt.Errorf("reset stalled jobs: %w", err)
+	}
+	return nil
+}
+
+// checkSnapshot opens the snapshot on its own and verifies that it is an
+// intact sharm database this build can migrate.
+func (s *Store) checkSnapshot(ctx context.Context, snapshotPath string) error {
+	snapshot, err := sql.Open("sqlite", snapshotPath)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer func() { _ = snapshot.Close() }()
+
+	var check string
+	if err := snapshot.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("check snapshot: %w", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("snapshot is corrupt: %s", check)
+	}
+
+	var version int64
+	if err := snapshot.QueryRowContext(ctx, "SELECT MAX(version_id) FROM goose_db_version").Scan(&version); err != nil {
+		return fmt.Errorf("snapshot is not a sharm database: %w", err)
+	}
+	current, err := goose.GetDBVersion(s.db)
+	if err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+	if version > current {
+		return fmt.Errorf("snapshot schema version %d is newer than this sharm supports (%d)", version, current)
+	}
+	return nil
+}
+
+// Ping checks that the database answers queries.
+func (s *Store) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
 func (s *Store) DB() *sql.DB {
 	return s.db
 }
@@ -87,25 +223,60 @@ func (s *Store) Queries() *sqlitedb.Queries {
 	return s.queries
 }
 
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. fn must only use the queries it is given: the store has a
+// single connection, which the transaction holds until it ends.
+func (s *Store) WithTx(fn func(q *sqlitedb.Queries) error) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) Save(m *domain.Media) error {
 	ctx := context.Background()
-	return s.queries.InsertMedia(ctx, sqlitedb.InsertMediaParams{
-		ID:            m.ID,
-		Type:          string(m.Type),
-		OriginalName:  m.OriginalName,
-		OriginalPath:  m.OriginalPath,
-		ConvertedPath: m.ConvertedPath,
-		Status:        string(m.Status),
-		Codec:         string(m.Codec),
-		ErrorMessage:  m.ErrorMessage,
-		RetentionDays: int64(m.RetentionDays),
-		FileSize:      m.FileSize,
-		Width:         int64(m.Width),
-		Height:        int64(m.Height),
-		ThumbPath:     m.ThumbPath,
-		CreatedAt:     m.CreatedAt,
-		ExpiresAt:     m.ExpiresAt,
-		ProbeJson:     m.ProbeJSON,
+	return s.WithTx(func(q *sqlitedb.Queries) error {
+		if err := q.InsertMedia(ctx, sqlitedb.InsertMediaParams{
+			ID:               m.ID,
+			Type:             string(m.Type),
+			OriginalName:     m.OriginalName,
+			OriginalPath:     m.OriginalPath,
+			ConvertedPath:    m.ConvertedPath,
+			Status:           string(m.Status),
+			Codec:            string(m.Codec),
+			ErrorMessage:     m.ErrorMessage,
+			RetentionDays:    int64(m.RetentionDays),
+			FileSize:         m.FileSize,
+			Width:            int64(m.Width),
+			Height:           int64(m.Height),
+			ThumbPath:        m.ThumbPath,
+			CreatedAt:        m.CreatedAt,
+			ExpiresAt:        m.ExpiresAt,
+			ProbeJson:        m.ProbeJSON,
+			OwnerID:          m.OwnerID,
+			RetentionSeconds: int64(m.Retention / time.Second),
+		}); err != nil {
+			return err
+		}
+
+		for _, tag := range m.Tags {
+			if err := q.InsertMediaTag(ctx, sqlitedb.InsertMediaTagParams{
+				MediaID: m.ID,
+				Tag:     tag,
+			}); err != nil {
+				return fmt.Errorf("insert tag %s: %w", tag, err)
+			}
+		}
+		return nil
 	})
 }
 
@@ -127,18 +298,61 @@ func (s *Store) Get(id string) (*domain.Media, error) {
 	}
 	media.Variants = variantListFromRows(variants)
 
+	tags, err := s.queries.ListTagsByMedia(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	media.Tags = tags
+
+	checksums, err := s.queries.ListChecksumsByMedia(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list checksums: %w", err)
+	}
+	if len(checksums) > 0 {
+		media.Checksums = make(map[string]string, len(checksums))
+		for _, c := range checksums {
+			media.Checksums[c.File] = c.Sha256
+		}
+	}
+
 	return media, nil
 }
 
 func (s *Store) Delete(id string) error {
 	ctx := context.Background()
-	if err := s.queries.DeleteJobsByMedia(ctx, id); err != nil {
+	return s.WithTx(func(q *sqlitedb.Queries) error {
+		return deleteMedia(ctx, q, id)
+	})
+}
+
+// DeleteMany deletes several media and everything attached to them in one
+// transaction: either all are removed or none are.
+func (s *Store) DeleteMany(ids []string) error {
+	ctx := context.Background()
+	return s.WithTx(func(q *sqlitedb.Queries) error {
+		for _, id := range ids {
+			if err := deleteMedia(ctx, q, id); err != nil {
+				return fmt.Errorf("delete media %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+func deleteMedia(ctx context.Context, q *sqlitedb.Queries, id string) error {
+	if err := q.DeleteJobsByMedia(ctx, id); err != nil {
 		return fmt.Errorf("delete jobs: %w", err)
 	}
-	if err := s.queries.DeleteVariantsByMedia(ctx, id); err != nil {
+	if err := q.DeleteVariantsByMedia(ctx, id); err != nil {
 		return fmt.Errorf("delete variants: %w", err)
 	}
-	return s.queries.DeleteMedia(ctx, id)
+	if err := q.DeleteTagsByMedia(ctx, id); err != nil {
+		return fmt.Errorf("delete tags: %w", err)
+	}
+	if err := q.DeleteChecksumsByMedia(ctx, id); err != nil {
+		return fmt.Errorf("delete checksums: %w", err)
+	}
+	return q.DeleteMedia(ctx, id)
 }
 
 func (s *Store) ListExpired() ([]*domain.Media, error) {
@@ -150,15 +364,139 @@ func (s *Store) ListExpired() ([]*domain.Media, error) {
 	return s.mediaListWithVariants(ctx, rows)
 }
 
-func (s *Store) ListAll() ([]*domain.Media, error) {
+// ListFailedOlderThan returns failed media whose last conversion attempt
+// finished more than age ago.
+func (s *Store) ListFailedOlderThan(age time.Duration) ([]*domain.Media, error) {
+	ctx := context.Background()
+	rows, err := s.queries.ListFailedMediaOlderThan(ctx, fmt.Sprintf("-%d seconds", int64(age.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	return s.mediaListWithVariants(ctx, rows)
+}
+
+// IterateByStatus calls fn for every media in the given status, one page of
+// mediaIDBatchSize at a time, so bulk operations never hold the whole
+// library in memory. Pages are keyed on the media ID, so fn may update or
+// delete the media it is given. Iteration stops at the first error fn
+// returns.
+func (s *Store) IterateByStatus(status domain.MediaStatus, fn func(*domain.Media) error) error {
+	ctx := context.Background()
+	afterID := ""
+	for {
+		rows, err := s.queries.ListMediaByStatusAfterID(ctx, sqlitedb.ListMediaByStatusAfterIDParams{
+			Status: string(status),
+			ID:     afterID,
+			Limit:  mediaIDBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list media page: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		page, err := s.mediaListWithVariants(ctx, rows)
+		if err != nil {
+			return err
+		}
+		for _, m := range page {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+// ListOwnedByStatus returns the owner's media in the given status, newest first.
+func (s *Store) ListOwnedByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error) {
+	ctx := context.Background()
+	rows, err := s.queries.ListOwnedMediaByStatus(ctx, sqlitedb.ListOwnedMediaByStatusParams{
+		OwnerID: ownerID,
+		Status:  string(status),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.mediaListWithVariants(ctx, rows)
+}
+
+func (s *Store) ListAll(ownerID int64) ([]*domain.Media, error) {
 	ctx := context.Background()
-	rows, err := s.queries.ListAllMedia(ctx)
+	rows, err := s.queries.ListAllMedia(ctx, ownerID)
 	if err != nil {
 		return nil, err
 	}
 	return s.mediaListWithVariants(ctx, rows)
 }
 
+// ListPaged returns one page of the owner's media in the requested order and
+// their total media count. Variants and tags for the page are batch-loaded by
+// media ID.
+func (s *Store) ListPaged(ownerID int64, sort domain.SortBy, limit, offset int) ([]*domain.Media, int, error) {
+	ctx := context.Background()
+	total, err := s.queries.CountMedia(ctx, ownerID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count media: %w", err)
+	}
+
+	rows, err := s.listMediaPage(ctx, ownerID, sort, int64(limit), int64(offset))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	media, err := s.mediaListWithVariants(ctx, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return media, int(total), nil
+}
+
+// listMediaPage maps sort to its query; ORDER BY can't be parameterized.
+func (s *Store) listMediaPage(ctx context.Context, ownerID int64, sort domain.SortBy, limit, offset int64) ([]sqlitedb.Medium, error) {
+	switch sort {
+	case domain.SortOldest:
+		return s.queries.ListMediaPagedOldest(ctx, sqlitedb.ListMediaPagedOldestParams{OwnerID: ownerID, Limit: limit, Offset: offset})
+	case domain.SortLargest:
+		return s.queries.ListMediaPagedLargest(ctx, sqlitedb.ListMediaPagedLargestParams{OwnerID: ownerID, Limit: limit, Offset: offset})
+	case domain.SortExpiring:
+		return s.queries.ListMediaPagedExpiring(ctx, sqlitedb.ListMediaPagedExpiringParams{OwnerID: ownerID, Limit: limit, Offset: offset})
+	default:
+		return s.queries.ListMediaPagedNewest(ctx, sqlitedb.ListMediaPagedNewestParams{OwnerID: ownerID, Limit: limit, Offset: offset})
+	}
+}
+
+func (s *Store) ListByTag(ownerID int64, tag string) ([]*domain.Media, error) {
+	ctx := context.Background()
+	rows, err := s.queries.ListMediaByTag(ctx, sqlitedb.ListMediaByTagParams{
+		OwnerID: ownerID,
+		Tag:     tag,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.mediaListWithVariants(ctx, rows)
+}
+
+// Search returns the owner's media whose original name or tags contain query.
+// LIKE is case-insensitive for ASCII in SQLite; wildcards in query are matched
+// literally.
+func (s *Store) Search(ownerID int64, query string) ([]*domain.Media, error) {
+	ctx := context.Background()
+	rows, err := s.queries.SearchMedia(ctx, sqlitedb.SearchMediaParams{
+		OwnerID: ownerID,
+		Pattern: "%" + likeEscaper.Replace(query) + "%",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.mediaListWithVariants(ctx, rows)
+}
+
+// likeEscaper escapes LIKE wildcards using the ESCAPE '!' clause of SearchMedia.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 func (s *Store) UpdateStatus(id string, status domain.MediaStatus, errMsg string) error {
 	ctx := context.Background()
 	return s.queries.UpdateMediaStatus(ctx, sqlitedb.UpdateMediaStatusParams{
@@ -171,6 +509,7 @@ func (s *Store) UpdateStatus(id string, status domain.MediaStatus, errMsg string
 func (s *Store) UpdateDone(m *domain.Media) error {
 	ctx := context.Background()
 	return s.queries.UpdateMediaDone(ctx, sqlitedb.UpdateMediaDoneParams{
+		OriginalPath:  m.OriginalPath,
 		ConvertedPath: m.ConvertedPath,
 		Codec:         string(m.Codec),
 		Width:         int64(m.Width),
@@ -189,8 +528,75 @@ func (s *Store) UpdateProbeJSON(id string, probeJSON string) error {
 	})
 }
 
+func (s *Store) UpdateStoryboard(id, spritePath, vttPath string) error {
+	ctx := context.Background()
+	return s.queries.UpdateMediaStoryboard(ctx, sqlitedb.UpdateMediaStoryboardParams{
+		StoryboardPath:    spritePath,
+		StoryboardVttPath: vttPath,
+		ID:                id,
+	})
+}
+
 // Variant methods
 
+// Stats aggregates media counts and on-disk sizes.
+func (s *Store) Stats() (domain.StorageStats, error) {
+	ctx := context.Background()
+	stats := domain.StorageStats{ByStatus: map[domain.MediaStatus]int{}}
+
+	rows, err := s.queries.CountMediaByStatus(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("count media by status: %w", err)
+	}
+	for _, row := range rows {
+		stats.ByStatus[domain.MediaStatus(row.Status)] = int(row.Count)
+		stats.TotalCount += int(row.Count)
+	}
+
+	expired, err := s.queries.CountExpiredMedia(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("count expired media: %w", err)
+	}
+	stats.ExpiredCount = int(expired)
+
+	if stats.MediaBytes, err = s.queries.SumMediaFileSize(ctx); err != nil {
+		return stats, fmt.Errorf("sum media size: %w", err)
+	}
+	if stats.VariantBytes, err = s.queries.SumVariantFileSize(ctx); err != nil {
+		return stats, fmt.Errorf("sum variant size: %w", err)
+	}
+	return stats, nil
+}
+
+// OwnerStats aggregates media counts and on-disk sizes of the owner's media.
+func (s *Store) OwnerStats(ownerID int64) (domain.StorageStats, error) {
+	ctx := context.Background()
+	stats := domain.StorageStats{ByStatus: map[domain.MediaStatus]int{}}
+
+	rows, err := s.queries.CountOwnedMediaByStatus(ctx, ownerID)
+	if err != nil {
+		return stats, fmt.Errorf("count media by status: %w", err)
+	}
+	for _, row := range rows {
+		stats.ByStatus[domain.MediaStatus(row.Status)] = int(row.Count)
+		stats.TotalCount += int(row.Count)
+	}
+
+	expired, err := s.queries.CountOwnedExpiredMedia(ctx, ownerID)
+	if err != nil {
+		return stats, fmt.Errorf("count expired media: %w", err)
+	}
+	stats.ExpiredCount = int(expired)
+
+	if stats.MediaBytes, err = s.queries.SumOwnedMediaFileSize(ctx, ownerID); err != nil {
+		return stats, fmt.Errorf("sum media size: %w", err)
+	}
+	if stats.VariantBytes, err = s.queries.SumOwnedVariantFileSize(ctx, ownerID); err != nil {
+		return stats, fmt.Errorf("sum variant size: %w", err)
+	}
+	return stats, nil
+}
+
 func (s *Store) SaveVariant(v *domain.Variant) error {
 	ctx := context.Background()
 	row, err := s.queries.InsertVariant(ctx, sqlitedb.InsertVariantParams{
@@ -205,6 +611,46 @@ func (s *Store) SaveVariant(v *domain.Variant) error {
 	return nil
 }
 
+func (s *Store) GetChecksum(mediaID, file string) (string, error) {
+	ctx := context.Background()
+	sum, err := s.queries.GetMediaChecksum(ctx, sqlitedb.GetMediaChecksumParams{
+		MediaID: mediaID,
+		File:    file,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", err
+	}
+	return sum, nil
+}
+
+func (s *Store) SaveChecksum(mediaID, file, sha256 string) error {
+	ctx := context.Background()
+	return s.queries.UpsertMediaChecksum(ctx, sqlitedb.UpsertMediaChecksumParams{
+		MediaID: mediaID,
+		File:    file,
+		Sha256:  sha256,
+	})
+}
+
+func (s *Store) FindByChecksum(ownerID int64, file, sha256 string) (string, error) {
+	ctx := context.Background()
+	id, err := s.queries.FindMediaIDByChecksum(ctx, sqlitedb.FindMediaIDByChecksumParams{
+		Sha256:  sha256,
+		File:    file,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+		return "", err
+	}
+	return id, nil
+}
+
 func (s *Store) GetVariant(id int64) (*domain.Variant, error) {
 	ctx := context.Background()
 	row, err := s.queries.GetVariant(ctx, id)
@@ -272,25 +718,38 @@ func (s *Store) DeleteVariantsByMedia(mediaID string) error {
 
 func mediumToMedia(row sqlitedb.Medium) *domain.Media {
 	return &domain.Media{
-		ID:            row.ID,
-		Type:          domain.MediaType(row.Type),
-		OriginalName:  row.OriginalName,
-		OriginalPath:  row.OriginalPath,
-		ConvertedPath: row.ConvertedPath,
-		Status:        domain.MediaStatus(row.Status),
-		Codec:         domain.Codec(row.Codec),
-		ErrorMessage:  row.ErrorMessage,
-		RetentionDays: int(row.RetentionDays),
-		FileSize:      row.FileSize,
-		Width:         int(row.Width),
-		Height:        int(row.Height),
-		ThumbPath:     row.ThumbPath,
-		CreatedAt:     row.CreatedAt,
-		ExpiresAt:     row.ExpiresAt,
-		ProbeJSON:     row.ProbeJson,
+		ID:                row.ID,
+		OwnerID:           row.OwnerID,
+		Type:              domain.MediaType(row.Type),
+		OriginalName:      row.OriginalName,
+		OriginalPath:      row.OriginalPath,
+		ConvertedPath:     row.ConvertedPath,
+		Status:            domain.MediaStatus(row.Status),
+		Codec:             domain.Codec(row.Codec),
+		ErrorMessage:      row.ErrorMessage,
+		Retention:         retentionFromRow(row.RetentionSeconds, row.RetentionDays),
+		RetentionDays:     int(row.RetentionDays),
+		FileSize:          row.FileSize,
+		Width:             int(row.Width),
+		Height:            int(row.Height),
+		ThumbPath:         row.ThumbPath,
+		CreatedAt:         row.CreatedAt,
+		ExpiresAt:         row.ExpiresAt,
+		ProbeJSON:         row.ProbeJson,
+		StoryboardPath:    row.StoryboardPath,
+		StoryboardVTTPath: row.StoryboardVttPath,
 	}
 }
 
+// retentionFromRow reads the retention of a row, falling back to its day
+// count for rows saved before retention was stored in seconds.
+func retentionFromRow(seconds, days int64) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(days) * domain.Day
+}
+
 func variantFromRow(row sqlitedb.MediaVariant) domain.Variant {
 	return domain.Variant{
 		ID:           row.ID,
@@ -314,18 +773,59 @@ func variantListFromRows(rows []sqlitedb.MediaVariant) []domain.Variant {
 	return result
 }
 
-func (s *Store) mediaListWithVariants(ctx context.Context, rows []sqlitedb.Medium) ([]*domain.Media, error) {
+// assembleMediaList attaches batch-loaded variants and tags to their media rows.
+func assembleMediaList(rows []sqlitedb.Medium, variants []sqlitedb.MediaVariant, tags []sqlitedb.MediaTag) []*domain.Media {
+	variantsByMedia := make(map[string][]domain.Variant, len(rows))
+	for _, v := range variants {
+		variantsByMedia[v.MediaID] = append(variantsByMedia[v.MediaID], variantFromRow(v))
+	}
+	tagsByMedia := make(map[string][]string, len(rows))
+	for _, t := range tags {
+		tagsByMedia[t.MediaID] = append(tagsByMedia[t.MediaID], t.Tag)
+	}
+
 	result := make([]*domain.Media, len(rows))
 	for i, row := range rows {
 		media := mediumToMedia(row)
-		variants, err := s.queries.ListVariantsByMedia(ctx, media.ID)
-		if err != nil {
-			return nil, fmt.Errorf("list variants for %s: %w", media.ID, err)
+		media.Variants = variantsByMedia[media.ID]
+		if media.Variants == nil {
+			media.Variants = []domain.Variant{}
 		}
-		media.Variants = variantListFromRows(variants)
+		media.Tags = tagsByMedia[media.ID]
 		result[i] = media
 	}
-	return result, nil
+	return result
+}
+
+// mediaIDBatchSize caps the number of IDs bound into a single IN (...) query,
+// keeping large libraries well under SQLite's host parameter limit.
+const mediaIDBatchSize = 500
+
+// mediaListWithVariants batch-loads variants and tags for rows, issuing one
+// query per relation per batch of IDs instead of one per media item.
+func (s *Store) mediaListWithVariants(ctx context.Context, rows []sqlitedb.Medium) ([]*domain.Media, error) {
+	var variants []sqlitedb.MediaVariant
+	var tags []sqlitedb.MediaTag
+	for start := 0; start < len(rows); start += mediaIDBatchSize {
+		end := min(start+mediaIDBatchSize, len(rows))
+		ids := make([]string, 0, end-start)
+		for _, row := range rows[start:end] {
+			ids = append(ids, row.ID)
+		}
+
+		batchVariants, err := s.queries.ListVariantsByMediaIDs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("list variants: %w", err)
+		}
+		variants = append(variants, batchVariants...)
+
+		batchTags, err := s.queries.ListTagsByMediaIDs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("list tags: %w", err)
+		}
+		tags = append(tags, batchTags...)
+	}
+	return assembleMediaList(rows, variants, tags), nil
 }
 
 func (s *Store) HasUser() (bool, error) {
@@ -347,6 +847,11 @@ func (s *Store) GetUser(username string) (*domain.User, error) {
 		ID:           row.ID,
 		Username:     row.Username,
 		PasswordHash: row.PasswordHash,
+		IsAdmin:      row.IsAdmin,
+		RecoveryHash: row.RecoveryCodeHash,
+		TOTPSecret:   row.TotpSecret,
+		TOTPEnabled:  row.TotpEnabled,
+		OIDCSubject:  row.OidcSubject,
 		CreatedAt:    row.CreatedAt,
 		UpdatedAt:    row.UpdatedAt,
 	}, nil
@@ -365,6 +870,34 @@ func (s *Store) GetFirstUser() (*domain.User, error) {
 		ID:           row.ID,
 		Username:     row.Username,
 		PasswordHash: row.PasswordHash,
+		IsAdmin:      row.IsAdmin,
+		RecoveryHash: row.RecoveryCodeHash,
+		TOTPSecret:   row.TotpSecret,
+		TOTPEnabled:  row.TotpEnabled,
+		OIDCSubject:  row.OidcSubject,
+		CreatedAt:    row.CreatedAt,
+		UpdatedAt:    row.UpdatedAt,
+	}, nil
+}
+
+func (s *Store) GetUserByOIDCSubject(subject string) (*domain.User, error) {
+	ctx := context.Background()
+	row, err := s.queries.GetUserByOIDCSubject(ctx, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &domain.User{
+		ID:           row.ID,
+		Username:     row.Username,
+		PasswordHash: row.PasswordHash,
+		IsAdmin:      row.IsAdmin,
+		RecoveryHash: row.RecoveryCodeHash,
+		TOTPSecret:   row.TotpSecret,
+		TOTPEnabled:  row.TotpEnabled,
+		OIDCSubject:  row.OidcSubject,
 		CreatedAt:    row.CreatedAt,
 		UpdatedAt:    row.UpdatedAt,
 	}, nil
@@ -383,17 +916,124 @@ func (s *Store) GetUserByID(id int64) (*domain.User, error) {
 		ID:           row.ID,
 		Username:     row.Username,
 		PasswordHash: row.PasswordHash,
+		IsAdmin:      row.IsAdmin,
+		RecoveryHash: row.RecoveryCodeHash,
+		TOTPSecret:   row.TotpSecret,
+		TOTPEnabled:  row.TotpEnabled,
+		OIDCSubject:  row.OidcSubject,
 		CreatedAt:    row.CreatedAt,
 		UpdatedAt:    row.UpdatedAt,
 	}, nil
 }
 
-func (s *Store) CreateUser(username, passwordHash string) error {
+func (s *Store) CreateUser(username, passwordHash string, isAdmin bool) error {
 	ctx := context.Background()
 	return s.queries.InsertUser(ctx, sqlitedb.InsertUserParams{
 		Username:     username,
 		PasswordHash: passwordHash,
+		IsAdmin:      isAdmin,
+	})
+}
+
+// CreateOIDCUser adds a user linked to an OIDC subject. It has no password,
+// so it can only sign in through the provider.
+func (s *Store) CreateOIDCUser(username, subject string, isAdmin bool) error {
+	ctx := context.Background()
+	return s.queries.InsertOIDCUser(ctx, sqlitedb.InsertOIDCUserParams{
+		Username:    username,
+		IsAdmin:     isAdmin,
+		OidcSubject: subject,
+	})
+}
+
+// SetRecoveryCode stores the hash of the user's recovery code; an empty hash
+// clears it.
+func (s *Store) SetRecoveryCode(id int64, codeHash string) error {
+	ctx := context.Background()
+	return s.queries.UpdateUserRecoveryCode(ctx, sqlitedb.UpdateUserRecoveryCodeParams{
+		RecoveryCodeHash: codeHash,
+		ID:               id,
+	})
+}
+
+// SetTOTP stores the user's TOTP secret and whether two-factor login is
+// enforced. An empty secret with enabled false turns 2FA off.
+func (s *Store) SetTOTP(id int64, secret string, enabled bool) error {
+	ctx := context.Background()
+	return s.queries.UpdateUserTOTP(ctx, sqlitedb.UpdateUserTOTPParams{
+		TotpSecret:  secret,
+		TotpEnabled: enabled,
+		ID:          id,
+	})
+}
+
+// ReplaceBackupCodes swaps the user's backup code hashes for codeHashes in a
+// single transaction.
+func (s *Store) ReplaceBackupCodes(userID int64, codeHashes []string) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin backup codes tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := s.queries.WithTx(tx)
+	if err := q.DeleteBackupCodesByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete backup codes: %w", err)
+	}
+	for _, codeHash := range codeHashes {
+		if err := q.InsertBackupCode(ctx, sqlitedb.InsertBackupCodeParams{
+			UserID:   userID,
+			CodeHash: codeHash,
+		}); err != nil {
+			return fmt.Errorf("insert backup code: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ConsumeBackupCode deletes the matching backup code and reports whether one
+// existed, so each code is accepted at most once.
+func (s *Store) ConsumeBackupCode(userID int64, codeHash string) (bool, error) {
+	ctx := context.Background()
+	n, err := s.queries.DeleteBackupCode(ctx, sqlitedb.DeleteBackupCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
 	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *Store) CountBackupCodes(userID int64) (int, error) {
+	ctx := context.Background()
+	n, err := s.queries.CountBackupCodes(ctx, userID)
+	return int(n), err
+}
+
+func (s *Store) ListUsers() ([]domain.User, error) {
+	ctx := context.Background()
+	rows, err := s.queries.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]domain.User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, domain.User{
+			ID:           row.ID,
+			Username:     row.Username,
+			PasswordHash: row.PasswordHash,
+			IsAdmin:      row.IsAdmin,
+			RecoveryHash: row.RecoveryCodeHash,
+			TOTPSecret:   row.TotpSecret,
+			TOTPEnabled:  row.TotpEnabled,
+			OIDCSubject:  row.OidcSubject,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+		})
+	}
+	return users, nil
 }
 
 func (s *Store) UpdatePassword(id int64, passwordHash string) error {
@@ -404,5 +1044,74 @@ func (s *Store) UpdatePassword(id int64, passwordHash string) error {
 	})
 }
 
+func (s *Store) CreateAPIKey(userID int64, label, keyHash string) (*domain.APIKey, error) {
+	ctx := context.Background()
+	row, err := s.queries.InsertAPIKey(ctx, sqlitedb.InsertAPIKeyParams{
+		UserID:  userID,
+		Label:   label,
+		KeyHash: keyHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyFromRow(row), nil
+}
+
+func (s *Store) GetAPIKey(id int64) (*domain.APIKey, error) {
+	ctx := context.Background()
+	row, err := s.queries.GetAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return apiKeyFromRow(row), nil
+}
+
+func (s *Store) ListAPIKeys(userID int64) ([]domain.APIKey, error) {
+	ctx := context.Background()
+	rows, err := s.queries.ListAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, *apiKeyFromRow(row))
+	}
+	return keys, nil
+}
+
+func (s *Store) RevokeAPIKey(userID, id int64) error {
+	ctx := context.Background()
+	n, err := s.queries.DeleteAPIKey(ctx, sqlitedb.DeleteAPIKeyParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) TouchAPIKey(id int64) error {
+	ctx := context.Background()
+	return s.queries.TouchAPIKey(ctx, id)
+}
+
+func apiKeyFromRow(row sqlitedb.ApiKey) *domain.APIKey {
+	return &domain.APIKey{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		Label:      row.Label,
+		KeyHash:    row.KeyHash,
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt.String,
+	}
+}
+
 var _ port.MediaStore = (*Store)(nil)
 var _ port.UserStore = (*Store)(nil)
@@ -0,0 +1,490 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/adapter/storage/sqlite/sqlitedb"
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssembleMediaList_GroupsByMediaID(t *testing.T) {
+	rows := []sqlitedb.Medium{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	variants := []sqlitedb.MediaVariant{
+		{ID: 1, MediaID: "a", Codec: "h264"},
+		{ID: 2, MediaID: "b", Codec: "av1"},
+		{ID: 3, MediaID: "a", Codec: "av1"},
+	}
+	tags := []sqlitedb.MediaTag{{MediaID: "b", Tag: "cats"}}
+
+	result := assembleMediaList(rows, variants, tags)
+
+	require.Len(t, result, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{result[0].ID, result[1].ID, result[2].ID})
+	assert.Len(t, result[0].Variants, 2)
+	assert.Equal(t, domain.CodecH264, result[0].Variants[0].Codec)
+	assert.Equal(t, domain.CodecAV1, result[0].Variants[1].Codec)
+	assert.Len(t, result[1].Variants, 1)
+	assert.Equal(t, []string{"cats"}, result[1].Tags)
+	assert.NotNil(t, result[2].Variants)
+	assert.Empty(t, result[2].Variants)
+}
+
+func TestStore_WithTx_RollsBackOnError(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+
+	err = store.WithTx(func(q *sqlitedb.Queries) error {
+		require.NoError(t, q.DeleteMedia(context.Background(), m.ID))
+		return assert.AnError
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	_, err = store.Get(m.ID)
+	assert.NoError(t, err, "delete should have been rolled back")
+}
+
+func TestStore_Retention(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	hourly := domain.NewMedia(domain.MediaTypeImage, "a.png", "/tmp/a.png", time.Hour)
+	require.NoError(t, store.Save(hourly))
+	got, err := store.Get(hourly.ID)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, got.Retention)
+	assert.Equal(t, 1, got.RetentionDays)
+
+	// Rows saved before retention was stored in seconds only have days.
+	legacy := domain.NewMedia(domain.MediaTypeImage, "b.png", "/tmp/b.png", 3*domain.Day)
+	require.NoError(t, store.Save(legacy))
+	_, err = store.db.Exec("UPDATE media SET retention_seconds = 0 WHERE id = ?", legacy.ID)
+	require.NoError(t, err)
+	got, err = store.Get(legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3*domain.Day, got.Retention)
+}
+
+func TestStore_ListAll_LoadsVariantsAcrossBatches(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	count := mediaIDBatchSize + 1
+	for i := range count {
+		m := domain.NewMedia(domain.MediaTypeVideo, fmt.Sprintf("clip%d.mp4", i), "/tmp/clip.mp4", 7*domain.Day)
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.SaveVariant(&domain.Variant{MediaID: m.ID, Codec: domain.CodecH264, Status: domain.VariantStatusPending}))
+	}
+
+	media, err := store.ListAll(0)
+
+	require.NoError(t, err)
+	require.Len(t, media, count)
+	for _, m := range media {
+		require.Len(t, m.Variants, 1, "media %s", m.ID)
+		assert.Equal(t, m.ID, m.Variants[0].MediaID)
+	}
+}
+
+func TestStore_ListFailedOlderThan(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	queue := NewJobQueue(store)
+
+	save := func(name string, status domain.MediaStatus, age time.Duration) *domain.Media {
+		m := domain.NewMedia(domain.MediaTypeVideo, name, "/tmp/"+name, 7*domain.Day)
+		m.Status = status
+		m.CreatedAt = time.Now().UTC().Add(-age)
+		require.NoError(t, store.Save(m))
+		return m
+	}
+	stale := save("stale.mp4", domain.MediaStatusFailed, 48*time.Hour)
+	save("fresh.mp4", domain.MediaStatusFailed, time.Hour)
+	save("done.mp4", domain.MediaStatusDone, 48*time.Hour)
+	retried := save("retried.mp4", domain.MediaStatusFailed, 48*time.Hour)
+
+	job, err := queue.Enqueue(retried.ID, domain.JobTypeConvert, domain.CodecAV1, 30, domain.JobPriorityNormal)
+	require.NoError(t, err)
+	require.NoError(t, queue.Fail(job.ID, "boom"))
+
+	failed, err := store.ListFailedOlderThan(24 * time.Hour)
+
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, stale.ID, failed[0].ID)
+}
+
+func TestStore_Search_MatchesNameOrTagLiterally(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	byName := domain.NewMedia(domain.MediaTypeVideo, "100%_done.mp4", "/tmp/a.mp4", 7*domain.Day)
+	byTag := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/b.mp4", 7*domain.Day)
+	byTag.Tags = []string{"holiday", "beach"}
+	other := domain.NewMedia(domain.MediaTypeVideo, "1000 done.mp4", "/tmp/c.mp4", 7*domain.Day)
+	notMine := domain.NewMedia(domain.MediaTypeVideo, "100%_done.mp4", "/tmp/d.mp4", 7*domain.Day)
+	notMine.OwnerID = 2
+	for _, m := range []*domain.Media{byName, byTag, other, notMine} {
+		if m.OwnerID == 0 {
+			m.OwnerID = 1
+		}
+		require.NoError(t, store.Save(m))
+	}
+
+	found, err := store.Search(1, "0%_d")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, byName.ID, found[0].ID)
+
+	found, err = store.Search(1, "olid")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, byTag.ID, found[0].ID)
+	assert.ElementsMatch(t, []string{"holiday", "beach"}, found[0].Tags)
+}
+
+func TestStore_APIKeys_ScopedToOwner(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	require.NoError(t, store.CreateUser("admin", "hash", true))
+	user, err := store.GetUser("admin")
+	require.NoError(t, err)
+
+	key, err := store.CreateAPIKey(user.ID, "ci", "deadbeef")
+	require.NoError(t, err)
+	assert.Empty(t, key.LastUsedAt)
+
+	require.NoError(t, store.TouchAPIKey(key.ID))
+	got, err := store.GetAPIKey(key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "deadbeef", got.KeyHash)
+	assert.NotEmpty(t, got.LastUsedAt)
+
+	assert.ErrorIs(t, store.RevokeAPIKey(user.ID+1, key.ID), domain.ErrNotFound)
+	require.NoError(t, store.RevokeAPIKey(user.ID, key.ID))
+
+	keys, err := store.ListAPIKeys(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = store.GetAPIKey(key.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestStore_ListPaged_FiltersByOwner(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	mine := domain.NewMedia(domain.MediaTypeVideo, "mine.mp4", "/tmp/a.mp4", 7*domain.Day)
+	mine.OwnerID = 1
+	mine.Tags = []string{"trip"}
+	theirs := domain.NewMedia(domain.MediaTypeVideo, "theirs.mp4", "/tmp/b.mp4", 7*domain.Day)
+	theirs.OwnerID = 2
+	theirs.Tags = []string{"trip"}
+	require.NoError(t, store.Save(mine))
+	require.NoError(t, store.Save(theirs))
+
+	page, total, err := store.ListPaged(1, domain.SortNewest, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, mine.ID, page[0].ID)
+	assert.Equal(t, int64(1), page[0].OwnerID)
+
+	tagged, err := store.ListByTag(2, "trip")
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, theirs.ID, tagged[0].ID)
+}
+
+func TestStore_ListOwnedByStatus(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	failed := domain.NewMedia(domain.MediaTypeVideo, "failed.mp4", "/tmp/a.mp4", 7*domain.Day)
+	failed.OwnerID = 1
+	failed.Status = domain.MediaStatusFailed
+	pending := domain.NewMedia(domain.MediaTypeVideo, "pending.mp4", "/tmp/b.mp4", 7*domain.Day)
+	pending.OwnerID = 1
+	theirs := domain.NewMedia(domain.MediaTypeVideo, "theirs.mp4", "/tmp/c.mp4", 7*domain.Day)
+	theirs.OwnerID = 2
+	theirs.Status = domain.MediaStatusFailed
+	for _, m := range []*domain.Media{failed, pending, theirs} {
+		require.NoError(t, store.Save(m))
+	}
+
+	list, err := store.ListOwnedByStatus(1, domain.MediaStatusFailed)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, failed.ID, list[0].ID)
+}
+
+func TestStore_IterateByStatus_VisitsEveryPage(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	const count = mediaIDBatchSize + 3
+	for i := range count {
+		m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+		m.OwnerID = int64(i%2 + 1)
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.SaveVariant(&domain.Variant{MediaID: m.ID, Codec: domain.CodecH264, Status: domain.VariantStatusDone}))
+		require.NoError(t, store.UpdateStatus(m.ID, domain.MediaStatusFailed, "boom"))
+	}
+	require.NoError(t, store.Save(domain.NewMedia(domain.MediaTypeVideo, "pending.mp4", "/tmp/pending.mp4", 7*domain.Day)))
+
+	seen := map[string]bool{}
+	err = store.IterateByStatus(domain.MediaStatusFailed, func(m *domain.Media) error {
+		assert.Equal(t, domain.MediaStatusFailed, m.Status)
+		assert.Len(t, m.Variants, 1)
+		seen[m.ID] = true
+		// Deleting the visited media must not disturb the following pages.
+		return store.Delete(m.ID)
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, count)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, store.IterateByStatus(domain.MediaStatusPending, func(*domain.Media) error { return stop }), stop)
+}
+
+func TestStore_OwnerStats(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	for owner, size := range map[int64]int64{1: 100, 2: 1000} {
+		m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+		m.OwnerID = owner
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.UpdateDone(&domain.Media{ID: m.ID, Status: domain.MediaStatusDone, FileSize: size}))
+		v := &domain.Variant{MediaID: m.ID, Codec: domain.CodecH264}
+		require.NoError(t, store.SaveVariant(v))
+		require.NoError(t, store.UpdateVariantDone(&domain.Variant{ID: v.ID, Path: "/tmp/clip_h264.mp4", FileSize: size / 10}))
+	}
+
+	stats, err := store.OwnerStats(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalCount)
+	assert.Equal(t, int64(100), stats.MediaBytes)
+	assert.Equal(t, int64(10), stats.VariantBytes)
+
+	all, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, all.TotalCount)
+	assert.Equal(t, int64(1210), all.TotalBytes())
+}
+
+func TestStore_CheckpointAndVacuum(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	for range 50 {
+		m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.Delete(m.ID))
+	}
+
+	require.NoError(t, store.Checkpoint(context.Background()))
+	wal, err := os.Stat(filepath.Join(dir, "sharm.db-wal"))
+	require.NoError(t, err)
+	assert.Zero(t, wal.Size(), "checkpoint should truncate the WAL")
+
+	require.NoError(t, store.Vacuum(context.Background()))
+}
+
+func TestStore_Backup(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(m))
+
+	restoreDir := t.TempDir()
+	f, err := os.Create(filepath.Join(restoreDir, "sharm.db"))
+	require.NoError(t, err)
+	require.NoError(t, store.Backup(context.Background(), f))
+	require.NoError(t, f.Close())
+
+	restored, err := NewStore(restoreDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = restored.Close() })
+	got, err := restored.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "clip.mp4", got.OriginalName)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.HasPrefix(e.Name(), ".backup-"), "temporary snapshot should be removed")
+	}
+}
+
+func TestStore_Restore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	kept := domain.NewMedia(domain.MediaTypeVideo, "kept.mp4", "/tmp/kept.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(kept))
+	snapshot := filepath.Join(t.TempDir(), "sharm.db")
+	f, err := os.Create(snapshot)
+	require.NoError(t, err)
+	require.NoError(t, store.Backup(context.Background(), f))
+	require.NoError(t, f.Close())
+
+	later := domain.NewMedia(domain.MediaTypeVideo, "later.mp4", "/tmp/later.mp4", 7*domain.Day)
+	require.NoError(t, store.Save(later))
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0600))
+	require.Error(t, store.Restore(context.Background(), garbage))
+	_, err = store.Get(later.ID)
+	require.NoError(t, err, "a rejected snapshot leaves the database alone")
+
+	require.NoError(t, store.Restore(context.Background(), snapshot))
+	_, err = store.Get(kept.ID)
+	assert.NoError(t, err)
+	_, err = store.Get(later.ID)
+	assert.Error(t, err, "media saved after the snapshot is gone")
+}
+
+func TestStore_ListingQueriesUseIndexes(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	queries := map[string]string{
+		"idx_media_owner_created":        "SELECT * FROM media WHERE owner_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?",
+		"idx_media_owner_status_created": "SELECT * FROM media WHERE owner_id = ? AND status = ? ORDER BY created_at DESC",
+		"idx_media_owner_size":           "SELECT * FROM media WHERE owner_id = ? ORDER BY file_size DESC, created_at DESC LIMIT ? OFFSET ?",
+		"idx_media_owner_expires":        "SELECT * FROM media WHERE owner_id = ? ORDER BY expires_at ASC, created_at DESC LIMIT ? OFFSET ?",
+		"idx_media_status_created":       "SELECT * FROM media WHERE status = ? ORDER BY created_at DESC",
+		"idx_media_expires":              "SELECT * FROM media WHERE expires_at < datetime('now')",
+		"idx_jobs_media_created":         "SELECT * FROM jobs WHERE media_id = ? ORDER BY created_at ASC",
+	}
+
+	for index, query := range queries {
+		t.Run(index, func(t *testing.T) {
+			args := make([]any, strings.Count(query, "?"))
+			for i := range args {
+				args[i] = 1
+			}
+			rows, err := store.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+			require.NoError(t, err)
+			defer func() { _ = rows.Close() }()
+
+			var plan []string
+			for rows.Next() {
+				var id, parent, unused int
+				var detail string
+				require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+				plan = append(plan, detail)
+			}
+			require.NoError(t, rows.Err())
+
+			joined := strings.Join(plan, "\n")
+			assert.Contains(t, joined, index)
+			assert.NotContains(t, joined, "TEMP B-TREE")
+		})
+	}
+}
+
+func TestStore_ListExpired_SkipsNeverExpiring(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	expired := domain.NewMedia(domain.MediaTypeImage, "old.png", "/tmp/old.png", domain.Day)
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	forever := domain.NewMedia(domain.MediaTypeImage, "keep.png", "/tmp/keep.png", domain.RetentionNever)
+	require.NoError(t, store.Save(expired))
+	require.NoError(t, store.Save(forever))
+
+	medias, err := store.ListExpired()
+	require.NoError(t, err)
+	require.Len(t, medias, 1)
+	assert.Equal(t, expired.ID, medias[0].ID)
+
+	got, err := store.Get(forever.ID)
+	require.NoError(t, err)
+	assert.True(t, got.NeverExpires())
+}
+
+func TestStore_DeleteMany(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	var ids []string
+	for range 3 {
+		m := domain.NewMedia(domain.MediaTypeVideo, "clip.mp4", "/tmp/clip.mp4", 7*domain.Day)
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.SaveVariant(&domain.Variant{MediaID: m.ID, Codec: domain.CodecH264, Status: domain.VariantStatusDone}))
+		ids = append(ids, m.ID)
+	}
+
+	require.NoError(t, store.DeleteMany(ids[:2]))
+
+	for _, id := range ids[:2] {
+		_, err := store.Get(id)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	}
+	kept, err := store.Get(ids[2])
+	require.NoError(t, err)
+	assert.Len(t, kept.Variants, 1)
+}
+
+func TestStore_FindByChecksum(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	save := func(ownerID int64, status domain.MediaStatus, sum string) *domain.Media {
+		m := domain.NewMedia(domain.MediaTypeImage, "a.png", "/tmp/a.png", 7*domain.Day)
+		m.OwnerID = ownerID
+		m.Status = status
+		require.NoError(t, store.Save(m))
+		require.NoError(t, store.SaveChecksum(m.ID, domain.ChecksumOriginal, sum))
+		return m
+	}
+	mine := save(1, domain.MediaStatusDone, "aaa")
+	save(2, domain.MediaStatusDone, "bbb")
+	save(1, domain.MediaStatusFailed, "ccc")
+
+	id, err := store.FindByChecksum(1, domain.ChecksumOriginal, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, mine.ID, id)
+
+	_, err = store.FindByChecksum(1, domain.ChecksumOriginal, "bbb")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "other users' media must not match")
+	_, err = store.FindByChecksum(1, domain.ChecksumOriginal, "ccc")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "failed media must not match")
+	_, err = store.FindByChecksum(1, "h264", "aaa")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
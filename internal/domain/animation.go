@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrAnimationTooLarge = errors.New("animation too large")
+
+// AnimationLimits bounds animated images (GIF, APNG, animated WebP) so a
+// crafted file with thousands of frames or huge dimensions cannot exhaust
+// CPU or memory when it is decoded. A zero limit is not enforced.
+type AnimationLimits struct {
+	MaxFrames    int
+	MaxDimension int
+}
+
+// Check returns ErrAnimationTooLarge, with the offending value, when probe
+// describes an animation exceeding the limits. Still images always pass.
+func (l AnimationLimits) Check(probe *ProbeResult) error {
+	if probe == nil {
+		return nil
+	}
+	vs := probe.VideoStream()
+	if vs == nil {
+		return nil
+	}
+	frames := vs.FrameCount(probe.Format.Duration)
+	if frames <= 1 {
+		return nil
+	}
+
+	if l.MaxFrames > 0 && frames > l.MaxFrames {
+		return fmt.Errorf("%w: %d frames exceeds the limit of %d", ErrAnimationTooLarge, frames, l.MaxFrames)
+	}
+	if l.MaxDimension > 0 && max(vs.Width, vs.Height) > l.MaxDimension {
+		return fmt.Errorf("%w: %dx%d exceeds the limit of %d pixels per side",
+			ErrAnimationTooLarge, vs.Width, vs.Height, l.MaxDimension)
+	}
+	return nil
+}
+
+// FrameCount returns the stream's frame count from nb_frames, or estimates it
+// from the duration (the stream's, else formatDuration) and average frame
+// rate. It returns 0 when neither is known.
+func (s *ProbeStream) FrameCount(formatDuration string) int {
+	if n, err := strconv.Atoi(s.NbFrames); err == nil && n > 0 {
+		return n
+	}
+
+	duration, err := strconv.ParseFloat(s.Duration, 64)
+	if err != nil || duration <= 0 {
+		duration, err = strconv.ParseFloat(formatDuration, 64)
+		if err != nil || duration <= 0 {
+			return 0
+		}
+	}
+	return int(duration*ParseFrameRate(s.AvgFrameRate) + 0.5)
+}
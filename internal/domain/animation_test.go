@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnimationLimits_Check(t *testing.T) {
+	limits := AnimationLimits{MaxFrames: 100, MaxDimension: 1000}
+	image := func(stream ProbeStream, duration string) *ProbeResult {
+		stream.CodecType = "video"
+		return &ProbeResult{Format: ProbeFormat{Duration: duration}, Streams: []ProbeStream{stream}}
+	}
+
+	tests := []struct {
+		name   string
+		limits AnimationLimits
+		probe  *ProbeResult
+		ok     bool
+	}{
+		{"still image", limits, image(ProbeStream{Width: 8000, Height: 8000, NbFrames: "1"}, ""), true},
+		{"small animation", limits, image(ProbeStream{Width: 320, Height: 240, NbFrames: "50"}, ""), true},
+		{"too many frames", limits, image(ProbeStream{Width: 320, Height: 240, NbFrames: "5000"}, ""), false},
+		{"frames estimated from duration", limits, image(ProbeStream{Width: 320, Height: 240, AvgFrameRate: "50/1"}, "10.0"), false},
+		{"too large", limits, image(ProbeStream{Width: 2000, Height: 200, NbFrames: "10"}, ""), false},
+		{"limits disabled", AnimationLimits{}, image(ProbeStream{Width: 2000, Height: 200, NbFrames: "5000"}, ""), true},
+		{"not probed", limits, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(tt.probe)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrAnimationTooLarge)
+			}
+		})
+	}
+}
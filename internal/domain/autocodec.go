@@ -0,0 +1,51 @@
+package domain
+
+// Thresholds for automatic codec selection. AV1 encodes slowly but roughly
+// halves file size, so it is only chosen where that saving matters.
+const (
+	// autoAV1MinShortSide is the smallest frame short side (1440p) that
+	// gets an AV1 variant regardless of length.
+	autoAV1MinShortSide = 1440
+	// autoAV1LongShortSide and autoAV1LongSeconds add AV1 for long 1080p
+	// and larger videos.
+	autoAV1LongShortSide = 1080
+	autoAV1LongSeconds   = 60
+)
+
+// AutoCodecs picks output codecs for an upload from its probe. Audio that
+// browsers already play gets none, so the upload is served as-is; other
+// audio gets Opus, which the encoder gives a higher bitrate for lossless
+// sources. Video gets H264, plus AV1 for HDR, 1440p and larger, or long
+// 1080p sources. Without a probe it falls back to the web-compatible
+// default.
+func AutoCodecs(mediaType MediaType, probe *ProbeResult) []Codec {
+	switch mediaType {
+	case MediaTypeAudio:
+		// Re-encoding lossy audio only loses quality
+		if probe.IsWebPlayableAudio() {
+			return nil
+		}
+		return []Codec{CodecOpus}
+	case MediaTypeVideo:
+	default:
+		return nil
+	}
+
+	codecs := []Codec{CodecH264}
+	if probe == nil {
+		return codecs
+	}
+	vs := probe.VideoStream()
+	if vs == nil {
+		return codecs
+	}
+
+	shortSide := min(vs.Width, vs.Height)
+	duration := ParseDuration(probe.Format.Duration)
+	if vs.IsHDR() ||
+		shortSide >= autoAV1MinShortSide ||
+		(shortSide >= autoAV1LongShortSide && duration >= autoAV1LongSeconds) {
+		codecs = append(codecs, CodecAV1)
+	}
+	return codecs
+}
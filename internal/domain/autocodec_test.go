@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoCodecs(t *testing.T) {
+	video := func(width, height int, duration string, mutate func(*ProbeStream)) *ProbeResult {
+		vs := ProbeStream{CodecType: "video", Width: width, Height: height, PixFmt: "yuv420p"}
+		if mutate != nil {
+			mutate(&vs)
+		}
+		return &ProbeResult{Format: ProbeFormat{Duration: duration}, Streams: []ProbeStream{vs}}
+	}
+	audio := func(format, codec string) *ProbeResult {
+		return &ProbeResult{Format: ProbeFormat{FormatName: format}, Streams: []ProbeStream{{CodecType: "audio", CodecName: codec}}}
+	}
+
+	tests := []struct {
+		name      string
+		mediaType MediaType
+		probe     *ProbeResult
+		want      []Codec
+	}{
+		{"audio without probe", MediaTypeAudio, nil, []Codec{CodecOpus}},
+		{"mp3", MediaTypeAudio, audio("mp3", "mp3"), nil},
+		{"m4a", MediaTypeAudio, audio("mov,mp4,m4a,3gp,3g2,mj2", "aac"), nil},
+		{"flac", MediaTypeAudio, audio("flac", "flac"), []Codec{CodecOpus}},
+		{"wav", MediaTypeAudio, audio("wav", "pcm_s16le"), []Codec{CodecOpus}},
+		{"opus in matroska", MediaTypeAudio, audio("matroska,webm", "opus"), []Codec{CodecOpus}},
+		{"image", MediaTypeImage, nil, nil},
+		{"video without probe", MediaTypeVideo, nil, []Codec{CodecH264}},
+		{"short sdr clip", MediaTypeVideo, video(1920, 1080, "12.5", nil), []Codec{CodecH264}},
+		{"long 1080p", MediaTypeVideo, video(1920, 1080, "300", nil), []Codec{CodecH264, CodecAV1}},
+		{"short vertical 4k", MediaTypeVideo, video(2160, 3840, "8", nil), []Codec{CodecH264, CodecAV1}},
+		{"long 720p", MediaTypeVideo, video(1280, 720, "600", nil), []Codec{CodecH264}},
+		{"short hdr clip", MediaTypeVideo, video(1280, 720, "5", func(s *ProbeStream) {
+			s.ColorTransfer = "smpte2084"
+		}), []Codec{CodecH264, CodecAV1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, AutoCodecs(tt.mediaType, tt.probe))
+		})
+	}
+}
+
+func TestProbeStream_IsHDR(t *testing.T) {
+	assert.True(t, (&ProbeStream{ColorTransfer: "arib-std-b67"}).IsHDR())
+	assert.True(t, (&ProbeStream{ColorSpace: "bt2020nc", PixFmt: "yuv420p10le"}).IsHDR())
+	assert.False(t, (&ProbeStream{ColorSpace: "bt2020nc", PixFmt: "yuv420p"}).IsHDR())
+	assert.False(t, (&ProbeStream{ColorSpace: "bt709", PixFmt: "yuv420p10le"}).IsHDR())
+}
@@ -0,0 +1,11 @@
+package domain
+
+// ChallengeWidget describes the client-side widget a visitor solves before
+// an anonymous upload. The widget writes its token into the ResponseField
+// input of the form it is placed in.
+type ChallengeWidget struct {
+	ScriptURL     string
+	Class         string
+	SiteKey       string
+	ResponseField string
+}
@@ -5,4 +5,13 @@ import "errors"
 var (
 	ErrNotFound = errors.New("resource not found")
 	ErrExpired  = errors.New("media has expired")
+
+	ErrOriginalMissing = errors.New("original file no longer exists")
+
+	// ErrNotCancellable means a media has no upload or conversion left to
+	// cancel.
+	ErrNotCancellable = errors.New("nothing to cancel")
+
+	// ErrChallengeFailed means an anti-abuse challenge was not solved.
+	ErrChallengeFailed = errors.New("challenge failed")
 )
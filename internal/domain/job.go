@@ -11,6 +11,16 @@ const (
 	JobTypeConvert   JobType = "convert"
 	JobTypeThumbnail JobType = "thumbnail"
 	JobTypeProbe     JobType = "probe"
+	// JobTypeStoryboard renders the scrubbing preview sprite of a video.
+	JobTypeStoryboard JobType = "storyboard"
+)
+
+// Job priorities: pending jobs with a higher priority are claimed first.
+// Thumbnails and probes are quick, so they run ahead of long conversions
+// and previews appear sooner.
+const (
+	JobPriorityNormal = 0
+	JobPriorityHigh   = 10
 )
 
 type JobStatus string
@@ -20,6 +30,9 @@ const (
 	JobStatusRunning JobStatus = "running"
 	JobStatusDone    JobStatus = "done"
 	JobStatusFailed  JobStatus = "failed"
+	// JobStatusCancelled marks a job stopped at the user's request before
+	// it finished.
+	JobStatusCancelled JobStatus = "cancelled"
 )
 
 type Job struct {
@@ -28,6 +41,7 @@ type Job struct {
 	Type         JobType
 	Codec        Codec
 	Fps          int
+	Priority     int
 	Status       JobStatus
 	ErrorMessage string
 	Attempts     int64
@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/base32"
 	"encoding/json"
+	"fmt"
 	"math"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -34,8 +36,32 @@ const (
 	CodecAV1  Codec = "av1"
 	CodecH264 Codec = "h264"
 	CodecOpus Codec = "opus"
+
+	// CodecAuto is not an output format: it asks Upload to pick codecs from
+	// the probed source with AutoCodecs.
+	CodecAuto Codec = "auto"
 )
 
+// ParseCodecs parses a comma-separated list of codecs, such as "av1,h264".
+// CodecAuto is accepted; duplicates are dropped.
+func ParseCodecs(list string) ([]Codec, error) {
+	var codecs []Codec
+	for _, name := range strings.Split(list, ",") {
+		codec := Codec(strings.ToLower(strings.TrimSpace(name)))
+		switch codec {
+		case "":
+			continue
+		case CodecAV1, CodecH264, CodecOpus, CodecAuto:
+			if !slices.Contains(codecs, codec) {
+				codecs = append(codecs, codec)
+			}
+		default:
+			return nil, fmt.Errorf("unknown codec %q", strings.TrimSpace(name))
+		}
+	}
+	return codecs, nil
+}
+
 type VariantStatus string
 
 const (
@@ -45,6 +71,12 @@ const (
 	VariantStatusFailed     VariantStatus = "failed"
 )
 
+// Checksum file keys for files that are not variants; variants use their codec name.
+const (
+	ChecksumOriginal  = "original"
+	ChecksumConverted = "converted"
+)
+
 type Variant struct {
 	ID           int64         `json:"id"`
 	MediaID      string        `json:"media_id"`
@@ -59,26 +91,36 @@ type Variant struct {
 }
 
 type Media struct {
-	ID            string      `json:"id"`
-	Type          MediaType   `json:"type"`
-	OriginalName  string      `json:"original_name"`
-	OriginalPath  string      `json:"original_path"`
-	ConvertedPath string      `json:"converted_path"`
-	Status        MediaStatus `json:"status"`
-	Codec         Codec       `json:"codec"`
-	ErrorMessage  string      `json:"error_message"`
-	RetentionDays int         `json:"retention_days"`
-	FileSize      int64       `json:"file_size"`
-	Width         int         `json:"width"`
-	Height        int         `json:"height"`
-	ThumbPath     string      `json:"thumb_path"`
-	CreatedAt     time.Time   `json:"created_at"`
-	ExpiresAt     time.Time   `json:"expires_at"`
-	Variants      []Variant   `json:"variants"`
-	ProbeJSON     string      `json:"probe_json"`
+	ID            string        `json:"id"`
+	OwnerID       int64         `json:"owner_id"`
+	Type          MediaType     `json:"type"`
+	OriginalName  string        `json:"original_name"`
+	OriginalPath  string        `json:"original_path"`
+	ConvertedPath string        `json:"converted_path"`
+	Status        MediaStatus   `json:"status"`
+	Codec         Codec         `json:"codec"`
+	ErrorMessage  string        `json:"error_message"`
+	Retention     time.Duration `json:"retention"`
+	// Deprecated: RetentionDays is Retention in whole days, rounded up, kept
+	// for API clients that read retention_days. Use Retention.
+	RetentionDays int    `json:"retention_days"`
+	FileSize      int64  `json:"file_size"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	ThumbPath     string `json:"thumb_path"`
+	// StoryboardPath and StoryboardVTTPath are the scrubbing preview sprite
+	// and the WebVTT mapping playback times to its tiles.
+	StoryboardPath    string            `json:"storyboard_path"`
+	StoryboardVTTPath string            `json:"storyboard_vtt_path"`
+	CreatedAt         time.Time         `json:"created_at"`
+	ExpiresAt         time.Time         `json:"expires_at"`
+	Variants          []Variant         `json:"variants"`
+	ProbeJSON         string            `json:"probe_json"`
+	Tags              []string          `json:"tags"`
+	Checksums         map[string]string `json:"checksums,omitempty"`
 }
 
-func NewMedia(mediaType MediaType, originalName, originalPath string, retentionDays int) *Media {
+func NewMedia(mediaType MediaType, originalName, originalPath string, retention time.Duration) *Media {
 	id := generateID()
 
 	return &Media{
@@ -87,12 +129,30 @@ func NewMedia(mediaType MediaType, originalName, originalPath string, retentionD
 		OriginalName:  originalName,
 		OriginalPath:  originalPath,
 		Status:        MediaStatusPending,
-		RetentionDays: retentionDays,
+		Retention:     retention,
+		RetentionDays: RetentionDays(retention),
 		CreatedAt:     time.Now(),
-		ExpiresAt:     time.Now().AddDate(0, 0, retentionDays),
+		ExpiresAt:     expiresAt(retention),
 	}
 }
 
+// expiresAt returns the expiry of media kept for retention from now.
+func expiresAt(retention time.Duration) time.Time {
+	if retention <= RetentionNever {
+		return NeverExpiresAt
+	}
+	return time.Now().Add(retention)
+}
+
+// RetentionDays converts a retention to whole days, rounded up, the unit
+// retention was stored in before it became a duration.
+func RetentionDays(retention time.Duration) int {
+	if retention <= RetentionNever {
+		return 0
+	}
+	return int(math.Ceil(float64(retention) / float64(Day)))
+}
+
 func generateID() string {
 	b := make([]byte, 5)
 	if _, err := rand.Read(b); err != nil {
@@ -101,18 +161,31 @@ func generateID() string {
 	return base32.StdEncoding.EncodeToString(b)[:8]
 }
 
+// HasStoryboard reports whether the scrubbing preview has been rendered.
+func (m *Media) HasStoryboard() bool {
+	return m.StoryboardPath != "" && m.StoryboardVTTPath != ""
+}
+
+// NeverExpires reports whether the media was uploaded without an expiry.
+func (m *Media) NeverExpires() bool {
+	return !m.ExpiresAt.Before(NeverExpiresAt)
+}
+
 func (m *Media) IsExpired() bool {
+	if m.NeverExpires() {
+		return false
+	}
 	return time.Now().After(m.ExpiresAt)
 }
 
-// DaysRemaining returns the number of days until expiration (rounded up).
-// Returns 0 if already expired.
-func (m *Media) DaysRemaining() int {
-	remaining := time.Until(m.ExpiresAt).Hours() / 24
+// TimeRemaining returns the time left until expiration, or 0 if already
+// expired. Check NeverExpires first.
+func (m *Media) TimeRemaining() time.Duration {
+	remaining := time.Until(m.ExpiresAt)
 	if remaining <= 0 {
 		return 0
 	}
-	return int(math.Ceil(remaining))
+	return remaining
 }
 
 func (m *Media) MarkAsDone(convertedPath string, codec Codec, width, height int, thumbPath string, fileSize int64) {
@@ -160,6 +233,15 @@ func (m *Media) BestVariant() *Variant {
 	return nil
 }
 
+// H264Variant returns the done H264 variant, or nil. It is the variant that
+// plays inline in chat apps and on every browser.
+func (m *Media) H264Variant() *Variant {
+	if v := m.VariantByCodec(CodecH264); v != nil && v.Status == VariantStatusDone {
+		return v
+	}
+	return nil
+}
+
 // codecMIME maps codecs to their MIME types.
 var codecMIME = map[Codec]string{
 	CodecAV1:  "video/webm",
@@ -298,6 +380,32 @@ var audioExts = map[string]bool{
 	".aac": true, ".m4a": true, ".wma": true, ".opus": true,
 }
 
+// IsAnimation reports whether the media is a video converted from an
+// animated GIF, which should play like one: looping, muted, no controls.
+func (m *Media) IsAnimation() bool {
+	if m.Type != MediaTypeVideo || m.ProbeJSON == "" {
+		return false
+	}
+	var probe struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+		} `json:"format"`
+	}
+	return json.Unmarshal([]byte(m.ProbeJSON), &probe) == nil && probe.Format.FormatName == "gif"
+}
+
+// SupportsCodec reports whether codec is a valid conversion target for this media type.
+func (t MediaType) SupportsCodec(codec Codec) bool {
+	switch t {
+	case MediaTypeVideo:
+		return codec == CodecAV1 || codec == CodecH264
+	case MediaTypeAudio:
+		return codec == CodecOpus
+	default:
+		return false
+	}
+}
+
 func DetectMediaType(filename string) MediaType {
 	ext := strings.ToLower(filepath.Ext(filename))
 	if imageExts[ext] {
@@ -309,3 +417,22 @@ func DetectMediaType(filename string) MediaType {
 	// Default to video for known video extensions or unknown types
 	return MediaTypeVideo
 }
+
+// MediaTypeFromMIME corrects a type detected from the file extension with
+// the MIME type sniffed from the content, so a photo renamed to .mp4 is still
+// handled as an image. It only settles image versus audio/video: containers
+// such as MP4 hold either, so the probe decides between those two.
+func MediaTypeFromMIME(detected MediaType, mime string) MediaType {
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return MediaTypeImage
+	case detected != MediaTypeImage:
+		return detected
+	case strings.HasPrefix(mime, "video/"):
+		return MediaTypeVideo
+	case strings.HasPrefix(mime, "audio/"), mime == "application/ogg":
+		return MediaTypeAudio
+	default:
+		return detected
+	}
+}
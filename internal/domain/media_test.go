@@ -14,6 +14,7 @@ func TestNewMedia(t *testing.T) {
 		mediaType     MediaType
 		originalName  string
 		originalPath  string
+		retention     time.Duration
 		retentionDays int
 	}{
 		{
@@ -21,6 +22,7 @@ func TestNewMedia(t *testing.T) {
 			mediaType:     MediaTypeVideo,
 			originalName:  "test.mp4",
 			originalPath:  "/uploads/test.mp4",
+			retention:     7 * Day,
 			retentionDays: 7,
 		},
 		{
@@ -28,6 +30,7 @@ func TestNewMedia(t *testing.T) {
 			mediaType:     MediaTypeAudio,
 			originalName:  "song.mp3",
 			originalPath:  "/uploads/song.mp3",
+			retention:     30 * Day,
 			retentionDays: 30,
 		},
 		{
@@ -35,13 +38,14 @@ func TestNewMedia(t *testing.T) {
 			mediaType:     MediaTypeImage,
 			originalName:  "photo.jpg",
 			originalPath:  "/uploads/photo.jpg",
-			retentionDays: 14,
+			retention:     36 * time.Hour,
+			retentionDays: 2,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			media := NewMedia(tt.mediaType, tt.originalName, tt.originalPath, tt.retentionDays)
+			media := NewMedia(tt.mediaType, tt.originalName, tt.originalPath, tt.retention)
 
 			assert.NotEmpty(t, media.ID, "ID should be generated")
 			assert.Len(t, media.ID, 8, "ID should be 8 characters")
@@ -49,10 +53,11 @@ func TestNewMedia(t *testing.T) {
 			assert.Equal(t, tt.originalName, media.OriginalName, "OriginalName should match")
 			assert.Equal(t, tt.originalPath, media.OriginalPath, "OriginalPath should match")
 			assert.Equal(t, MediaStatusPending, media.Status, "Status should be converting")
-			assert.Equal(t, tt.retentionDays, media.RetentionDays, "RetentionDays should match")
+			assert.Equal(t, tt.retention, media.Retention, "Retention should match")
+			assert.Equal(t, tt.retentionDays, media.RetentionDays, "RetentionDays should round up to whole days")
 
-			expectedExpiry := media.CreatedAt.AddDate(0, 0, tt.retentionDays)
-			assert.WithinDuration(t, expectedExpiry, media.ExpiresAt, time.Second, "ExpiresAt should be CreatedAt + retention days")
+			expectedExpiry := media.CreatedAt.Add(tt.retention)
+			assert.WithinDuration(t, expectedExpiry, media.ExpiresAt, time.Second, "ExpiresAt should be CreatedAt + retention")
 		})
 	}
 }
@@ -78,6 +83,11 @@ func TestMedia_IsExpired(t *testing.T) {
 			expiresAt: time.Now().Add(time.Millisecond),
 			want:      false,
 		},
+		{
+			name:      "never expires",
+			expiresAt: NeverExpiresAt,
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,7 +102,7 @@ func TestMedia_IsExpired(t *testing.T) {
 }
 
 func TestMedia_MarkAsDone(t *testing.T) {
-	media := NewMedia(MediaTypeVideo, "test.mp4", "/uploads/test.mp4", 7)
+	media := NewMedia(MediaTypeVideo, "test.mp4", "/uploads/test.mp4", 7*Day)
 
 	convertedPath := "/converted/test.mp4"
 	codec := CodecH264
@@ -112,6 +122,32 @@ func TestMedia_MarkAsDone(t *testing.T) {
 	assert.Equal(t, fileSize, media.FileSize, "FileSize should match")
 }
 
+func TestParseCodecs(t *testing.T) {
+	codecs, err := ParseCodecs(" AV1, h264,,av1 ")
+	assert.NoError(t, err)
+	assert.Equal(t, []Codec{CodecAV1, CodecH264}, codecs)
+
+	codecs, err = ParseCodecs("")
+	assert.NoError(t, err)
+	assert.Empty(t, codecs)
+
+	_, err = ParseCodecs("h264,vp9")
+	assert.ErrorContains(t, err, "vp9")
+}
+
+func TestMedia_H264Variant(t *testing.T) {
+	media := &Media{Variants: []Variant{
+		{Codec: CodecAV1, Status: VariantStatusDone},
+		{Codec: CodecH264, Status: VariantStatusProcessing},
+	}}
+	assert.Nil(t, media.H264Variant(), "an unfinished H264 variant is not served")
+
+	media.Variants[1].Status = VariantStatusDone
+	if assert.NotNil(t, media.H264Variant()) {
+		assert.Equal(t, CodecH264, media.H264Variant().Codec)
+	}
+}
+
 func TestMedia_BestVariantForAccept(t *testing.T) {
 	av1Done := Variant{Codec: CodecAV1, Status: VariantStatusDone, Path: "/v/av1.webm"}
 	h264Done := Variant{Codec: CodecH264, Status: VariantStatusDone, Path: "/v/h264.mp4"}
@@ -201,7 +237,7 @@ func TestMedia_BestVariantForAccept(t *testing.T) {
 }
 
 func TestMedia_MarkAsFailed(t *testing.T) {
-	media := NewMedia(MediaTypeVideo, "test.mp4", "/uploads/test.mp4", 7)
+	media := NewMedia(MediaTypeVideo, "test.mp4", "/uploads/test.mp4", 7*Day)
 
 	errMsg := "conversion failed: unsupported format"
 	err := errors.New(errMsg)
@@ -211,3 +247,118 @@ func TestMedia_MarkAsFailed(t *testing.T) {
 	assert.Equal(t, MediaStatusFailed, media.Status, "Status should be failed")
 	assert.Equal(t, errMsg, media.ErrorMessage, "ErrorMessage should match")
 }
+
+func TestMediaType_SupportsCodec(t *testing.T) {
+	assert.True(t, MediaTypeVideo.SupportsCodec(CodecAV1))
+	assert.True(t, MediaTypeVideo.SupportsCodec(CodecH264))
+	assert.False(t, MediaTypeVideo.SupportsCodec(CodecOpus))
+	assert.True(t, MediaTypeAudio.SupportsCodec(CodecOpus))
+	assert.False(t, MediaTypeAudio.SupportsCodec(CodecH264))
+	assert.False(t, MediaTypeImage.SupportsCodec(CodecAV1))
+}
+
+func TestMediaTypeFromMIME(t *testing.T) {
+	assert.Equal(t, MediaTypeImage, MediaTypeFromMIME(MediaTypeVideo, "image/png"))
+	assert.Equal(t, MediaTypeVideo, MediaTypeFromMIME(MediaTypeImage, "video/mp4"))
+	assert.Equal(t, MediaTypeAudio, MediaTypeFromMIME(MediaTypeImage, "audio/mpeg"))
+	assert.Equal(t, MediaTypeAudio, MediaTypeFromMIME(MediaTypeImage, "application/ogg"))
+	// An MP4 container may hold audio only, so it does not override audio.
+	assert.Equal(t, MediaTypeAudio, MediaTypeFromMIME(MediaTypeAudio, "video/mp4"))
+	assert.Equal(t, MediaTypeVideo, MediaTypeFromMIME(MediaTypeVideo, "application/octet-stream"))
+}
+
+func TestProbeResult_StreamMediaType(t *testing.T) {
+	video := ProbeStream{CodecType: "video", CodecName: "h264"}
+	audio := ProbeStream{CodecType: "audio", CodecName: "mp3"}
+	cover := ProbeStream{CodecType: "video", CodecName: "mjpeg", Disposition: map[string]int{"attached_pic": 1}}
+
+	tests := []struct {
+		name   string
+		probe  *ProbeResult
+		want   MediaType
+		wantOK bool
+	}{
+		{"video with audio", &ProbeResult{Streams: []ProbeStream{video, audio}}, MediaTypeVideo, true},
+		{"audio only", &ProbeResult{Streams: []ProbeStream{audio}}, MediaTypeAudio, true},
+		{"audio with cover art", &ProbeResult{Streams: []ProbeStream{audio, cover}}, MediaTypeAudio, true},
+		{"no streams", &ProbeResult{}, "", false},
+		{"no probe", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.probe.StreamMediaType()
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestProbeResult_IsDecodable(t *testing.T) {
+	video := ProbeStream{CodecType: "video", CodecName: "h264"}
+	timedAudio := ProbeStream{CodecType: "audio", CodecName: "opus", Duration: "3.5"}
+	cover := ProbeStream{CodecType: "video", CodecName: "mjpeg", Disposition: map[string]int{"attached_pic": 1}, Duration: "1.0"}
+
+	tests := []struct {
+		name  string
+		probe *ProbeResult
+		want  bool
+	}{
+		{"format duration", &ProbeResult{Format: ProbeFormat{Duration: "10.0"}, Streams: []ProbeStream{video}}, true},
+		{"stream duration only", &ProbeResult{Format: ProbeFormat{Duration: "N/A"}, Streams: []ProbeStream{timedAudio}}, true},
+		{"zero duration", &ProbeResult{Format: ProbeFormat{Duration: "0.000000"}, Streams: []ProbeStream{video}}, false},
+		{"unknown duration", &ProbeResult{Format: ProbeFormat{Duration: "N/A"}, Streams: []ProbeStream{video}}, false},
+		{"cover art only", &ProbeResult{Format: ProbeFormat{Duration: "1.0"}, Streams: []ProbeStream{cover}}, false},
+		{"no streams", &ProbeResult{Format: ProbeFormat{Duration: "10.0"}}, false},
+		{"no probe", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.probe.IsDecodable())
+		})
+	}
+}
+
+func TestProbeResult_IsWebOptimized(t *testing.T) {
+	mp4 := ProbeFormat{FormatName: "mov,mp4,m4a,3gp,3g2,mj2"}
+	h264 := ProbeStream{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p"}
+	aac := ProbeStream{CodecType: "audio", CodecName: "aac"}
+
+	tests := []struct {
+		name  string
+		probe ProbeResult
+		want  bool
+	}{
+		{"h264 aac mp4", ProbeResult{Format: mp4, Streams: []ProbeStream{h264, aac}}, true},
+		{"silent h264 mp4", ProbeResult{Format: mp4, Streams: []ProbeStream{h264}}, true},
+		{"matroska", ProbeResult{Format: ProbeFormat{FormatName: "matroska,webm"}, Streams: []ProbeStream{h264, aac}}, false},
+		{"hevc", ProbeResult{Format: mp4, Streams: []ProbeStream{{CodecType: "video", CodecName: "hevc", PixFmt: "yuv420p"}, aac}}, false},
+		{"10-bit h264", ProbeResult{Format: mp4, Streams: []ProbeStream{{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p10le"}}}, false},
+		{"opus audio", ProbeResult{Format: mp4, Streams: []ProbeStream{h264, {CodecType: "audio", CodecName: "opus"}}}, false},
+		{"audio only", ProbeResult{Format: mp4, Streams: []ProbeStream{aac}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.probe.IsWebOptimized())
+		})
+	}
+}
+
+func TestProbeResult_IsAnimatedGIF(t *testing.T) {
+	gif := ProbeFormat{FormatName: "gif"}
+
+	assert.True(t, (&ProbeResult{Format: gif, Streams: []ProbeStream{{CodecType: "video", CodecName: "gif", NbFrames: "24"}}}).IsAnimatedGIF())
+	assert.False(t, (&ProbeResult{Format: gif, Streams: []ProbeStream{{CodecType: "video", CodecName: "gif", NbFrames: "1"}}}).IsAnimatedGIF())
+	assert.False(t, (&ProbeResult{Format: ProbeFormat{FormatName: "webp_pipe"}, Streams: []ProbeStream{{CodecType: "video", NbFrames: "24"}}}).IsAnimatedGIF())
+	assert.False(t, (*ProbeResult)(nil).IsAnimatedGIF())
+}
+
+func TestMedia_IsAnimation(t *testing.T) {
+	converted := &Media{Type: MediaTypeVideo, ProbeJSON: `{"format":{"format_name":"gif"}}`}
+	assert.True(t, converted.IsAnimation())
+
+	video := &Media{Type: MediaTypeVideo, ProbeJSON: `{"format":{"format_name":"mov,mp4,m4a,3gp,3g2,mj2"}}`}
+	assert.False(t, video.IsAnimation())
+
+	still := &Media{Type: MediaTypeImage, ProbeJSON: `{"format":{"format_name":"gif"}}`}
+	assert.False(t, still.IsAnimation())
+}
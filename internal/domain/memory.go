@@ -0,0 +1,34 @@
+package domain
+
+// Rough peak ffmpeg memory per conversion: a fixed overhead plus a cost per
+// megapixel of frame area. SVT-AV1 keeps many more reference frames and
+// lookahead buffers than x264, so it dominates at high resolutions.
+const (
+	av1BaseMemoryMB          = 256
+	av1MemoryPerMegapixelMB  = 400
+	h264BaseMemoryMB         = 64
+	h264MemoryPerMegapixelMB = 64
+	audioMemoryMB            = 64
+
+	// Unknown dimensions are costed as 1080p.
+	defaultEstimateWidth  = 1920
+	defaultEstimateHeight = 1080
+)
+
+// EstimateConvertMemoryMB estimates the peak memory in MB that encoding a
+// width x height source to codec needs.
+func EstimateConvertMemoryMB(codec Codec, width, height int) int64 {
+	if width <= 0 || height <= 0 {
+		width, height = defaultEstimateWidth, defaultEstimateHeight
+	}
+	megapixels := float64(width) * float64(height) / 1e6
+
+	switch codec {
+	case CodecAV1:
+		return av1BaseMemoryMB + int64(megapixels*av1MemoryPerMegapixelMB)
+	case CodecH264:
+		return h264BaseMemoryMB + int64(megapixels*h264MemoryPerMegapixelMB)
+	default:
+		return audioMemoryMB
+	}
+}
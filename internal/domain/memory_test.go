@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateConvertMemoryMB(t *testing.T) {
+	fullHD := EstimateConvertMemoryMB(CodecAV1, 1920, 1080)
+	uhd := EstimateConvertMemoryMB(CodecAV1, 3840, 2160)
+
+	assert.Greater(t, uhd, 3*fullHD, "4K AV1 should cost several times 1080p")
+	assert.Less(t, EstimateConvertMemoryMB(CodecH264, 3840, 2160), uhd)
+	assert.Equal(t, fullHD, EstimateConvertMemoryMB(CodecAV1, 0, 0), "unknown size is costed as 1080p")
+	assert.Equal(t, int64(audioMemoryMB), EstimateConvertMemoryMB(CodecOpus, 0, 0))
+}
@@ -1,11 +1,22 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
+	"strings"
 )
 
+// ErrUndecodable is returned for audio or video uploads that ffprobe cannot
+// find a playable stream or a duration in.
+var ErrUndecodable = errors.New("no decodable audio or video")
+
+// ErrTooManyPixels is returned for uploads whose frames are larger than the
+// configured pixel budget, such as decompression bombs claiming a huge size.
+var ErrTooManyPixels = errors.New("resolution too large")
+
 type ProbeFormat struct {
 	FormatName string            `json:"format_name"`
 	FormatLong string            `json:"format_long_name"`
@@ -25,18 +36,27 @@ type ProbeStream struct {
 	Height        int               `json:"height"`
 	PixFmt        string            `json:"pix_fmt"`
 	ColorSpace    string            `json:"color_space"`
+	ColorTransfer string            `json:"color_transfer"`
 	ColorRange    string            `json:"color_range"`
 	RFrameRate    string            `json:"r_frame_rate"`
 	AvgFrameRate  string            `json:"avg_frame_rate"`
+	NbFrames      string            `json:"nb_frames"`
 	Duration      string            `json:"duration"`
 	BitRate       string            `json:"bit_rate"`
 	SampleRate    string            `json:"sample_rate"`
 	Channels      int               `json:"channels"`
 	ChannelLayout string            `json:"channel_layout"`
 	BitsPerSample int               `json:"bits_per_sample"`
+	Disposition   map[string]int    `json:"disposition"`
 	Tags          map[string]string `json:"tags"`
 }
 
+// IsAttachedPicture reports whether the stream is embedded cover art, which
+// ffprobe lists as a video stream of an audio file.
+func (s *ProbeStream) IsAttachedPicture() bool {
+	return s.Disposition["attached_pic"] == 1
+}
+
 type ProbeResult struct {
 	Format  ProbeFormat   `json:"format"`
 	Streams []ProbeStream `json:"streams"`
@@ -61,6 +81,118 @@ func (p *ProbeResult) AudioStream() *ProbeStream {
 	return nil
 }
 
+// IsAnimatedGIF reports whether the probed file is a GIF with more than one
+// frame.
+func (p *ProbeResult) IsAnimatedGIF() bool {
+	if p == nil || p.Format.FormatName != "gif" {
+		return false
+	}
+	vs := p.VideoStream()
+	return vs != nil && vs.FrameCount(p.Format.Duration) > 1
+}
+
+// StreamMediaType reports whether the file's content is video or audio,
+// ignoring cover art. ok is false when there is no audio or video stream.
+func (p *ProbeResult) StreamMediaType() (t MediaType, ok bool) {
+	if p == nil {
+		return "", false
+	}
+	hasAudio := false
+	for i := range p.Streams {
+		switch {
+		case p.Streams[i].CodecType == "video" && !p.Streams[i].IsAttachedPicture():
+			return MediaTypeVideo, true
+		case p.Streams[i].CodecType == "audio":
+			hasAudio = true
+		}
+	}
+	if hasAudio {
+		return MediaTypeAudio, true
+	}
+	return "", false
+}
+
+// IsDecodable reports whether the file has an audio or video stream, cover
+// art aside, and a non-zero duration in its format or any of its streams.
+// Files failing this usually probe fine but produce broken conversions.
+func (p *ProbeResult) IsDecodable() bool {
+	if _, ok := p.StreamMediaType(); !ok {
+		return false
+	}
+	if ParseDuration(p.Format.Duration) > 0 {
+		return true
+	}
+	return slices.ContainsFunc(p.Streams, func(s ProbeStream) bool {
+		return ParseDuration(s.Duration) > 0
+	})
+}
+
+// IsWebOptimized reports whether the file is an MP4 that browsers can play
+// without conversion: 8-bit 4:2:0 H264 video and AAC audio, if any. Faststart
+// cannot be probed, so it is not checked.
+func (p *ProbeResult) IsWebOptimized() bool {
+	if !slices.Contains(strings.Split(p.Format.FormatName, ","), "mp4") {
+		return false
+	}
+	vs := p.VideoStream()
+	if vs == nil || vs.CodecName != "h264" || vs.PixFmt != "yuv420p" {
+		return false
+	}
+	if as := p.AudioStream(); as != nil && as.CodecName != "aac" {
+		return false
+	}
+	return true
+}
+
+// webAudioCodecs lists, by ffprobe format name, the containers browsers play
+// audio from and the codecs they play in each.
+var webAudioCodecs = map[string][]string{
+	"mp3": {"mp3"},
+	"aac": {"aac"},
+	"mp4": {"aac", "mp3"},
+	"ogg": {"opus", "vorbis"},
+}
+
+// IsWebPlayableAudio reports whether the file is audio, cover art aside,
+// that browsers play as uploaded: MP3, AAC, or Opus and Vorbis in Ogg.
+func (p *ProbeResult) IsWebPlayableAudio() bool {
+	if t, ok := p.StreamMediaType(); !ok || t != MediaTypeAudio {
+		return false
+	}
+	codec := p.AudioStream().CodecName
+	for _, format := range strings.Split(p.Format.FormatName, ",") {
+		if slices.Contains(webAudioCodecs[format], codec) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLossless reports whether the stream is audio in a lossless codec, such
+// as FLAC, ALAC or the PCM of WAV files.
+func (s *ProbeStream) IsLossless() bool {
+	if s.CodecType != "audio" {
+		return false
+	}
+	switch s.CodecName {
+	case "flac", "alac", "wavpack", "ape", "tta", "truehd", "mlp":
+		return true
+	}
+	return strings.HasPrefix(s.CodecName, "pcm_")
+}
+
+// IsHDR reports whether the stream uses an HDR transfer function (PQ or HLG),
+// or BT.2020 colour with a high bit depth pixel format when the transfer is
+// not tagged.
+func (s *ProbeStream) IsHDR() bool {
+	switch s.ColorTransfer {
+	case "smpte2084", "arib-std-b67":
+		return true
+	}
+	return strings.HasPrefix(s.ColorSpace, "bt2020") &&
+		(strings.Contains(s.PixFmt, "10") || strings.Contains(s.PixFmt, "12"))
+}
+
 func (p *ProbeResult) Dimensions() (width int, height int) {
 	vs := p.VideoStream()
 	if vs != nil {
@@ -69,6 +201,20 @@ func (p *ProbeResult) Dimensions() (width int, height int) {
 	return 0, 0
 }
 
+// CheckPixels returns ErrTooManyPixels, with the offending resolution, when
+// the video stream's frames hold more than maxPixels pixels. A zero budget
+// is not enforced.
+func (p *ProbeResult) CheckPixels(maxPixels int64) error {
+	if p == nil || maxPixels <= 0 {
+		return nil
+	}
+	width, height := p.Dimensions()
+	if int64(width)*int64(height) > maxPixels {
+		return fmt.Errorf("%w: %dx%d exceeds the limit of %d pixels", ErrTooManyPixels, width, height, maxPixels)
+	}
+	return nil
+}
+
 func ParseFrameRate(fraction string) float64 {
 	if fraction == "" || fraction == "0/0" {
 		return 0
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionNever is the retention of media that never expire.
+const RetentionNever time.Duration = 0
+
+// Day is the unit of day-based retention.
+const Day = 24 * time.Hour
+
+// NeverExpiresAt is the expiry stored for media that never expire. A date
+// rather than a NULL keeps them last when sorting by expiry.
+var NeverExpiresAt = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// DefaultRetentionPresets are the retentions offered on the upload form.
+var DefaultRetentionPresets = []time.Duration{time.Hour, Day, 7 * Day, 30 * Day, 90 * Day, 365 * Day, RetentionNever}
+
+// RetentionPolicy bounds the retention an upload may ask for. Max 0 means
+// no upper bound, which also allows media that never expire. Presets are
+// the choices offered on the upload form.
+type RetentionPolicy struct {
+	Default time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	Presets []time.Duration
+}
+
+// AllowsNever reports whether uploads may opt out of expiry.
+func (p RetentionPolicy) AllowsNever() bool {
+	return p.Max == 0
+}
+
+// Clamp fits a requested retention into the policy's bounds. Zero or a
+// negative value asks for no expiry, which falls back to the longest allowed
+// retention when the policy has a maximum.
+func (p RetentionPolicy) Clamp(requested time.Duration) time.Duration {
+	if requested <= 0 {
+		if p.AllowsNever() {
+			return RetentionNever
+		}
+		return p.Max
+	}
+	if requested < p.Min {
+		return p.Min
+	}
+	if p.Max > 0 && requested > p.Max {
+		return p.Max
+	}
+	return requested
+}
+
+// ParseRetention parses a retention: "never", a bare number of days as
+// retention used to be given, a number of days or weeks such as "3d" or
+// "2w", or a Go duration such as "12h". A bare number of zero or less also
+// means never, as it did when retention was counted in days.
+func ParseRetention(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "never" {
+		return RetentionNever, nil
+	}
+
+	if days, err := strconv.Atoi(s); err == nil {
+		if days <= 0 {
+			return RetentionNever, nil
+		}
+		if days > math.MaxInt64/int(Day) {
+			return 0, fmt.Errorf("retention too long: %q", s)
+		}
+		return time.Duration(days) * Day, nil
+	}
+
+	unit := Day
+	number := s
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		unit, number = 7*Day, n
+	} else if n, ok := strings.CutSuffix(s, "d"); ok {
+		number = n
+	}
+	if count, err := strconv.Atoi(number); err == nil {
+		if count < 0 {
+			return 0, fmt.Errorf("retention must not be negative: %q", s)
+		}
+		if count > math.MaxInt64/int(unit) {
+			return 0, fmt.Errorf("retention too long: %q", s)
+		}
+		return time.Duration(count) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("retention must not be negative: %q", s)
+	}
+	return d, nil
+}
+
+// FormatRetention writes a retention in the form ParseRetention reads back,
+// in days when it is a whole number of them.
+func FormatRetention(d time.Duration) string {
+	switch {
+	case d == RetentionNever:
+		return "never"
+	case d%Day == 0:
+		return fmt.Sprintf("%dd", d/Day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return d.String()
+	}
+}
+
+// RetentionLabel names a retention for people, in the largest calendar unit
+// it is a whole number of: "1 hour", "3 days", "2 weeks", "1 month".
+func RetentionLabel(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * Day, "year"},
+		{30 * Day, "month"},
+		{7 * Day, "week"},
+		{Day, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	if d == RetentionNever {
+		return "Never"
+	}
+	for _, u := range units {
+		if d >= u.size && d%u.size == 0 {
+			return plural(int(d/u.size), u.name)
+		}
+	}
+	return d.String()
+}
+
+// FormatRemaining describes the time left before an expiry, rounded up to
+// whole days, hours or minutes, whichever is the largest unit it spans.
+func FormatRemaining(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return plural(0, "minute")
+	case d >= Day:
+		return plural(int(math.Ceil(float64(d)/float64(Day))), "day")
+	case d >= time.Hour:
+		return plural(int(math.Ceil(float64(d)/float64(time.Hour))), "hour")
+	default:
+		return plural(int(math.Ceil(float64(d)/float64(time.Minute))), "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy_Clamp(t *testing.T) {
+	bounded := RetentionPolicy{Default: 7 * Day, Min: time.Hour, Max: 30 * Day}
+	assert.Equal(t, 7*Day, bounded.Clamp(7*Day))
+	assert.Equal(t, 30*Day, bounded.Clamp(10000*Day))
+	assert.Equal(t, 30*Day, bounded.Clamp(0), "no expiry falls back to the maximum")
+	assert.Equal(t, 30*Day, bounded.Clamp(-1))
+	assert.False(t, bounded.AllowsNever())
+
+	atLeastThree := RetentionPolicy{Default: 7 * Day, Min: 3 * Day, Max: 30 * Day}
+	assert.Equal(t, 3*Day, atLeastThree.Clamp(time.Hour))
+
+	unbounded := RetentionPolicy{Default: 7 * Day, Min: time.Hour}
+	assert.Equal(t, 10000*Day, unbounded.Clamp(10000*Day))
+	assert.Equal(t, RetentionNever, unbounded.Clamp(0))
+	assert.Equal(t, RetentionNever, unbounded.Clamp(-1))
+	assert.True(t, unbounded.AllowsNever())
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"7", 7 * Day},
+		{"0", RetentionNever},
+		{"-1", RetentionNever},
+		{"never", RetentionNever},
+		{" Never ", RetentionNever},
+		{"1h", time.Hour},
+		{"90m", 90 * time.Minute},
+		{"3d", 3 * Day},
+		{"2w", 14 * Day},
+		{"106751", 106751 * Day},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"", "forever", "-2d", "-1h", "1y", "213504", "106752", "106752d", "15251w"} {
+		_, err := ParseRetention(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFormatRetention_RoundTrips(t *testing.T) {
+	for _, d := range append(DefaultRetentionPresets, 36*time.Hour, 90*time.Minute) {
+		got, err := ParseRetention(FormatRetention(d))
+		assert.NoError(t, err)
+		assert.Equal(t, d, got)
+	}
+	assert.Equal(t, "1h", FormatRetention(time.Hour))
+	assert.Equal(t, "7d", FormatRetention(7*Day))
+	assert.Equal(t, "never", FormatRetention(RetentionNever))
+}
+
+func TestRetentionLabel(t *testing.T) {
+	assert.Equal(t, "1 hour", RetentionLabel(time.Hour))
+	assert.Equal(t, "1 day", RetentionLabel(Day))
+	assert.Equal(t, "1 week", RetentionLabel(7*Day))
+	assert.Equal(t, "3 days", RetentionLabel(3*Day))
+	assert.Equal(t, "1 month", RetentionLabel(30*Day))
+	assert.Equal(t, "3 months", RetentionLabel(90*Day))
+	assert.Equal(t, "1 year", RetentionLabel(365*Day))
+	assert.Equal(t, "36 hours", RetentionLabel(36*time.Hour))
+	assert.Equal(t, "Never", RetentionLabel(RetentionNever))
+}
+
+func TestFormatRemaining(t *testing.T) {
+	assert.Equal(t, "3 days", FormatRemaining(2*Day+time.Hour))
+	assert.Equal(t, "1 day", FormatRemaining(Day))
+	assert.Equal(t, "5 hours", FormatRemaining(4*time.Hour+time.Second))
+	assert.Equal(t, "1 minute", FormatRemaining(time.Second))
+	assert.Equal(t, "0 minutes", FormatRemaining(0))
+}
+
+func TestNewMedia_NeverExpires(t *testing.T) {
+	media := NewMedia(MediaTypeImage, "a.png", "/tmp/a.png", RetentionNever)
+
+	assert.True(t, media.NeverExpires())
+	assert.False(t, media.IsExpired())
+	assert.Equal(t, NeverExpiresAt, media.ExpiresAt)
+	assert.False(t, NewMedia(MediaTypeImage, "a.png", "/tmp/a.png", 7*Day).NeverExpires())
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	SlugMinLength = 3
+	SlugMaxLength = 64
+)
+
+var (
+	ErrInvalidSlug = errors.New("invalid slug")
+	ErrSlugTaken   = errors.New("slug already taken")
+)
+
+// NormalizeSlug lowercases a custom share slug and checks it is 3 to 64
+// letters, digits and dashes, not starting or ending with a dash.
+func NormalizeSlug(s string) (string, error) {
+	slug := strings.ToLower(strings.TrimSpace(s))
+	if len(slug) < SlugMinLength || len(slug) > SlugMaxLength {
+		return "", fmt.Errorf("%w: must be %d to %d characters", ErrInvalidSlug, SlugMinLength, SlugMaxLength)
+	}
+	if slug[0] == '-' || slug[len(slug)-1] == '-' {
+		return "", fmt.Errorf("%w: must not start or end with a dash", ErrInvalidSlug)
+	}
+	for _, c := range slug {
+		if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-') {
+			return "", fmt.Errorf("%w: only letters, digits and dashes are allowed", ErrInvalidSlug)
+		}
+	}
+	return slug, nil
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSlug(t *testing.T) {
+	slug, err := NormalizeSlug("  My-Demo-2 ")
+	require.NoError(t, err)
+	assert.Equal(t, "my-demo-2", slug)
+
+	for _, bad := range []string{"", "ab", strings.Repeat("a", 65), "-demo", "demo-", "my demo", "my_demo", "../etc", "démo"} {
+		_, err := NormalizeSlug(bad)
+		assert.ErrorIs(t, err, ErrInvalidSlug, "slug %q", bad)
+	}
+}
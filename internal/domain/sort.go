@@ -0,0 +1,25 @@
+package domain
+
+// SortBy selects the ordering of media listings. Only these values reach the
+// store, which maps each one to a fixed query.
+type SortBy string
+
+const (
+	SortNewest   SortBy = "newest"
+	SortOldest   SortBy = "oldest"
+	SortLargest  SortBy = "largest"
+	SortExpiring SortBy = "expiring"
+)
+
+// SortOptions lists the supported orderings in display order.
+var SortOptions = []SortBy{SortNewest, SortOldest, SortLargest, SortExpiring}
+
+// ParseSortBy returns the matching SortBy, defaulting to newest-first.
+func ParseSortBy(value string) SortBy {
+	for _, s := range SortOptions {
+		if string(s) == value {
+			return s
+		}
+	}
+	return SortNewest
+}
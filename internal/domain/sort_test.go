@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSortBy(t *testing.T) {
+	assert.Equal(t, SortNewest, ParseSortBy(""))
+	assert.Equal(t, SortNewest, ParseSortBy("created_at; DROP TABLE media"))
+	assert.Equal(t, SortOldest, ParseSortBy("oldest"))
+	assert.Equal(t, SortLargest, ParseSortBy("largest"))
+	assert.Equal(t, SortExpiring, ParseSortBy("expiring"))
+}
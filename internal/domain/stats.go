@@ -0,0 +1,15 @@
+package domain
+
+// StorageStats summarizes how much disk the library consumes.
+type StorageStats struct {
+	TotalCount   int                 `json:"total_count"`
+	ExpiredCount int                 `json:"expired_count"`
+	ByStatus     map[MediaStatus]int `json:"by_status"`
+	MediaBytes   int64               `json:"media_bytes"`
+	VariantBytes int64               `json:"variant_bytes"`
+}
+
+// TotalBytes returns the combined size of media and their variants.
+func (s StorageStats) TotalBytes() int64 {
+	return s.MediaBytes + s.VariantBytes
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThumbnailSeek is where in a video its thumbnail frame is captured: a fixed
+// Offset, or Percent of the duration when Percent is set.
+type ThumbnailSeek struct {
+	Offset  time.Duration
+	Percent float64
+}
+
+// DefaultThumbnailSeek skips the first second, which is often black.
+var DefaultThumbnailSeek = ThumbnailSeek{Offset: time.Second}
+
+// ParseThumbnailSeek parses a Go duration such as "1s" or a percentage of
+// the duration such as "10%".
+func ParseThumbnailSeek(s string) (ThumbnailSeek, error) {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil || p < 0 || p >= 100 {
+			return ThumbnailSeek{}, fmt.Errorf("percentage must be between 0 and 100: %q", s)
+		}
+		return ThumbnailSeek{Percent: p}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return ThumbnailSeek{}, err
+	}
+	if d < 0 {
+		return ThumbnailSeek{}, fmt.Errorf("offset must not be negative: %q", s)
+	}
+	return ThumbnailSeek{Offset: d}, nil
+}
+
+// At returns the capture offset for a video lasting duration seconds, or 0
+// for the first frame when the offset would fall past the end. A percentage
+// of an unknown (zero) duration also yields the first frame.
+func (t ThumbnailSeek) At(duration float64) time.Duration {
+	total := time.Duration(duration * float64(time.Second))
+	offset := t.Offset
+	if t.Percent > 0 {
+		offset = time.Duration(float64(total) * t.Percent / 100)
+	}
+	if total > 0 && offset >= total {
+		return 0
+	}
+	return offset
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThumbnailSeek(t *testing.T) {
+	seek, err := ParseThumbnailSeek("3s")
+	require.NoError(t, err)
+	assert.Equal(t, ThumbnailSeek{Offset: 3 * time.Second}, seek)
+
+	seek, err = ParseThumbnailSeek("10%")
+	require.NoError(t, err)
+	assert.Equal(t, ThumbnailSeek{Percent: 10}, seek)
+
+	for _, invalid := range []string{"", "soon", "-1s", "150%", "x%"} {
+		_, err := ParseThumbnailSeek(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestThumbnailSeek_At(t *testing.T) {
+	tests := []struct {
+		name     string
+		seek     ThumbnailSeek
+		duration float64
+		want     time.Duration
+	}{
+		{"fixed offset", ThumbnailSeek{Offset: time.Second}, 60, time.Second},
+		{"percentage", ThumbnailSeek{Percent: 10}, 60, 6 * time.Second},
+		{"short clip falls back to first frame", ThumbnailSeek{Offset: time.Second}, 0.5, 0},
+		{"unknown duration keeps offset", ThumbnailSeek{Offset: time.Second}, 0, time.Second},
+		{"percentage of unknown duration", ThumbnailSeek{Percent: 10}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.seek.At(tt.duration))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+// TranscodePolicy decides whether uploads that browsers can already play are
+// re-encoded or served as-is.
+type TranscodePolicy string
+
+const (
+	// TranscodePolicyAlways re-encodes every upload for uniform output.
+	TranscodePolicyAlways TranscodePolicy = "always"
+	// TranscodePolicyPassthrough serves web-optimized H264 uploads without
+	// re-encoding them.
+	TranscodePolicyPassthrough TranscodePolicy = "passthrough"
+)
+
+// ParseTranscodePolicy returns the matching policy and whether value named one.
+func ParseTranscodePolicy(value string) (TranscodePolicy, bool) {
+	switch p := TranscodePolicy(value); p {
+	case TranscodePolicyAlways, TranscodePolicyPassthrough:
+		return p, true
+	}
+	return "", false
+}
+
+// Passthrough reports whether the upload described by probe can stand in for
+// an encode to codec. Only H264 video without a frame rate change qualifies.
+func (p TranscodePolicy) Passthrough(mediaType MediaType, codec Codec, fps int, probe *ProbeResult) bool {
+	return p == TranscodePolicyPassthrough &&
+		mediaType == MediaTypeVideo &&
+		codec == CodecH264 &&
+		fps == 0 &&
+		probe != nil && probe.IsWebOptimized()
+}
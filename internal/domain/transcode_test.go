@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTranscodePolicy(t *testing.T) {
+	p, ok := ParseTranscodePolicy("passthrough")
+	assert.True(t, ok)
+	assert.Equal(t, TranscodePolicyPassthrough, p)
+
+	_, ok = ParseTranscodePolicy("sometimes")
+	assert.False(t, ok)
+}
+
+func TestTranscodePolicy_Passthrough(t *testing.T) {
+	webOptimized := &ProbeResult{
+		Format: ProbeFormat{FormatName: "mov,mp4,m4a,3gp,3g2,mj2"},
+		Streams: []ProbeStream{
+			{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		policy    TranscodePolicy
+		mediaType MediaType
+		codec     Codec
+		fps       int
+		probe     *ProbeResult
+		want      bool
+	}{
+		{"passthrough h264", TranscodePolicyPassthrough, MediaTypeVideo, CodecH264, 0, webOptimized, true},
+		{"always re-encodes", TranscodePolicyAlways, MediaTypeVideo, CodecH264, 0, webOptimized, false},
+		{"other codec", TranscodePolicyPassthrough, MediaTypeVideo, CodecAV1, 0, webOptimized, false},
+		{"frame rate change", TranscodePolicyPassthrough, MediaTypeVideo, CodecH264, 30, webOptimized, false},
+		{"not probed", TranscodePolicyPassthrough, MediaTypeVideo, CodecH264, 0, nil, false},
+		{"not web-optimized", TranscodePolicyPassthrough, MediaTypeVideo, CodecH264, 0, &ProbeResult{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Passthrough(tt.mediaType, tt.codec, tt.fps, tt.probe))
+		})
+	}
+}
@@ -4,6 +4,39 @@ type User struct {
 	ID           int64
 	Username     string
 	PasswordHash string
+	IsAdmin      bool
+	RecoveryHash string
+	TOTPSecret   string
+	TOTPEnabled  bool
+	OIDCSubject  string
 	CreatedAt    string
 	UpdatedAt    string
 }
+
+// OIDCIdentity is the verified identity returned by an OpenID Connect
+// provider. Subject is stable per provider; Username and Email are hints for
+// naming a new local account.
+type OIDCIdentity struct {
+	Subject  string
+	Username string
+	Email    string
+}
+
+// TOTPStatus summarises a user's two-factor setup. PendingURI is the
+// provisioning URI of an enrollment that has not been confirmed yet.
+type TOTPStatus struct {
+	Enabled     bool
+	PendingURI  string
+	BackupCodes int
+}
+
+// APIKey is a long-lived credential for programmatic access. Only a hash of
+// the secret is stored; the raw key is shown once when it is created.
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	Label      string
+	KeyHash    string
+	CreatedAt  string
+	LastUsedAt string
+}
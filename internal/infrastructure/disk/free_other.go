@@ -0,0 +1,10 @@
+//go:build !unix
+
+package disk
+
+import "errors"
+
+// FreeBytes is not implemented on this platform.
+func FreeBytes(string) (uint64, error) {
+	return 0, errors.New("free space check not supported on this platform")
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package disk
+
+import "syscall"
+
+// FreeBytes returns the space available to unprivileged users on the
+// filesystem containing path.
+func FreeBytes(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil //nolint:gosec,unconvert // field types vary by platform
+}
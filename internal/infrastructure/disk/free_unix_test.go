@@ -0,0 +1,20 @@
+//go:build unix
+
+package disk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeBytes(t *testing.T) {
+	free, err := FreeBytes(t.TempDir())
+	assert.NoError(t, err)
+	assert.Positive(t, free)
+}
+
+func TestFreeBytes_MissingPath(t *testing.T) {
+	_, err := FreeBytes("/does/not/exist")
+	assert.Error(t, err)
+}
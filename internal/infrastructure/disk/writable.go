@@ -0,0 +1,17 @@
+package disk
+
+import "os"
+
+// CheckWritable reports an error unless a file can be created in dir.
+func CheckWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
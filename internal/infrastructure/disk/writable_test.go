@@ -0,0 +1,21 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, CheckWritable(dir))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "probe file should be removed")
+
+	assert.Error(t, CheckWritable(filepath.Join(dir, "missing")))
+}
@@ -1,7 +1,10 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 )
 
@@ -12,11 +15,89 @@ var (
 	Warn  *log.Logger
 )
 
+// Log formats accepted by SetFormat.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	format   = FormatText
+	minLevel = slog.LevelDebug
+)
+
 func init() {
+	useText(os.Stdout)
+}
+
+// SetFormat switches every logger to format. It replaces the loggers, so
+// call it at startup before logging from other goroutines.
+func SetFormat(name string) error {
+	switch name {
+	case FormatText, FormatJSON:
+		format = name
+	default:
+		return fmt.Errorf("unknown log format %q", name)
+	}
+	apply(os.Stdout)
+	return nil
+}
+
+// SetLevel drops lines below level: debug, info, warn or error. Like
+// SetFormat it replaces the loggers.
+func SetLevel(name string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	minLevel = level
+	apply(os.Stdout)
+	return nil
+}
+
+func apply(w io.Writer) {
+	if format == FormatJSON {
+		useJSON(w)
+	} else {
+		useText(w)
+	}
+}
+
+// gate returns w for loggers at level or above the minimum, and a writer
+// that drops everything for the others.
+func gate(w io.Writer, level slog.Level) io.Writer {
+	if level < minLevel {
+		return io.Discard
+	}
+	return w
+}
+
+func useText(w io.Writer) {
 	logFlags := log.Ldate | log.Ltime | log.LUTC | log.Lshortfile
 
-	Info = log.New(os.Stdout, "INFO: ", logFlags)
-	Error = log.New(os.Stdout, "ERROR: ", logFlags)
-	Debug = log.New(os.Stdout, "DEBUG: ", logFlags)
-	Warn = log.New(os.Stdout, "WARN: ", logFlags)
+	Info = log.New(gate(w, slog.LevelInfo), "INFO: ", logFlags)
+	Error = log.New(gate(w, slog.LevelError), "ERROR: ", logFlags)
+	Debug = log.New(gate(w, slog.LevelDebug), "DEBUG: ", logFlags)
+	Warn = log.New(gate(w, slog.LevelWarn), "WARN: ", logFlags)
+}
+
+// useJSON routes the loggers through slog, writing one JSON object per line
+// with time, level, source and msg fields. Untrusted values still go through
+// SanitizeForLog so messages read the same in both formats.
+func useJSON(w io.Writer) {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     minLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.TimeValue(a.Value.Time().UTC())
+			}
+			return a
+		},
+	})
+
+	Info = slog.NewLogLogger(handler, slog.LevelInfo)
+	Error = slog.NewLogLogger(handler, slog.LevelError)
+	Debug = slog.NewLogLogger(handler, slog.LevelDebug)
+	Warn = slog.NewLogLogger(handler, slog.LevelWarn)
 }
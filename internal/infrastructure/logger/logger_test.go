@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseJSON(t *testing.T) {
+	t.Cleanup(func() { useText(os.Stdout) })
+	var buf bytes.Buffer
+	useJSON(&buf)
+
+	Warn.Printf("upload %s rejected", SanitizeForLog("evil\nname"))
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, `upload evil\nname rejected`, line["msg"])
+	assert.NotEmpty(t, line["time"])
+	source, ok := line["source"].(map[string]any)
+	require.True(t, ok)
+	assert.True(t, strings.HasSuffix(source["file"].(string), "logger_test.go"), "source should be the caller")
+}
+
+func TestSetFormat_Unknown(t *testing.T) {
+	assert.Error(t, SetFormat("xml"))
+}
+
+func TestLevelGate(t *testing.T) {
+	t.Cleanup(func() {
+		minLevel = slog.LevelDebug
+		useText(os.Stdout)
+	})
+	require.NoError(t, SetLevel("warn"))
+
+	var buf bytes.Buffer
+	useText(&buf)
+	Debug.Printf("noise")
+	Info.Printf("noise")
+	Warn.Printf("disk low")
+	Error.Printf("disk full")
+	assert.NotContains(t, buf.String(), "noise")
+	assert.Contains(t, buf.String(), "WARN: ")
+	assert.Contains(t, buf.String(), "ERROR: ")
+
+	buf.Reset()
+	useJSON(&buf)
+	Info.Printf("noise")
+	Error.Printf("disk full")
+	assert.NotContains(t, buf.String(), "noise")
+	assert.Contains(t, buf.String(), `"level":"ERROR"`)
+
+	assert.Error(t, SetLevel("verbose"))
+}
@@ -0,0 +1,74 @@
+// Package metrics exposes Prometheus collectors registered on the default registry.
+package metrics
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sharm"
+
+var (
+	UploadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Number of accepted uploads by media type.",
+	}, []string{"type"})
+
+	ConversionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conversions_total",
+		Help:      "Number of successful conversions by codec.",
+	}, []string{"codec"})
+
+	ConversionFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conversion_failures_total",
+		Help:      "Number of failed conversions by codec.",
+	}, []string{"codec"})
+
+	ConversionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "conversion_duration_seconds",
+		Help:      "Time spent converting media by codec.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+	}, []string{"codec"})
+
+	ActiveSSEConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sse_connections",
+		Help:      "Number of open server-sent event subscriptions.",
+	})
+
+	SSESubscriptionsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sse_subscriptions_dropped_total",
+		Help:      "Number of server-sent event subscriptions evicted by a newer one for the same media, or refused at the global limit.",
+	}, []string{"reason"})
+)
+
+// RegisterJobsPending exposes the job queue backlog, read from count at scrape time.
+func RegisterJobsPending(count func() (int, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_pending",
+		Help:      "Number of jobs waiting to be claimed by a worker.",
+	}, func() float64 {
+		n, err := count()
+		if err != nil {
+			logger.Error.Printf("metrics: failed to count pending jobs: %v", err)
+			return math.NaN()
+		}
+		return float64(n)
+	})
+}
+
+// Handler serves the default registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
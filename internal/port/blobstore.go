@@ -0,0 +1,26 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrPresignUnsupported is returned by blob stores that cannot hand out
+// URLs; their blobs are served by Sharm itself.
+var ErrPresignUnsupported = errors.New("blob store does not presign URLs")
+
+// BlobStore holds the media files served to viewers. Files are still
+// produced on local disk, where ffmpeg works on them, then stored under a
+// key: their slash-separated path relative to the data directory.
+type BlobStore interface {
+	// Put stores the file at localPath under key.
+	Put(ctx context.Context, key, localPath string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Presign returns a temporary URL that serves key directly, answering
+	// with contentType and disposition, when set, as the Content-Type and
+	// Content-Disposition headers.
+	Presign(ctx context.Context, key, contentType, disposition string) (string, error)
+}
@@ -1,10 +1,23 @@
 package port
 
-import "github.com/bnema/sharm/internal/domain"
+import (
+	"context"
 
+	"github.com/bnema/sharm/internal/domain"
+)
+
+// MediaConverter encodes media. Cancelling ctx stops an encode in progress.
 type MediaConverter interface {
-	Convert(inputPath, outputDir, id string) (outputPath string, codec string, err error)
-	ConvertCodec(inputPath, outputDir, id string, codec domain.Codec, fps int) (outputPath string, err error)
-	Thumbnail(inputPath, outputPath string) error
+	Convert(ctx context.Context, inputPath, outputDir, id string) (outputPath string, codec string, err error)
+	// ConvertCodec encodes inputPath to codec. src is the probe taken at
+	// upload, nil when there is none, so encoders need not probe again.
+	ConvertCodec(ctx context.Context, inputPath, outputDir, id string, codec domain.Codec, fps int, src *domain.ProbeResult) (outputPath string, err error)
+	Thumbnail(ctx context.Context, inputPath, outputPath string) error
+	// Storyboard renders a sprite of frames taken at intervals and a WebVTT
+	// file mapping playback times to its tiles, which it links as spriteURL.
+	Storyboard(ctx context.Context, inputPath, spritePath, vttPath, spriteURL string) error
 	Probe(inputPath string) (*domain.ProbeResult, error)
+	// Verify decodes the first seconds of inputPath and fails if the file
+	// is truncated or corrupt beyond what probing detects.
+	Verify(ctx context.Context, inputPath string) error
 }
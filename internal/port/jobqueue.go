@@ -3,9 +3,24 @@ package port
 import "github.com/bnema/sharm/internal/domain"
 
 type JobQueue interface {
-	Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps int) (*domain.Job, error)
+	// Enqueue queues a job; higher priority jobs are claimed first
+	Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps, priority int) (*domain.Job, error)
+	// EnqueueVariant atomically saves a pending variant and queues its convert job
+	EnqueueVariant(v *domain.Variant, fps int) (*domain.Job, error)
+	// RequeueVariant atomically resets a failed variant to pending and queues a new convert job
+	RequeueVariant(v *domain.Variant, fps int) (*domain.Job, error)
 	Claim() (*domain.Job, error)
 	Complete(jobID int64) error
 	Fail(jobID int64, errMsg string) error
+	// Requeue returns a running job to pending, for jobs interrupted by shutdown
+	Requeue(jobID int64) error
+	// Defer returns a running job to pending without counting the claim as
+	// an attempt, for jobs the worker could not admit
+	Defer(jobID int64) error
 	ResetStalled() error
+	// CancelByMedia marks the pending and running jobs of a media cancelled
+	// and returns how many it stopped
+	CancelByMedia(mediaID string) (int, error)
+	PendingCount() (int, error)
+	ListByMedia(mediaID string) ([]domain.Job, error)
 }
@@ -36,6 +36,66 @@ func (_m *JobQueueMock) EXPECT() *JobQueueMock_Expecter {
 	return &JobQueueMock_Expecter{mock: &_m.Mock}
 }
 
+// CancelByMedia provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) CancelByMedia(mediaID string) (int, error) {
+	ret := _mock.Called(mediaID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelByMedia")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return returnFunc(mediaID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) int); ok {
+		r0 = returnFunc(mediaID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(mediaID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// JobQueueMock_CancelByMedia_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CancelByMedia'
+type JobQueueMock_CancelByMedia_Call struct {
+	*mock.Call
+}
+
+// CancelByMedia is a helper method to define mock.On call
+//   - mediaID string
+func (_e *JobQueueMock_Expecter) CancelByMedia(mediaID interface{}) *JobQueueMock_CancelByMedia_Call {
+	return &JobQueueMock_CancelByMedia_Call{Call: _e.mock.On("CancelByMedia", mediaID)}
+}
+
+func (_c *JobQueueMock_CancelByMedia_Call) Run(run func(mediaID string)) *JobQueueMock_CancelByMedia_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_CancelByMedia_Call) Return(n int, err error) *JobQueueMock_CancelByMedia_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *JobQueueMock_CancelByMedia_Call) RunAndReturn(run func(mediaID string) (int, error)) *JobQueueMock_CancelByMedia_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Claim provides a mock function for the type JobQueueMock
 func (_mock *JobQueueMock) Claim() (*domain.Job, error) {
 	ret := _mock.Called()
@@ -142,9 +202,60 @@ func (_c *JobQueueMock_Complete_Call) RunAndReturn(run func(jobID int64) error)
 	return _c
 }
 
+// Defer provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) Defer(jobID int64) error {
+	ret := _mock.Called(jobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Defer")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = returnFunc(jobID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// JobQueueMock_Defer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Defer'
+type JobQueueMock_Defer_Call struct {
+	*mock.Call
+}
+
+// Defer is a helper method to define mock.On call
+//   - jobID int64
+func (_e *JobQueueMock_Expecter) Defer(jobID interface{}) *JobQueueMock_Defer_Call {
+	return &JobQueueMock_Defer_Call{Call: _e.mock.On("Defer", jobID)}
+}
+
+func (_c *JobQueueMock_Defer_Call) Run(run func(jobID int64)) *JobQueueMock_Defer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_Defer_Call) Return(err error) *JobQueueMock_Defer_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *JobQueueMock_Defer_Call) RunAndReturn(run func(jobID int64) error) *JobQueueMock_Defer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Enqueue provides a mock function for the type JobQueueMock
-func (_mock *JobQueueMock) Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps int) (*domain.Job, error) {
-	ret := _mock.Called(mediaID, jobType, codec, fps)
+func (_mock *JobQueueMock) Enqueue(mediaID string, jobType domain.JobType, codec domain.Codec, fps int, priority int) (*domain.Job, error) {
+	ret := _mock.Called(mediaID, jobType, codec, fps, priority)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Enqueue")
@@ -152,18 +263,18 @@ func (_mock *JobQueueMock) Enqueue(mediaID string, jobType domain.JobType, codec
 
 	var r0 *domain.Job
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string, domain.JobType, domain.Codec, int) (*domain.Job, error)); ok {
-		return returnFunc(mediaID, jobType, codec, fps)
+	if returnFunc, ok := ret.Get(0).(func(string, domain.JobType, domain.Codec, int, int) (*domain.Job, error)); ok {
+		return returnFunc(mediaID, jobType, codec, fps, priority)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, domain.JobType, domain.Codec, int) *domain.Job); ok {
-		r0 = returnFunc(mediaID, jobType, codec, fps)
+	if returnFunc, ok := ret.Get(0).(func(string, domain.JobType, domain.Codec, int, int) *domain.Job); ok {
+		r0 = returnFunc(mediaID, jobType, codec, fps, priority)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*domain.Job)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, domain.JobType, domain.Codec, int) error); ok {
-		r1 = returnFunc(mediaID, jobType, codec, fps)
+	if returnFunc, ok := ret.Get(1).(func(string, domain.JobType, domain.Codec, int, int) error); ok {
+		r1 = returnFunc(mediaID, jobType, codec, fps, priority)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -180,11 +291,12 @@ type JobQueueMock_Enqueue_Call struct {
 //   - jobType domain.JobType
 //   - codec domain.Codec
 //   - fps int
-func (_e *JobQueueMock_Expecter) Enqueue(mediaID interface{}, jobType interface{}, codec interface{}, fps interface{}) *JobQueueMock_Enqueue_Call {
-	return &JobQueueMock_Enqueue_Call{Call: _e.mock.On("Enqueue", mediaID, jobType, codec, fps)}
+//   - priority int
+func (_e *JobQueueMock_Expecter) Enqueue(mediaID interface{}, jobType interface{}, codec interface{}, fps interface{}, priority interface{}) *JobQueueMock_Enqueue_Call {
+	return &JobQueueMock_Enqueue_Call{Call: _e.mock.On("Enqueue", mediaID, jobType, codec, fps, priority)}
 }
 
-func (_c *JobQueueMock_Enqueue_Call) Run(run func(mediaID string, jobType domain.JobType, codec domain.Codec, fps int)) *JobQueueMock_Enqueue_Call {
+func (_c *JobQueueMock_Enqueue_Call) Run(run func(mediaID string, jobType domain.JobType, codec domain.Codec, fps int, priority int)) *JobQueueMock_Enqueue_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
@@ -202,11 +314,16 @@ func (_c *JobQueueMock_Enqueue_Call) Run(run func(mediaID string, jobType domain
 		if args[3] != nil {
 			arg3 = args[3].(int)
 		}
+		var arg4 int
+		if args[4] != nil {
+			arg4 = args[4].(int)
+		}
 		run(
 			arg0,
 			arg1,
 			arg2,
 			arg3,
+			arg4,
 		)
 	})
 	return _c
@@ -217,7 +334,75 @@ func (_c *JobQueueMock_Enqueue_Call) Return(job *domain.Job, err error) *JobQueu
 	return _c
 }
 
-func (_c *JobQueueMock_Enqueue_Call) RunAndReturn(run func(mediaID string, jobType domain.JobType, codec domain.Codec, fps int) (*domain.Job, error)) *JobQueueMock_Enqueue_Call {
+func (_c *JobQueueMock_Enqueue_Call) RunAndReturn(run func(mediaID string, jobType domain.JobType, codec domain.Codec, fps int, priority int) (*domain.Job, error)) *JobQueueMock_Enqueue_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EnqueueVariant provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) EnqueueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	ret := _mock.Called(v, fps)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnqueueVariant")
+	}
+
+	var r0 *domain.Job
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(*domain.Variant, int) (*domain.Job, error)); ok {
+		return returnFunc(v, fps)
+	}
+	if returnFunc, ok := ret.Get(0).(func(*domain.Variant, int) *domain.Job); ok {
+		r0 = returnFunc(v, fps)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Job)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(*domain.Variant, int) error); ok {
+		r1 = returnFunc(v, fps)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// JobQueueMock_EnqueueVariant_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnqueueVariant'
+type JobQueueMock_EnqueueVariant_Call struct {
+	*mock.Call
+}
+
+// EnqueueVariant is a helper method to define mock.On call
+//   - v *domain.Variant
+//   - fps int
+func (_e *JobQueueMock_Expecter) EnqueueVariant(v interface{}, fps interface{}) *JobQueueMock_EnqueueVariant_Call {
+	return &JobQueueMock_EnqueueVariant_Call{Call: _e.mock.On("EnqueueVariant", v, fps)}
+}
+
+func (_c *JobQueueMock_EnqueueVariant_Call) Run(run func(v *domain.Variant, fps int)) *JobQueueMock_EnqueueVariant_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *domain.Variant
+		if args[0] != nil {
+			arg0 = args[0].(*domain.Variant)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_EnqueueVariant_Call) Return(r *domain.Job, err error) *JobQueueMock_EnqueueVariant_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *JobQueueMock_EnqueueVariant_Call) RunAndReturn(run func(v *domain.Variant, fps int) (*domain.Job, error)) *JobQueueMock_EnqueueVariant_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -279,6 +464,240 @@ func (_c *JobQueueMock_Fail_Call) RunAndReturn(run func(jobID int64, errMsg stri
 	return _c
 }
 
+// ListByMedia provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) ListByMedia(mediaID string) ([]domain.Job, error) {
+	ret := _mock.Called(mediaID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByMedia")
+	}
+
+	var r0 []domain.Job
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) ([]domain.Job, error)); ok {
+		return returnFunc(mediaID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) []domain.Job); ok {
+		r0 = returnFunc(mediaID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Job)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(mediaID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// JobQueueMock_ListByMedia_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByMedia'
+type JobQueueMock_ListByMedia_Call struct {
+	*mock.Call
+}
+
+// ListByMedia is a helper method to define mock.On call
+//   - mediaID string
+func (_e *JobQueueMock_Expecter) ListByMedia(mediaID interface{}) *JobQueueMock_ListByMedia_Call {
+	return &JobQueueMock_ListByMedia_Call{Call: _e.mock.On("ListByMedia", mediaID)}
+}
+
+func (_c *JobQueueMock_ListByMedia_Call) Run(run func(mediaID string)) *JobQueueMock_ListByMedia_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_ListByMedia_Call) Return(jobs []domain.Job, err error) *JobQueueMock_ListByMedia_Call {
+	_c.Call.Return(jobs, err)
+	return _c
+}
+
+func (_c *JobQueueMock_ListByMedia_Call) RunAndReturn(run func(mediaID string) ([]domain.Job, error)) *JobQueueMock_ListByMedia_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PendingCount provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) PendingCount() (int, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for PendingCount")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (int, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() int); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// JobQueueMock_PendingCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PendingCount'
+type JobQueueMock_PendingCount_Call struct {
+	*mock.Call
+}
+
+// PendingCount is a helper method to define mock.On call
+func (_e *JobQueueMock_Expecter) PendingCount() *JobQueueMock_PendingCount_Call {
+	return &JobQueueMock_PendingCount_Call{Call: _e.mock.On("PendingCount")}
+}
+
+func (_c *JobQueueMock_PendingCount_Call) Run(run func()) *JobQueueMock_PendingCount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_PendingCount_Call) Return(n int, err error) *JobQueueMock_PendingCount_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *JobQueueMock_PendingCount_Call) RunAndReturn(run func() (int, error)) *JobQueueMock_PendingCount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Requeue provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) Requeue(jobID int64) error {
+	ret := _mock.Called(jobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Requeue")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = returnFunc(jobID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// JobQueueMock_Requeue_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Requeue'
+type JobQueueMock_Requeue_Call struct {
+	*mock.Call
+}
+
+// Requeue is a helper method to define mock.On call
+//   - jobID int64
+func (_e *JobQueueMock_Expecter) Requeue(jobID interface{}) *JobQueueMock_Requeue_Call {
+	return &JobQueueMock_Requeue_Call{Call: _e.mock.On("Requeue", jobID)}
+}
+
+func (_c *JobQueueMock_Requeue_Call) Run(run func(jobID int64)) *JobQueueMock_Requeue_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_Requeue_Call) Return(err error) *JobQueueMock_Requeue_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *JobQueueMock_Requeue_Call) RunAndReturn(run func(jobID int64) error) *JobQueueMock_Requeue_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequeueVariant provides a mock function for the type JobQueueMock
+func (_mock *JobQueueMock) RequeueVariant(v *domain.Variant, fps int) (*domain.Job, error) {
+	ret := _mock.Called(v, fps)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequeueVariant")
+	}
+
+	var r0 *domain.Job
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(*domain.Variant, int) (*domain.Job, error)); ok {
+		return returnFunc(v, fps)
+	}
+	if returnFunc, ok := ret.Get(0).(func(*domain.Variant, int) *domain.Job); ok {
+		r0 = returnFunc(v, fps)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Job)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(*domain.Variant, int) error); ok {
+		r1 = returnFunc(v, fps)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// JobQueueMock_RequeueVariant_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequeueVariant'
+type JobQueueMock_RequeueVariant_Call struct {
+	*mock.Call
+}
+
+// RequeueVariant is a helper method to define mock.On call
+//   - v *domain.Variant
+//   - fps int
+func (_e *JobQueueMock_Expecter) RequeueVariant(v interface{}, fps interface{}) *JobQueueMock_RequeueVariant_Call {
+	return &JobQueueMock_RequeueVariant_Call{Call: _e.mock.On("RequeueVariant", v, fps)}
+}
+
+func (_c *JobQueueMock_RequeueVariant_Call) Run(run func(v *domain.Variant, fps int)) *JobQueueMock_RequeueVariant_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *domain.Variant
+		if args[0] != nil {
+			arg0 = args[0].(*domain.Variant)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *JobQueueMock_RequeueVariant_Call) Return(r *domain.Job, err error) *JobQueueMock_RequeueVariant_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *JobQueueMock_RequeueVariant_Call) RunAndReturn(run func(v *domain.Variant, fps int) (*domain.Job, error)) *JobQueueMock_RequeueVariant_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ResetStalled provides a mock function for the type JobQueueMock
 func (_mock *JobQueueMock) ResetStalled() error {
 	ret := _mock.Called()
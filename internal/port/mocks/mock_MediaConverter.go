@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	"context"
 	"github.com/bnema/sharm/internal/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -37,8 +38,8 @@ func (_m *MediaConverterMock) EXPECT() *MediaConverterMock_Expecter {
 }
 
 // Convert provides a mock function for the type MediaConverterMock
-func (_mock *MediaConverterMock) Convert(inputPath string, outputDir string, id string) (string, string, error) {
-	ret := _mock.Called(inputPath, outputDir, id)
+func (_mock *MediaConverterMock) Convert(ctx context.Context, inputPath string, outputDir string, id string) (string, string, error) {
+	ret := _mock.Called(ctx, inputPath, outputDir, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Convert")
@@ -47,21 +48,21 @@ func (_mock *MediaConverterMock) Convert(inputPath string, outputDir string, id
 	var r0 string
 	var r1 string
 	var r2 error
-	if returnFunc, ok := ret.Get(0).(func(string, string, string) (string, string, error)); ok {
-		return returnFunc(inputPath, outputDir, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) (string, string, error)); ok {
+		return returnFunc(ctx, inputPath, outputDir, id)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, string, string) string); ok {
-		r0 = returnFunc(inputPath, outputDir, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = returnFunc(ctx, inputPath, outputDir, id)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, string, string) string); ok {
-		r1 = returnFunc(inputPath, outputDir, id)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string) string); ok {
+		r1 = returnFunc(ctx, inputPath, outputDir, id)
 	} else {
 		r1 = ret.Get(1).(string)
 	}
-	if returnFunc, ok := ret.Get(2).(func(string, string, string) error); ok {
-		r2 = returnFunc(inputPath, outputDir, id)
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string, string, string) error); ok {
+		r2 = returnFunc(ctx, inputPath, outputDir, id)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -74,18 +75,19 @@ type MediaConverterMock_Convert_Call struct {
 }
 
 // Convert is a helper method to define mock.On call
+//   - ctx context.Context
 //   - inputPath string
 //   - outputDir string
 //   - id string
-func (_e *MediaConverterMock_Expecter) Convert(inputPath interface{}, outputDir interface{}, id interface{}) *MediaConverterMock_Convert_Call {
-	return &MediaConverterMock_Convert_Call{Call: _e.mock.On("Convert", inputPath, outputDir, id)}
+func (_e *MediaConverterMock_Expecter) Convert(ctx interface{}, inputPath interface{}, outputDir interface{}, id interface{}) *MediaConverterMock_Convert_Call {
+	return &MediaConverterMock_Convert_Call{Call: _e.mock.On("Convert", ctx, inputPath, outputDir, id)}
 }
 
-func (_c *MediaConverterMock_Convert_Call) Run(run func(inputPath string, outputDir string, id string)) *MediaConverterMock_Convert_Call {
+func (_c *MediaConverterMock_Convert_Call) Run(run func(ctx context.Context, inputPath string, outputDir string, id string)) *MediaConverterMock_Convert_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(context.Context)
 		}
 		var arg1 string
 		if args[1] != nil {
@@ -95,10 +97,15 @@ func (_c *MediaConverterMock_Convert_Call) Run(run func(inputPath string, output
 		if args[2] != nil {
 			arg2 = args[2].(string)
 		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
 		run(
 			arg0,
 			arg1,
 			arg2,
+			arg3,
 		)
 	})
 	return _c
@@ -109,14 +116,14 @@ func (_c *MediaConverterMock_Convert_Call) Return(outputPath string, codec strin
 	return _c
 }
 
-func (_c *MediaConverterMock_Convert_Call) RunAndReturn(run func(inputPath string, outputDir string, id string) (string, string, error)) *MediaConverterMock_Convert_Call {
+func (_c *MediaConverterMock_Convert_Call) RunAndReturn(run func(ctx context.Context, inputPath string, outputDir string, id string) (string, string, error)) *MediaConverterMock_Convert_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // ConvertCodec provides a mock function for the type MediaConverterMock
-func (_mock *MediaConverterMock) ConvertCodec(inputPath string, outputDir string, id string, codec domain.Codec, fps int) (string, error) {
-	ret := _mock.Called(inputPath, outputDir, id, codec, fps)
+func (_mock *MediaConverterMock) ConvertCodec(ctx context.Context, inputPath string, outputDir string, id string, codec domain.Codec, fps int, src *domain.ProbeResult) (string, error) {
+	ret := _mock.Called(ctx, inputPath, outputDir, id, codec, fps, src)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ConvertCodec")
@@ -124,16 +131,16 @@ func (_mock *MediaConverterMock) ConvertCodec(inputPath string, outputDir string
 
 	var r0 string
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string, string, string, domain.Codec, int) (string, error)); ok {
-		return returnFunc(inputPath, outputDir, id, codec, fps)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, domain.Codec, int, *domain.ProbeResult) (string, error)); ok {
+		return returnFunc(ctx, inputPath, outputDir, id, codec, fps, src)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, string, string, domain.Codec, int) string); ok {
-		r0 = returnFunc(inputPath, outputDir, id, codec, fps)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, domain.Codec, int, *domain.ProbeResult) string); ok {
+		r0 = returnFunc(ctx, inputPath, outputDir, id, codec, fps, src)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, string, string, domain.Codec, int) error); ok {
-		r1 = returnFunc(inputPath, outputDir, id, codec, fps)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string, domain.Codec, int, *domain.ProbeResult) error); ok {
+		r1 = returnFunc(ctx, inputPath, outputDir, id, codec, fps, src)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -146,20 +153,22 @@ type MediaConverterMock_ConvertCodec_Call struct {
 }
 
 // ConvertCodec is a helper method to define mock.On call
+//   - ctx context.Context
 //   - inputPath string
 //   - outputDir string
 //   - id string
 //   - codec domain.Codec
 //   - fps int
-func (_e *MediaConverterMock_Expecter) ConvertCodec(inputPath interface{}, outputDir interface{}, id interface{}, codec interface{}, fps interface{}) *MediaConverterMock_ConvertCodec_Call {
-	return &MediaConverterMock_ConvertCodec_Call{Call: _e.mock.On("ConvertCodec", inputPath, outputDir, id, codec, fps)}
+//   - src *domain.ProbeResult
+func (_e *MediaConverterMock_Expecter) ConvertCodec(ctx interface{}, inputPath interface{}, outputDir interface{}, id interface{}, codec interface{}, fps interface{}, src interface{}) *MediaConverterMock_ConvertCodec_Call {
+	return &MediaConverterMock_ConvertCodec_Call{Call: _e.mock.On("ConvertCodec", ctx, inputPath, outputDir, id, codec, fps, src)}
 }
 
-func (_c *MediaConverterMock_ConvertCodec_Call) Run(run func(inputPath string, outputDir string, id string, codec domain.Codec, fps int)) *MediaConverterMock_ConvertCodec_Call {
+func (_c *MediaConverterMock_ConvertCodec_Call) Run(run func(ctx context.Context, inputPath string, outputDir string, id string, codec domain.Codec, fps int, src *domain.ProbeResult)) *MediaConverterMock_ConvertCodec_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(context.Context)
 		}
 		var arg1 string
 		if args[1] != nil {
@@ -169,13 +178,21 @@ func (_c *MediaConverterMock_ConvertCodec_Call) Run(run func(inputPath string, o
 		if args[2] != nil {
 			arg2 = args[2].(string)
 		}
-		var arg3 domain.Codec
+		var arg3 string
 		if args[3] != nil {
-			arg3 = args[3].(domain.Codec)
+			arg3 = args[3].(string)
 		}
-		var arg4 int
+		var arg4 domain.Codec
 		if args[4] != nil {
-			arg4 = args[4].(int)
+			arg4 = args[4].(domain.Codec)
+		}
+		var arg5 int
+		if args[5] != nil {
+			arg5 = args[5].(int)
+		}
+		var arg6 *domain.ProbeResult
+		if args[6] != nil {
+			arg6 = args[6].(*domain.ProbeResult)
 		}
 		run(
 			arg0,
@@ -183,6 +200,8 @@ func (_c *MediaConverterMock_ConvertCodec_Call) Run(run func(inputPath string, o
 			arg2,
 			arg3,
 			arg4,
+			arg5,
+			arg6,
 		)
 	})
 	return _c
@@ -193,7 +212,7 @@ func (_c *MediaConverterMock_ConvertCodec_Call) Return(outputPath string, err er
 	return _c
 }
 
-func (_c *MediaConverterMock_ConvertCodec_Call) RunAndReturn(run func(inputPath string, outputDir string, id string, codec domain.Codec, fps int) (string, error)) *MediaConverterMock_ConvertCodec_Call {
+func (_c *MediaConverterMock_ConvertCodec_Call) RunAndReturn(run func(ctx context.Context, inputPath string, outputDir string, id string, codec domain.Codec, fps int, src *domain.ProbeResult) (string, error)) *MediaConverterMock_ConvertCodec_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -260,17 +279,92 @@ func (_c *MediaConverterMock_Probe_Call) RunAndReturn(run func(inputPath string)
 	return _c
 }
 
+// Storyboard provides a mock function for the type MediaConverterMock
+func (_mock *MediaConverterMock) Storyboard(ctx context.Context, inputPath string, spritePath string, vttPath string, spriteURL string) error {
+	ret := _mock.Called(ctx, inputPath, spritePath, vttPath, spriteURL)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Storyboard")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, string) error); ok {
+		r0 = returnFunc(ctx, inputPath, spritePath, vttPath, spriteURL)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaConverterMock_Storyboard_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Storyboard'
+type MediaConverterMock_Storyboard_Call struct {
+	*mock.Call
+}
+
+// Storyboard is a helper method to define mock.On call
+//   - ctx context.Context
+//   - inputPath string
+//   - spritePath string
+//   - vttPath string
+//   - spriteURL string
+func (_e *MediaConverterMock_Expecter) Storyboard(ctx interface{}, inputPath interface{}, spritePath interface{}, vttPath interface{}, spriteURL interface{}) *MediaConverterMock_Storyboard_Call {
+	return &MediaConverterMock_Storyboard_Call{Call: _e.mock.On("Storyboard", ctx, inputPath, spritePath, vttPath, spriteURL)}
+}
+
+func (_c *MediaConverterMock_Storyboard_Call) Run(run func(ctx context.Context, inputPath string, spritePath string, vttPath string, spriteURL string)) *MediaConverterMock_Storyboard_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 string
+		if args[4] != nil {
+			arg4 = args[4].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaConverterMock_Storyboard_Call) Return(err error) *MediaConverterMock_Storyboard_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaConverterMock_Storyboard_Call) RunAndReturn(run func(ctx context.Context, inputPath string, spritePath string, vttPath string, spriteURL string) error) *MediaConverterMock_Storyboard_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Thumbnail provides a mock function for the type MediaConverterMock
-func (_mock *MediaConverterMock) Thumbnail(inputPath string, outputPath string) error {
-	ret := _mock.Called(inputPath, outputPath)
+func (_mock *MediaConverterMock) Thumbnail(ctx context.Context, inputPath string, outputPath string) error {
+	ret := _mock.Called(ctx, inputPath, outputPath)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Thumbnail")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = returnFunc(inputPath, outputPath)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, inputPath, outputPath)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -283,25 +377,31 @@ type MediaConverterMock_Thumbnail_Call struct {
 }
 
 // Thumbnail is a helper method to define mock.On call
+//   - ctx context.Context
 //   - inputPath string
 //   - outputPath string
-func (_e *MediaConverterMock_Expecter) Thumbnail(inputPath interface{}, outputPath interface{}) *MediaConverterMock_Thumbnail_Call {
-	return &MediaConverterMock_Thumbnail_Call{Call: _e.mock.On("Thumbnail", inputPath, outputPath)}
+func (_e *MediaConverterMock_Expecter) Thumbnail(ctx interface{}, inputPath interface{}, outputPath interface{}) *MediaConverterMock_Thumbnail_Call {
+	return &MediaConverterMock_Thumbnail_Call{Call: _e.mock.On("Thumbnail", ctx, inputPath, outputPath)}
 }
 
-func (_c *MediaConverterMock_Thumbnail_Call) Run(run func(inputPath string, outputPath string)) *MediaConverterMock_Thumbnail_Call {
+func (_c *MediaConverterMock_Thumbnail_Call) Run(run func(ctx context.Context, inputPath string, outputPath string)) *MediaConverterMock_Thumbnail_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(context.Context)
 		}
 		var arg1 string
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -312,7 +412,64 @@ func (_c *MediaConverterMock_Thumbnail_Call) Return(err error) *MediaConverterMo
 	return _c
 }
 
-func (_c *MediaConverterMock_Thumbnail_Call) RunAndReturn(run func(inputPath string, outputPath string) error) *MediaConverterMock_Thumbnail_Call {
+func (_c *MediaConverterMock_Thumbnail_Call) RunAndReturn(run func(ctx context.Context, inputPath string, outputPath string) error) *MediaConverterMock_Thumbnail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Verify provides a mock function for the type MediaConverterMock
+func (_mock *MediaConverterMock) Verify(ctx context.Context, inputPath string) error {
+	ret := _mock.Called(ctx, inputPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Verify")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, inputPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaConverterMock_Verify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Verify'
+type MediaConverterMock_Verify_Call struct {
+	*mock.Call
+}
+
+// Verify is a helper method to define mock.On call
+//   - ctx context.Context
+//   - inputPath string
+func (_e *MediaConverterMock_Expecter) Verify(ctx interface{}, inputPath interface{}) *MediaConverterMock_Verify_Call {
+	return &MediaConverterMock_Verify_Call{Call: _e.mock.On("Verify", ctx, inputPath)}
+}
+
+func (_c *MediaConverterMock_Verify_Call) Run(run func(ctx context.Context, inputPath string)) *MediaConverterMock_Verify_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaConverterMock_Verify_Call) Return(err error) *MediaConverterMock_Verify_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaConverterMock_Verify_Call) RunAndReturn(run func(ctx context.Context, inputPath string) error) *MediaConverterMock_Verify_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -7,6 +7,7 @@ package mocks
 import (
 	"github.com/bnema/sharm/internal/domain"
 	mock "github.com/stretchr/testify/mock"
+	"time"
 )
 
 // NewMediaStoreMock creates a new instance of MediaStoreMock. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -87,6 +88,57 @@ func (_c *MediaStoreMock_Delete_Call) RunAndReturn(run func(id string) error) *M
 	return _c
 }
 
+// DeleteMany provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) DeleteMany(ids []string) error {
+	ret := _mock.Called(ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMany")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = returnFunc(ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaStoreMock_DeleteMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMany'
+type MediaStoreMock_DeleteMany_Call struct {
+	*mock.Call
+}
+
+// DeleteMany is a helper method to define mock.On call
+//   - ids []string
+func (_e *MediaStoreMock_Expecter) DeleteMany(ids interface{}) *MediaStoreMock_DeleteMany_Call {
+	return &MediaStoreMock_DeleteMany_Call{Call: _e.mock.On("DeleteMany", ids)}
+}
+
+func (_c *MediaStoreMock_DeleteMany_Call) Run(run func(ids []string)) *MediaStoreMock_DeleteMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []string
+		if args[0] != nil {
+			arg0 = args[0].([]string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_DeleteMany_Call) Return(err error) *MediaStoreMock_DeleteMany_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaStoreMock_DeleteMany_Call) RunAndReturn(run func(ids []string) error) *MediaStoreMock_DeleteMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteVariantsByMedia provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) DeleteVariantsByMedia(mediaID string) error {
 	ret := _mock.Called(mediaID)
@@ -138,6 +190,78 @@ func (_c *MediaStoreMock_DeleteVariantsByMedia_Call) RunAndReturn(run func(media
 	return _c
 }
 
+// FindByChecksum provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) FindByChecksum(ownerID int64, file string, sha256 string) (string, error) {
+	ret := _mock.Called(ownerID, file, sha256)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByChecksum")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int64, string, string) (string, error)); ok {
+		return returnFunc(ownerID, file, sha256)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64, string, string) string); ok {
+		r0 = returnFunc(ownerID, file, sha256)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64, string, string) error); ok {
+		r1 = returnFunc(ownerID, file, sha256)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_FindByChecksum_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByChecksum'
+type MediaStoreMock_FindByChecksum_Call struct {
+	*mock.Call
+}
+
+// FindByChecksum is a helper method to define mock.On call
+//   - ownerID int64
+//   - file string
+//   - sha256 string
+func (_e *MediaStoreMock_Expecter) FindByChecksum(ownerID interface{}, file interface{}, sha256 interface{}) *MediaStoreMock_FindByChecksum_Call {
+	return &MediaStoreMock_FindByChecksum_Call{Call: _e.mock.On("FindByChecksum", ownerID, file, sha256)}
+}
+
+func (_c *MediaStoreMock_FindByChecksum_Call) Run(run func(ownerID int64, file string, sha256 string)) *MediaStoreMock_FindByChecksum_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_FindByChecksum_Call) Return(_a0 string, _a1 error) *MediaStoreMock_FindByChecksum_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MediaStoreMock_FindByChecksum_Call) RunAndReturn(run func(ownerID int64, file string, sha256 string) (string, error)) *MediaStoreMock_FindByChecksum_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Get provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) Get(id string) (*domain.Media, error) {
 	ret := _mock.Called(id)
@@ -200,6 +324,72 @@ func (_c *MediaStoreMock_Get_Call) RunAndReturn(run func(id string) (*domain.Med
 	return _c
 }
 
+// GetChecksum provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) GetChecksum(mediaID string, file string) (string, error) {
+	ret := _mock.Called(mediaID, file)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChecksum")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, string) (string, error)); ok {
+		return returnFunc(mediaID, file)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = returnFunc(mediaID, file)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = returnFunc(mediaID, file)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_GetChecksum_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetChecksum'
+type MediaStoreMock_GetChecksum_Call struct {
+	*mock.Call
+}
+
+// GetChecksum is a helper method to define mock.On call
+//   - mediaID string
+//   - file string
+func (_e *MediaStoreMock_Expecter) GetChecksum(mediaID interface{}, file interface{}) *MediaStoreMock_GetChecksum_Call {
+	return &MediaStoreMock_GetChecksum_Call{Call: _e.mock.On("GetChecksum", mediaID, file)}
+}
+
+func (_c *MediaStoreMock_GetChecksum_Call) Run(run func(mediaID string, file string)) *MediaStoreMock_GetChecksum_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_GetChecksum_Call) Return(s string, err error) *MediaStoreMock_GetChecksum_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_GetChecksum_Call) RunAndReturn(run func(mediaID string, file string) (string, error)) *MediaStoreMock_GetChecksum_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetVariant provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) GetVariant(id int64) (*domain.Variant, error) {
 	ret := _mock.Called(id)
@@ -330,9 +520,66 @@ func (_c *MediaStoreMock_GetVariantByMediaAndCodec_Call) RunAndReturn(run func(m
 	return _c
 }
 
+// IterateByStatus provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) IterateByStatus(status domain.MediaStatus, fn func(*domain.Media) error) error {
+	ret := _mock.Called(status, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateByStatus")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(domain.MediaStatus, func(*domain.Media) error) error); ok {
+		r0 = returnFunc(status, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaStoreMock_IterateByStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IterateByStatus'
+type MediaStoreMock_IterateByStatus_Call struct {
+	*mock.Call
+}
+
+// IterateByStatus is a helper method to define mock.On call
+//   - status domain.MediaStatus
+//   - fn func(*domain.Media) error
+func (_e *MediaStoreMock_Expecter) IterateByStatus(status interface{}, fn interface{}) *MediaStoreMock_IterateByStatus_Call {
+	return &MediaStoreMock_IterateByStatus_Call{Call: _e.mock.On("IterateByStatus", status, fn)}
+}
+
+func (_c *MediaStoreMock_IterateByStatus_Call) Run(run func(status domain.MediaStatus, fn func(*domain.Media) error)) *MediaStoreMock_IterateByStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 domain.MediaStatus
+		if args[0] != nil {
+			arg0 = args[0].(domain.MediaStatus)
+		}
+		var arg1 func(*domain.Media) error
+		if args[1] != nil {
+			arg1 = args[1].(func(*domain.Media) error)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_IterateByStatus_Call) Return(err error) *MediaStoreMock_IterateByStatus_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaStoreMock_IterateByStatus_Call) RunAndReturn(run func(status domain.MediaStatus, fn func(*domain.Media) error) error) *MediaStoreMock_IterateByStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListAll provides a mock function for the type MediaStoreMock
-func (_mock *MediaStoreMock) ListAll() ([]*domain.Media, error) {
-	ret := _mock.Called()
+func (_mock *MediaStoreMock) ListAll(ownerID int64) ([]*domain.Media, error) {
+	ret := _mock.Called(ownerID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ListAll")
@@ -340,18 +587,18 @@ func (_mock *MediaStoreMock) ListAll() ([]*domain.Media, error) {
 
 	var r0 []*domain.Media
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func() ([]*domain.Media, error)); ok {
-		return returnFunc()
+	if returnFunc, ok := ret.Get(0).(func(int64) ([]*domain.Media, error)); ok {
+		return returnFunc(ownerID)
 	}
-	if returnFunc, ok := ret.Get(0).(func() []*domain.Media); ok {
-		r0 = returnFunc()
+	if returnFunc, ok := ret.Get(0).(func(int64) []*domain.Media); ok {
+		r0 = returnFunc(ownerID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*domain.Media)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func() error); ok {
-		r1 = returnFunc()
+	if returnFunc, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = returnFunc(ownerID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -364,13 +611,20 @@ type MediaStoreMock_ListAll_Call struct {
 }
 
 // ListAll is a helper method to define mock.On call
-func (_e *MediaStoreMock_Expecter) ListAll() *MediaStoreMock_ListAll_Call {
-	return &MediaStoreMock_ListAll_Call{Call: _e.mock.On("ListAll")}
+//   - ownerID int64
+func (_e *MediaStoreMock_Expecter) ListAll(ownerID interface{}) *MediaStoreMock_ListAll_Call {
+	return &MediaStoreMock_ListAll_Call{Call: _e.mock.On("ListAll", ownerID)}
 }
 
-func (_c *MediaStoreMock_ListAll_Call) Run(run func()) *MediaStoreMock_ListAll_Call {
+func (_c *MediaStoreMock_ListAll_Call) Run(run func(ownerID int64)) *MediaStoreMock_ListAll_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		run(
+			arg0,
+		)
 	})
 	return _c
 }
@@ -380,7 +634,75 @@ func (_c *MediaStoreMock_ListAll_Call) Return(medias []*domain.Media, err error)
 	return _c
 }
 
-func (_c *MediaStoreMock_ListAll_Call) RunAndReturn(run func() ([]*domain.Media, error)) *MediaStoreMock_ListAll_Call {
+func (_c *MediaStoreMock_ListAll_Call) RunAndReturn(run func(ownerID int64) ([]*domain.Media, error)) *MediaStoreMock_ListAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByTag provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) ListByTag(ownerID int64, tag string) ([]*domain.Media, error) {
+	ret := _mock.Called(ownerID, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByTag")
+	}
+
+	var r0 []*domain.Media
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int64, string) ([]*domain.Media, error)); ok {
+		return returnFunc(ownerID, tag)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64, string) []*domain.Media); ok {
+		r0 = returnFunc(ownerID, tag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Media)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = returnFunc(ownerID, tag)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_ListByTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByTag'
+type MediaStoreMock_ListByTag_Call struct {
+	*mock.Call
+}
+
+// ListByTag is a helper method to define mock.On call
+//   - ownerID int64
+//   - tag string
+func (_e *MediaStoreMock_Expecter) ListByTag(ownerID interface{}, tag interface{}) *MediaStoreMock_ListByTag_Call {
+	return &MediaStoreMock_ListByTag_Call{Call: _e.mock.On("ListByTag", ownerID, tag)}
+}
+
+func (_c *MediaStoreMock_ListByTag_Call) Run(run func(ownerID int64, tag string)) *MediaStoreMock_ListByTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_ListByTag_Call) Return(medias []*domain.Media, err error) *MediaStoreMock_ListByTag_Call {
+	_c.Call.Return(medias, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_ListByTag_Call) RunAndReturn(run func(ownerID int64, tag string) ([]*domain.Media, error)) *MediaStoreMock_ListByTag_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -440,50 +762,50 @@ func (_c *MediaStoreMock_ListExpired_Call) RunAndReturn(run func() ([]*domain.Me
 	return _c
 }
 
-// ListVariantsByMedia provides a mock function for the type MediaStoreMock
-func (_mock *MediaStoreMock) ListVariantsByMedia(mediaID string) ([]domain.Variant, error) {
-	ret := _mock.Called(mediaID)
+// ListFailedOlderThan provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) ListFailedOlderThan(age time.Duration) ([]*domain.Media, error) {
+	ret := _mock.Called(age)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListVariantsByMedia")
+		panic("no return value specified for ListFailedOlderThan")
 	}
 
-	var r0 []domain.Variant
+	var r0 []*domain.Media
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string) ([]domain.Variant, error)); ok {
-		return returnFunc(mediaID)
+	if returnFunc, ok := ret.Get(0).(func(time.Duration) ([]*domain.Media, error)); ok {
+		return returnFunc(age)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string) []domain.Variant); ok {
-		r0 = returnFunc(mediaID)
+	if returnFunc, ok := ret.Get(0).(func(time.Duration) []*domain.Media); ok {
+		r0 = returnFunc(age)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]domain.Variant)
+			r0 = ret.Get(0).([]*domain.Media)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
-		r1 = returnFunc(mediaID)
+	if returnFunc, ok := ret.Get(1).(func(time.Duration) error); ok {
+		r1 = returnFunc(age)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MediaStoreMock_ListVariantsByMedia_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListVariantsByMedia'
-type MediaStoreMock_ListVariantsByMedia_Call struct {
+// MediaStoreMock_ListFailedOlderThan_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListFailedOlderThan'
+type MediaStoreMock_ListFailedOlderThan_Call struct {
 	*mock.Call
 }
 
-// ListVariantsByMedia is a helper method to define mock.On call
-//   - mediaID string
-func (_e *MediaStoreMock_Expecter) ListVariantsByMedia(mediaID interface{}) *MediaStoreMock_ListVariantsByMedia_Call {
-	return &MediaStoreMock_ListVariantsByMedia_Call{Call: _e.mock.On("ListVariantsByMedia", mediaID)}
+// ListFailedOlderThan is a helper method to define mock.On call
+//   - age time.Duration
+func (_e *MediaStoreMock_Expecter) ListFailedOlderThan(age interface{}) *MediaStoreMock_ListFailedOlderThan_Call {
+	return &MediaStoreMock_ListFailedOlderThan_Call{Call: _e.mock.On("ListFailedOlderThan", age)}
 }
 
-func (_c *MediaStoreMock_ListVariantsByMedia_Call) Run(run func(mediaID string)) *MediaStoreMock_ListVariantsByMedia_Call {
+func (_c *MediaStoreMock_ListFailedOlderThan_Call) Run(run func(age time.Duration)) *MediaStoreMock_ListFailedOlderThan_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 time.Duration
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(time.Duration)
 		}
 		run(
 			arg0,
@@ -492,17 +814,293 @@ func (_c *MediaStoreMock_ListVariantsByMedia_Call) Run(run func(mediaID string))
 	return _c
 }
 
-func (_c *MediaStoreMock_ListVariantsByMedia_Call) Return(variants []domain.Variant, err error) *MediaStoreMock_ListVariantsByMedia_Call {
-	_c.Call.Return(variants, err)
+func (_c *MediaStoreMock_ListFailedOlderThan_Call) Return(medias []*domain.Media, err error) *MediaStoreMock_ListFailedOlderThan_Call {
+	_c.Call.Return(medias, err)
 	return _c
 }
 
-func (_c *MediaStoreMock_ListVariantsByMedia_Call) RunAndReturn(run func(mediaID string) ([]domain.Variant, error)) *MediaStoreMock_ListVariantsByMedia_Call {
+func (_c *MediaStoreMock_ListFailedOlderThan_Call) RunAndReturn(run func(age time.Duration) ([]*domain.Media, error)) *MediaStoreMock_ListFailedOlderThan_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Save provides a mock function for the type MediaStoreMock
+// ListOwnedByStatus provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) ListOwnedByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error) {
+	ret := _mock.Called(ownerID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOwnedByStatus")
+	}
+
+	var r0 []*domain.Media
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int64, domain.MediaStatus) ([]*domain.Media, error)); ok {
+		return returnFunc(ownerID, status)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64, domain.MediaStatus) []*domain.Media); ok {
+		r0 = returnFunc(ownerID, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Media)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64, domain.MediaStatus) error); ok {
+		r1 = returnFunc(ownerID, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_ListOwnedByStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListOwnedByStatus'
+type MediaStoreMock_ListOwnedByStatus_Call struct {
+	*mock.Call
+}
+
+// ListOwnedByStatus is a helper method to define mock.On call
+//   - ownerID int64
+//   - status domain.MediaStatus
+func (_e *MediaStoreMock_Expecter) ListOwnedByStatus(ownerID interface{}, status interface{}) *MediaStoreMock_ListOwnedByStatus_Call {
+	return &MediaStoreMock_ListOwnedByStatus_Call{Call: _e.mock.On("ListOwnedByStatus", ownerID, status)}
+}
+
+func (_c *MediaStoreMock_ListOwnedByStatus_Call) Run(run func(ownerID int64, status domain.MediaStatus)) *MediaStoreMock_ListOwnedByStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		var arg1 domain.MediaStatus
+		if args[1] != nil {
+			arg1 = args[1].(domain.MediaStatus)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_ListOwnedByStatus_Call) Return(medias []*domain.Media, err error) *MediaStoreMock_ListOwnedByStatus_Call {
+	_c.Call.Return(medias, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_ListOwnedByStatus_Call) RunAndReturn(run func(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error)) *MediaStoreMock_ListOwnedByStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListPaged provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) ListPaged(ownerID int64, sort domain.SortBy, limit int, offset int) ([]*domain.Media, int, error) {
+	ret := _mock.Called(ownerID, sort, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaged")
+	}
+
+	var r0 []*domain.Media
+	var r1 int
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(int64, domain.SortBy, int, int) ([]*domain.Media, int, error)); ok {
+		return returnFunc(ownerID, sort, limit, offset)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64, domain.SortBy, int, int) []*domain.Media); ok {
+		r0 = returnFunc(ownerID, sort, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Media)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64, domain.SortBy, int, int) int); ok {
+		r1 = returnFunc(ownerID, sort, limit, offset)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(int64, domain.SortBy, int, int) error); ok {
+		r2 = returnFunc(ownerID, sort, limit, offset)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MediaStoreMock_ListPaged_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListPaged'
+type MediaStoreMock_ListPaged_Call struct {
+	*mock.Call
+}
+
+// ListPaged is a helper method to define mock.On call
+//   - ownerID int64
+//   - sort domain.SortBy
+//   - limit int
+//   - offset int
+func (_e *MediaStoreMock_Expecter) ListPaged(ownerID interface{}, sort interface{}, limit interface{}, offset interface{}) *MediaStoreMock_ListPaged_Call {
+	return &MediaStoreMock_ListPaged_Call{Call: _e.mock.On("ListPaged", ownerID, sort, limit, offset)}
+}
+
+func (_c *MediaStoreMock_ListPaged_Call) Run(run func(ownerID int64, sort domain.SortBy, limit int, offset int)) *MediaStoreMock_ListPaged_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		var arg1 domain.SortBy
+		if args[1] != nil {
+			arg1 = args[1].(domain.SortBy)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 int
+		if args[3] != nil {
+			arg3 = args[3].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_ListPaged_Call) Return(medias []*domain.Media, n int, err error) *MediaStoreMock_ListPaged_Call {
+	_c.Call.Return(medias, n, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_ListPaged_Call) RunAndReturn(run func(ownerID int64, sort domain.SortBy, limit int, offset int) ([]*domain.Media, int, error)) *MediaStoreMock_ListPaged_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListVariantsByMedia provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) ListVariantsByMedia(mediaID string) ([]domain.Variant, error) {
+	ret := _mock.Called(mediaID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListVariantsByMedia")
+	}
+
+	var r0 []domain.Variant
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) ([]domain.Variant, error)); ok {
+		return returnFunc(mediaID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) []domain.Variant); ok {
+		r0 = returnFunc(mediaID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Variant)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(mediaID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_ListVariantsByMedia_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListVariantsByMedia'
+type MediaStoreMock_ListVariantsByMedia_Call struct {
+	*mock.Call
+}
+
+// ListVariantsByMedia is a helper method to define mock.On call
+//   - mediaID string
+func (_e *MediaStoreMock_Expecter) ListVariantsByMedia(mediaID interface{}) *MediaStoreMock_ListVariantsByMedia_Call {
+	return &MediaStoreMock_ListVariantsByMedia_Call{Call: _e.mock.On("ListVariantsByMedia", mediaID)}
+}
+
+func (_c *MediaStoreMock_ListVariantsByMedia_Call) Run(run func(mediaID string)) *MediaStoreMock_ListVariantsByMedia_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_ListVariantsByMedia_Call) Return(variants []domain.Variant, err error) *MediaStoreMock_ListVariantsByMedia_Call {
+	_c.Call.Return(variants, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_ListVariantsByMedia_Call) RunAndReturn(run func(mediaID string) ([]domain.Variant, error)) *MediaStoreMock_ListVariantsByMedia_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// OwnerStats provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) OwnerStats(ownerID int64) (domain.StorageStats, error) {
+	ret := _mock.Called(ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OwnerStats")
+	}
+
+	var r0 domain.StorageStats
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int64) (domain.StorageStats, error)); ok {
+		return returnFunc(ownerID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64) domain.StorageStats); ok {
+		r0 = returnFunc(ownerID)
+	} else {
+		r0 = ret.Get(0).(domain.StorageStats)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = returnFunc(ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_OwnerStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OwnerStats'
+type MediaStoreMock_OwnerStats_Call struct {
+	*mock.Call
+}
+
+// OwnerStats is a helper method to define mock.On call
+//   - ownerID int64
+func (_e *MediaStoreMock_Expecter) OwnerStats(ownerID interface{}) *MediaStoreMock_OwnerStats_Call {
+	return &MediaStoreMock_OwnerStats_Call{Call: _e.mock.On("OwnerStats", ownerID)}
+}
+
+func (_c *MediaStoreMock_OwnerStats_Call) Run(run func(ownerID int64)) *MediaStoreMock_OwnerStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_OwnerStats_Call) Return(storageStats domain.StorageStats, err error) *MediaStoreMock_OwnerStats_Call {
+	_c.Call.Return(storageStats, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_OwnerStats_Call) RunAndReturn(run func(ownerID int64) (domain.StorageStats, error)) *MediaStoreMock_OwnerStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Save provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) Save(m *domain.Media) error {
 	ret := _mock.Called(m)
 
@@ -553,6 +1151,69 @@ func (_c *MediaStoreMock_Save_Call) RunAndReturn(run func(m *domain.Media) error
 	return _c
 }
 
+// SaveChecksum provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) SaveChecksum(mediaID string, file string, sha256 string) error {
+	ret := _mock.Called(mediaID, file, sha256)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveChecksum")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = returnFunc(mediaID, file, sha256)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaStoreMock_SaveChecksum_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveChecksum'
+type MediaStoreMock_SaveChecksum_Call struct {
+	*mock.Call
+}
+
+// SaveChecksum is a helper method to define mock.On call
+//   - mediaID string
+//   - file string
+//   - sha256 string
+func (_e *MediaStoreMock_Expecter) SaveChecksum(mediaID interface{}, file interface{}, sha256 interface{}) *MediaStoreMock_SaveChecksum_Call {
+	return &MediaStoreMock_SaveChecksum_Call{Call: _e.mock.On("SaveChecksum", mediaID, file, sha256)}
+}
+
+func (_c *MediaStoreMock_SaveChecksum_Call) Run(run func(mediaID string, file string, sha256 string)) *MediaStoreMock_SaveChecksum_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_SaveChecksum_Call) Return(err error) *MediaStoreMock_SaveChecksum_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaStoreMock_SaveChecksum_Call) RunAndReturn(run func(mediaID string, file string, sha256 string) error) *MediaStoreMock_SaveChecksum_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SaveVariant provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) SaveVariant(v *domain.Variant) error {
 	ret := _mock.Called(v)
@@ -604,6 +1265,127 @@ func (_c *MediaStoreMock_SaveVariant_Call) RunAndReturn(run func(v *domain.Varia
 	return _c
 }
 
+// Search provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) Search(ownerID int64, query string) ([]*domain.Media, error) {
+	ret := _mock.Called(ownerID, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 []*domain.Media
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int64, string) ([]*domain.Media, error)); ok {
+		return returnFunc(ownerID, query)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int64, string) []*domain.Media); ok {
+		r0 = returnFunc(ownerID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Media)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = returnFunc(ownerID, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_Search_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Search'
+type MediaStoreMock_Search_Call struct {
+	*mock.Call
+}
+
+// Search is a helper method to define mock.On call
+//   - ownerID int64
+//   - query string
+func (_e *MediaStoreMock_Expecter) Search(ownerID interface{}, query interface{}) *MediaStoreMock_Search_Call {
+	return &MediaStoreMock_Search_Call{Call: _e.mock.On("Search", ownerID, query)}
+}
+
+func (_c *MediaStoreMock_Search_Call) Run(run func(ownerID int64, query string)) *MediaStoreMock_Search_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int64
+		if args[0] != nil {
+			arg0 = args[0].(int64)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_Search_Call) Return(medias []*domain.Media, err error) *MediaStoreMock_Search_Call {
+	_c.Call.Return(medias, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_Search_Call) RunAndReturn(run func(ownerID int64, query string) ([]*domain.Media, error)) *MediaStoreMock_Search_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Stats provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) Stats() (domain.StorageStats, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stats")
+	}
+
+	var r0 domain.StorageStats
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (domain.StorageStats, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() domain.StorageStats); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(domain.StorageStats)
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MediaStoreMock_Stats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stats'
+type MediaStoreMock_Stats_Call struct {
+	*mock.Call
+}
+
+// Stats is a helper method to define mock.On call
+func (_e *MediaStoreMock_Expecter) Stats() *MediaStoreMock_Stats_Call {
+	return &MediaStoreMock_Stats_Call{Call: _e.mock.On("Stats")}
+}
+
+func (_c *MediaStoreMock_Stats_Call) Run(run func()) *MediaStoreMock_Stats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_Stats_Call) Return(storageStats domain.StorageStats, err error) *MediaStoreMock_Stats_Call {
+	_c.Call.Return(storageStats, err)
+	return _c
+}
+
+func (_c *MediaStoreMock_Stats_Call) RunAndReturn(run func() (domain.StorageStats, error)) *MediaStoreMock_Stats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateDone provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) UpdateDone(m *domain.Media) error {
 	ret := _mock.Called(m)
@@ -775,6 +1557,69 @@ func (_c *MediaStoreMock_UpdateStatus_Call) RunAndReturn(run func(id string, sta
 	return _c
 }
 
+// UpdateStoryboard provides a mock function for the type MediaStoreMock
+func (_mock *MediaStoreMock) UpdateStoryboard(id string, spritePath string, vttPath string) error {
+	ret := _mock.Called(id, spritePath, vttPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStoryboard")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = returnFunc(id, spritePath, vttPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MediaStoreMock_UpdateStoryboard_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateStoryboard'
+type MediaStoreMock_UpdateStoryboard_Call struct {
+	*mock.Call
+}
+
+// UpdateStoryboard is a helper method to define mock.On call
+//   - id string
+//   - spritePath string
+//   - vttPath string
+func (_e *MediaStoreMock_Expecter) UpdateStoryboard(id interface{}, spritePath interface{}, vttPath interface{}) *MediaStoreMock_UpdateStoryboard_Call {
+	return &MediaStoreMock_UpdateStoryboard_Call{Call: _e.mock.On("UpdateStoryboard", id, spritePath, vttPath)}
+}
+
+func (_c *MediaStoreMock_UpdateStoryboard_Call) Run(run func(id string, spritePath string, vttPath string)) *MediaStoreMock_UpdateStoryboard_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MediaStoreMock_UpdateStoryboard_Call) Return(err error) *MediaStoreMock_UpdateStoryboard_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MediaStoreMock_UpdateStoryboard_Call) RunAndReturn(run func(id string, spritePath string, vttPath string) error) *MediaStoreMock_UpdateStoryboard_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateVariantDone provides a mock function for the type MediaStoreMock
 func (_mock *MediaStoreMock) UpdateVariantDone(v *domain.Variant) error {
 	ret := _mock.Called(v)
@@ -1,16 +1,38 @@
 package port
 
-import "github.com/bnema/sharm/internal/domain"
+import (
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+)
 
 type MediaStore interface {
 	Save(m *domain.Media) error
 	Get(id string) (*domain.Media, error)
 	Delete(id string) error
+	// DeleteMany deletes all of ids in one transaction
+	DeleteMany(ids []string) error
 	ListExpired() ([]*domain.Media, error)
-	ListAll() ([]*domain.Media, error)
+	ListFailedOlderThan(age time.Duration) ([]*domain.Media, error)
+	// IterateByStatus calls fn for every media of every owner in the given
+	// status, a page at a time
+	IterateByStatus(status domain.MediaStatus, fn func(*domain.Media) error) error
+
+	// Listing methods only return media owned by ownerID
+	ListAll(ownerID int64) ([]*domain.Media, error)
+	ListPaged(ownerID int64, sort domain.SortBy, limit, offset int) ([]*domain.Media, int, error)
+	ListByTag(ownerID int64, tag string) ([]*domain.Media, error)
+	Search(ownerID int64, query string) ([]*domain.Media, error)
+	ListOwnedByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error)
+
 	UpdateStatus(id string, status domain.MediaStatus, errMsg string) error
+	// UpdateDone marks m done with its original, converted and thumbnail
+	// paths, codec, dimensions and size.
 	UpdateDone(m *domain.Media) error
 	UpdateProbeJSON(id string, probeJSON string) error
+	UpdateStoryboard(id, spritePath, vttPath string) error
+	Stats() (domain.StorageStats, error)
+	OwnerStats(ownerID int64) (domain.StorageStats, error)
 
 	// Variant methods
 	SaveVariant(v *domain.Variant) error
@@ -20,4 +42,11 @@ type MediaStore interface {
 	UpdateVariantStatus(id int64, status domain.VariantStatus, errMsg string) error
 	UpdateVariantDone(v *domain.Variant) error
 	DeleteVariantsByMedia(mediaID string) error
+
+	// Checksum methods; file is "original", "converted" or a codec name
+	GetChecksum(mediaID, file string) (string, error)
+	SaveChecksum(mediaID, file, sha256 string) error
+	// FindByChecksum returns the newest media of ownerID, not failed, whose
+	// file has the given checksum
+	FindByChecksum(ownerID int64, file, sha256 string) (string, error)
 }
@@ -6,7 +6,20 @@ type UserStore interface {
 	HasUser() (bool, error)
 	GetUser(username string) (*domain.User, error)
 	GetUserByID(id int64) (*domain.User, error)
+	GetUserByOIDCSubject(subject string) (*domain.User, error)
 	GetFirstUser() (*domain.User, error)
-	CreateUser(username, passwordHash string) error
+	ListUsers() ([]domain.User, error)
+	CreateUser(username, passwordHash string, isAdmin bool) error
+	CreateOIDCUser(username, subject string, isAdmin bool) error
 	UpdatePassword(id int64, passwordHash string) error
+	SetRecoveryCode(id int64, codeHash string) error
+	SetTOTP(id int64, secret string, enabled bool) error
+	ReplaceBackupCodes(userID int64, codeHashes []string) error
+	ConsumeBackupCode(userID int64, codeHash string) (bool, error)
+	CountBackupCodes(userID int64) (int, error)
+	CreateAPIKey(userID int64, label, keyHash string) (*domain.APIKey, error)
+	GetAPIKey(id int64) (*domain.APIKey, error)
+	ListAPIKeys(userID int64) ([]domain.APIKey, error)
+	RevokeAPIKey(userID, id int64) error
+	TouchAPIKey(id int64) error
 }
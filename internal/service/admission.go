@@ -0,0 +1,44 @@
+package service
+
+import "sync"
+
+// memoryGate admits conversions while their estimated memory fits in a
+// budget shared by all workers.
+type memoryGate struct {
+	mu       sync.Mutex
+	budgetMB int64
+	inUseMB  int64
+}
+
+// newMemoryGate returns nil, which admits everything, when budgetMB is 0.
+func newMemoryGate(budgetMB int64) *memoryGate {
+	if budgetMB <= 0 {
+		return nil
+	}
+	return &memoryGate{budgetMB: budgetMB}
+}
+
+// tryAcquire reserves mb if it fits in the remaining budget. A job larger
+// than the whole budget is still admitted when nothing else is running, so
+// it runs alone instead of never.
+func (g *memoryGate) tryAcquire(mb int64) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.inUseMB > 0 && g.inUseMB+mb > g.budgetMB {
+		return false
+	}
+	g.inUseMB += mb
+	return true
+}
+
+func (g *memoryGate) release(mb int64) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inUseMB -= mb
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strconv"
@@ -14,6 +18,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
 	"github.com/bnema/sharm/internal/port"
 )
 
@@ -26,6 +31,25 @@ var (
 	ErrWrongPassword   = errors.New("wrong password")
 	ErrWeakPassword    = errors.New("password does not meet requirements")
 	ErrInvalidUsername = errors.New("invalid username")
+	ErrNotAdmin        = errors.New("admin privileges required")
+	ErrInvalidAPIKey   = errors.New("invalid api key")
+	ErrInvalidLabel    = errors.New("invalid api key label")
+	ErrInvalidRecovery = errors.New("invalid recovery code")
+)
+
+// apiKeyPrefix marks raw API keys so they are recognisable in config files
+// and secret scanners. Keys have the form sharm_<id>_<secret>.
+const (
+	apiKeyPrefix      = "sharm"
+	apiKeySecretBytes = 32
+	apiKeyMaxLabel    = 100
+)
+
+// Recovery codes are 80 random bits rendered as base32 in dash-separated
+// groups of four, e.g. ABCD-EFGH-IJKL-MNOP.
+const (
+	recoveryCodeBytes    = 10
+	oneTimeCodeGroupSize = 4
 )
 
 func validateUsername(username string) error {
@@ -104,22 +128,34 @@ func formatMissingRequirements(missing []string) string {
 	return result
 }
 
+// DefaultTokenTTL is the session lifetime used when AUTH_TOKEN_TTL is unset.
+const DefaultTokenTTL = 7 * 24 * time.Hour
+
 type AuthService struct {
 	store     port.UserStore
 	secretKey string
+	tokenTTL  time.Duration
 }
 
-func NewAuthService(store port.UserStore, secretKey string) *AuthService {
+func NewAuthService(store port.UserStore, secretKey string, tokenTTL time.Duration) *AuthService {
 	return &AuthService{
 		store:     store,
 		secretKey: secretKey,
+		tokenTTL:  tokenTTL,
 	}
 }
 
+// TokenTTL is how long a session token from GenerateToken stays valid.
+func (s *AuthService) TokenTTL() time.Duration {
+	return s.tokenTTL
+}
+
 func (s *AuthService) HasUser() (bool, error) {
 	return s.store.HasUser()
 }
 
+// CreateUser creates the initial admin account during setup. It fails once
+// any user exists; further accounts are added by an admin through AddUser.
 func (s *AuthService) CreateUser(username, password string) error {
 	hasUser, err := s.store.HasUser()
 	if err != nil {
@@ -128,13 +164,35 @@ func (s *AuthService) CreateUser(username, password string) error {
 	if hasUser {
 		return ErrUserExists
 	}
+	return s.createUser(username, password, true)
+}
+
+// AddUser lets an admin create an additional, non-admin account.
+func (s *AuthService) AddUser(admin *domain.User, username, password string) error {
+	if admin == nil || !admin.IsAdmin {
+		return ErrNotAdmin
+	}
 
+	if _, err := s.store.GetUser(username); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	return s.createUser(username, password, false)
+}
+
+// ListUsers returns every account, for the admin user list.
+func (s *AuthService) ListUsers() ([]domain.User, error) {
+	return s.store.ListUsers()
+}
+
+func (s *AuthService) createUser(username, password string, isAdmin bool) error {
 	if validateErr := validateUsername(username); validateErr != nil {
 		return fmt.Errorf("%w: %w", ErrInvalidUsername, validateErr)
 	}
 
 	if validateErr := validatePasswordStrength(password); validateErr != nil {
-		return validateErr
+		return fmt.Errorf("%w: %w", ErrWeakPassword, validateErr)
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -142,7 +200,7 @@ func (s *AuthService) CreateUser(username, password string) error {
 		return err
 	}
 
-	return s.store.CreateUser(username, string(passwordHash))
+	return s.store.CreateUser(username, string(passwordHash), isAdmin)
 }
 
 func (s *AuthService) ValidatePassword(username, password string) error {
@@ -205,7 +263,7 @@ func (s *AuthService) ValidateToken(token string) (*domain.User, error) {
 		return nil, ErrInvalidToken
 	}
 
-	expirationTime := time.Unix(ts, 0).Add(7 * 24 * time.Hour)
+	expirationTime := time.Unix(ts, 0).Add(s.tokenTTL)
 	if time.Now().After(expirationTime) {
 		return nil, ErrExpiredToken
 	}
@@ -213,6 +271,20 @@ func (s *AuthService) ValidateToken(token string) (*domain.User, error) {
 	return user, nil
 }
 
+// TokenAge returns how long ago token was issued. It does not check the
+// signature; call it only on a token that passed ValidateToken.
+func (s *AuthService) TokenAge(token string) (time.Duration, error) {
+	timestamp, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return time.Since(time.Unix(ts, 0)), nil
+}
+
 func (s *AuthService) ChangePassword(username, oldPassword, newPassword string) error {
 	user, err := s.store.GetUser(username)
 	if err != nil {
@@ -235,3 +307,184 @@ func (s *AuthService) ChangePassword(username, oldPassword, newPassword string)
 
 	return s.store.UpdatePassword(user.ID, string(passwordHash))
 }
+
+// CreateAPIKey issues a new API key for userID. The returned raw key is not
+// stored and cannot be recovered later.
+func (s *AuthService) CreateAPIKey(userID int64, label string) (string, *domain.APIKey, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", nil, fmt.Errorf("%w: must not be empty", ErrInvalidLabel)
+	}
+	if len(label) > apiKeyMaxLabel {
+		return "", nil, fmt.Errorf("%w: must be at most %d characters", ErrInvalidLabel, apiKeyMaxLabel)
+	}
+
+	buf := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+
+	key, err := s.store.CreateAPIKey(userID, label, hashAPIKeySecret(secret))
+	if err != nil {
+		return "", nil, fmt.Errorf("store api key: %w", err)
+	}
+
+	raw := apiKeyPrefix + "_" + strconv.FormatInt(key.ID, 10) + "_" + secret
+	return raw, key, nil
+}
+
+func (s *AuthService) ListAPIKeys(userID int64) ([]domain.APIKey, error) {
+	return s.store.ListAPIKeys(userID)
+}
+
+func (s *AuthService) RevokeAPIKey(userID, keyID int64) error {
+	return s.store.RevokeAPIKey(userID, keyID)
+}
+
+// ValidateAPIKey resolves a raw API key to its owner. It is the bearer-token
+// counterpart of ValidateToken.
+func (s *AuthService) ValidateAPIKey(raw string) (*domain.User, error) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidAPIKey
+	}
+
+	keyID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.store.GetAPIKey(keyID)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(parts[2])), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.store.GetUserByID(key.UserID)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.store.TouchAPIKey(key.ID); err != nil {
+		logger.Warn.Printf("failed to update last use of api key %d: %v", key.ID, err)
+	}
+
+	return user, nil
+}
+
+// hashAPIKeySecret returns the hex SHA-256 of an API key secret. The secret
+// is high-entropy random data, so a fast hash is sufficient here.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateRecoveryCode issues a new one-time recovery code for userID,
+// replacing any previous one. Only its hash is stored, so the returned code
+// must be shown to the user immediately.
+func (s *AuthService) GenerateRecoveryCode(userID int64) (string, error) {
+	code, codeHash, err := newOneTimeCode(recoveryCodeBytes)
+	if err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+
+	if err := s.store.SetRecoveryCode(userID, codeHash); err != nil {
+		return "", fmt.Errorf("store recovery code: %w", err)
+	}
+	return code, nil
+}
+
+// ResetPasswordWithCode sets a new password for the user owning code and
+// invalidates the code.
+func (s *AuthService) ResetPasswordWithCode(code, newPassword string) error {
+	codeHash := hashOneTimeCode(normalizeOneTimeCode(code))
+	if codeHash == "" {
+		return ErrInvalidRecovery
+	}
+
+	users, err := s.store.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	var user *domain.User
+	for i := range users {
+		if users[i].RecoveryHash == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(codeHash), []byte(users[i].RecoveryHash)) == 1 {
+			user = &users[i]
+		}
+	}
+	if user == nil {
+		return ErrInvalidRecovery
+	}
+
+	if validateErr := validatePasswordStrength(newPassword); validateErr != nil {
+		return fmt.Errorf("%w: %w", ErrWeakPassword, validateErr)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdatePassword(user.ID, string(passwordHash)); err != nil {
+		return err
+	}
+	if err := s.store.SetRecoveryCode(user.ID, ""); err != nil {
+		return fmt.Errorf("invalidate recovery code: %w", err)
+	}
+	return nil
+}
+
+// HasRecoveryCode reports whether userID has an unused recovery code.
+func (s *AuthService) HasRecoveryCode(userID int64) (bool, error) {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.RecoveryHash != "", nil
+}
+
+// newOneTimeCode returns a random base32 code of nbytes entropy, formatted
+// for display, together with the hash to store.
+func newOneTimeCode(nbytes int) (string, string, error) {
+	buf := make([]byte, nbytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)
+
+	var groups []string
+	for i := 0; i < len(raw); i += oneTimeCodeGroupSize {
+		groups = append(groups, raw[i:min(i+oneTimeCodeGroupSize, len(raw))])
+	}
+	return strings.Join(groups, "-"), hashOneTimeCode(raw), nil
+}
+
+// normalizeOneTimeCode strips separators and whitespace and upper-cases the
+// code so that users can type it loosely.
+func normalizeOneTimeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// hashOneTimeCode returns the hex SHA-256 of a normalized recovery or backup
+// code, or an empty string for an empty code so it never matches a cleared
+// hash.
+func hashOneTimeCode(code string) string {
+	if code == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
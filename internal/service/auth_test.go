@@ -14,6 +14,7 @@ import (
 
 	"github.com/bnema/sharm/internal/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockUserStore struct {
@@ -21,6 +22,9 @@ type mockUserStore struct {
 	hasUser       bool
 	createUserErr error
 	getUserErr    error
+	apiKeys       []domain.APIKey
+	touched       []int64
+	backupCodes   []string
 }
 
 func (m *mockUserStore) HasUser() (bool, error) {
@@ -48,7 +52,7 @@ func (m *mockUserStore) GetUserByID(id int64) (*domain.User, error) {
 	return m.user, nil
 }
 
-func (m *mockUserStore) CreateUser(username, passwordHash string) error {
+func (m *mockUserStore) CreateUser(username, passwordHash string, isAdmin bool) error {
 	if m.createUserErr != nil {
 		return m.createUserErr
 	}
@@ -56,11 +60,40 @@ func (m *mockUserStore) CreateUser(username, passwordHash string) error {
 		ID:           1,
 		Username:     username,
 		PasswordHash: passwordHash,
+		IsAdmin:      isAdmin,
 	}
 	m.hasUser = true
 	return nil
 }
 
+func (m *mockUserStore) GetUserByOIDCSubject(subject string) (*domain.User, error) {
+	if m.user == nil || m.user.OIDCSubject != subject {
+		return nil, domain.ErrNotFound
+	}
+	return m.user, nil
+}
+
+func (m *mockUserStore) CreateOIDCUser(username, subject string, isAdmin bool) error {
+	if m.createUserErr != nil {
+		return m.createUserErr
+	}
+	m.user = &domain.User{
+		ID:          1,
+		Username:    username,
+		IsAdmin:     isAdmin,
+		OIDCSubject: subject,
+	}
+	m.hasUser = true
+	return nil
+}
+
+func (m *mockUserStore) ListUsers() ([]domain.User, error) {
+	if m.user == nil {
+		return nil, nil
+	}
+	return []domain.User{*m.user}, nil
+}
+
 func (m *mockUserStore) UpdatePassword(id int64, passwordHash string) error {
 	if m.user != nil {
 		m.user.PasswordHash = passwordHash
@@ -68,10 +101,89 @@ func (m *mockUserStore) UpdatePassword(id int64, passwordHash string) error {
 	return nil
 }
 
+func (m *mockUserStore) SetRecoveryCode(id int64, codeHash string) error {
+	if m.user != nil {
+		m.user.RecoveryHash = codeHash
+	}
+	return nil
+}
+
+func (m *mockUserStore) SetTOTP(id int64, secret string, enabled bool) error {
+	if m.user != nil {
+		m.user.TOTPSecret = secret
+		m.user.TOTPEnabled = enabled
+	}
+	return nil
+}
+
+func (m *mockUserStore) ReplaceBackupCodes(userID int64, codeHashes []string) error {
+	m.backupCodes = append([]string(nil), codeHashes...)
+	return nil
+}
+
+func (m *mockUserStore) ConsumeBackupCode(userID int64, codeHash string) (bool, error) {
+	for i, h := range m.backupCodes {
+		if h == codeHash {
+			m.backupCodes = append(m.backupCodes[:i], m.backupCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockUserStore) CountBackupCodes(userID int64) (int, error) {
+	return len(m.backupCodes), nil
+}
+
+func (m *mockUserStore) CreateAPIKey(userID int64, label, keyHash string) (*domain.APIKey, error) {
+	key := domain.APIKey{
+		ID:      int64(len(m.apiKeys) + 1),
+		UserID:  userID,
+		Label:   label,
+		KeyHash: keyHash,
+	}
+	m.apiKeys = append(m.apiKeys, key)
+	return &key, nil
+}
+
+func (m *mockUserStore) GetAPIKey(id int64) (*domain.APIKey, error) {
+	for i := range m.apiKeys {
+		if m.apiKeys[i].ID == id {
+			return &m.apiKeys[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockUserStore) ListAPIKeys(userID int64) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	for _, key := range m.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *mockUserStore) RevokeAPIKey(userID, id int64) error {
+	for i, key := range m.apiKeys {
+		if key.ID == id && key.UserID == userID {
+			m.apiKeys = append(m.apiKeys[:i], m.apiKeys[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *mockUserStore) TouchAPIKey(id int64) error {
+	m.touched = append(m.touched, id)
+	return nil
+}
+
 func TestAuthService_HasUser(t *testing.T) {
 	t.Run("returns false when no user exists", func(t *testing.T) {
 		store := &mockUserStore{hasUser: false}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		hasUser, err := svc.HasUser()
 		assert.NoError(t, err)
 		assert.False(t, hasUser)
@@ -79,7 +191,7 @@ func TestAuthService_HasUser(t *testing.T) {
 
 	t.Run("returns true when user exists", func(t *testing.T) {
 		store := &mockUserStore{hasUser: true}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		hasUser, err := svc.HasUser()
 		assert.NoError(t, err)
 		assert.True(t, hasUser)
@@ -89,7 +201,7 @@ func TestAuthService_HasUser(t *testing.T) {
 func TestAuthService_CreateUser(t *testing.T) {
 	t.Run("creates user successfully", func(t *testing.T) {
 		store := &mockUserStore{hasUser: false}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.CreateUser("admin", "P@ssw0rd123")
 		assert.NoError(t, err)
 		assert.True(t, store.hasUser)
@@ -97,7 +209,7 @@ func TestAuthService_CreateUser(t *testing.T) {
 
 	t.Run("returns error when user already exists", func(t *testing.T) {
 		store := &mockUserStore{hasUser: true}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.CreateUser("admin", "P@ssw0rd123")
 		assert.ErrorIs(t, err, ErrUserExists)
 	})
@@ -114,7 +226,7 @@ func TestAuthService_ValidatePassword(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.ValidatePassword("admin", "P@ssw0rd123")
 		assert.NoError(t, err)
 	})
@@ -127,14 +239,14 @@ func TestAuthService_ValidatePassword(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.ValidatePassword("admin", "wrongpassword")
 		assert.ErrorIs(t, err, ErrWrongPassword)
 	})
 
 	t.Run("returns error for non-existent user", func(t *testing.T) {
 		store := &mockUserStore{getUserErr: errors.New("not found")}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.ValidatePassword("nonexistent", "password")
 		assert.ErrorIs(t, err, ErrInvalidCreds)
 	})
@@ -151,7 +263,7 @@ func TestAuthService_GenerateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		token, err := svc.GenerateToken("admin")
 		assert.NoError(t, err)
 		parts := strings.Split(token, ":")
@@ -172,7 +284,7 @@ func TestAuthService_GenerateToken(t *testing.T) {
 			},
 		}
 		secretKey := "test-secret-key"
-		svc := NewAuthService(store, secretKey)
+		svc := NewAuthService(store, secretKey, DefaultTokenTTL)
 		token, err := svc.GenerateToken("admin")
 		assert.NoError(t, err)
 
@@ -194,7 +306,7 @@ func TestAuthService_GenerateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		token1, _ := svc.GenerateToken("admin")
 		time.Sleep(1 * time.Second)
 		token2, _ := svc.GenerateToken("admin")
@@ -217,7 +329,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		token, _ := svc.GenerateToken("admin")
 		user, err := svc.ValidateToken(token)
 		assert.NoError(t, err)
@@ -233,7 +345,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 
 		tests := []struct {
 			name  string
@@ -261,7 +373,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 
 		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 		wrongSignature := base64.URLEncoding.EncodeToString([]byte("wrong"))
@@ -280,7 +392,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 			},
 		}
 		secretKey := "test-secret-key"
-		svc := NewAuthService(store, secretKey)
+		svc := NewAuthService(store, secretKey, DefaultTokenTTL)
 
 		oldTimestamp := time.Now().Add(-8 * 24 * time.Hour).Unix()
 		userID := "1"
@@ -302,7 +414,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 			},
 		}
 		secretKey := "test-secret-key"
-		svc := NewAuthService(store, secretKey)
+		svc := NewAuthService(store, secretKey, DefaultTokenTTL)
 
 		recentTimestamp := time.Now().Add(-6 * 24 * time.Hour).Unix()
 		userID := "1"
@@ -316,6 +428,32 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		assert.NotNil(t, user)
 	})
 
+	t.Run("honours a configured TTL", func(t *testing.T) {
+		store := &mockUserStore{
+			user: &domain.User{
+				ID:           1,
+				Username:     "admin",
+				PasswordHash: string(passwordHash),
+			},
+		}
+		secretKey := "test-secret-key"
+		svc := NewAuthService(store, secretKey, time.Hour)
+
+		signed := func(issued time.Time) string {
+			ts := strconv.FormatInt(issued.Unix(), 10)
+			mac := hmac.New(sha256.New, []byte(secretKey))
+			mac.Write([]byte(ts + ":1"))
+			return ts + ":1:" + base64.URLEncoding.EncodeToString(mac.Sum(nil))
+		}
+
+		_, err := svc.ValidateToken(signed(time.Now().Add(-time.Hour - time.Second)))
+		assert.ErrorIs(t, err, ErrExpiredToken)
+
+		user, err := svc.ValidateToken(signed(time.Now().Add(-59 * time.Minute)))
+		assert.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
 	t.Run("handles invalid timestamp in token", func(t *testing.T) {
 		store := &mockUserStore{
 			user: &domain.User{
@@ -324,7 +462,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 
 		invalidTimestamp := "not-a-number"
 
@@ -338,6 +476,21 @@ func TestAuthService_ValidateToken(t *testing.T) {
 	})
 }
 
+func TestAuthService_TokenAge(t *testing.T) {
+	svc := NewAuthService(&mockUserStore{}, "test-secret-key", DefaultTokenTTL)
+
+	issued := strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)
+	age, err := svc.TokenAge(issued + ":1:signature")
+	require.NoError(t, err)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), age.Seconds(), 5)
+
+	_, err = svc.TokenAge("not-a-number:1:signature")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = svc.TokenAge("garbage")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
 func TestAuthService_ChangePassword(t *testing.T) {
 	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("P@ssw0rd123"), bcrypt.DefaultCost)
 
@@ -349,7 +502,7 @@ func TestAuthService_ChangePassword(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.ChangePassword("admin", "P@ssw0rd123", "N3wP@ssw0rd!")
 		assert.NoError(t, err)
 		assert.NotEqual(t, string(passwordHash), store.user.PasswordHash)
@@ -363,8 +516,133 @@ func TestAuthService_ChangePassword(t *testing.T) {
 				PasswordHash: string(passwordHash),
 			},
 		}
-		svc := NewAuthService(store, "test-secret-key")
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
 		err := svc.ChangePassword("admin", "wrongpassword", "N3wP@ssw0rd!")
 		assert.ErrorIs(t, err, ErrWrongPassword)
 	})
 }
+
+func TestAuthService_APIKeys(t *testing.T) {
+	t.Run("created key validates and is only stored hashed", func(t *testing.T) {
+		store := &mockUserStore{user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+
+		raw, key, err := svc.CreateAPIKey(1, "  ci  ")
+		require.NoError(t, err)
+		assert.Equal(t, "ci", key.Label)
+		assert.True(t, strings.HasPrefix(raw, "sharm_1_"))
+		assert.NotContains(t, key.KeyHash, strings.TrimPrefix(raw, "sharm_1_"))
+
+		user, err := svc.ValidateAPIKey(raw)
+		require.NoError(t, err)
+		assert.Equal(t, "admin", user.Username)
+		assert.Equal(t, []int64{1}, store.touched)
+	})
+
+	t.Run("rejects malformed, unknown, and tampered keys", func(t *testing.T) {
+		store := &mockUserStore{user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		raw, _, err := svc.CreateAPIKey(1, "ci")
+		require.NoError(t, err)
+
+		for _, candidate := range []string{"", "garbage", "sharm_x_abc", "sharm_2_abc", raw + "x"} {
+			_, err := svc.ValidateAPIKey(candidate)
+			assert.ErrorIs(t, err, ErrInvalidAPIKey, candidate)
+		}
+	})
+
+	t.Run("revoked key no longer validates", func(t *testing.T) {
+		store := &mockUserStore{user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		raw, key, err := svc.CreateAPIKey(1, "ci")
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RevokeAPIKey(1, key.ID))
+		_, err = svc.ValidateAPIKey(raw)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("rejects empty label", func(t *testing.T) {
+		svc := NewAuthService(&mockUserStore{}, "test-secret-key", DefaultTokenTTL)
+		_, _, err := svc.CreateAPIKey(1, "   ")
+		assert.ErrorIs(t, err, ErrInvalidLabel)
+	})
+}
+
+func TestAuthService_AddUser(t *testing.T) {
+	admin := &domain.User{ID: 1, Username: "admin", IsAdmin: true}
+
+	t.Run("admin adds a non-admin user", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, getUserErr: domain.ErrNotFound}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		err := svc.AddUser(admin, "alice", "P@ssw0rd123")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", store.user.Username)
+		assert.False(t, store.user.IsAdmin)
+	})
+
+	t.Run("non-admin is refused", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, getUserErr: domain.ErrNotFound}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		err := svc.AddUser(&domain.User{ID: 2, Username: "bob"}, "alice", "P@ssw0rd123")
+		assert.ErrorIs(t, err, ErrNotAdmin)
+	})
+
+	t.Run("existing username is refused", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, user: &domain.User{ID: 2, Username: "alice"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		err := svc.AddUser(admin, "alice", "P@ssw0rd123")
+		assert.ErrorIs(t, err, ErrUserExists)
+	})
+
+	t.Run("weak password is refused", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, getUserErr: domain.ErrNotFound}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		err := svc.AddUser(admin, "alice", "short")
+		assert.ErrorIs(t, err, ErrWeakPassword)
+	})
+}
+
+func TestAuthService_ResetPasswordWithCode(t *testing.T) {
+	newStore := func() *mockUserStore {
+		return &mockUserStore{hasUser: true, user: &domain.User{ID: 1, Username: "admin", IsAdmin: true}}
+	}
+
+	t.Run("valid code resets password and is consumed", func(t *testing.T) {
+		store := newStore()
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		code, err := svc.GenerateRecoveryCode(1)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[A-Z2-7]{4}(-[A-Z2-7]{4})+$`, code)
+		assert.NotEqual(t, code, store.user.RecoveryHash)
+
+		// Codes are accepted regardless of case and separators.
+		loose := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+		require.NoError(t, svc.ResetPasswordWithCode(loose, "N3w-P@ssword"))
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(store.user.PasswordHash), []byte("N3w-P@ssword")))
+		assert.Empty(t, store.user.RecoveryHash)
+
+		err = svc.ResetPasswordWithCode(code, "0ther-P@ssword")
+		assert.ErrorIs(t, err, ErrInvalidRecovery)
+	})
+
+	t.Run("wrong code is refused", func(t *testing.T) {
+		store := newStore()
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		_, err := svc.GenerateRecoveryCode(1)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, svc.ResetPasswordWithCode("AAAA-BBBB-CCCC-DDDD", "N3w-P@ssword"), ErrInvalidRecovery)
+		assert.ErrorIs(t, svc.ResetPasswordWithCode("", "N3w-P@ssword"), ErrInvalidRecovery)
+	})
+
+	t.Run("weak password keeps the code", func(t *testing.T) {
+		store := newStore()
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		code, err := svc.GenerateRecoveryCode(1)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, svc.ResetPasswordWithCode(code, "short"), ErrWeakPassword)
+		assert.NotEmpty(t, store.user.RecoveryHash)
+	})
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// blobKey is the key a file under dataDir is stored at in a blob store.
+func blobKey(dataDir, path string) (string, error) {
+	rel, err := filepath.Rel(dataDir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the data directory", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// putBlobs stores finished files in blobs. A nil blob store keeps files on
+// local disk only.
+func putBlobs(ctx context.Context, blobs port.BlobStore, dataDir string, paths ...string) error {
+	if blobs == nil {
+		return nil
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		key, err := blobKey(dataDir, path)
+		if err != nil {
+			return err
+		}
+		if err := blobs.Put(ctx, key, path); err != nil {
+			return fmt.Errorf("store %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// deleteBlobs removes files from blobs, logging failures: the media is gone
+// either way and an orphaned blob only costs space.
+func deleteBlobs(ctx context.Context, blobs port.BlobStore, dataDir string, paths ...string) {
+	if blobs == nil {
+		return
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		key, err := blobKey(dataDir, path)
+		if err == nil {
+			err = blobs.Delete(ctx, key)
+		}
+		if err != nil {
+			logger.Warn.Printf("failed to delete blob for %s: %v", path, err)
+		}
+	}
+}
+
+// fetchBlob restores the file at path from blobs, for when the local copy
+// is gone, e.g. pruned to save space.
+func fetchBlob(ctx context.Context, blobs port.BlobStore, dataDir, path string) error {
+	if blobs == nil {
+		return errors.New("no blob store")
+	}
+	key, err := blobKey(dataDir, path)
+	if err != nil {
+		return err
+	}
+	r, err := blobs.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("fetch %s: %w", key, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	tmpPath := path + ".part"
+	out, err := os.Create(tmpPath) //nolint:gosec // path comes from the media store
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("fetch %s: %w", key, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// BlobURL returns a direct URL serving the file at path when the blob
+// store hands them out, so viewers download it without going through Sharm.
+// The URL answers with contentType and disposition, when set.
+func (s *MediaService) BlobURL(ctx context.Context, path, contentType, disposition string) (string, bool) {
+	if s.blobs == nil {
+		return "", false
+	}
+	key, err := blobKey(s.dataDir, path)
+	if err != nil {
+		return "", false
+	}
+	url, err := s.blobs.Presign(ctx, key, contentType, disposition)
+	if err != nil {
+		if !errors.Is(err, port.ErrPresignUnsupported) {
+			logger.Error.Printf("failed to presign %s: %v", key, err)
+		}
+		return "", false
+	}
+	return url, true
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port"
+	"github.com/bnema/sharm/internal/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memBlobs is an object store keeping blobs in memory.
+type memBlobs struct {
+	blobs   map[string]string
+	presign bool
+}
+
+func (m *memBlobs) Put(ctx context.Context, key, localPath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	m.blobs[key] = string(data)
+	return nil
+}
+
+func (m *memBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func (m *memBlobs) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+func (m *memBlobs) Presign(ctx context.Context, key, contentType, disposition string) (string, error) {
+	if !m.presign {
+		return "", port.ErrPresignUnsupported
+	}
+	return "https://bucket.example.com/" + key + "?X-Amz-Signature=abc", nil
+}
+
+func TestBlobKey(t *testing.T) {
+	key, err := blobKey("/data", "/data/converted/AB12CD34_h264.mp4")
+	require.NoError(t, err)
+	assert.Equal(t, "converted/AB12CD34_h264.mp4", key)
+
+	_, err = blobKey("/data", "/etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestMediaService_BlobURL(t *testing.T) {
+	dataDir := t.TempDir()
+	blobs := &memBlobs{blobs: map[string]string{}}
+	svc := NewMediaService(nil, nil, nil, NewEventBus(0, 0), dataDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, Blobs: blobs, ForceH264: true})
+
+	_, ok := svc.BlobURL(context.Background(), filepath.Join(dataDir, "uploads", "AB12CD34_clip.mp4"), "", "")
+	assert.False(t, ok, "store without presigning")
+
+	blobs.presign = true
+	url, ok := svc.BlobURL(context.Background(), filepath.Join(dataDir, "uploads", "AB12CD34_clip.mp4"), "", "")
+	assert.True(t, ok)
+	assert.Equal(t, "https://bucket.example.com/uploads/AB12CD34_clip.mp4?X-Amz-Signature=abc", url)
+
+	_, ok = svc.BlobURL(context.Background(), "/elsewhere/clip.mp4", "", "")
+	assert.False(t, ok)
+}
+
+func TestMediaService_Delete_RemovesBlobs(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	dataDir := t.TempDir()
+	blobs := &memBlobs{blobs: map[string]string{
+		"uploads/AB12CD34_clip.mp4":    "original",
+		"converted/AB12CD34_h264.mp4":  "h264",
+		"converted/AB12CD34_thumb.jpg": "thumb",
+		"converted/OTHER000_thumb.jpg": "other",
+	}}
+	svc := NewMediaService(mockStore, nil, nil, NewEventBus(0, 0), dataDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, Blobs: blobs, ForceH264: true})
+
+	media := &domain.Media{
+		ID:           "AB12CD34",
+		OriginalPath: filepath.Join(dataDir, "uploads", "AB12CD34_clip.mp4"),
+		ThumbPath:    filepath.Join(dataDir, "converted", "AB12CD34_thumb.jpg"),
+		Variants:     []domain.Variant{{Codec: domain.CodecH264, Path: filepath.Join(dataDir, "converted", "AB12CD34_h264.mp4")}},
+	}
+	mockStore.EXPECT().Get("AB12CD34").Return(media, nil).Once()
+	mockStore.EXPECT().Delete("AB12CD34").Return(nil).Once()
+
+	require.NoError(t, svc.Delete("AB12CD34"))
+	assert.Equal(t, map[string]string{"converted/OTHER000_thumb.jpg": "other"}, blobs.blobs)
+}
+
+func TestFetchBlob(t *testing.T) {
+	dataDir := t.TempDir()
+	blobs := &memBlobs{blobs: map[string]string{"uploads/AB12CD34_clip.mp4": "original"}}
+	path := filepath.Join(dataDir, "uploads", "AB12CD34_clip.mp4")
+
+	require.NoError(t, fetchBlob(context.Background(), blobs, dataDir, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+
+	assert.Error(t, fetchBlob(context.Background(), blobs, dataDir, filepath.Join(dataDir, "uploads", "missing.mp4")))
+	assert.Error(t, fetchBlob(context.Background(), nil, dataDir, path))
+}
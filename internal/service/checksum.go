@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// Checksum returns the hex SHA-256 of a media file. Stored checksums are
+// returned as-is; missing ones (legacy media) are computed from path and cached.
+func (s *MediaService) Checksum(mediaID, file, path string) (string, error) {
+	sum, err := s.store.GetChecksum(mediaID, file)
+	if err == nil {
+		return sum, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return "", fmt.Errorf("get checksum: %w", err)
+	}
+
+	sum, err = fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	if saveErr := s.store.SaveChecksum(mediaID, file, sum); saveErr != nil {
+		logger.Error.Printf("failed to cache checksum for %s/%s: %v", mediaID, file, saveErr)
+	}
+	return sum, nil
+}
+
+// saveOriginalChecksum records the checksum of a new upload so clients can
+// find it by content; an empty sum is computed from the original. Failures
+// are logged; Checksum computes it again later.
+func (s *MediaService) saveOriginalChecksum(media *domain.Media, sum string) {
+	if sum == "" {
+		var err error
+		if sum, err = fileSHA256(media.OriginalPath); err != nil {
+			logger.Error.Printf("failed to hash upload %s: %v", media.ID, err)
+			return
+		}
+	}
+	if err := s.store.SaveChecksum(media.ID, domain.ChecksumOriginal, sum); err != nil {
+		logger.Error.Printf("failed to save checksum for %s: %v", media.ID, err)
+	}
+}
+
+// FindByChecksum returns the newest live media of ownerID whose original
+// upload has the hex SHA-256 sum, or domain.ErrNotFound.
+func (s *MediaService) FindByChecksum(ownerID int64, sum string) (*domain.Media, error) {
+	id, err := s.store.FindByChecksum(ownerID, domain.ChecksumOriginal, strings.ToLower(sum))
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
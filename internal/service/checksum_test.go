@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sha256("hello")
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestMediaService_Checksum_ReturnsStored(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	mockStore.EXPECT().GetChecksum("abc", domain.ChecksumOriginal).Return("stored", nil).Once()
+
+	sum, err := service.Checksum("abc", domain.ChecksumOriginal, "/does/not/matter")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "stored", sum)
+}
+
+func TestMediaService_Checksum_ComputesAndCachesLegacy(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	path := filepath.Join(t.TempDir(), "file.mp4")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0600))
+
+	mockStore.EXPECT().GetChecksum("abc", "h264").Return("", domain.ErrNotFound).Once()
+	mockStore.EXPECT().SaveChecksum("abc", "h264", helloSHA256).Return(nil).Once()
+
+	sum, err := service.Checksum("abc", "h264", path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, helloSHA256, sum)
+}
+
+func TestMediaService_Checksum_StoreError(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	mockStore.EXPECT().GetChecksum("abc", domain.ChecksumOriginal).Return("", errors.New("db locked")).Once()
+
+	_, err := service.Checksum("abc", domain.ChecksumOriginal, "/does/not/matter")
+
+	assert.Error(t, err)
+}
+
+func TestMediaService_FindByChecksum(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	mockStore.EXPECT().FindByChecksum(int64(1), domain.ChecksumOriginal, helloSHA256).Return("abc", nil).Once()
+	mockStore.EXPECT().Get("abc").Return(&domain.Media{ID: "abc", ExpiresAt: domain.NeverExpiresAt}, nil).Once()
+
+	media, err := service.FindByChecksum(1, strings.ToUpper(helloSHA256))
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc", media.ID)
+}
+
+func TestMediaService_SaveOriginalChecksum(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	path := filepath.Join(t.TempDir(), "abc_hello.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0600))
+	mockStore.EXPECT().SaveChecksum("abc", domain.ChecksumOriginal, helloSHA256).Return(nil).Once()
+
+	service.saveOriginalChecksum(&domain.Media{ID: "abc", OriginalPath: path}, "")
+}
+
+func TestMediaService_Upload_DedupReturnsExisting(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true, DedupUploads: true})
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "upload-*.tmp")
+	require.NoError(t, err)
+	_, _ = tmpFile.WriteString("hello")
+
+	existing := &domain.Media{ID: "abc", OwnerID: 1, ExpiresAt: domain.NeverExpiresAt}
+	mockStore.EXPECT().FindByChecksum(int64(1), domain.ChecksumOriginal, helloSHA256).Return("abc", nil).Once()
+	mockStore.EXPECT().Get("abc").Return(existing, nil).Once()
+
+	media, err := service.Upload(1, "hello.txt", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc", media.ID)
+	assert.NoFileExists(t, tmpFile.Name(), "the duplicate is not kept")
+
+	// Anonymous uploads, custom links and frame rate changes never reuse
+	// existing media.
+	assert.Nil(t, service.duplicateOf(0, helloSHA256, "", time.Hour, nil, 0, nil))
+	assert.Nil(t, service.duplicateOf(1, helloSHA256, "my-link", time.Hour, nil, 0, nil))
+	assert.Nil(t, service.duplicateOf(1, helloSHA256, "", time.Hour, nil, 30, nil))
+}
+
+func TestMediaService_DuplicateOf_RequestDiffers(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		codecs    []domain.Codec
+		tags      []string
+		reused    bool
+	}{
+		{"same request", time.Hour, nil, nil, true},
+		{"shorter retention", 30 * time.Minute, nil, nil, true},
+		{"longer retention", 30 * domain.Day, nil, nil, false},
+		{"never expiring", domain.RetentionNever, nil, nil, false},
+		{"existing codec", time.Hour, []domain.Codec{domain.CodecH264}, nil, true},
+		{"auto codecs", time.Hour, []domain.Codec{domain.CodecAuto}, nil, true},
+		{"missing codec", time.Hour, []domain.Codec{domain.CodecH264, domain.CodecAV1}, nil, false},
+		{"existing tag", time.Hour, nil, []string{"Work"}, true},
+		{"new tag", time.Hour, nil, []string{"work", "holiday"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := mocks.NewMediaStoreMock(t)
+			service := NewMediaService(mockStore, nil, nil, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true, DedupUploads: true})
+
+			// Uploaded a little earlier, for two hours.
+			existing := &domain.Media{
+				ID: "abc", OwnerID: 1, Tags: []string{"work"}, ExpiresAt: time.Now().Add(90 * time.Minute),
+				Variants: []domain.Variant{{Codec: domain.CodecH264, Status: domain.VariantStatusDone}},
+			}
+			mockStore.EXPECT().FindByChecksum(int64(1), domain.ChecksumOriginal, helloSHA256).Return("abc", nil).Once()
+			mockStore.EXPECT().Get("abc").Return(existing, nil).Once()
+
+			got := service.duplicateOf(1, helloSHA256, "", tt.retention, tt.codecs, 0, tt.tags)
+
+			if tt.reused {
+				assert.Same(t, existing, got)
+			} else {
+				assert.Nil(t, got, "an upload asking for more than the existing media gives is stored anew")
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"sync/atomic"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/disk"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+)
+
+// DiskMonitor tracks free space on the data volume. When it drops below the
+// threshold, uploads are refused (read-only mode) until space is recovered.
+type DiskMonitor struct {
+	dir          string
+	minFreeBytes uint64
+	freeBytes    func(path string) (uint64, error)
+	low          atomic.Bool
+}
+
+// NewDiskMonitor watches dir. A minFreeBytes of 0 disables the check.
+func NewDiskMonitor(dir string, minFreeBytes uint64) *DiskMonitor {
+	return &DiskMonitor{
+		dir:          dir,
+		minFreeBytes: minFreeBytes,
+		freeBytes:    disk.FreeBytes,
+	}
+}
+
+// Check samples free space and updates the low-space state, logging on transitions.
+func (m *DiskMonitor) Check() {
+	if m.minFreeBytes == 0 {
+		return
+	}
+
+	free, err := m.freeBytes(m.dir)
+	if err != nil {
+		logger.Error.Printf("failed to check free space on %s: %v", m.dir, err)
+		return
+	}
+
+	low := free < m.minFreeBytes
+	if m.low.Swap(low) == low {
+		return
+	}
+	if low {
+		logger.Error.Printf("CRITICAL: free space on %s is %s (threshold %s), uploads are paused",
+			m.dir, domain.FormatSize(int64(free)), domain.FormatSize(int64(m.minFreeBytes))) //nolint:gosec // sizes fit in int64
+	} else {
+		logger.Info.Printf("free space on %s recovered to %s, uploads resumed", m.dir, domain.FormatSize(int64(free))) //nolint:gosec // sizes fit in int64
+	}
+}
+
+// Low reports whether free space was below the threshold at the last check.
+func (m *DiskMonitor) Low() bool {
+	return m.low.Load()
+}
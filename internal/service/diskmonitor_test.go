@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskMonitor_Check(t *testing.T) {
+	m := NewDiskMonitor("/data", 1000)
+	free := uint64(5000)
+	m.freeBytes = func(string) (uint64, error) { return free, nil }
+
+	m.Check()
+	assert.False(t, m.Low())
+
+	free = 500
+	m.Check()
+	assert.True(t, m.Low())
+
+	free = 2000
+	m.Check()
+	assert.False(t, m.Low())
+}
+
+func TestDiskMonitor_CheckErrorKeepsState(t *testing.T) {
+	m := NewDiskMonitor("/data", 1000)
+	m.freeBytes = func(string) (uint64, error) { return 10, nil }
+	m.Check()
+
+	m.freeBytes = func(string) (uint64, error) { return 0, errors.New("statfs failed") }
+	m.Check()
+
+	assert.True(t, m.Low())
+}
+
+func TestDiskMonitor_Disabled(t *testing.T) {
+	m := NewDiskMonitor("/data", 0)
+	m.freeBytes = func(string) (uint64, error) { return 0, nil }
+
+	m.Check()
+
+	assert.False(t, m.Low())
+}
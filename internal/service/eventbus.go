@@ -1,50 +1,122 @@
 package service
 
 import (
+	"errors"
+	"slices"
 	"sync"
+
+	"github.com/bnema/sharm/internal/infrastructure/metrics"
 )
 
+// historySize bounds how many media the bus remembers a last status for.
+const historySize = 1024
+
+// ErrTooManySubscribers is returned by Subscribe when the bus already holds
+// its maximum number of subscriptions across all media.
+var ErrTooManySubscribers = errors.New("too many event subscribers")
+
 type EventBus struct {
 	subscribers map[string][]chan Event
+	listeners   []func(mediaID string, event Event)
+	// last holds the latest "status" event per media, oldest first in order,
+	// so a subscriber that arrives just after a change still sees it.
+	last  map[string]Event
+	order []string
+	// maxPerMedia and maxTotal cap subscriptions; 0 means unlimited.
+	maxPerMedia int
+	maxTotal    int
+	total       int
 	mu          sync.RWMutex
 }
 
-func NewEventBus() *EventBus {
+// NewEventBus creates a bus allowing at most maxPerMedia subscribers per
+// media and maxTotal overall, where 0 disables a limit.
+func NewEventBus(maxPerMedia, maxTotal int) *EventBus {
 	return &EventBus{
 		subscribers: make(map[string][]chan Event),
+		last:        make(map[string]Event),
+		maxPerMedia: maxPerMedia,
+		maxTotal:    maxTotal,
 	}
 }
 
-func (eb *EventBus) Subscribe(mediaID string) chan Event {
+// Subscribe returns a channel receiving the events of mediaID. The last
+// status event published for it, if any, is delivered first.
+//
+// A media already at its subscriber limit drops its oldest subscriber,
+// whose channel is closed: that is usually a tab that reconnected. When the
+// bus is full across all media, Subscribe fails with ErrTooManySubscribers.
+func (eb *EventBus) Subscribe(mediaID string) (chan Event, error) {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
+	subs := eb.subscribers[mediaID]
+	if eb.maxPerMedia > 0 && len(subs) >= eb.maxPerMedia {
+		eb.remove(mediaID, subs[0])
+		metrics.SSESubscriptionsDropped.WithLabelValues("evicted").Inc()
+	} else if eb.maxTotal > 0 && eb.total >= eb.maxTotal {
+		metrics.SSESubscriptionsDropped.WithLabelValues("refused").Inc()
+		return nil, ErrTooManySubscribers
+	}
+
 	ch := make(chan Event, 16)
+	if event, ok := eb.last[mediaID]; ok {
+		ch <- event
+	}
 	eb.subscribers[mediaID] = append(eb.subscribers[mediaID], ch)
-	return ch
+	eb.total++
+	metrics.ActiveSSEConnections.Inc()
+	return ch, nil
 }
 
+// Unsubscribe removes and closes ch. It is a no-op if ch was evicted.
 func (eb *EventBus) Unsubscribe(mediaID string, ch chan Event) {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
+	eb.remove(mediaID, ch)
+}
+
+// remove drops ch from the subscribers of mediaID and closes it, if it is
+// still subscribed. The caller holds eb.mu.
+func (eb *EventBus) remove(mediaID string, ch chan Event) {
 	subs := eb.subscribers[mediaID]
-	for i, sub := range subs {
-		if sub == ch {
-			eb.subscribers[mediaID] = append(subs[:i], subs[i+1:]...)
-			close(ch)
-			break
-		}
+	i := slices.Index(subs, ch)
+	if i < 0 {
+		return
 	}
+	subs = slices.Delete(subs, i, i+1)
+	close(ch)
+	eb.total--
+	metrics.ActiveSSEConnections.Dec()
 
-	if len(eb.subscribers[mediaID]) == 0 {
+	if len(subs) == 0 {
 		delete(eb.subscribers, mediaID)
+	} else {
+		eb.subscribers[mediaID] = subs
 	}
 }
 
+// Listen registers fn to receive every published event, whatever the media.
+// fn runs synchronously inside Publish, so it must be fast and must not block.
+func (eb *EventBus) Listen(fn func(mediaID string, event Event)) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.listeners = append(eb.listeners, fn)
+}
+
 func (eb *EventBus) Publish(mediaID string, event Event) {
-	eb.mu.RLock()
-	defer eb.mu.RUnlock()
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if event.Type == "status" {
+		eb.remember(mediaID, event)
+	}
+
+	for _, fn := range eb.listeners {
+		fn(mediaID, event)
+	}
 
 	for _, ch := range eb.subscribers[mediaID] {
 		select {
@@ -54,3 +126,16 @@ func (eb *EventBus) Publish(mediaID string, event Event) {
 		}
 	}
 }
+
+// remember records event as the last status of mediaID, forgetting the
+// least recently updated media beyond historySize.
+func (eb *EventBus) remember(mediaID string, event Event) {
+	if _, ok := eb.last[mediaID]; ok {
+		eb.order = slices.DeleteFunc(eb.order, func(id string) bool { return id == mediaID })
+	} else if len(eb.order) >= historySize {
+		delete(eb.last, eb.order[0])
+		eb.order = eb.order[1:]
+	}
+	eb.last[mediaID] = event
+	eb.order = append(eb.order, mediaID)
+}
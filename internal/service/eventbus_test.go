@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_ListenReceivesAllMedia(t *testing.T) {
+	eb := NewEventBus(0, 0)
+	var got []string
+	eb.Listen(func(mediaID string, event Event) {
+		got = append(got, mediaID+":"+event.Type)
+	})
+
+	eb.Publish("a", Event{Type: "status"})
+	eb.Publish("b", Event{Type: EventTypeChanged})
+
+	assert.Equal(t, []string{"a:status", "b:changed"}, got)
+}
+
+func TestEventBus_SubscribeReceivesOwnMedia(t *testing.T) {
+	eb := NewEventBus(0, 0)
+	ch, err := eb.Subscribe("a")
+	require.NoError(t, err)
+	defer eb.Unsubscribe("a", ch)
+
+	eb.Publish("b", Event{Type: "status"})
+	eb.Publish("a", Event{Type: "status", Status: "done"})
+
+	event := <-ch
+	assert.Equal(t, "done", event.Status)
+	assert.Empty(t, ch)
+}
+
+func TestEventBus_SubscribeReplaysLastStatus(t *testing.T) {
+	eb := NewEventBus(0, 0)
+	eb.Publish("a", Event{Type: "status", Status: "processing"})
+	eb.Publish("a", Event{Type: "status", Status: "done"})
+	eb.Publish("a", Event{Type: EventTypeChanged})
+
+	ch, err := eb.Subscribe("a")
+	require.NoError(t, err)
+	defer eb.Unsubscribe("a", ch)
+
+	event := <-ch
+	assert.Equal(t, "done", event.Status)
+	assert.Empty(t, ch)
+}
+
+func TestEventBus_HistoryIsBounded(t *testing.T) {
+	eb := NewEventBus(0, 0)
+	for i := range historySize + 1 {
+		eb.Publish(fmt.Sprint(i), Event{Type: "status", Status: "done"})
+	}
+
+	assert.Len(t, eb.last, historySize)
+	assert.NotContains(t, eb.last, "0")
+	assert.Contains(t, eb.last, fmt.Sprint(historySize))
+}
+
+func TestEventBus_EvictsOldestSubscriberOfMedia(t *testing.T) {
+	eb := NewEventBus(2, 0)
+	first, err := eb.Subscribe("a")
+	require.NoError(t, err)
+	second, err := eb.Subscribe("a")
+	require.NoError(t, err)
+
+	third, err := eb.Subscribe("a")
+	require.NoError(t, err)
+
+	_, open := <-first
+	assert.False(t, open, "evicted channel should be closed")
+	assert.Equal(t, []chan Event{second, third}, eb.subscribers["a"])
+
+	// The evicted subscriber's own cleanup must not close anything twice.
+	eb.Unsubscribe("a", first)
+	eb.Unsubscribe("a", second)
+	eb.Unsubscribe("a", third)
+	assert.Empty(t, eb.subscribers)
+	assert.Zero(t, eb.total)
+}
+
+func TestEventBus_RefusesBeyondGlobalLimit(t *testing.T) {
+	eb := NewEventBus(0, 2)
+	a, err := eb.Subscribe("a")
+	require.NoError(t, err)
+	_, err = eb.Subscribe("b")
+	require.NoError(t, err)
+
+	_, err = eb.Subscribe("c")
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	eb.Unsubscribe("a", a)
+	_, err = eb.Subscribe("c")
+	assert.NoError(t, err)
+}
@@ -1,15 +1,20 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bnema/sharm/internal/domain"
 	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/infrastructure/metrics"
 	"github.com/bnema/sharm/internal/port"
 )
 
@@ -17,19 +22,147 @@ type MediaService struct {
 	store     port.MediaStore
 	converter port.MediaConverter
 	jobQueue  port.JobQueue
+	events    EventPublisher
+	dataDir   string
 	uploadDir string
+
+	// blobs stores finished files for serving; nil keeps them on local
+	// disk only.
+	blobs port.BlobStore
+
+	// lazyVariants encodes only the primary codec at upload time; other
+	// codecs are encoded the first time they are requested.
+	lazyVariants bool
+	variantMu    sync.Mutex
+
+	// transcodePolicy decides whether web-optimized uploads are served
+	// as-is instead of being re-encoded.
+	transcodePolicy domain.TranscodePolicy
+
+	// animationLimits rejects animated images too large to decode safely.
+	animationLimits domain.AnimationLimits
+
+	// storyboards queues a scrubbing preview for every video upload.
+	storyboards bool
+
+	// verifyUploads decodes the start of uploads served without
+	// conversion before accepting them.
+	verifyUploads bool
+
+	// defaultCodecs are encoded for uploads that ask for no codec.
+	defaultCodecs []domain.Codec
+
+	// forceH264 adds H264 to every video upload, the format chat apps and
+	// all browsers play.
+	forceH264 bool
+
+	// dedupUploads returns the uploader's existing media for a file whose
+	// content they already uploaded instead of storing it again.
+	dedupUploads bool
+
+	// maxPixels rejects uploads whose frames are larger, before ffmpeg
+	// decodes them; 0 allows any size.
+	maxPixels int64
 }
 
-func NewMediaService(store port.MediaStore, converter port.MediaConverter, jobQueue port.JobQueue, dataDir string) *MediaService {
+// MediaConfig holds the optional behaviour of a MediaService; the zero value
+// converts every upload to the codecs it asks for and keeps files on local
+// disk.
+type MediaConfig struct {
+	// Blobs stores finished files for serving; nil keeps them on local disk
+	// only.
+	Blobs port.BlobStore
+
+	// LazyVariants encodes only the primary codec at upload time; other
+	// codecs are encoded the first time they are requested.
+	LazyVariants bool
+
+	// TranscodePolicy decides whether web-optimized uploads are served
+	// as-is instead of being re-encoded.
+	TranscodePolicy domain.TranscodePolicy
+
+	// AnimationLimits rejects animated images too large to decode safely.
+	AnimationLimits domain.AnimationLimits
+
+	// Storyboards queues a scrubbing preview for every video upload.
+	Storyboards bool
+
+	// VerifyUploads decodes the start of uploads served without conversion
+	// before accepting them.
+	VerifyUploads bool
+
+	// DefaultCodecs are encoded for uploads that ask for no codec.
+	DefaultCodecs []domain.Codec
+
+	// ForceH264 adds H264 to every video upload.
+	ForceH264 bool
+
+	// DedupUploads reuses the uploader's existing media for a file whose
+	// content they already uploaded.
+	DedupUploads bool
+
+	// MaxPixels rejects uploads whose frames are larger; 0 allows any size.
+	MaxPixels int64
+}
+
+func NewMediaService(
+	store port.MediaStore,
+	converter port.MediaConverter,
+	jobQueue port.JobQueue,
+	events EventPublisher,
+	dataDir string,
+	cfg MediaConfig,
+) *MediaService {
 	return &MediaService{
-		store:     store,
-		converter: converter,
-		jobQueue:  jobQueue,
-		uploadDir: filepath.Join(dataDir, "uploads"),
+		store:           store,
+		converter:       converter,
+		jobQueue:        jobQueue,
+		events:          events,
+		dataDir:         dataDir,
+		uploadDir:       filepath.Join(dataDir, "uploads"),
+		lazyVariants:    cfg.LazyVariants,
+		transcodePolicy: cfg.TranscodePolicy,
+		animationLimits: cfg.AnimationLimits,
+		storyboards:     cfg.Storyboards,
+		blobs:           cfg.Blobs,
+		verifyUploads:   cfg.VerifyUploads,
+		defaultCodecs:   cfg.DefaultCodecs,
+		forceH264:       cfg.ForceH264,
+		dedupUploads:    cfg.DedupUploads,
+		maxPixels:       cfg.MaxPixels,
 	}
 }
 
-func (s *MediaService) Upload(filename string, file *os.File, retentionDays int, mediaType domain.MediaType, codecs []domain.Codec, fps int) (*domain.Media, error) {
+func (s *MediaService) Upload(
+	ownerID int64,
+	filename string,
+	file *os.File,
+	retention time.Duration,
+	mediaType domain.MediaType,
+	codecs []domain.Codec,
+	fps int,
+	tags []string,
+	slug string,
+) (*domain.Media, error) {
+	if slug != "" {
+		var err error
+		if slug, err = s.claimableSlug(slug); err != nil {
+			return nil, err
+		}
+	}
+
+	// Hash before storing anything, so an identical earlier upload can
+	// stand in for this one. A failure only skips the checksum.
+	sum, hashErr := fileSHA256(file.Name())
+	if hashErr != nil {
+		logger.Error.Printf("failed to hash upload %s: %v", logger.SanitizeForLog(filename), hashErr)
+	}
+	if existing := s.duplicateOf(ownerID, sum, slug, retention, codecs, fps, tags); existing != nil {
+		_ = os.Remove(file.Name())
+		logger.Info.Printf("upload %s is identical to %s, reusing it", logger.SanitizeForLog(filename), existing.ID)
+		return existing, nil
+	}
+
 	if err := os.MkdirAll(s.uploadDir, 0750); err != nil {
 		logger.Error.Printf("failed to create upload directory: %v", err)
 		return nil, fmt.Errorf("failed to create upload directory: %w", err)
@@ -51,7 +184,11 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 		}
 	}
 
-	media := domain.NewMedia(mediaType, filename, uploadPath, retentionDays)
+	media := domain.NewMedia(mediaType, filename, uploadPath, retention)
+	media.OwnerID = ownerID
+	if slug != "" {
+		media.ID = slug
+	}
 
 	finalUploadPath := filepath.Join(s.uploadDir, fmt.Sprintf("%s_%s", media.ID, filepath.Base(filename)))
 	if err := os.Rename(uploadPath, finalUploadPath); err != nil {
@@ -60,6 +197,7 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 		return nil, fmt.Errorf("failed to finalize upload: %w", err)
 	}
 	media.OriginalPath = finalUploadPath
+	media.Tags = SanitizeTags(tags)
 
 	probeResult, _ := s.converter.Probe(finalUploadPath)
 	if probeResult != nil {
@@ -73,13 +211,119 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 		media.Height = height
 	}
 
+	// The probe only reads headers; thumbnails and conversions decode the
+	// frames, so a file claiming a huge resolution is refused first.
+	if err := probeResult.CheckPixels(s.maxPixels); err != nil {
+		_ = os.Remove(finalUploadPath)
+		logger.Warn.Printf("rejected upload %s: %v", media.ID, err)
+		return nil, err
+	}
+
+	// The extension can lie: a video renamed to .mp3 must still be converted
+	// and served as video, so the probed streams have the last word.
+	if mediaType != domain.MediaTypeImage {
+		if actual, ok := probeResult.StreamMediaType(); ok && actual != mediaType {
+			logger.Warn.Printf("upload %s: %s has %s content, treating it as %s",
+				media.ID, logger.SanitizeForLog(filename), actual, actual)
+			mediaType = actual
+			media.Type = actual
+			// Codecs were picked for the wrong type; fall back to auto
+			// selection when none of them fit the real one.
+			supported := slices.DeleteFunc(slices.Clone(codecs), func(c domain.Codec) bool {
+				return c != domain.CodecAuto && !actual.SupportsCodec(c)
+			})
+			if len(supported) == 0 && len(codecs) > 0 {
+				supported = []domain.Codec{domain.CodecAuto}
+			}
+			codecs = supported
+		}
+	}
+
+	if mediaType != domain.MediaTypeImage && !probeResult.IsDecodable() {
+		_ = os.Remove(finalUploadPath)
+		logger.Warn.Printf("rejected upload %s: %s is empty or corrupt", media.ID, logger.SanitizeForLog(filename))
+		return nil, domain.ErrUndecodable
+	}
+
+	if mediaType == domain.MediaTypeImage {
+		if err := s.animationLimits.Check(probeResult); err != nil {
+			_ = os.Remove(finalUploadPath)
+			logger.Warn.Printf("rejected upload %s: %v", media.ID, err)
+			return nil, err
+		}
+	}
+
+	// Animated GIFs become a looping video, far smaller and smoother than
+	// the GIF; still GIFs stay images.
+	fromGIF := mediaType == domain.MediaTypeImage && probeResult.IsAnimatedGIF()
+	if fromGIF {
+		logger.Info.Printf("upload %s is an animated GIF, converting to video", media.ID)
+		mediaType = domain.MediaTypeVideo
+		media.Type = domain.MediaTypeVideo
+	}
+
+	var passthrough bool
+	if mediaType != domain.MediaTypeImage {
+		if len(codecs) == 0 {
+			codecs = slices.DeleteFunc(slices.Clone(s.defaultCodecs), func(c domain.Codec) bool {
+				return c != domain.CodecAuto && !mediaType.SupportsCodec(c)
+			})
+		}
+		if slices.Contains(codecs, domain.CodecAuto) {
+			codecs = domain.AutoCodecs(mediaType, probeResult)
+			logger.Info.Printf("auto codec selection for %s: %v", media.ID, codecs)
+		}
+
+		// Ensure H264 is included for video uploads (Discord/web compat).
+		// A GIF has no video to serve as uploaded, so it always gets one.
+		if (s.forceH264 || fromGIF) && mediaType == domain.MediaTypeVideo && !slices.Contains(codecs, domain.CodecH264) {
+			codecs = append(codecs, domain.CodecH264)
+		}
+
+		if s.lazyVariants && len(codecs) > 1 {
+			codecs = primaryCodecs(mediaType, codecs)
+		}
+
+		// With a single codec the original can be served directly; otherwise
+		// the worker passes the H264 variant through and encodes the rest.
+		passthrough = len(codecs) == 1 && s.transcodePolicy.Passthrough(mediaType, codecs[0], fps, probeResult)
+	}
+
+	// Files served as uploaded never go through an encode that would fail
+	// on them, so a truncated one would only break in viewers' players.
+	if s.verifyUploads && (mediaType == domain.MediaTypeImage || passthrough || len(codecs) == 0) {
+		if err := s.converter.Verify(context.Background(), finalUploadPath); err != nil {
+			_ = os.Remove(finalUploadPath)
+			logger.Warn.Printf("rejected upload %s: %s does not decode: %v", media.ID, logger.SanitizeForLog(filename), err)
+			return nil, domain.ErrUndecodable
+		}
+	}
+
+	if err := putBlobs(context.Background(), s.blobs, s.dataDir, finalUploadPath); err != nil {
+		_ = os.Remove(finalUploadPath)
+		logger.Error.Printf("failed to store upload %s: %v", media.ID, err)
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
 	if err := s.store.Save(media); err != nil {
-		_ = os.Remove(uploadPath)
+		_ = os.Remove(finalUploadPath)
+		deleteBlobs(context.Background(), s.blobs, s.dataDir, finalUploadPath)
+		// Another upload may have claimed the slug since it was checked.
+		if slug != "" {
+			if _, getErr := s.store.Get(slug); getErr == nil {
+				return nil, domain.ErrSlugTaken
+			}
+		}
 		logger.Error.Printf("failed to save media metadata %s: %v", media.ID, err)
 		return nil, fmt.Errorf("failed to save media metadata: %w", err)
 	}
 
-	logger.Info.Printf("media uploaded: id=%s, type=%s, filename=%s, retention=%d days, codecs=%v", media.ID, mediaType, filename, retentionDays, codecs)
+	s.saveOriginalChecksum(media, sum)
+
+	logger.Info.Printf("media uploaded: id=%s, type=%s, filename=%s, retention=%s, codecs=%v, tags=%v",
+		media.ID, mediaType, filename, domain.FormatRetention(retention), codecs, media.Tags)
+	metrics.UploadsTotal.WithLabelValues(string(mediaType)).Inc()
+	defer s.publishChanged(media.ID)
 
 	if mediaType == domain.MediaTypeImage {
 		fileInfo, _ := os.Stat(finalUploadPath)
@@ -94,9 +338,11 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 		return media, nil
 	}
 
-	// Ensure H264 is always included for video uploads (Discord/web compat)
-	if mediaType == domain.MediaTypeVideo && !slices.Contains(codecs, domain.CodecH264) {
-		codecs = append(codecs, domain.CodecH264)
+	s.queueStoryboard(media)
+
+	if passthrough {
+		logger.Info.Printf("upload %s is already web-optimized, skipping conversion", media.ID)
+		return s.markOriginalDone(media, codecs[0])
 	}
 
 	if len(codecs) == 0 {
@@ -111,7 +357,7 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 		}
 
 		if mediaType == domain.MediaTypeVideo && s.jobQueue != nil {
-			if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeThumbnail, "", 0); err != nil {
+			if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeThumbnail, "", 0, domain.JobPriorityHigh); err != nil {
 				logger.Error.Printf("failed to enqueue thumbnail job for %s: %v", media.ID, err)
 			}
 		}
@@ -126,12 +372,8 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 				Codec:   codec,
 				Status:  domain.VariantStatusPending,
 			}
-			if err := s.store.SaveVariant(v); err != nil {
-				logger.Error.Printf("failed to save variant for %s codec %s: %v", media.ID, codec, err)
-				continue
-			}
-			if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeConvert, codec, fps); err != nil {
-				logger.Error.Printf("failed to enqueue convert job for %s codec %s: %v", media.ID, codec, err)
+			if _, err := s.jobQueue.EnqueueVariant(v, fps); err != nil {
+				logger.Error.Printf("failed to queue variant for %s codec %s: %v", media.ID, codec, err)
 			}
 		}
 	}
@@ -139,6 +381,132 @@ func (s *MediaService) Upload(filename string, file *os.File, retentionDays int,
 	return media, nil
 }
 
+// duplicateOf returns the live media of ownerID whose original has the hex
+// SHA-256 sum, when deduplication is on and it already gives what the new
+// upload asks for: it stays at least as long, has the requested codecs and
+// carries the tags. Uploads asking for a custom link or a frame rate always
+// create new media, and anonymous uploads are never matched: that would
+// hand visitors the links of each other's uploads.
+func (s *MediaService) duplicateOf(ownerID int64, sum, slug string, retention time.Duration, codecs []domain.Codec, fps int, tags []string) *domain.Media {
+	if !s.dedupUploads || sum == "" || slug != "" || fps != 0 || ownerID == 0 {
+		return nil
+	}
+	existing, err := s.FindByChecksum(ownerID, sum)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) && !errors.Is(err, domain.ErrExpired) {
+			logger.Error.Printf("failed to look up duplicate upload: %v", err)
+		}
+		return nil
+	}
+
+	if !existing.NeverExpires() && (retention <= domain.RetentionNever || existing.ExpiresAt.Before(time.Now().Add(retention))) {
+		return nil
+	}
+	for _, codec := range codecs {
+		if codec != domain.CodecAuto && existing.VariantByCodec(codec) == nil {
+			return nil
+		}
+	}
+	for _, tag := range SanitizeTags(tags) {
+		if !slices.Contains(existing.Tags, tag) {
+			return nil
+		}
+	}
+	return existing
+}
+
+// claimableSlug normalizes a custom slug and checks no media uses it yet.
+func (s *MediaService) claimableSlug(slug string) (string, error) {
+	slug, err := domain.NormalizeSlug(slug)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.store.Get(slug)
+	switch {
+	case err == nil:
+		return "", domain.ErrSlugTaken
+	case !errors.Is(err, domain.ErrNotFound):
+		logger.Error.Printf("failed to look up slug %s: %v", slug, err)
+		return "", fmt.Errorf("failed to look up slug: %w", err)
+	}
+	return slug, nil
+}
+
+// queueStoryboard queues the scrubbing preview of a video upload when
+// storyboards are enabled. Animations loop without controls, so they get none.
+func (s *MediaService) queueStoryboard(media *domain.Media) {
+	if !s.storyboards || s.jobQueue == nil || media.Type != domain.MediaTypeVideo || media.IsAnimation() {
+		return
+	}
+	if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeStoryboard, "", 0, domain.JobPriorityNormal); err != nil {
+		logger.Error.Printf("failed to enqueue storyboard job for %s: %v", media.ID, err)
+	}
+}
+
+// markOriginalDone serves the original file as the converted output and
+// queues a thumbnail, skipping conversion.
+func (s *MediaService) markOriginalDone(media *domain.Media, codec domain.Codec) (*domain.Media, error) {
+	var fileSize int64
+	if fileInfo, err := os.Stat(media.OriginalPath); err == nil {
+		fileSize = fileInfo.Size()
+	}
+	media.MarkAsDone(media.OriginalPath, codec, media.Width, media.Height, "", fileSize)
+	if err := s.store.UpdateDone(media); err != nil {
+		logger.Error.Printf("failed to update media as done: %v", err)
+	}
+
+	if s.jobQueue != nil {
+		if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeThumbnail, "", 0, domain.JobPriorityHigh); err != nil {
+			logger.Error.Printf("failed to enqueue thumbnail job for %s: %v", media.ID, err)
+		}
+	}
+	return media, nil
+}
+
+// primaryCodecs picks the codecs encoded upfront in lazy mode: H264 for video
+// (the web-compatible default), otherwise the first selected codec.
+func primaryCodecs(mediaType domain.MediaType, codecs []domain.Codec) []domain.Codec {
+	if mediaType == domain.MediaTypeVideo && slices.Contains(codecs, domain.CodecH264) {
+		return []domain.Codec{domain.CodecH264}
+	}
+	return codecs[:1]
+}
+
+// RequestVariant returns the media's variant for codec. In lazy mode a missing
+// variant is created and queued for encoding; callers should treat a pending
+// variant as "not ready yet". Returns domain.ErrNotFound when no variant exists
+// and none can be created.
+func (s *MediaService) RequestVariant(media *domain.Media, codec domain.Codec) (*domain.Variant, error) {
+	if v := media.VariantByCodec(codec); v != nil {
+		return v, nil
+	}
+	if !s.lazyVariants || s.jobQueue == nil || media.OriginalPath == "" || !media.Type.SupportsCodec(codec) {
+		return nil, domain.ErrNotFound
+	}
+
+	// Serialize so concurrent first requests don't queue the same encode twice
+	s.variantMu.Lock()
+	defer s.variantMu.Unlock()
+
+	if v, err := s.store.GetVariantByMediaAndCodec(media.ID, codec); err == nil {
+		return v, nil
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("get variant: %w", err)
+	}
+
+	v := &domain.Variant{
+		MediaID: media.ID,
+		Codec:   codec,
+		Status:  domain.VariantStatusPending,
+	}
+	if _, err := s.jobQueue.EnqueueVariant(v, 0); err != nil {
+		return nil, fmt.Errorf("queue variant: %w", err)
+	}
+	logger.Info.Printf("lazy variant queued: id=%s, codec=%s", media.ID, codec)
+	s.publishChanged(media.ID)
+	return v, nil
+}
+
 func (s *MediaService) Get(id string) (*domain.Media, error) {
 	media, err := s.store.Get(id)
 	if err != nil {
@@ -152,8 +520,55 @@ func (s *MediaService) Get(id string) (*domain.Media, error) {
 	return media, nil
 }
 
-func (s *MediaService) ListAll() ([]*domain.Media, error) {
-	return s.store.ListAll()
+// Lookup returns the media even when it has expired, for requests that
+// manage it rather than serve it.
+func (s *MediaService) Lookup(id string) (*domain.Media, error) {
+	return s.store.Get(id)
+}
+
+func (s *MediaService) ListAll(ownerID int64) ([]*domain.Media, error) {
+	return s.store.ListAll(ownerID)
+}
+
+// ListPaged returns one page of the owner's media in the given order plus
+// their total number of media.
+func (s *MediaService) ListPaged(ownerID int64, sort domain.SortBy, limit, offset int) ([]*domain.Media, int, error) {
+	return s.store.ListPaged(ownerID, sort, limit, offset)
+}
+
+// ListByTag returns the owner's media carrying the given tag. The tag is
+// sanitized the same way as on upload, so "Vacation " matches "vacation".
+func (s *MediaService) ListByTag(ownerID int64, tag string) ([]*domain.Media, error) {
+	tags := SanitizeTags([]string{tag})
+	if len(tags) == 0 {
+		return s.store.ListAll(ownerID)
+	}
+	return s.store.ListByTag(ownerID, tags[0])
+}
+
+// Search matches the owner's media by filename or tag. An empty query lists
+// everything they own.
+func (s *MediaService) Search(ownerID int64, query string) ([]*domain.Media, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return s.store.ListAll(ownerID)
+	}
+	return s.store.Search(ownerID, query)
+}
+
+// ListByStatus returns the owner's media in the given status, newest first.
+func (s *MediaService) ListByStatus(ownerID int64, status domain.MediaStatus) ([]*domain.Media, error) {
+	return s.store.ListOwnedByStatus(ownerID, status)
+}
+
+// Stats reports storage usage across all media and variants.
+func (s *MediaService) Stats() (domain.StorageStats, error) {
+	return s.store.Stats()
+}
+
+// OwnerStats reports storage usage of the owner's media and variants.
+func (s *MediaService) OwnerStats(ownerID int64) (domain.StorageStats, error) {
+	return s.store.OwnerStats(ownerID)
 }
 
 func (s *MediaService) Delete(id string) error {
@@ -162,25 +577,74 @@ func (s *MediaService) Delete(id string) error {
 		return err
 	}
 
-	// Remove variant files
-	for _, v := range media.Variants {
-		if v.Path != "" {
-			_ = os.Remove(v.Path)
-		}
+	s.removeFiles(media)
+
+	if err := s.store.Delete(id); err != nil {
+		return err
 	}
+	s.publishChanged(id)
+	return nil
+}
 
-	// Remove files from disk
-	if media.OriginalPath != "" {
-		_ = os.Remove(media.OriginalPath)
+// DeleteMany deletes the media among ids that ownerID owns and returns how
+// many were deleted, with the reason for each ID that was not: unknown IDs
+// and media of other users get domain.ErrNotFound. Rows are removed in one
+// transaction before any file, so a failed transaction leaves all in place.
+func (s *MediaService) DeleteMany(ownerID int64, ids []string) (int, map[string]error) {
+	failed := make(map[string]error)
+	var medias []*domain.Media
+	var mediaIDs []string
+	for _, id := range ids {
+		if slices.Contains(mediaIDs, id) {
+			continue
+		}
+		media, err := s.store.Get(id)
+		if err == nil && media.OwnerID != ownerID {
+			err = domain.ErrNotFound
+		}
+		if err != nil {
+			failed[id] = err
+			continue
+		}
+		medias = append(medias, media)
+		mediaIDs = append(mediaIDs, id)
 	}
-	if media.ConvertedPath != "" {
-		_ = os.Remove(media.ConvertedPath)
+	if len(medias) == 0 {
+		return 0, failed
 	}
-	if media.ThumbPath != "" {
-		_ = os.Remove(media.ThumbPath)
+
+	if err := s.store.DeleteMany(mediaIDs); err != nil {
+		logger.Error.Printf("failed to delete %d media: %v", len(mediaIDs), err)
+		for _, id := range mediaIDs {
+			failed[id] = fmt.Errorf("failed to delete media: %w", err)
+		}
+		return 0, failed
 	}
 
-	return s.store.Delete(id)
+	for _, media := range medias {
+		s.removeFiles(media)
+		s.publishChanged(media.ID)
+	}
+	logger.Info.Printf("bulk deleted %d media", len(medias))
+	return len(medias), failed
+}
+
+// removeFiles deletes the original, converted, variant, thumbnail and
+// storyboard files of media, and their blobs, ignoring files that are
+// already gone.
+func (s *MediaService) removeFiles(media *domain.Media) {
+	paths := []string{media.OriginalPath, media.ConvertedPath, media.ThumbPath, media.StoryboardPath, media.StoryboardVTTPath}
+	for _, v := range media.Variants {
+		paths = append(paths, v.Path)
+	}
+	// Passthrough media serve their original as the converted file.
+	paths = slices.Compact(slices.Sorted(slices.Values(paths)))
+	for _, path := range paths {
+		if path != "" {
+			_ = os.Remove(path)
+		}
+	}
+	deleteBlobs(context.Background(), s.blobs, s.dataDir, paths...)
 }
 
 func (s *MediaService) Cleanup() error {
@@ -190,24 +654,226 @@ func (s *MediaService) Cleanup() error {
 	}
 
 	for _, media := range expired {
-		for _, v := range media.Variants {
-			if v.Path != "" {
-				_ = os.Remove(v.Path)
-			}
-		}
-		_ = os.Remove(media.OriginalPath)
-		_ = os.Remove(media.ConvertedPath)
-		_ = os.Remove(media.ThumbPath)
-		_ = s.store.Delete(media.ID)
+		s.purge(media)
 	}
 
 	return nil
 }
 
+// CleanupFailed deletes media whose conversion failed more than olderThan
+// ago and returns how many were removed.
+func (s *MediaService) CleanupFailed(olderThan time.Duration) (int, error) {
+	failed, err := s.store.ListFailedOlderThan(olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("list failed media: %w", err)
+	}
+
+	for _, media := range failed {
+		s.purge(media)
+	}
+	return len(failed), nil
+}
+
+// purge removes a media's files and record, ignoring files already gone.
+func (s *MediaService) purge(media *domain.Media) {
+	s.removeFiles(media)
+	_ = s.store.Delete(media.ID)
+	s.publishChanged(media.ID)
+}
+
+// publishChanged tells listeners (such as the dashboard cache) that media changed.
+func (s *MediaService) publishChanged(mediaID string) {
+	s.events.Publish(mediaID, Event{Type: EventTypeChanged})
+}
+
 func (s *MediaService) ProbeFile(filePath string) (*domain.ProbeResult, error) {
 	return s.converter.Probe(filePath)
 }
 
+// cancelledMessage is the error recorded on media and variants whose
+// conversion was cancelled.
+const cancelledMessage = "cancelled"
+
+// Cancel stops the conversions of media still waiting or running. Their
+// jobs are marked cancelled and their variants failed, so they can be
+// reconverted later; media left without any finished variant fails too.
+// Running conversions are told to stop through an EventTypeCancelled event,
+// and remove their partial output. domain.ErrNotCancellable is returned
+// when nothing is in flight.
+func (s *MediaService) Cancel(media *domain.Media) error {
+	inFlight := media.Status == domain.MediaStatusPending || media.Status == domain.MediaStatusProcessing
+	var variants []domain.Variant
+	for _, v := range media.Variants {
+		if v.Status == domain.VariantStatusPending || v.Status == domain.VariantStatusProcessing {
+			variants = append(variants, v)
+		}
+	}
+	if !inFlight && len(variants) == 0 {
+		return domain.ErrNotCancellable
+	}
+
+	cancelled, err := s.jobQueue.CancelByMedia(media.ID)
+	if err != nil {
+		return fmt.Errorf("cancel jobs: %w", err)
+	}
+	for _, v := range variants {
+		if err := s.store.UpdateVariantStatus(v.ID, domain.VariantStatusFailed, cancelledMessage); err != nil {
+			return fmt.Errorf("cancel %s: %w", v.Codec, err)
+		}
+	}
+
+	if inFlight {
+		media, err = s.store.Get(media.ID)
+		if err != nil {
+			return fmt.Errorf("re-fetch media: %w", err)
+		}
+		if best := media.BestVariant(); best != nil {
+			media.MarkAsDone(best.Path, best.Codec, best.Width, best.Height, media.ThumbPath, best.FileSize)
+			if err := s.store.UpdateDone(media); err != nil {
+				return fmt.Errorf("update media done: %w", err)
+			}
+			s.events.Publish(media.ID, Event{Type: "status", Status: string(domain.MediaStatusDone)})
+		} else {
+			if err := s.store.UpdateStatus(media.ID, domain.MediaStatusFailed, cancelledMessage); err != nil {
+				return fmt.Errorf("update media status: %w", err)
+			}
+			s.events.Publish(media.ID, Event{Type: "status", Status: string(domain.MediaStatusFailed), Message: cancelledMessage})
+		}
+	}
+
+	s.events.Publish(media.ID, Event{Type: EventTypeCancelled})
+	logger.Info.Printf("conversion cancelled: id=%s jobs=%d", media.ID, cancelled)
+	s.publishChanged(media.ID)
+	return nil
+}
+
+// JobLogs returns the media and the jobs run for it, oldest first, so admins
+// can see why conversions failed. Expired media are still reported.
+func (s *MediaService) JobLogs(id string) (*domain.Media, []domain.Job, error) {
+	media, err := s.store.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	jobs, err := s.jobQueue.ListByMedia(id)
+	if err != nil {
+		logger.Error.Printf("failed to list jobs for %s: %v", id, err)
+		return nil, nil, fmt.Errorf("list jobs: %w", err)
+	}
+	return media, jobs, nil
+}
+
+// Reconvert queues failed conversions of media again with the frame rate
+// they were first requested with. Failed variants go back to pending; media
+// without variants get a legacy conversion job. An original missing on disk
+// is fetched back from the blob store; domain.ErrOriginalMissing is
+// returned when it is gone from both.
+func (s *MediaService) Reconvert(media *domain.Media) error {
+	if media.OriginalPath == "" {
+		return domain.ErrOriginalMissing
+	}
+	if _, err := os.Stat(media.OriginalPath); err != nil {
+		if fetchErr := fetchBlob(context.Background(), s.blobs, s.dataDir, media.OriginalPath); fetchErr != nil {
+			return domain.ErrOriginalMissing
+		}
+	}
+
+	jobs, err := s.jobQueue.ListByMedia(media.ID)
+	if err != nil {
+		return fmt.Errorf("list jobs: %w", err)
+	}
+
+	if err := s.store.UpdateStatus(media.ID, domain.MediaStatusPending, ""); err != nil {
+		return fmt.Errorf("reset media status: %w", err)
+	}
+
+	if len(media.Variants) == 0 {
+		if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeConvert, "", lastConvertFPS(jobs, ""), domain.JobPriorityNormal); err != nil {
+			return fmt.Errorf("enqueue convert job: %w", err)
+		}
+	}
+	for _, v := range media.Variants {
+		if v.Status != domain.VariantStatusFailed {
+			continue
+		}
+		if _, err := s.jobQueue.RequeueVariant(&v, lastConvertFPS(jobs, v.Codec)); err != nil {
+			return fmt.Errorf("requeue %s: %w", v.Codec, err)
+		}
+	}
+
+	logger.Info.Printf("reconvert queued: id=%s", media.ID)
+	s.publishChanged(media.ID)
+	return nil
+}
+
+// ReconvertFailed reconverts every failed media. Media whose original is
+// gone are skipped; other errors stop the run.
+func (s *MediaService) ReconvertFailed() (requeued, skipped int, err error) {
+	err = s.store.IterateByStatus(domain.MediaStatusFailed, func(media *domain.Media) error {
+		err := s.Reconvert(media)
+		if errors.Is(err, domain.ErrOriginalMissing) {
+			skipped++
+			return nil
+		}
+		if err != nil {
+			logger.Error.Printf("failed to reconvert %s: %v", media.ID, err)
+			return fmt.Errorf("reconvert %s: %w", media.ID, err)
+		}
+		requeued++
+		return nil
+	})
+	return requeued, skipped, err
+}
+
+// RegenerateThumbnails queues a new thumbnail for every converted video, so
+// changed thumbnail settings apply to existing media. Videos whose source
+// file is gone are skipped; other errors stop the run.
+func (s *MediaService) RegenerateThumbnails() (queued, skipped int, err error) {
+	// The thumbnail job marks its media done, so media still converting or
+	// failed are left alone.
+	err = s.store.IterateByStatus(domain.MediaStatusDone, func(media *domain.Media) error {
+		if media.Type != domain.MediaTypeVideo {
+			return nil
+		}
+		if _, err := os.Stat(previewSource(media)); err != nil {
+			skipped++
+			return nil
+		}
+		if _, err := s.jobQueue.Enqueue(media.ID, domain.JobTypeThumbnail, "", 0, domain.JobPriorityNormal); err != nil {
+			logger.Error.Printf("failed to enqueue thumbnail job for %s: %v", media.ID, err)
+			return fmt.Errorf("enqueue thumbnail %s: %w", media.ID, err)
+		}
+		queued++
+		return nil
+	})
+	return queued, skipped, err
+}
+
+// lastConvertFPS returns the frame rate of the latest convert job for codec.
+func lastConvertFPS(jobs []domain.Job, codec domain.Codec) int {
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Type == domain.JobTypeConvert && jobs[i].Codec == codec {
+			return jobs[i].Fps
+		}
+	}
+	return 0
+}
+
+// maxTagLength bounds a single tag so a malformed form can't store huge values.
+const maxTagLength = 50
+
+// SanitizeTags trims and lowercases tags, dropping empties, overlong values and duplicates.
+func SanitizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength || slices.Contains(result, tag) {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return result
+}
+
 func isCrossDeviceError(err error) bool {
 	if err == nil {
 		return false
@@ -216,6 +882,9 @@ func isCrossDeviceError(err error) bool {
 		strings.Contains(err.Error(), "cross-device")
 }
 
+// copyFile copies src to dstPath for moves across filesystems. The copy is
+// written to a temporary file next to dstPath and renamed into place once
+// complete, so a crash mid-copy never leaves a truncated file at dstPath.
 func copyFile(src *os.File, dstPath string) error {
 	srcFile, err := os.Open(src.Name())
 	if err != nil {
@@ -223,24 +892,39 @@ func copyFile(src *os.File, dstPath string) error {
 	}
 	defer srcFile.Close() //nolint:errcheck
 
-	dstFile, err := os.Create(dstPath)
+	srcInfo, err := srcFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer dstFile.Close() //nolint:errcheck
-
-	if _, copyErr := io.Copy(dstFile, srcFile); copyErr != nil {
-		return fmt.Errorf("failed to copy file contents: %w", copyErr)
+		return fmt.Errorf("failed to stat source file: %w", err)
 	}
 
-	srcInfo, err := src.Stat()
+	tmpFile, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".*.part")
 	if err != nil {
-		return fmt.Errorf("failed to stat source file: %w", err)
+		return fmt.Errorf("failed to create destination file: %w", err)
 	}
+	tmpPath := tmpFile.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
 
-	if err := os.Chmod(dstPath, srcInfo.Mode()); err != nil {
+	if _, err := io.Copy(tmpFile, srcFile); err != nil {
+		return fmt.Errorf("failed to copy file contents: %w", err)
+	}
+	if err := tmpFile.Chmod(srcInfo.Mode()); err != nil {
 		return fmt.Errorf("failed to set file permissions: %w", err)
 	}
-
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("failed to flush destination file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close destination file: %w", err)
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		return fmt.Errorf("failed to move destination file into place: %w", err)
+	}
+	committed = true
 	return nil
 }
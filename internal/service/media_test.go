@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func TestMediaService_Upload_VideoNoCodecs(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
 	require.NoError(t, err)
@@ -28,6 +29,8 @@ func TestMediaService_Upload_VideoNoCodecs(t *testing.T) {
 	_, _ = tmpFile.WriteString("test content")
 
 	probeResult := &domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "10.0"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
 		RawJSON: "{}",
 	}
 	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).
@@ -37,17 +40,14 @@ func TestMediaService_Upload_VideoNoCodecs(t *testing.T) {
 	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).
 		Return(nil).
 		Once()
+	expectOriginalChecksum(mockStore)
 
 	// H264 is auto-injected for video uploads even with no codecs selected
-	mockStore.EXPECT().SaveVariant(mock.AnythingOfType("*domain.Variant")).
-		Return(nil).
-		Once()
-
-	mockJobQueue.EXPECT().Enqueue(mock.AnythingOfType("string"), domain.JobTypeConvert, domain.CodecH264, 0).
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
 		Return(&domain.Job{}, nil).
 		Once()
 
-	result, err := service.Upload("test.mp4", tmpFile, 7, domain.MediaTypeVideo, nil, 0)
+	result, err := service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -66,7 +66,7 @@ func TestMediaService_Upload_VideoWithCodecs(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
 	require.NoError(t, err)
@@ -74,6 +74,8 @@ func TestMediaService_Upload_VideoWithCodecs(t *testing.T) {
 	_, _ = tmpFile.WriteString("test content")
 
 	probeResult := &domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "10.0"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
 		RawJSON: "{}",
 	}
 	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).
@@ -83,21 +85,18 @@ func TestMediaService_Upload_VideoWithCodecs(t *testing.T) {
 	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).
 		Return(nil).
 		Once()
+	expectOriginalChecksum(mockStore)
 
-	mockStore.EXPECT().SaveVariant(mock.AnythingOfType("*domain.Variant")).
-		Return(nil).
-		Times(2)
-
-	mockJobQueue.EXPECT().Enqueue(mock.AnythingOfType("string"), domain.JobTypeConvert, domain.CodecAV1, 30).
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecAV1), 30).
 		Return(&domain.Job{}, nil).
 		Once()
 
-	mockJobQueue.EXPECT().Enqueue(mock.AnythingOfType("string"), domain.JobTypeConvert, domain.CodecH264, 30).
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 30).
 		Return(&domain.Job{}, nil).
 		Once()
 
 	codecs := []domain.Codec{domain.CodecAV1, domain.CodecH264}
-	result, err := service.Upload("test.mp4", tmpFile, 7, domain.MediaTypeVideo, codecs, 30)
+	result, err := service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, codecs, 30, nil, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -110,13 +109,13 @@ func TestMediaService_Upload_CreateDirectoryFails(t *testing.T) {
 	mockConverter := mocks.NewMediaConverterMock(t)
 	mockJobQueue := mocks.NewJobQueueMock(t)
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, "/invalid/path/that/cannot/be/created/\x00")
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), "/invalid/path/that/cannot/be/created/\x00", MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
 	require.NoError(t, err)
 	defer os.Remove(tmpFile.Name()) //nolint:errcheck
 
-	result, err := service.Upload("test.mp4", tmpFile, 7, domain.MediaTypeVideo, nil, 0)
+	result, err := service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -129,27 +128,51 @@ func TestMediaService_Upload_FileMoveFails(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
 	require.NoError(t, err)
 	_ = tmpFile.Close()
 	_ = os.Remove(tmpFile.Name())
 
-	result, err := service.Upload("test.mp4", tmpFile, 7, domain.MediaTypeVideo, nil, 0)
+	result, err := service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "failed to save upload")
 }
 
+func TestCopyFile_LeavesNoPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.Create(filepath.Join(dir, "src.mp4"))
+	require.NoError(t, err)
+	_, _ = src.WriteString("test content")
+	require.NoError(t, src.Close())
+
+	dst := filepath.Join(dir, "uploads", "dst.mp4")
+	require.NoError(t, os.Mkdir(filepath.Dir(dst), 0750))
+	require.NoError(t, copyFile(src, dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "test content", string(data))
+
+	// A copy that cannot be moved into place cleans up its temporary file.
+	require.NoError(t, os.Remove(dst))
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "taken"), 0750))
+	require.Error(t, copyFile(src, dst))
+	entries, err := os.ReadDir(filepath.Dir(dst))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dst.mp4", entries[0].Name())
+}
+
 func TestMediaService_Upload_StoreSaveFails(t *testing.T) {
 	mockStore := mocks.NewMediaStoreMock(t)
 	mockConverter := mocks.NewMediaConverterMock(t)
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
 	require.NoError(t, err)
@@ -157,6 +180,8 @@ func TestMediaService_Upload_StoreSaveFails(t *testing.T) {
 	_, _ = tmpFile.WriteString("test content")
 
 	probeResult := &domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "10.0"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
 		RawJSON: "{}",
 	}
 	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).
@@ -167,7 +192,7 @@ func TestMediaService_Upload_StoreSaveFails(t *testing.T) {
 		Return(errors.New("store save failed")).
 		Once()
 
-	result, err := service.Upload("test.mp4", tmpFile, 7, domain.MediaTypeVideo, nil, 0)
+	result, err := service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -184,9 +209,9 @@ func TestMediaService_Get_Success(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
-	media := domain.NewMedia(domain.MediaTypeVideo, "test.mp4", "/path/to/test.mp4", 7)
+	media := domain.NewMedia(domain.MediaTypeVideo, "test.mp4", "/path/to/test.mp4", 7*domain.Day)
 
 	mockStore.EXPECT().Get("media-id").
 		Return(media, nil).
@@ -205,7 +230,7 @@ func TestMediaService_Get_NotFound(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	mockStore.EXPECT().Get("media-id").
 		Return(nil, errors.New("not found")).
@@ -224,9 +249,10 @@ func TestMediaService_Get_Expired(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
-	media := domain.NewMedia(domain.MediaTypeVideo, "test.mp4", "/path/to/test.mp4", -1)
+	media := domain.NewMedia(domain.MediaTypeVideo, "test.mp4", "/path/to/test.mp4", domain.Day)
+	media.ExpiresAt = time.Now().Add(-time.Hour)
 
 	mockStore.EXPECT().Get("media-id").
 		Return(media, nil).
@@ -245,7 +271,7 @@ func TestMediaService_Cleanup_Success(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	uploadDir := filepath.Join(tempDir, "uploads")
 	convertedDir := filepath.Join(tempDir, "converted")
@@ -296,13 +322,39 @@ func TestMediaService_Cleanup_Success(t *testing.T) {
 	assert.True(t, os.IsNotExist(err), "thumbnail file should be deleted")
 }
 
+func TestMediaService_CleanupFailed(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	tempDir := t.TempDir()
+
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	originalFile := filepath.Join(tempDir, "original.mp4")
+	require.NoError(t, os.WriteFile(originalFile, []byte("original"), 0644))
+
+	mockStore.EXPECT().ListFailedOlderThan(24*time.Hour).
+		Return([]*domain.Media{{ID: "failed-media", OriginalPath: originalFile}}, nil).
+		Once()
+	mockStore.EXPECT().Delete("failed-media").
+		Return(nil).
+		Once()
+
+	removed, err := service.CleanupFailed(24 * time.Hour)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = os.Stat(originalFile)
+	assert.True(t, os.IsNotExist(err), "original file should be deleted")
+}
+
 func TestMediaService_Cleanup_NoExpiredMedia(t *testing.T) {
 	mockStore := mocks.NewMediaStoreMock(t)
 	mockConverter := mocks.NewMediaConverterMock(t)
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	mockStore.EXPECT().ListExpired().
 		Return([]*domain.Media{}, nil).
@@ -319,7 +371,7 @@ func TestMediaService_Cleanup_ContinuesOnFileDeletionErrors(t *testing.T) {
 	mockJobQueue := mocks.NewJobQueueMock(t)
 	tempDir := t.TempDir()
 
-	service := NewMediaService(mockStore, mockConverter, mockJobQueue, tempDir)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
 
 	media := &domain.Media{
 		ID:            "expired-media",
@@ -340,3 +392,701 @@ func TestMediaService_Cleanup_ContinuesOnFileDeletionErrors(t *testing.T) {
 
 	assert.NoError(t, err, "cleanup should succeed even if file deletion fails")
 }
+
+func TestSanitizeTags(t *testing.T) {
+	tags := SanitizeTags([]string{" Vacation", "work", "", "vacation", "  ", "WORK ", strings.Repeat("x", maxTagLength+1)})
+
+	assert.Equal(t, []string{"vacation", "work"}, tags)
+}
+
+func TestMediaService_ListByTag_NormalizesTag(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	expected := []*domain.Media{{ID: "abc", Tags: []string{"vacation"}}}
+	mockStore.EXPECT().ListByTag(int64(1), "vacation").Return(expected, nil).Once()
+
+	result, err := service.ListByTag(1, "  Vacation ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestMediaService_Search(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	expected := []*domain.Media{{ID: "abc", OriginalName: "holiday.mp4"}}
+	mockStore.EXPECT().Search(int64(1), "holiday").Return(expected, nil).Once()
+
+	result, err := service.Search(1, "  holiday ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestMediaService_Search_EmptyQueryListsAll(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	mockStore.EXPECT().ListAll(int64(1)).Return([]*domain.Media{}, nil).Once()
+
+	_, err := service.Search(1, "   ")
+
+	assert.NoError(t, err)
+}
+
+func TestMediaService_Upload_DefaultCodecsWithoutForcedH264(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+
+	defaults := []domain.Codec{domain.CodecAV1, domain.CodecOpus}
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, DefaultCodecs: defaults})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(&domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "10.0"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
+	}, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	// Opus does not apply to video and H264 is not forced: AV1 only
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecAV1), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	_, err = service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+
+	assert.NoError(t, err)
+}
+
+func TestMediaService_Upload_LazyVariantsEncodesOnlyH264(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{LazyVariants: true, TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(&domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "10.0"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
+	}, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	codecs := []domain.Codec{domain.CodecAV1, domain.CodecH264}
+	_, err = service.Upload(1, "test.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, codecs, 0, nil, "")
+
+	assert.NoError(t, err)
+}
+
+func TestMediaService_RequestVariant_ExistingVariant(t *testing.T) {
+	service := NewMediaService(mocks.NewMediaStoreMock(t), mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{LazyVariants: true, TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+	media := &domain.Media{
+		ID:       "abc",
+		Type:     domain.MediaTypeVideo,
+		Variants: []domain.Variant{{Codec: domain.CodecH264, Status: domain.VariantStatusDone}},
+	}
+
+	v, err := service.RequestVariant(media, domain.CodecH264)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.VariantStatusDone, v.Status)
+}
+
+func TestMediaService_RequestVariant_DisabledReturnsNotFound(t *testing.T) {
+	service := NewMediaService(mocks.NewMediaStoreMock(t), mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, OriginalPath: "/data/uploads/abc.mp4"}
+
+	_, err := service.RequestVariant(media, domain.CodecAV1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestMediaService_RequestVariant_UnsupportedCodec(t *testing.T) {
+	service := NewMediaService(mocks.NewMediaStoreMock(t), mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{LazyVariants: true, TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, OriginalPath: "/data/uploads/abc.mp4"}
+
+	_, err := service.RequestVariant(media, domain.CodecOpus)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestMediaService_RequestVariant_QueuesMissingVariant(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{LazyVariants: true, TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, OriginalPath: "/data/uploads/abc.mp4"}
+
+	mockStore.EXPECT().GetVariantByMediaAndCodec("abc", domain.CodecAV1).Return(nil, domain.ErrNotFound).Once()
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecAV1), 0).Return(&domain.Job{}, nil).Once()
+
+	v, err := service.RequestVariant(media, domain.CodecAV1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.VariantStatusPending, v.Status)
+	assert.Equal(t, domain.CodecAV1, v.Codec)
+}
+
+func TestMediaService_Stats(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	expected := domain.StorageStats{
+		TotalCount:   3,
+		ExpiredCount: 1,
+		ByStatus:     map[domain.MediaStatus]int{domain.MediaStatusDone: 2, domain.MediaStatusFailed: 1},
+		MediaBytes:   2048,
+		VariantBytes: 1024,
+	}
+	mockStore.EXPECT().Stats().Return(expected, nil).Once()
+
+	stats, err := service.Stats()
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stats)
+	assert.Equal(t, int64(3072), stats.TotalBytes())
+}
+
+func TestMediaService_Upload_SkipsWebOptimizedVideo(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyPassthrough, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+	_, _ = tmpFile.WriteString("test content")
+
+	probeResult := &domain.ProbeResult{
+		Format: domain.ProbeFormat{FormatName: "mov,mp4,m4a,3gp,3g2,mj2", Duration: "12.0"},
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p", Width: 1080, Height: 1920},
+			{CodecType: "audio", CodecName: "aac"},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockStore.EXPECT().UpdateDone(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	mockJobQueue.EXPECT().Enqueue(mock.AnythingOfType("string"), domain.JobTypeThumbnail, domain.Codec(""), 0, domain.JobPriorityHigh).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	result, err := service.Upload(1, "phone.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaStatusDone, result.Status)
+	assert.Equal(t, domain.CodecH264, result.Codec)
+	assert.Equal(t, result.OriginalPath, result.ConvertedPath)
+	assert.Equal(t, 1080, result.Width)
+}
+
+func TestMediaService_Upload_AlwaysPolicyReencodesWebOptimizedVideo(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+	_, _ = tmpFile.WriteString("test content")
+
+	probeResult := &domain.ProbeResult{
+		Format: domain.ProbeFormat{FormatName: "mov,mp4,m4a,3gp,3g2,mj2", Duration: "12.0"},
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "h264", PixFmt: "yuv420p", Width: 1080, Height: 1920},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	result, err := service.Upload(1, "phone.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaStatusPending, result.Status)
+	assert.Empty(t, result.ConvertedPath)
+}
+
+func TestMediaService_Upload_AutoCodecsFromProbe(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mkv")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+	_, _ = tmpFile.WriteString("test content")
+
+	probeResult := &domain.ProbeResult{
+		Format: domain.ProbeFormat{Duration: "4.0"},
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "hevc", PixFmt: "yuv420p10le", ColorTransfer: "smpte2084", Width: 1280, Height: 720},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecAV1), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	_, err = service.Upload(1, "hdr.mkv", tmpFile, 7*domain.Day, domain.MediaTypeVideo, []domain.Codec{domain.CodecAuto}, 0, nil, "")
+
+	require.NoError(t, err)
+}
+
+func TestMediaService_Upload_AutoServesWebAudioAsIs(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	service := NewMediaService(mockStore, mockConverter, mocks.NewJobQueueMock(t), NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp3")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+	_, _ = tmpFile.WriteString("test content")
+
+	probeResult := &domain.ProbeResult{
+		Format:  domain.ProbeFormat{FormatName: "mp3", Duration: "180.0"},
+		Streams: []domain.ProbeStream{{CodecType: "audio", CodecName: "mp3", BitRate: "320000"}},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	// No variant is queued: the MP3 is served as uploaded
+	mockStore.EXPECT().UpdateDone(mock.MatchedBy(func(m *domain.Media) bool {
+		return m.Status == domain.MediaStatusDone && m.ConvertedPath == m.OriginalPath
+	})).Return(nil).Once()
+
+	_, err = service.Upload(1, "song.mp3", tmpFile, 7*domain.Day, domain.MediaTypeAudio, []domain.Codec{domain.CodecAuto}, 0, nil, "")
+
+	require.NoError(t, err)
+}
+
+func TestMediaService_Upload_CorrectsTypeFromProbe(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp3")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	probeResult := &domain.ProbeResult{
+		Format: domain.ProbeFormat{Duration: "8.0"},
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "h264", Width: 1280, Height: 720},
+			{CodecType: "audio", CodecName: "aac"},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.MatchedBy(func(m *domain.Media) bool {
+		return m.Type == domain.MediaTypeVideo
+	})).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	result, err := service.Upload(1, "renamed.mp3", tmpFile, 7*domain.Day, domain.MediaTypeAudio, []domain.Codec{domain.CodecOpus}, 0, nil, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaTypeVideo, result.Type)
+}
+
+func TestMediaService_Upload_AnimatedGIFBecomesVideo(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	// Without FORCE_H264 or DEFAULT_CODECS, the GIF still needs a video to play
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.gif")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	probeResult := &domain.ProbeResult{
+		Format:  domain.ProbeFormat{FormatName: "gif"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "gif", Width: 320, Height: 240, NbFrames: "40"}},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+	mockStore.EXPECT().Save(mock.MatchedBy(func(m *domain.Media) bool {
+		return m.Type == domain.MediaTypeVideo
+	})).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	result, err := service.Upload(1, "dance.gif", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.MediaTypeVideo, result.Type)
+	assert.Equal(t, domain.MediaStatusPending, result.Status)
+}
+
+func TestMediaService_Upload_RejectsOversizedAnimation(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	limits := domain.AnimationLimits{MaxFrames: 100}
+	service := NewMediaService(mockStore, mockConverter, nil, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, AnimationLimits: limits, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.gif")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	probeResult := &domain.ProbeResult{
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "gif", Width: 64, Height: 64, NbFrames: "10000"},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+
+	_, err = service.Upload(1, "huge.gif", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "")
+
+	assert.ErrorIs(t, err, domain.ErrAnimationTooLarge)
+	entries, _ := os.ReadDir(service.uploadDir)
+	assert.Empty(t, entries, "rejected upload should be removed")
+}
+
+func TestMediaService_Upload_RejectsTooManyPixels(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	service := NewMediaService(mockStore, mockConverter, nil, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true, MaxPixels: 100_000_000})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.png")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	// A few bytes of PNG can claim any size in their header.
+	probeResult := &domain.ProbeResult{
+		Streams: []domain.ProbeStream{
+			{CodecType: "video", CodecName: "png", Width: 65535, Height: 65535},
+		},
+	}
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(probeResult, nil).Once()
+
+	_, err = service.Upload(1, "bomb.png", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "")
+
+	assert.ErrorIs(t, err, domain.ErrTooManyPixels)
+	assert.Contains(t, err.Error(), "65535x65535")
+	entries, _ := os.ReadDir(service.uploadDir)
+	assert.Empty(t, entries, "rejected upload should be removed")
+}
+
+func TestMediaService_ReconvertFailed(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	tempDir := t.TempDir()
+
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	originalFile := filepath.Join(tempDir, "original.mp4")
+	require.NoError(t, os.WriteFile(originalFile, []byte("original"), 0644))
+
+	retry := &domain.Media{
+		ID:           "retry",
+		Status:       domain.MediaStatusFailed,
+		OriginalPath: originalFile,
+		Variants: []domain.Variant{
+			{ID: 1, MediaID: "retry", Codec: domain.CodecH264, Status: domain.VariantStatusDone},
+			{ID: 2, MediaID: "retry", Codec: domain.CodecAV1, Status: domain.VariantStatusFailed},
+		},
+	}
+	gone := &domain.Media{ID: "gone", Status: domain.MediaStatusFailed, OriginalPath: filepath.Join(tempDir, "deleted.mp4")}
+
+	mockStore.EXPECT().IterateByStatus(domain.MediaStatusFailed, mock.Anything).
+		RunAndReturn(func(_ domain.MediaStatus, fn func(*domain.Media) error) error {
+			for _, m := range []*domain.Media{retry, gone} {
+				if err := fn(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).
+		Once()
+	mockJobQueue.EXPECT().ListByMedia("retry").
+		Return([]domain.Job{{Type: domain.JobTypeConvert, Codec: domain.CodecAV1, Fps: 60}}, nil).
+		Once()
+	mockStore.EXPECT().UpdateStatus("retry", domain.MediaStatusPending, "").Return(nil).Once()
+	mockJobQueue.EXPECT().RequeueVariant(mock.MatchedBy(func(v *domain.Variant) bool { return v.ID == 2 }), 60).
+		Return(&domain.Job{ID: 7}, nil).
+		Once()
+
+	requeued, skipped, err := service.ReconvertFailed()
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, requeued)
+	assert.Equal(t, 1, skipped)
+}
+
+func TestMediaService_RegenerateThumbnails(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	tempDir := t.TempDir()
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mockJobQueue, NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	file := filepath.Join(tempDir, "clip.mp4")
+	require.NoError(t, os.WriteFile(file, []byte("video"), 0600))
+	missing := filepath.Join(tempDir, "deleted.mp4")
+
+	video := &domain.Media{ID: "video", Type: domain.MediaTypeVideo, Status: domain.MediaStatusDone, OriginalPath: file}
+	legacy := &domain.Media{ID: "legacy", Type: domain.MediaTypeVideo, Status: domain.MediaStatusDone, OriginalPath: missing, ConvertedPath: file}
+	gone := &domain.Media{ID: "gone", Type: domain.MediaTypeVideo, Status: domain.MediaStatusDone, OriginalPath: missing}
+	image := &domain.Media{ID: "image", Type: domain.MediaTypeImage, Status: domain.MediaStatusDone, OriginalPath: file}
+
+	mockStore.EXPECT().IterateByStatus(domain.MediaStatusDone, mock.Anything).
+		RunAndReturn(func(_ domain.MediaStatus, fn func(*domain.Media) error) error {
+			for _, m := range []*domain.Media{video, legacy, gone, image} {
+				if err := fn(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).
+		Once()
+	for _, id := range []string{"video", "legacy"} {
+		mockJobQueue.EXPECT().Enqueue(id, domain.JobTypeThumbnail, domain.Codec(""), 0, domain.JobPriorityNormal).
+			Return(&domain.Job{}, nil).
+			Once()
+	}
+
+	queued, skipped, err := service.RegenerateThumbnails()
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, 1, skipped)
+}
+
+// variantFor matches a pending variant queued for codec.
+func variantFor(codec domain.Codec) any {
+	return mock.MatchedBy(func(v *domain.Variant) bool {
+		return v.Codec == codec && v.Status == domain.VariantStatusPending
+	})
+}
+
+func TestMediaService_Upload_CustomSlug(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	service := NewMediaService(mockStore, mockConverter, nil, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.png")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockStore.EXPECT().Get("my-demo").Return(nil, domain.ErrNotFound).Once()
+	mockConverter.EXPECT().Probe(mock.Anything).Return(&domain.ProbeResult{}, nil).Once()
+	mockStore.EXPECT().Save(mock.MatchedBy(func(m *domain.Media) bool { return m.ID == "my-demo" })).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockStore.EXPECT().UpdateDone(mock.Anything).Return(nil).Once()
+
+	result, err := service.Upload(1, "shot.png", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "My-Demo")
+
+	require.NoError(t, err)
+	assert.Equal(t, "my-demo", result.ID)
+	assert.Equal(t, "my-demo_shot.png", filepath.Base(result.OriginalPath))
+}
+
+func TestMediaService_Upload_RejectsTakenOrInvalidSlug(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	service := NewMediaService(mockStore, nil, nil, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.png")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockStore.EXPECT().Get("my-demo").Return(&domain.Media{ID: "my-demo"}, nil).Once()
+
+	_, err = service.Upload(1, "shot.png", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "my-demo")
+	assert.ErrorIs(t, err, domain.ErrSlugTaken)
+
+	_, err = service.Upload(1, "shot.png", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "my demo")
+	assert.ErrorIs(t, err, domain.ErrInvalidSlug)
+
+	_, err = os.Stat(tmpFile.Name())
+	assert.NoError(t, err, "rejected upload should leave the temp file alone")
+}
+
+func TestMediaService_DeleteMany(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	tempDir := t.TempDir()
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	mine := filepath.Join(tempDir, "mine.mp4")
+	require.NoError(t, os.WriteFile(mine, []byte("video"), 0644))
+	theirs := filepath.Join(tempDir, "theirs.mp4")
+	require.NoError(t, os.WriteFile(theirs, []byte("video"), 0644))
+
+	mockStore.EXPECT().Get("mine").Return(&domain.Media{ID: "mine", OwnerID: 1, OriginalPath: mine}, nil).Once()
+	mockStore.EXPECT().Get("theirs").Return(&domain.Media{ID: "theirs", OwnerID: 2, OriginalPath: theirs}, nil).Once()
+	mockStore.EXPECT().Get("gone").Return(nil, domain.ErrNotFound).Once()
+	mockStore.EXPECT().DeleteMany([]string{"mine"}).Return(nil).Once()
+
+	deleted, failed := service.DeleteMany(1, []string{"mine", "theirs", "gone", "mine"})
+
+	assert.Equal(t, 1, deleted)
+	assert.Len(t, failed, 2)
+	assert.ErrorIs(t, failed["theirs"], domain.ErrNotFound)
+	assert.ErrorIs(t, failed["gone"], domain.ErrNotFound)
+	_, err := os.Stat(mine)
+	assert.True(t, os.IsNotExist(err), "owned file should be deleted")
+	_, err = os.Stat(theirs)
+	assert.NoError(t, err, "another user's file must be kept")
+}
+
+func TestMediaService_DeleteMany_StoreFailureKeepsFiles(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	tempDir := t.TempDir()
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mocks.NewJobQueueMock(t), NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	original := filepath.Join(tempDir, "a.mp4")
+	require.NoError(t, os.WriteFile(original, []byte("video"), 0644))
+
+	mockStore.EXPECT().Get("a").Return(&domain.Media{ID: "a", OwnerID: 1, OriginalPath: original}, nil).Once()
+	mockStore.EXPECT().DeleteMany([]string{"a"}).Return(errors.New("database is locked")).Once()
+
+	deleted, failed := service.DeleteMany(1, []string{"a"})
+
+	assert.Zero(t, deleted)
+	assert.Error(t, failed["a"])
+	_, err := os.Stat(original)
+	assert.NoError(t, err, "files must survive a failed transaction")
+}
+
+// expectOriginalChecksum accepts the checksum an upload records for its original.
+func expectOriginalChecksum(store *mocks.MediaStoreMock) {
+	store.EXPECT().SaveChecksum(mock.AnythingOfType("string"), domain.ChecksumOriginal, mock.AnythingOfType("string")).
+		Return(nil).
+		Once()
+}
+
+func TestMediaService_Upload_RejectsUndecodable(t *testing.T) {
+	mockConverter := mocks.NewMediaConverterMock(t)
+	tempDir := t.TempDir()
+	service := NewMediaService(mocks.NewMediaStoreMock(t), mockConverter, mocks.NewJobQueueMock(t), NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(&domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "N/A"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
+	}, nil).Once()
+
+	_, err = service.Upload(1, "broken.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+
+	assert.ErrorIs(t, err, domain.ErrUndecodable)
+	entries, err := os.ReadDir(filepath.Join(tempDir, "uploads"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the rejected file should be removed")
+}
+
+func TestMediaService_Upload_VerifyRejectsCorruptImage(t *testing.T) {
+	mockConverter := mocks.NewMediaConverterMock(t)
+	tempDir := t.TempDir()
+	service := NewMediaService(mocks.NewMediaStoreMock(t), mockConverter, mocks.NewJobQueueMock(t), NewEventBus(0, 0), tempDir, MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, VerifyUploads: true, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.png")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(&domain.ProbeResult{
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "png", Width: 64, Height: 64}},
+	}, nil).Once()
+	mockConverter.EXPECT().Verify(mock.Anything, mock.AnythingOfType("string")).
+		Return(errors.New("exit status 1: Truncated data")).
+		Once()
+
+	_, err = service.Upload(1, "cut.png", tmpFile, 7*domain.Day, domain.MediaTypeImage, nil, 0, nil, "")
+
+	assert.ErrorIs(t, err, domain.ErrUndecodable)
+	entries, err := os.ReadDir(filepath.Join(tempDir, "uploads"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the rejected file should be removed")
+}
+
+func TestMediaService_Upload_QueuesStoryboard(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+
+	service := NewMediaService(mockStore, mockConverter, mockJobQueue, NewEventBus(0, 0), t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, Storyboards: true, ForceH264: true})
+
+	tmpFile, err := os.CreateTemp("", "test_upload_*.mp4")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+	_, _ = tmpFile.WriteString("test content")
+
+	mockConverter.EXPECT().Probe(mock.AnythingOfType("string")).Return(&domain.ProbeResult{
+		Format:  domain.ProbeFormat{Duration: "600.0", FormatName: "mov,mp4,m4a,3gp,3g2,mj2"},
+		Streams: []domain.ProbeStream{{CodecType: "video", CodecName: "h264"}},
+		RawJSON: `{"format":{"format_name":"mov,mp4,m4a,3gp,3g2,mj2"}}`,
+	}, nil).Once()
+	mockStore.EXPECT().Save(mock.AnythingOfType("*domain.Media")).Return(nil).Once()
+	expectOriginalChecksum(mockStore)
+	mockJobQueue.EXPECT().EnqueueVariant(variantFor(domain.CodecH264), 0).Return(&domain.Job{}, nil).Once()
+	mockJobQueue.EXPECT().Enqueue(mock.AnythingOfType("string"), domain.JobTypeStoryboard, domain.Codec(""), 0, domain.JobPriorityNormal).
+		Return(&domain.Job{}, nil).
+		Once()
+
+	_, err = service.Upload(1, "talk.mp4", tmpFile, 7*domain.Day, domain.MediaTypeVideo, nil, 0, nil, "")
+	require.NoError(t, err)
+}
+
+func TestMediaService_Cancel(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	bus := NewEventBus(0, 0)
+	var published []string
+	bus.Listen(func(_ string, event Event) { published = append(published, event.Type+":"+event.Status) })
+	service := NewMediaService(mockStore, mocks.NewMediaConverterMock(t), mockJobQueue, bus, t.TempDir(), MediaConfig{TranscodePolicy: domain.TranscodePolicyAlways, ForceH264: true})
+
+	media := &domain.Media{
+		ID:     "abc",
+		Status: domain.MediaStatusProcessing,
+		Variants: []domain.Variant{
+			{ID: 1, MediaID: "abc", Codec: domain.CodecAV1, Status: domain.VariantStatusProcessing},
+			{ID: 2, MediaID: "abc", Codec: domain.CodecH264, Status: domain.VariantStatusPending},
+		},
+	}
+	mockJobQueue.EXPECT().CancelByMedia("abc").Return(2, nil).Once()
+	mockStore.EXPECT().UpdateVariantStatus(int64(1), domain.VariantStatusFailed, "cancelled").Return(nil).Once()
+	mockStore.EXPECT().UpdateVariantStatus(int64(2), domain.VariantStatusFailed, "cancelled").Return(nil).Once()
+	mockStore.EXPECT().Get("abc").Return(&domain.Media{ID: "abc", Status: domain.MediaStatusProcessing, Variants: []domain.Variant{
+		{ID: 1, Codec: domain.CodecAV1, Status: domain.VariantStatusFailed},
+		{ID: 2, Codec: domain.CodecH264, Status: domain.VariantStatusFailed},
+	}}, nil).Once()
+	mockStore.EXPECT().UpdateStatus("abc", domain.MediaStatusFailed, "cancelled").Return(nil).Once()
+
+	require.NoError(t, service.Cancel(media))
+	assert.Equal(t, []string{"status:failed", "cancelled:", "changed:"}, published)
+
+	done := &domain.Media{ID: "done", Status: domain.MediaStatusDone, Variants: []domain.Variant{
+		{ID: 3, Codec: domain.CodecAV1, Status: domain.VariantStatusDone},
+	}}
+	assert.ErrorIs(t, service.Cancel(done), domain.ErrNotCancellable)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/port"
+)
+
+// Notifier announces media whose conversion finished, successfully or not.
+type Notifier interface {
+	Notify(ctx context.Context, media *domain.Media) error
+}
+
+// ConversionNotifier forwards finished conversions from the event bus to a
+// Notifier. The worker reports "done" again whenever a lazy variant finishes,
+// so each media is only announced when its final status changes.
+type ConversionNotifier struct {
+	store    port.MediaStore
+	notifier Notifier
+
+	// notified holds the last announced status per media, oldest first in
+	// order, bounded like the event bus history.
+	mu       sync.Mutex
+	notified map[string]domain.MediaStatus
+	order    []string
+}
+
+func NewConversionNotifier(store port.MediaStore, notifier Notifier) *ConversionNotifier {
+	return &ConversionNotifier{
+		store:    store,
+		notifier: notifier,
+		notified: make(map[string]domain.MediaStatus),
+	}
+}
+
+// Handle is an EventBus listener. Listeners must not block, so the media is
+// loaded and announced in the background.
+func (n *ConversionNotifier) Handle(mediaID string, event Event) {
+	if event.Type != "status" {
+		return
+	}
+	status := domain.MediaStatus(event.Status)
+	if status != domain.MediaStatusDone && status != domain.MediaStatusFailed {
+		return
+	}
+
+	n.mu.Lock()
+	if n.notified[mediaID] == status {
+		n.mu.Unlock()
+		return
+	}
+	n.remember(mediaID, status)
+	n.mu.Unlock()
+
+	go n.notify(mediaID)
+}
+
+// remember records status as announced for mediaID, forgetting the least
+// recently announced media beyond historySize.
+func (n *ConversionNotifier) remember(mediaID string, status domain.MediaStatus) {
+	if _, ok := n.notified[mediaID]; ok {
+		n.order = slices.DeleteFunc(n.order, func(id string) bool { return id == mediaID })
+	} else if len(n.order) >= historySize {
+		delete(n.notified, n.order[0])
+		n.order = n.order[1:]
+	}
+	n.notified[mediaID] = status
+	n.order = append(n.order, mediaID)
+}
+
+func (n *ConversionNotifier) notify(mediaID string) {
+	media, err := n.store.Get(mediaID)
+	if err != nil {
+		logger.Error.Printf("notify: failed to load media %s: %v", mediaID, err)
+		return
+	}
+	if err := n.notifier.Notify(context.Background(), media); err != nil {
+		logger.Error.Printf("notify: failed to announce media %s: %v", mediaID, err)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanNotifier passes every announced media to a channel.
+type chanNotifier chan *domain.Media
+
+func (c chanNotifier) Notify(_ context.Context, media *domain.Media) error {
+	c <- media
+	return nil
+}
+
+func TestConversionNotifier_AnnouncesEachFinalStatusOnce(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	announced := make(chanNotifier, 4)
+	n := NewConversionNotifier(mockStore, announced)
+
+	mockStore.EXPECT().Get("abc").Return(&domain.Media{ID: "abc", Status: domain.MediaStatusDone}, nil).Once()
+
+	n.Handle("abc", Event{Type: "status", Status: string(domain.MediaStatusProcessing)})
+	n.Handle("abc", Event{Type: EventTypeChanged})
+	n.Handle("abc", Event{Type: "status", Status: string(domain.MediaStatusDone)})
+	// A lazy variant finishing later reports done again
+	n.Handle("abc", Event{Type: "status", Status: string(domain.MediaStatusDone)})
+
+	select {
+	case media := <-announced:
+		assert.Equal(t, "abc", media.ID)
+	case <-time.After(time.Second):
+		require.FailNow(t, "media was not announced")
+	}
+	select {
+	case <-announced:
+		assert.Fail(t, "media announced twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestConversionNotifier_ForgetsOldestBeyondHistory(t *testing.T) {
+	n := NewConversionNotifier(nil, nil)
+
+	for i := range historySize + 1 {
+		n.remember(fmt.Sprintf("m%d", i), domain.MediaStatusDone)
+	}
+	n.remember("m1", domain.MediaStatusFailed)
+
+	assert.Len(t, n.notified, historySize)
+	assert.NotContains(t, n.notified, "m0")
+	assert.Equal(t, domain.MediaStatusFailed, n.notified["m1"])
+	assert.Equal(t, "m1", n.order[len(n.order)-1])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+var ErrInvalidIdentity = errors.New("identity provider returned no subject")
+
+// LoginWithOIDC returns the local user linked to identity's subject, creating
+// one on first sign-in. On a fresh install that account becomes the admin.
+// Existing accounts are never linked by username, so a provider account
+// cannot take over a local one; a name clash fails with ErrUserExists.
+func (s *AuthService) LoginWithOIDC(identity *domain.OIDCIdentity) (*domain.User, error) {
+	if identity == nil || identity.Subject == "" {
+		return nil, ErrInvalidIdentity
+	}
+
+	user, err := s.store.GetUserByOIDCSubject(identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	username := oidcUsername(identity)
+	if validateErr := validateUsername(username); validateErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUsername, validateErr)
+	}
+	if _, err := s.store.GetUser(username); err == nil {
+		return nil, ErrUserExists
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	hasUser, err := s.store.HasUser()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateOIDCUser(username, identity.Subject, !hasUser); err != nil {
+		return nil, fmt.Errorf("create oidc user: %w", err)
+	}
+	return s.store.GetUserByOIDCSubject(identity.Subject)
+}
+
+// oidcUsername derives a local username from the provider's claims,
+// preferring preferred_username, then the email's local part, then the
+// subject. Characters validateUsername rejects become hyphens.
+func oidcUsername(identity *domain.OIDCIdentity) string {
+	name := identity.Username
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if name == "" {
+		name = identity.Subject
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	for len(name) > 50 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
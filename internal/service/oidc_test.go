@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_LoginWithOIDC(t *testing.T) {
+	t.Run("creates an admin on first sign-in", func(t *testing.T) {
+		store := &mockUserStore{getUserErr: domain.ErrNotFound}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+
+		user, err := svc.LoginWithOIDC(&domain.OIDCIdentity{Subject: "abc-123", Email: "jane.doe@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "jane-doe", user.Username)
+		assert.Equal(t, "abc-123", user.OIDCSubject)
+		assert.True(t, user.IsAdmin)
+		assert.Empty(t, user.PasswordHash)
+	})
+
+	t.Run("returns the linked user", func(t *testing.T) {
+		linked := &domain.User{ID: 7, Username: "jane", OIDCSubject: "abc-123"}
+		svc := NewAuthService(&mockUserStore{user: linked, hasUser: true}, "test-secret-key", DefaultTokenTTL)
+
+		user, err := svc.LoginWithOIDC(&domain.OIDCIdentity{Subject: "abc-123", Username: "renamed"})
+		require.NoError(t, err)
+		assert.Equal(t, linked, user)
+	})
+
+	t.Run("does not link an existing local account", func(t *testing.T) {
+		local := &domain.User{ID: 1, Username: "jane", PasswordHash: "hash"}
+		svc := NewAuthService(&mockUserStore{user: local, hasUser: true}, "test-secret-key", DefaultTokenTTL)
+
+		_, err := svc.LoginWithOIDC(&domain.OIDCIdentity{Subject: "abc-123", Username: "jane"})
+		assert.ErrorIs(t, err, ErrUserExists)
+	})
+
+	t.Run("rejects an identity without subject", func(t *testing.T) {
+		svc := NewAuthService(&mockUserStore{}, "test-secret-key", DefaultTokenTTL)
+
+		_, err := svc.LoginWithOIDC(&domain.OIDCIdentity{Username: "jane"})
+		assert.ErrorIs(t, err, ErrInvalidIdentity)
+	})
+}
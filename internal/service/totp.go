@@ -0,0 +1,256 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+
+	"github.com/bnema/sharm/internal/domain"
+)
+
+var (
+	ErrInvalidTOTP        = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled    = errors.New("two-factor enrollment not started")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrInvalidChallenge   = errors.New("invalid or expired two-factor challenge")
+)
+
+const (
+	totpIssuer = "Sharm"
+	// Backup codes are 40 random bits, rendered as XXXX-XXXX.
+	backupCodeCount = 10
+	backupCodeBytes = 5
+	// totpChallengeTTL bounds the time between a correct password and the
+	// second factor.
+	totpChallengeTTL = 5 * time.Minute
+)
+
+// totpValidateOpts accepts codes from the previous, current and next 30s
+// step to tolerate clock drift.
+var totpValidateOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TOTPStatus reports the user's 2FA state for the settings page. PendingURI
+// is set while an enrollment awaits confirmation.
+func (s *AuthService) TOTPStatus(userID int64) (*domain.TOTPStatus, error) {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.TOTPStatus{Enabled: user.TOTPEnabled}
+	if user.TOTPEnabled {
+		status.BackupCodes, err = s.store.CountBackupCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+	} else if user.TOTPSecret != "" {
+		status.PendingURI = totpKeyURI(user.Username, user.TOTPSecret)
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for userID and returns its
+// otpauth:// provisioning URI. 2FA is not enforced until EnableTOTP confirms
+// a code from the authenticator.
+func (s *AuthService) BeginTOTPEnrollment(userID int64) (string, error) {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.TOTPEnabled {
+		return "", ErrTOTPAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: user.Username})
+	if err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+
+	if err := s.store.SetTOTP(userID, key.Secret(), false); err != nil {
+		return "", fmt.Errorf("store totp secret: %w", err)
+	}
+	return key.URL(), nil
+}
+
+// EnableTOTP confirms the pending enrollment with a current code, turns 2FA
+// on and returns a fresh set of backup codes. The codes are not stored in
+// clear and cannot be shown again.
+func (s *AuthService) EnableTOTP(userID int64, code string) ([]string, error) {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if !validateTOTPCode(user.TOTPSecret, code) {
+		return nil, ErrInvalidTOTP
+	}
+
+	codes := make([]string, 0, backupCodeCount)
+	hashes := make([]string, 0, backupCodeCount)
+	for range backupCodeCount {
+		code, codeHash, err := newOneTimeCode(backupCodeBytes)
+		if err != nil {
+			return nil, fmt.Errorf("generate backup code: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, codeHash)
+	}
+
+	if err := s.store.ReplaceBackupCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("store backup codes: %w", err)
+	}
+	if err := s.store.SetTOTP(userID, user.TOTPSecret, true); err != nil {
+		return nil, fmt.Errorf("enable totp: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns 2FA off after checking a current or backup code, and
+// drops the secret and remaining backup codes.
+func (s *AuthService) DisableTOTP(userID int64, code string) error {
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		if err := s.verifySecondFactor(user, code); err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.SetTOTP(userID, "", false); err != nil {
+		return fmt.Errorf("disable totp: %w", err)
+	}
+	return s.store.ReplaceBackupCodes(userID, nil)
+}
+
+// RequiresTOTP reports whether logging in as username needs a second factor.
+func (s *AuthService) RequiresTOTP(username string) (bool, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return false, err
+	}
+	return user.TOTPEnabled, nil
+}
+
+// GenerateTOTPChallenge returns a short-lived token proving that username
+// passed the password check. It is signed like a session token but over a
+// distinct message, so neither can stand in for the other.
+func (s *AuthService) GenerateTOTPChallenge(username string) (string, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	userID := strconv.FormatInt(user.ID, 10)
+	return timestamp + ":" + userID + ":" + s.signTOTPChallenge(timestamp, userID), nil
+}
+
+// VerifyTOTPChallenge checks a challenge from GenerateTOTPChallenge and the
+// user's second factor, which is either a TOTP code or an unused backup code.
+func (s *AuthService) VerifyTOTPChallenge(challenge, code string) (*domain.User, error) {
+	parts := strings.Split(challenge, ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidChallenge
+	}
+
+	timestamp, userIDStr, signature := parts[0], parts[1], parts[2]
+	if !hmac.Equal([]byte(signature), []byte(s.signTOTPChallenge(timestamp, userIDStr))) {
+		return nil, ErrInvalidChallenge
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)) > totpChallengeTTL {
+		return nil, ErrInvalidChallenge
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.store.GetUserByID(userID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AuthService) signTOTPChallenge(timestamp, userID string) string {
+	mac := hmac.New(sha256.New, []byte(s.secretKey))
+	mac.Write([]byte("totp:" + timestamp + ":" + userID))
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySecondFactor accepts a current TOTP code, or else consumes a
+// matching backup code.
+func (s *AuthService) verifySecondFactor(user *domain.User, code string) error {
+	if validateTOTPCode(user.TOTPSecret, code) {
+		return nil
+	}
+
+	codeHash := hashOneTimeCode(normalizeOneTimeCode(code))
+	if codeHash == "" {
+		return ErrInvalidTOTP
+	}
+	ok, err := s.store.ConsumeBackupCode(user.ID, codeHash)
+	if err != nil {
+		return fmt.Errorf("check backup code: %w", err)
+	}
+	if !ok {
+		return ErrInvalidTOTP
+	}
+	return nil
+}
+
+func validateTOTPCode(secret, code string) bool {
+	code = strings.ReplaceAll(code, " ", "")
+	if secret == "" || len(code) != int(otp.DigitsSix) {
+		return false
+	}
+	ok, err := totp.ValidateCustom(code, secret, time.Now().UTC(), totpValidateOpts)
+	return err == nil && ok
+}
+
+// totpKeyURI rebuilds the provisioning URI for a stored secret so a pending
+// enrollment can be shown again.
+func totpKeyURI(username, secret string) string {
+	raw, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return ""
+	}
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: username,
+		Secret:      raw,
+	})
+	if err != nil {
+		return ""
+	}
+	return key.URL()
+}
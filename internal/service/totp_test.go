@@ -0,0 +1,126 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enrollTOTP(t *testing.T, svc *AuthService) (string, []string) {
+	t.Helper()
+	uri, err := svc.BeginTOTPEnrollment(1)
+	require.NoError(t, err)
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	secret := u.Query().Get("secret")
+	require.NotEmpty(t, secret)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	backupCodes, err := svc.EnableTOTP(1, code)
+	require.NoError(t, err)
+	return secret, backupCodes
+}
+
+func TestAuthService_TOTPEnrollment(t *testing.T) {
+	t.Run("enrollment is not enforced until confirmed", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+
+		uri, err := svc.BeginTOTPEnrollment(1)
+		require.NoError(t, err)
+		assert.Contains(t, uri, "otpauth://totp/")
+
+		required, err := svc.RequiresTOTP("admin")
+		require.NoError(t, err)
+		assert.False(t, required)
+
+		status, err := svc.TOTPStatus(1)
+		require.NoError(t, err)
+		assert.Equal(t, uri, status.PendingURI)
+
+		_, err = svc.EnableTOTP(1, "000000")
+		assert.ErrorIs(t, err, ErrInvalidTOTP)
+	})
+
+	t.Run("confirming enables 2FA and issues backup codes", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+
+		_, backupCodes := enrollTOTP(t, svc)
+		assert.Len(t, backupCodes, backupCodeCount)
+		assert.NotContains(t, store.backupCodes, backupCodes[0])
+
+		required, err := svc.RequiresTOTP("admin")
+		require.NoError(t, err)
+		assert.True(t, required)
+
+		_, err = svc.BeginTOTPEnrollment(1)
+		assert.ErrorIs(t, err, ErrTOTPAlreadyEnabled)
+	})
+
+	t.Run("disabling requires a valid code", func(t *testing.T) {
+		store := &mockUserStore{hasUser: true, user: &domain.User{ID: 1, Username: "admin"}}
+		svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+		secret, _ := enrollTOTP(t, svc)
+
+		assert.ErrorIs(t, svc.DisableTOTP(1, "000000"), ErrInvalidTOTP)
+
+		code, err := totp.GenerateCode(secret, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, svc.DisableTOTP(1, code))
+		assert.False(t, store.user.TOTPEnabled)
+		assert.Empty(t, store.user.TOTPSecret)
+		assert.Empty(t, store.backupCodes)
+	})
+}
+
+func TestAuthService_VerifyTOTPChallenge(t *testing.T) {
+	store := &mockUserStore{hasUser: true, user: &domain.User{ID: 1, Username: "admin"}}
+	svc := NewAuthService(store, "test-secret-key", DefaultTokenTTL)
+	secret, backupCodes := enrollTOTP(t, svc)
+
+	challenge, err := svc.GenerateTOTPChallenge("admin")
+	require.NoError(t, err)
+
+	t.Run("accepts a code from the adjacent time step", func(t *testing.T) {
+		code, err := totp.GenerateCode(secret, time.Now().Add(-30*time.Second))
+		require.NoError(t, err)
+		user, err := svc.VerifyTOTPChallenge(challenge, code)
+		require.NoError(t, err)
+		assert.Equal(t, "admin", user.Username)
+	})
+
+	t.Run("rejects a code two steps old", func(t *testing.T) {
+		code, err := totp.GenerateCode(secret, time.Now().Add(-90*time.Second))
+		require.NoError(t, err)
+		_, err = svc.VerifyTOTPChallenge(challenge, code)
+		assert.ErrorIs(t, err, ErrInvalidTOTP)
+	})
+
+	t.Run("backup codes work once", func(t *testing.T) {
+		_, err := svc.VerifyTOTPChallenge(challenge, backupCodes[0])
+		require.NoError(t, err)
+		_, err = svc.VerifyTOTPChallenge(challenge, backupCodes[0])
+		assert.ErrorIs(t, err, ErrInvalidTOTP)
+	})
+
+	t.Run("rejects session tokens and tampered challenges", func(t *testing.T) {
+		code, err := totp.GenerateCode(secret, time.Now())
+		require.NoError(t, err)
+
+		token, err := svc.GenerateToken("admin")
+		require.NoError(t, err)
+		_, err = svc.VerifyTOTPChallenge(token, code)
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+		_, err = svc.VerifyTOTPChallenge(challenge+"x", code)
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+}
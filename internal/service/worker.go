@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bnema/sharm/internal/domain"
 	"github.com/bnema/sharm/internal/infrastructure/logger"
+	"github.com/bnema/sharm/internal/infrastructure/metrics"
 	"github.com/bnema/sharm/internal/port"
 )
 
@@ -19,16 +22,56 @@ type WorkerPool struct {
 	eventBus  EventPublisher
 	dataDir   string
 	workers   int
+
+	// blobs stores finished outputs for serving; nil keeps them on local
+	// disk only.
+	blobs port.BlobStore
+
+	// transcodePolicy lets H264 variants of web-optimized uploads reuse the
+	// original file instead of being encoded.
+	transcodePolicy domain.TranscodePolicy
+
+	// memory defers conversions whose estimated memory would exceed the
+	// budget; nil when no budget is set.
+	memory *memoryGate
+
+	// paused stops workers from claiming new jobs; running jobs finish.
+	paused atomic.Bool
+
+	// running holds the cancel function of each running job by media ID,
+	// so cancelling a media stops its conversions.
+	runningMu sync.Mutex
+	running   map[string]map[int64]context.CancelFunc
+
+	// busy is held for reading while a worker claims and runs a job, so
+	// Drain can wait for running jobs by taking it for writing.
+	busy sync.RWMutex
+
+	wg sync.WaitGroup
 }
 
 type EventPublisher interface {
 	Publish(mediaID string, event Event)
 }
 
+// EventTypeChanged is published by MediaService whenever media is created,
+// deleted or gains a variant outside of the worker's status updates.
+const EventTypeChanged = "changed"
+
+// EventTypeVariant is published when one codec of a media starts, finishes
+// or fails converting, with the variant status and its Codec.
+const EventTypeVariant = "variant"
+
+// EventTypeCancelled is published by MediaService.Cancel; workers stop the
+// running jobs of the media when they receive it.
+const EventTypeCancelled = "cancelled"
+
 type Event struct {
-	Type    string // "status", "progress"
+	Type    string // "status", "progress", "changed", "variant", "cancelled"
 	Status  string
 	Message string
+	// Codec is the variant a "variant" event is about.
+	Codec domain.Codec
 }
 
 func NewWorkerPool(
@@ -38,17 +81,26 @@ func NewWorkerPool(
 	eventBus EventPublisher,
 	dataDir string,
 	workers int,
+	transcodePolicy domain.TranscodePolicy,
+	memoryBudgetMB int64,
+	blobs port.BlobStore,
 ) *WorkerPool {
 	return &WorkerPool{
-		jobQueue:  jobQueue,
-		store:     store,
-		converter: converter,
-		eventBus:  eventBus,
-		dataDir:   dataDir,
-		workers:   workers,
+		jobQueue:        jobQueue,
+		store:           store,
+		converter:       converter,
+		eventBus:        eventBus,
+		dataDir:         dataDir,
+		workers:         workers,
+		transcodePolicy: transcodePolicy,
+		memory:          newMemoryGate(memoryBudgetMB),
+		blobs:           blobs,
+		running:         make(map[string]map[int64]context.CancelFunc),
 	}
 }
 
+// Start launches the workers. They stop claiming jobs once ctx is
+// cancelled; Wait blocks until they have exited.
 func (wp *WorkerPool) Start(ctx context.Context) {
 	// Reset any stalled jobs from previous runs
 	if err := wp.jobQueue.ResetStalled(); err != nil {
@@ -56,12 +108,111 @@ func (wp *WorkerPool) Start(ctx context.Context) {
 	}
 
 	for i := range wp.workers {
+		wp.wg.Add(1)
 		go wp.runWorker(ctx, i)
 	}
 	logger.Info.Printf("started %d workers", wp.workers)
 }
 
+// Wait blocks until every worker has exited after its context was cancelled,
+// or returns ctx's error if ctx is done first.
+func (wp *WorkerPool) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause stops workers from claiming jobs once their current one is done.
+// Queued jobs stay pending until Resume.
+func (wp *WorkerPool) Pause() {
+	if !wp.paused.Swap(true) {
+		logger.Info.Printf("worker pool paused")
+	}
+}
+
+// Resume lets paused workers claim jobs again.
+func (wp *WorkerPool) Resume() {
+	if wp.paused.Swap(false) {
+		logger.Info.Printf("worker pool resumed")
+	}
+}
+
+func (wp *WorkerPool) Paused() bool {
+	return wp.paused.Load()
+}
+
+// Cancel stops the running jobs of mediaID; their handlers see their
+// context cancelled. It does not block, so it can run as an EventBus
+// listener.
+func (wp *WorkerPool) Cancel(mediaID string) {
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+
+	for _, cancel := range wp.running[mediaID] {
+		cancel()
+	}
+}
+
+// Handle cancels the running jobs of a media on EventTypeCancelled events.
+// Register it with EventBus.Listen.
+func (wp *WorkerPool) Handle(mediaID string, event Event) {
+	if event.Type == EventTypeCancelled {
+		wp.Cancel(mediaID)
+	}
+}
+
+// track returns a context for job that Cancel can stop, and a function
+// to call once the job has finished.
+func (wp *WorkerPool) track(ctx context.Context, job *domain.Job) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+	if wp.running[job.MediaID] == nil {
+		wp.running[job.MediaID] = make(map[int64]context.CancelFunc)
+	}
+	wp.running[job.MediaID][job.ID] = cancel
+
+	return jobCtx, func() {
+		cancel()
+		wp.runningMu.Lock()
+		defer wp.runningMu.Unlock()
+		delete(wp.running[job.MediaID], job.ID)
+		if len(wp.running[job.MediaID]) == 0 {
+			delete(wp.running, job.MediaID)
+		}
+	}
+}
+
+// Drain pauses the pool and blocks until running jobs have finished, or
+// returns ctx's error if ctx is done first. The pool stays paused until
+// Resume.
+func (wp *WorkerPool) Drain(ctx context.Context) error {
+	wp.Pause()
+	done := make(chan struct{})
+	go func() {
+		wp.busy.Lock()
+		wp.busy.Unlock() //nolint:staticcheck // only waits for running jobs
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (wp *WorkerPool) runWorker(ctx context.Context, id int) {
+	defer wp.wg.Done()
 	for {
 		select {
 		case <-ctx.Done():
@@ -70,38 +221,151 @@ func (wp *WorkerPool) runWorker(ctx context.Context, id int) {
 		default:
 		}
 
-		job, err := wp.jobQueue.Claim()
+		if wp.paused.Load() {
+			sleep(ctx, 500*time.Millisecond)
+			continue
+		}
+
+		if idle := wp.step(ctx, id); idle > 0 {
+			sleep(ctx, idle)
+		}
+	}
+}
+
+// step claims and runs one job, returning how long to wait before polling
+// again.
+func (wp *WorkerPool) step(ctx context.Context, id int) time.Duration {
+	wp.busy.RLock()
+	defer wp.busy.RUnlock()
+
+	// Drain may have paused the pool while this worker waited for the lock
+	if wp.paused.Load() {
+		return 500 * time.Millisecond
+	}
+
+	// Conversions over the memory budget stay claimed while the worker
+	// looks further down the queue, so they do not hold up the jobs behind
+	// them. They go back to pending, keeping their place, once a job is
+	// picked or the queue runs out.
+	var deferred []int64
+	var job *domain.Job
+	var needMB int64
+	for {
+		next, err := wp.jobQueue.Claim()
 		if err != nil {
+			wp.requeueDeferred(id, deferred)
 			logger.Error.Printf("worker %d: failed to claim job: %v", id, err)
-			time.Sleep(2 * time.Second)
-			continue
+			return 2 * time.Second
+		}
+		if next == nil {
+			break
 		}
+		need, admitted := wp.admit(next)
+		if admitted {
+			job, needMB = next, need
+			break
+		}
+		logger.Info.Printf("worker %d: deferring job %d, needs ~%d MB over the conversion memory budget", id, next.ID, need)
+		deferred = append(deferred, next.ID)
+	}
+	wp.requeueDeferred(id, deferred)
 
-		if job == nil {
-			// No pending jobs, wait before polling again
-			time.Sleep(500 * time.Millisecond)
-			continue
+	if job == nil {
+		if len(deferred) > 0 {
+			// Retried once running conversions free enough memory
+			return 2 * time.Second
 		}
+		// No pending jobs, wait before polling again
+		return 500 * time.Millisecond
+	}
+
+	logger.Info.Printf("worker %d: processing job %d (type=%s, media=%s, codec=%s)", id, job.ID, job.Type, job.MediaID, job.Codec)
+	wp.processJob(ctx, job)
+	wp.memory.release(needMB)
+	return 0
+}
+
+// requeueDeferred returns jobs claimed but not admitted to pending, without
+// counting the claim as an attempt.
+func (wp *WorkerPool) requeueDeferred(id int, jobIDs []int64) {
+	for _, jobID := range jobIDs {
+		if err := wp.jobQueue.Defer(jobID); err != nil {
+			logger.Error.Printf("worker %d: failed to requeue job %d: %v", id, jobID, err)
+		}
+	}
+}
+
+// admit reserves the estimated memory of a convert job, reporting false when
+// it does not fit in the budget right now. Other jobs are always admitted.
+func (wp *WorkerPool) admit(job *domain.Job) (int64, bool) {
+	if wp.memory == nil || job.Type != domain.JobTypeConvert {
+		return 0, true
+	}
+
+	var width, height int
+	if media, err := wp.store.Get(job.MediaID); err == nil {
+		width, height = media.Width, media.Height
+	}
+	codec := job.Codec
+	if codec == "" {
+		// Legacy conversions try AV1 first
+		codec = domain.CodecAV1
+	}
+	needMB := domain.EstimateConvertMemoryMB(codec, width, height)
+	return needMB, wp.memory.tryAcquire(needMB)
+}
 
-		logger.Info.Printf("worker %d: processing job %d (type=%s, media=%s, codec=%s)", id, job.ID, job.Type, job.MediaID, job.Codec)
-		wp.processJob(job)
+// sleep waits for d or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
 	}
 }
 
-func (wp *WorkerPool) processJob(job *domain.Job) {
+func (wp *WorkerPool) processJob(ctx context.Context, job *domain.Job) {
 	var err error
+	jobCtx, done := wp.track(ctx, job)
+	defer done()
 
 	switch job.Type {
 	case domain.JobTypeConvert:
-		err = wp.handleConvert(job)
+		start := time.Now()
+		err = wp.handleConvert(jobCtx, job)
+		codec := string(job.Codec)
+		metrics.ConversionDuration.WithLabelValues(codec).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.ConversionFailuresTotal.WithLabelValues(codec).Inc()
+		} else {
+			metrics.ConversionsTotal.WithLabelValues(codec).Inc()
+		}
 	case domain.JobTypeThumbnail:
-		err = wp.handleThumbnail(job)
+		err = wp.handleThumbnail(jobCtx, job)
 	case domain.JobTypeProbe:
 		err = wp.handleProbe(job)
+	case domain.JobTypeStoryboard:
+		err = wp.handleStoryboard(jobCtx, job)
 	default:
 		err = fmt.Errorf("unknown job type: %s", job.Type)
 	}
 
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: put the job back instead of recording a failure so
+		// it is picked up on the next start.
+		logger.Info.Printf("job %d interrupted by shutdown, requeuing: %v", job.ID, err)
+		if requeueErr := wp.jobQueue.Requeue(job.ID); requeueErr != nil {
+			logger.Error.Printf("failed to requeue job %d: %v", job.ID, requeueErr)
+		}
+		return
+	}
+	if err != nil && jobCtx.Err() != nil {
+		// Cancelled by the user: MediaService.Cancel already recorded the
+		// job, its variant and the media as cancelled.
+		logger.Info.Printf("job %d cancelled: %v", job.ID, err)
+		return
+	}
 	if err != nil {
 		logger.Error.Printf("job %d failed: %v", job.ID, err)
 		_ = wp.jobQueue.Fail(job.ID, err.Error())
@@ -121,7 +385,7 @@ func (wp *WorkerPool) processJob(job *domain.Job) {
 	logger.Info.Printf("job %d completed", job.ID)
 }
 
-func (wp *WorkerPool) handleConvert(job *domain.Job) error {
+func (wp *WorkerPool) handleConvert(ctx context.Context, job *domain.Job) error {
 	media, err := wp.store.Get(job.MediaID)
 	if err != nil {
 		return fmt.Errorf("get media: %w", err)
@@ -140,30 +404,40 @@ func (wp *WorkerPool) handleConvert(job *domain.Job) error {
 
 	// Per-variant conversion
 	if job.Codec != "" {
-		return wp.handleVariantConvert(job, media, convertedDir)
+		return wp.handleVariantConvert(ctx, job, media, convertedDir)
 	}
 
 	// Legacy: old-style conversion (no codec specified, try AV1 then H264)
-	return wp.handleLegacyConvert(job, media, convertedDir)
+	return wp.handleLegacyConvert(ctx, job, media, convertedDir)
 }
 
-func (wp *WorkerPool) handleVariantConvert(job *domain.Job, media *domain.Media, convertedDir string) error {
+func (wp *WorkerPool) handleVariantConvert(ctx context.Context, job *domain.Job, media *domain.Media, convertedDir string) error {
 	variant, err := wp.store.GetVariantByMediaAndCodec(media.ID, job.Codec)
 	if err != nil {
 		return fmt.Errorf("get variant: %w", err)
 	}
 
 	_ = wp.store.UpdateVariantStatus(variant.ID, domain.VariantStatusProcessing, "")
-	wp.publishEvent(media.ID, "status", string(domain.MediaStatusProcessing), "")
+	wp.publishVariantEvent(media.ID, job.Codec, domain.VariantStatusProcessing, "")
 
 	convertedDir = filepath.Join(wp.dataDir, "converted")
 	if err := os.MkdirAll(convertedDir, 0750); err != nil {
 		return fmt.Errorf("create converted directory: %w", err)
 	}
 
-	outputPath, err := wp.converter.ConvertCodec(media.OriginalPath, convertedDir, media.ID, job.Codec, job.Fps)
-	if err != nil {
-		return fmt.Errorf("convert %s: %w", job.Codec, err)
+	var outputPath string
+	if wp.passthrough(job, media) {
+		logger.Info.Printf("media %s is already web-optimized, using original for %s", media.ID, job.Codec)
+		outputPath = media.OriginalPath
+	} else {
+		src, _ := media.ParseProbe()
+		outputPath, err = wp.converter.ConvertCodec(ctx, media.OriginalPath, convertedDir, media.ID, job.Codec, job.Fps, src)
+		if err != nil {
+			return fmt.Errorf("convert %s: %w", job.Codec, err)
+		}
+		if err := putBlobs(ctx, wp.blobs, wp.dataDir, outputPath); err != nil {
+			return err
+		}
 	}
 
 	var width, height int
@@ -187,6 +461,16 @@ func (wp *WorkerPool) handleVariantConvert(job *domain.Job, media *domain.Media,
 		fileSize = fileInfo.Size()
 	}
 
+	// A cancellation arriving once the encode is over still wins: the
+	// output is removed instead of being published.
+	if err := ctx.Err(); err != nil {
+		if outputPath != media.OriginalPath {
+			_ = os.Remove(outputPath)
+			deleteBlobs(context.Background(), wp.blobs, wp.dataDir, outputPath)
+		}
+		return fmt.Errorf("convert %s: %w", job.Codec, err)
+	}
+
 	variant.Path = outputPath
 	variant.FileSize = fileSize
 	variant.Width = width
@@ -195,11 +479,23 @@ func (wp *WorkerPool) handleVariantConvert(job *domain.Job, media *domain.Media,
 	if updateErr := wp.store.UpdateVariantDone(variant); updateErr != nil {
 		return fmt.Errorf("update variant done: %w", updateErr)
 	}
+	wp.publishVariantEvent(media.ID, job.Codec, domain.VariantStatusDone, "")
+
+	// Store the checksum now so it is replaced whenever a variant is re-encoded
+	if sum, sumErr := fileSHA256(outputPath); sumErr != nil {
+		logger.Error.Printf("checksum failed for %s/%s: %v", media.ID, variant.Codec, sumErr)
+	} else if saveErr := wp.store.SaveChecksum(media.ID, string(variant.Codec), sum); saveErr != nil {
+		logger.Error.Printf("failed to store checksum for %s/%s: %v", media.ID, variant.Codec, saveErr)
+	}
 
 	if media.Type == domain.MediaTypeVideo && media.ThumbPath == "" {
 		thumbPath := filepath.Join(convertedDir, media.ID+"_thumb.jpg")
-		if thumbErr := wp.converter.Thumbnail(outputPath, thumbPath); thumbErr != nil {
-			logger.Error.Printf("thumbnail failed for %s: %v", media.ID, err)
+		thumbErr := wp.converter.Thumbnail(ctx, outputPath, thumbPath)
+		if thumbErr == nil {
+			thumbErr = putBlobs(ctx, wp.blobs, wp.dataDir, thumbPath)
+		}
+		if thumbErr != nil {
+			logger.Error.Printf("thumbnail failed for %s: %v", media.ID, thumbErr)
 		} else {
 			media.ThumbPath = thumbPath
 		}
@@ -225,15 +521,26 @@ func (wp *WorkerPool) handleVariantConvert(job *domain.Job, media *domain.Media,
 			return fmt.Errorf("update media done: %w", err)
 		}
 		wp.publishEvent(media.ID, "status", string(domain.MediaStatusDone), "")
-	} else {
-		wp.publishEvent(media.ID, "status", string(domain.MediaStatusProcessing), "")
 	}
 
 	return nil
 }
 
-func (wp *WorkerPool) handleLegacyConvert(job *domain.Job, media *domain.Media, convertedDir string) error {
-	convertedPath, codec, err := wp.converter.Convert(media.OriginalPath, convertedDir, media.ID)
+// passthrough reports whether the transcode policy lets the original file
+// serve as the output of job.
+func (wp *WorkerPool) passthrough(job *domain.Job, media *domain.Media) bool {
+	if wp.transcodePolicy != domain.TranscodePolicyPassthrough {
+		return false
+	}
+	probeResult, err := wp.converter.Probe(media.OriginalPath)
+	if err != nil {
+		return false
+	}
+	return wp.transcodePolicy.Passthrough(media.Type, job.Codec, job.Fps, probeResult)
+}
+
+func (wp *WorkerPool) handleLegacyConvert(ctx context.Context, job *domain.Job, media *domain.Media, convertedDir string) error {
+	convertedPath, codec, err := wp.converter.Convert(ctx, media.OriginalPath, convertedDir, media.ID)
 	if err != nil {
 		return fmt.Errorf("convert: %w", err)
 	}
@@ -245,18 +552,27 @@ func (wp *WorkerPool) handleLegacyConvert(job *domain.Job, media *domain.Media,
 	width, height := probeResult.Dimensions()
 
 	thumbPath := filepath.Join(convertedDir, media.ID+"_thumb.jpg")
-	if err := wp.converter.Thumbnail(convertedPath, thumbPath); err != nil {
+	if err := wp.converter.Thumbnail(ctx, convertedPath, thumbPath); err != nil {
 		return fmt.Errorf("thumbnail: %w", err)
 	}
 
+	if err := putBlobs(ctx, wp.blobs, wp.dataDir, convertedPath, thumbPath); err != nil {
+		return err
+	}
+
 	fileInfo, _ := os.Stat(convertedPath)
 	media.MarkAsDone(convertedPath, domain.Codec(codec), width, height, thumbPath, fileInfo.Size())
+	// The original is removed below; forget its path so it is no longer
+	// offered for download.
+	originalPath := media.OriginalPath
+	media.OriginalPath = ""
 
 	if err := wp.store.UpdateDone(media); err != nil {
 		return fmt.Errorf("update media done: %w", err)
 	}
 
-	_ = os.Remove(media.OriginalPath)
+	_ = os.Remove(originalPath)
+	deleteBlobs(ctx, wp.blobs, wp.dataDir, originalPath)
 
 	wp.publishEvent(media.ID, "status", string(domain.MediaStatusDone), "")
 	return nil
@@ -269,6 +585,7 @@ func (wp *WorkerPool) failVariant(job *domain.Job) {
 		return
 	}
 	_ = wp.store.UpdateVariantStatus(variant.ID, domain.VariantStatusFailed, job.ErrorMessage)
+	wp.publishVariantEvent(job.MediaID, job.Codec, domain.VariantStatusFailed, job.ErrorMessage)
 
 	// Re-fetch media to check if all variants are terminal
 	media, err := wp.store.Get(job.MediaID)
@@ -292,7 +609,7 @@ func (wp *WorkerPool) failVariant(job *domain.Job) {
 	}
 }
 
-func (wp *WorkerPool) handleThumbnail(job *domain.Job) error {
+func (wp *WorkerPool) handleThumbnail(ctx context.Context, job *domain.Job) error {
 	media, err := wp.store.Get(job.MediaID)
 	if err != nil {
 		return fmt.Errorf("get media: %w", err)
@@ -304,17 +621,53 @@ func (wp *WorkerPool) handleThumbnail(job *domain.Job) error {
 	}
 	thumbPath := filepath.Join(convertedDir, media.ID+"_thumb.jpg")
 
-	// Use original path as source for thumbnail
-	sourcePath := media.OriginalPath
-
-	if err := wp.converter.Thumbnail(sourcePath, thumbPath); err != nil {
+	if err := wp.converter.Thumbnail(ctx, previewSource(media), thumbPath); err != nil {
 		return fmt.Errorf("thumbnail: %w", err)
 	}
+	if err := putBlobs(ctx, wp.blobs, wp.dataDir, thumbPath); err != nil {
+		return err
+	}
 
 	media.ThumbPath = thumbPath
 	return wp.store.UpdateDone(media)
 }
 
+// previewSource returns the file thumbnails and storyboards are rendered
+// from: the original, or the converted file when a legacy conversion has
+// removed the original.
+func previewSource(media *domain.Media) string {
+	if _, err := os.Stat(media.OriginalPath); err != nil && media.ConvertedPath != "" {
+		return media.ConvertedPath
+	}
+	return media.OriginalPath
+}
+
+// storyboardSpriteURL is where the storyboard VTT points players for the
+// sprite, relative to the VTT's own URL.
+const storyboardSpriteURL = "sprites.jpg"
+
+func (wp *WorkerPool) handleStoryboard(ctx context.Context, job *domain.Job) error {
+	media, err := wp.store.Get(job.MediaID)
+	if err != nil {
+		return fmt.Errorf("get media: %w", err)
+	}
+
+	convertedDir := filepath.Join(wp.dataDir, "converted")
+	if err := os.MkdirAll(convertedDir, 0750); err != nil {
+		return fmt.Errorf("create converted directory: %w", err)
+	}
+	spritePath := filepath.Join(convertedDir, media.ID+"_sprites.jpg")
+	vttPath := filepath.Join(convertedDir, media.ID+"_sprites.vtt")
+
+	if err := wp.converter.Storyboard(ctx, previewSource(media), spritePath, vttPath, storyboardSpriteURL); err != nil {
+		return fmt.Errorf("storyboard: %w", err)
+	}
+	if err := putBlobs(ctx, wp.blobs, wp.dataDir, spritePath, vttPath); err != nil {
+		return err
+	}
+	return wp.store.UpdateStoryboard(media.ID, spritePath, vttPath)
+}
+
 func (wp *WorkerPool) handleProbe(job *domain.Job) error {
 	media, err := wp.store.Get(job.MediaID)
 	if err != nil {
@@ -337,6 +690,17 @@ func (wp *WorkerPool) handleProbe(job *domain.Job) error {
 	return wp.store.UpdateDone(media)
 }
 
+func (wp *WorkerPool) publishVariantEvent(mediaID string, codec domain.Codec, status domain.VariantStatus, message string) {
+	if wp.eventBus != nil {
+		wp.eventBus.Publish(mediaID, Event{
+			Type:    EventTypeVariant,
+			Status:  string(status),
+			Message: message,
+			Codec:   codec,
+		})
+	}
+}
+
 func (wp *WorkerPool) publishEvent(mediaID, eventType, status, message string) {
 	if wp.eventBus != nil {
 		wp.eventBus.Publish(mediaID, Event{
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnema/sharm/internal/domain"
+	"github.com/bnema/sharm/internal/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_ProcessJob_RequeuesOnShutdown(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	pool := NewWorkerPool(mockJobQueue, mockStore, mocks.NewMediaConverterMock(t), nil, t.TempDir(), 1, domain.TranscodePolicyAlways, 0, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockStore.EXPECT().Get("abc").Return(nil, context.Canceled).Once()
+	mockJobQueue.EXPECT().Requeue(int64(7)).Return(nil).Once()
+
+	pool.processJob(ctx, &domain.Job{ID: 7, MediaID: "abc", Type: domain.JobTypeProbe})
+}
+
+func TestWorkerPool_Wait(t *testing.T) {
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	pool := NewWorkerPool(mockJobQueue, mocks.NewMediaStoreMock(t), mocks.NewMediaConverterMock(t), nil, t.TempDir(), 2, domain.TranscodePolicyAlways, 0, nil)
+
+	mockJobQueue.EXPECT().ResetStalled().Return(nil).Once()
+	mockJobQueue.EXPECT().Claim().Return(nil, nil).Maybe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	require.NoError(t, pool.Wait(waitCtx))
+}
+
+func TestWorkerPool_Wait_Timeout(t *testing.T) {
+	pool := NewWorkerPool(nil, nil, nil, nil, t.TempDir(), 0, domain.TranscodePolicyAlways, 0, nil)
+	pool.wg.Add(1)
+	defer pool.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestMemoryGate(t *testing.T) {
+	gate := newMemoryGate(1000)
+
+	assert.True(t, gate.tryAcquire(600))
+	assert.False(t, gate.tryAcquire(600), "second job would exceed the budget")
+	assert.True(t, gate.tryAcquire(400))
+
+	gate.release(600)
+	gate.release(400)
+	assert.True(t, gate.tryAcquire(4000), "an oversized job runs when nothing else is")
+	assert.False(t, gate.tryAcquire(1))
+
+	assert.True(t, newMemoryGate(0).tryAcquire(1<<40), "no budget admits everything")
+}
+
+func TestWorkerPool_Admit_UsesMediaResolution(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	pool := NewWorkerPool(nil, mockStore, nil, nil, t.TempDir(), 2, domain.TranscodePolicyAlways, 4000, nil)
+
+	mockStore.EXPECT().Get("uhd").Return(&domain.Media{ID: "uhd", Width: 3840, Height: 2160}, nil).Twice()
+	job := &domain.Job{ID: 1, MediaID: "uhd", Type: domain.JobTypeConvert, Codec: domain.CodecAV1}
+
+	need, ok := pool.admit(job)
+	require.True(t, ok)
+	assert.Equal(t, domain.EstimateConvertMemoryMB(domain.CodecAV1, 3840, 2160), need)
+
+	_, ok = pool.admit(job)
+	assert.False(t, ok, "a second 4K AV1 encode does not fit")
+
+	_, ok = pool.admit(&domain.Job{ID: 2, MediaID: "uhd", Type: domain.JobTypeThumbnail})
+	assert.True(t, ok, "non-convert jobs bypass the budget")
+}
+
+func TestWorkerPool_Step_SkipsDeferredJobs(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	pool := NewWorkerPool(mockJobQueue, mockStore, nil, nil, t.TempDir(), 2, domain.TranscodePolicyAlways, 4000, nil)
+	require.True(t, pool.memory.tryAcquire(4000), "another conversion holds the whole budget")
+
+	mockStore.EXPECT().Get("uhd").Return(&domain.Media{ID: "uhd", Width: 3840, Height: 2160}, nil).Once()
+	mockJobQueue.EXPECT().Claim().Return(&domain.Job{ID: 1, MediaID: "uhd", Type: domain.JobTypeConvert, Codec: domain.CodecAV1}, nil).Once()
+	mockJobQueue.EXPECT().Claim().Return(&domain.Job{ID: 2, MediaID: "other", Type: "bogus"}, nil).Once()
+	mockJobQueue.EXPECT().Defer(int64(1)).Return(nil).Once()
+	// The job behind the deferred one runs instead of waiting for it
+	mockJobQueue.EXPECT().Fail(int64(2), mock.AnythingOfType("string")).Return(nil).Once()
+
+	assert.Zero(t, pool.step(context.Background(), 1))
+
+	mockStore.EXPECT().Get("uhd").Return(&domain.Media{ID: "uhd", Width: 3840, Height: 2160}, nil).Once()
+	mockJobQueue.EXPECT().Claim().Return(&domain.Job{ID: 1, MediaID: "uhd", Type: domain.JobTypeConvert, Codec: domain.CodecAV1}, nil).Once()
+	mockJobQueue.EXPECT().Claim().Return(nil, nil).Once()
+	mockJobQueue.EXPECT().Defer(int64(1)).Return(nil).Once()
+
+	assert.Equal(t, 2*time.Second, pool.step(context.Background(), 1), "only deferred jobs left")
+}
+
+func TestWorkerPool_PausedWorkersDoNotClaim(t *testing.T) {
+	mockJobQueue := mocks.NewJobQueueMock(t)
+	pool := NewWorkerPool(mockJobQueue, mocks.NewMediaStoreMock(t), mocks.NewMediaConverterMock(t), nil, t.TempDir(), 1, domain.TranscodePolicyAlways, 0, nil)
+	pool.Pause()
+	require.True(t, pool.Paused())
+
+	// Claim has no expectation: the mock fails the test if a paused worker calls it
+	mockJobQueue.EXPECT().ResetStalled().Return(nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.NoError(t, pool.Wait(context.Background()))
+
+	pool.Resume()
+	assert.False(t, pool.Paused())
+}
+
+func TestWorkerPool_HandleStoryboard(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	dataDir := t.TempDir()
+	pool := NewWorkerPool(mocks.NewJobQueueMock(t), mockStore, mockConverter, nil, dataDir, 1, domain.TranscodePolicyAlways, 0, nil)
+
+	// The legacy conversion removed the original, so the converted file is used
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, OriginalPath: "/gone/abc.mov", ConvertedPath: "/data/abc.mp4"}
+	sprite := filepath.Join(dataDir, "converted", "abc_sprites.jpg")
+	vtt := filepath.Join(dataDir, "converted", "abc_sprites.vtt")
+
+	mockStore.EXPECT().Get("abc").Return(media, nil).Once()
+	mockConverter.EXPECT().Storyboard(mock.Anything, "/data/abc.mp4", sprite, vtt, "sprites.jpg").Return(nil).Once()
+	mockStore.EXPECT().UpdateStoryboard("abc", sprite, vtt).Return(nil).Once()
+
+	require.NoError(t, pool.handleStoryboard(context.Background(), &domain.Job{MediaID: "abc", Type: domain.JobTypeStoryboard}))
+}
+
+func TestWorkerPool_HandleLegacyConvert_ForgetsOriginal(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	dataDir := t.TempDir()
+	pool := NewWorkerPool(mocks.NewJobQueueMock(t), mockStore, mockConverter, NewEventBus(0, 0), dataDir, 1, domain.TranscodePolicyAlways, 0, nil)
+
+	convertedDir := filepath.Join(dataDir, "converted")
+	require.NoError(t, os.MkdirAll(convertedDir, 0750))
+	original := filepath.Join(dataDir, "abc_clip.mov")
+	converted := filepath.Join(convertedDir, "abc.mp4")
+	require.NoError(t, os.WriteFile(original, []byte("original"), 0600))
+	require.NoError(t, os.WriteFile(converted, []byte("converted"), 0600))
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, OriginalPath: original}
+
+	mockConverter.EXPECT().Convert(mock.Anything, original, convertedDir, "abc").Return(converted, "h264", nil).Once()
+	mockConverter.EXPECT().Probe(converted).Return(&domain.ProbeResult{}, nil).Once()
+	mockConverter.EXPECT().Thumbnail(mock.Anything, converted, mock.Anything).Return(nil).Once()
+	mockStore.EXPECT().UpdateDone(mock.MatchedBy(func(m *domain.Media) bool {
+		return m.OriginalPath == "" && m.ConvertedPath == converted
+	})).Return(nil).Once()
+
+	require.NoError(t, pool.handleLegacyConvert(context.Background(), &domain.Job{MediaID: "abc"}, media, convertedDir))
+	assert.NoFileExists(t, original)
+}
+
+func TestWorkerPool_Drain(t *testing.T) {
+	pool := NewWorkerPool(nil, nil, nil, nil, t.TempDir(), 0, domain.TranscodePolicyAlways, 0, nil)
+
+	// A worker in the middle of a job
+	pool.busy.RLock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Drain(ctx), context.DeadlineExceeded)
+	assert.True(t, pool.Paused())
+
+	pool.busy.RUnlock()
+	require.NoError(t, pool.Drain(context.Background()))
+	assert.True(t, pool.Paused(), "the pool stays paused after draining")
+}
+
+func TestWorkerPool_FailVariant_PublishesVariantEvent(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	bus := NewEventBus(0, 0)
+	pool := NewWorkerPool(mocks.NewJobQueueMock(t), mockStore, mocks.NewMediaConverterMock(t), bus, t.TempDir(), 1, domain.TranscodePolicyAlways, 0, nil)
+	events, err := bus.Subscribe("abc")
+	require.NoError(t, err)
+
+	mockStore.EXPECT().GetVariantByMediaAndCodec("abc", domain.CodecH264).Return(&domain.Variant{ID: 3, Codec: domain.CodecH264}, nil).Once()
+	mockStore.EXPECT().UpdateVariantStatus(int64(3), domain.VariantStatusFailed, "").Return(nil).Once()
+	// The AV1 variant is still converting, so the media keeps its status
+	mockStore.EXPECT().Get("abc").Return(&domain.Media{ID: "abc", Status: domain.MediaStatusProcessing, Variants: []domain.Variant{
+		{Codec: domain.CodecAV1, Status: domain.VariantStatusProcessing},
+		{Codec: domain.CodecH264, Status: domain.VariantStatusFailed},
+	}}, nil).Once()
+
+	pool.failVariant(&domain.Job{MediaID: "abc", Codec: domain.CodecH264})
+
+	select {
+	case event := <-events:
+		assert.Equal(t, Event{Type: EventTypeVariant, Status: string(domain.VariantStatusFailed), Codec: domain.CodecH264}, event)
+	default:
+		t.Fatal("no variant event published")
+	}
+	assert.Empty(t, events, "the media status did not change")
+}
+
+func TestWorkerPool_HandleVariantConvert_PassesUploadProbe(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	pool := NewWorkerPool(mocks.NewJobQueueMock(t), mockStore, mockConverter, NewEventBus(0, 0), t.TempDir(), 1, domain.TranscodePolicyAlways, 0, nil)
+
+	media := &domain.Media{
+		ID:           "abc",
+		Type:         domain.MediaTypeAudio,
+		OriginalPath: "/data/uploads/abc.flac",
+		ProbeJSON:    `{"streams":[{"codec_type":"audio","codec_name":"flac"}]}`,
+	}
+	mockStore.EXPECT().GetVariantByMediaAndCodec("abc", domain.CodecOpus).Return(&domain.Variant{ID: 3, Codec: domain.CodecOpus}, nil).Once()
+	mockStore.EXPECT().UpdateVariantStatus(int64(3), domain.VariantStatusProcessing, "").Return(nil).Once()
+	mockConverter.EXPECT().ConvertCodec(mock.Anything, media.OriginalPath, mock.Anything, "abc", domain.CodecOpus, 0, mock.MatchedBy(func(src *domain.ProbeResult) bool {
+		return src != nil && src.AudioStream().CodecName == "flac"
+	})).Return("", errors.New("stop")).Once()
+
+	job := &domain.Job{ID: 7, MediaID: "abc", Type: domain.JobTypeConvert, Codec: domain.CodecOpus}
+	assert.Error(t, pool.handleVariantConvert(context.Background(), job, media, ""))
+}
+
+func TestWorkerPool_Cancel_StopsRunningConversion(t *testing.T) {
+	mockStore := mocks.NewMediaStoreMock(t)
+	mockConverter := mocks.NewMediaConverterMock(t)
+	bus := NewEventBus(0, 0)
+	// No Fail or Complete is expected: MediaService.Cancel records the outcome
+	pool := NewWorkerPool(mocks.NewJobQueueMock(t), mockStore, mockConverter, bus, t.TempDir(), 1, domain.TranscodePolicyAlways, 0, nil)
+	bus.Listen(pool.Handle)
+
+	media := &domain.Media{ID: "abc", Type: domain.MediaTypeVideo, Status: domain.MediaStatusProcessing, OriginalPath: "/data/uploads/abc.mp4"}
+	mockStore.EXPECT().Get("abc").Return(media, nil).Once()
+	mockStore.EXPECT().GetVariantByMediaAndCodec("abc", domain.CodecAV1).Return(&domain.Variant{ID: 3, Codec: domain.CodecAV1}, nil).Once()
+	mockStore.EXPECT().UpdateVariantStatus(int64(3), domain.VariantStatusProcessing, "").Return(nil).Once()
+
+	started := make(chan struct{})
+	mockConverter.EXPECT().ConvertCodec(mock.Anything, media.OriginalPath, mock.Anything, "abc", domain.CodecAV1, 0, mock.Anything).
+		RunAndReturn(func(ctx context.Context, _, _, _ string, _ domain.Codec, _ int, _ *domain.ProbeResult) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		}).
+		Once()
+
+	done := make(chan struct{})
+	go func() {
+		pool.processJob(context.Background(), &domain.Job{ID: 7, MediaID: "abc", Type: domain.JobTypeConvert, Codec: domain.CodecAV1})
+		close(done)
+	}()
+
+	<-started
+	bus.Publish("abc", Event{Type: EventTypeCancelled})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled conversion kept running")
+	}
+	assert.Empty(t, pool.running, "finished jobs are no longer tracked")
+}
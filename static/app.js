@@ -244,6 +244,13 @@ async function uploadChunk(uploadId, chunkIndex, chunk, maxRetries) {
       if (csrfToken) headers['X-CSRF-Token'] = csrfToken;
       const resp = await fetch('/upload/chunk', { method: 'POST', body: fd, headers });
       if (resp.ok) return true;
+      // Rate limited - wait as told; this does not use up a retry
+      if (resp.status === 429) {
+        const wait = parseInt(resp.headers.get('Retry-After'), 10) || 1;
+        await new Promise((r) => setTimeout(r, wait * 1000));
+        attempt--;
+        continue;
+      }
       // Don't retry on client errors (4xx) - these won't succeed on retry
       if (resp.status < 500) return false;
       // Retry on server errors (5xx)
@@ -306,6 +313,16 @@ async function chunkedUpload(file, form) {
     fd.append('retention', retentionSelect.value);
   }
 
+  const tagsInput = form.querySelector('[name="tags"]');
+  if (tagsInput instanceof HTMLInputElement) {
+    fd.append('tags', tagsInput.value);
+  }
+
+  const slugInput = form.querySelector('[name="slug"]');
+  if (slugInput instanceof HTMLInputElement) {
+    fd.append('slug', slugInput.value);
+  }
+
   form.querySelectorAll('[name="codecs"]:checked').forEach((cb) => {
     if (cb instanceof HTMLInputElement) {
       fd.append('codecs', cb.value);
@@ -400,7 +417,7 @@ function initUploadPage() {
   });
 
   // Codec checkbox change handler for FPS visibility
-  document.querySelectorAll('#codec-av1 input, #codec-h264 input').forEach((cb) => {
+  document.querySelectorAll('#codec-auto input, #codec-av1 input, #codec-h264 input').forEach((cb) => {
     cb.addEventListener('change', updateFpsVisibility);
   });
 }
@@ -414,6 +431,7 @@ function handleFileSelect(input) {
   const av1 = document.getElementById('codec-av1');
   const h264 = document.getElementById('codec-h264');
   const opus = document.getElementById('codec-opus');
+  const auto = document.getElementById('codec-auto');
   const fpsOpts = document.getElementById('fps-options');
   const probeResult = document.getElementById('probe-result');
 
@@ -434,12 +452,14 @@ function handleFileSelect(input) {
     if (av1) av1.style.display = 'flex';
     if (h264) h264.style.display = 'flex';
     if (opus) opus.style.display = 'none';
+    if (auto) auto.style.display = 'flex';
     updateFpsVisibility();
   } else if (isAudio) {
     if (opts) opts.style.display = 'block';
     if (av1) av1.style.display = 'none';
     if (h264) h264.style.display = 'none';
     if (opus) opus.style.display = 'flex';
+    if (auto) auto.style.display = 'flex';
     if (fpsOpts) fpsOpts.style.display = 'none';
   } else {
     if (opts) opts.style.display = 'none';
@@ -460,12 +480,16 @@ function updateFpsVisibility() {
   const fpsOpts = document.getElementById('fps-options');
   const av1Input = document.querySelector('#codec-av1 input');
   const h264Input = document.querySelector('#codec-h264 input');
+  const autoInput = document.querySelector('#codec-auto input');
 
   const av1Checked = av1Input instanceof HTMLInputElement && av1Input.checked;
   const h264Checked = h264Input instanceof HTMLInputElement && h264Input.checked;
+  // Auto only yields video codecs when the video options are shown
+  const isVideo = document.getElementById('codec-h264')?.style.display !== 'none';
+  const autoChecked = isVideo && autoInput instanceof HTMLInputElement && autoInput.checked;
 
   if (fpsOpts) {
-    fpsOpts.style.display = av1Checked || h264Checked ? 'block' : 'none';
+    fpsOpts.style.display = av1Checked || h264Checked || autoChecked ? 'block' : 'none';
   }
 }
 
@@ -0,0 +1,121 @@
+// @ts-check
+
+/**
+ * Scrubbing previews for the share page player.
+ *
+ * Videos with a storyboard carry a metadata track whose cues point at tiles
+ * of a sprite (`sprites.jpg#xywh=x,y,w,h`). Native controls do not show
+ * them, so hovering the bottom of the video, where the seek bar sits, shows
+ * the tile for the time under the pointer.
+ */
+
+/** Height in pixels of the strip along the bottom that counts as the seek bar. */
+const SEEK_BAR_HEIGHT = 48;
+
+/**
+ * @typedef {Object} Tile
+ * @property {string} url - Absolute sprite URL
+ * @property {number} x
+ * @property {number} y
+ * @property {number} w
+ * @property {number} h
+ */
+
+/**
+ * Parse a cue payload such as `sprites.jpg#xywh=0,0,160,90`.
+ * @param {string} text
+ * @param {string} base - URL the sprite link is relative to
+ * @returns {Tile | null}
+ */
+function parseTile(text, base) {
+  const [link, fragment] = text.trim().split('#xywh=');
+  if (!link || !fragment) return null;
+  const [x, y, w, h] = fragment.split(',').map(Number);
+  if ([x, y, w, h].some(Number.isNaN)) return null;
+  return { url: new URL(link, base).href, x, y, w, h };
+}
+
+/**
+ * Find the tile shown at time t.
+ * @param {TextTrack} track
+ * @param {number} t - Seconds
+ * @param {string} base
+ * @returns {Tile | null}
+ */
+function tileAt(track, t, base) {
+  const cues = track.cues;
+  if (!cues) return null;
+  for (let i = 0; i < cues.length; i++) {
+    const cue = /** @type {VTTCue} */ (cues[i]);
+    if (t >= cue.startTime && t < cue.endTime) {
+      return parseTile(cue.text, base);
+    }
+  }
+  return null;
+}
+
+/**
+ * Attach the hover preview to a video with a thumbnails track.
+ * @param {HTMLVideoElement} video
+ * @param {HTMLTrackElement} trackEl
+ */
+function attachScrubber(video, trackEl) {
+  const track = trackEl.track;
+  // Metadata tracks only load their cues once they are not disabled.
+  track.mode = 'hidden';
+
+  const wrapper = /** @type {HTMLElement} */ (video.parentElement);
+  wrapper.style.position = 'relative';
+
+  const preview = document.createElement('div');
+  preview.setAttribute('aria-hidden', 'true');
+  Object.assign(preview.style, {
+    position: 'absolute',
+    display: 'none',
+    pointerEvents: 'none',
+    border: '1px solid rgba(255,255,255,0.6)',
+    borderRadius: '4px',
+    boxShadow: '0 2px 8px rgba(0,0,0,0.5)',
+    backgroundRepeat: 'no-repeat',
+    zIndex: '10',
+  });
+  wrapper.appendChild(preview);
+
+  video.addEventListener('mousemove', (e) => {
+    const rect = video.getBoundingClientRect();
+    const fromBottom = rect.bottom - e.clientY;
+    if (fromBottom > SEEK_BAR_HEIGHT || !Number.isFinite(video.duration)) {
+      preview.style.display = 'none';
+      return;
+    }
+
+    const ratio = Math.min(Math.max((e.clientX - rect.left) / rect.width, 0), 1);
+    const tile = tileAt(track, ratio * video.duration, trackEl.src);
+    if (!tile) {
+      preview.style.display = 'none';
+      return;
+    }
+
+    const left = Math.min(Math.max(e.clientX - rect.left - tile.w / 2, 0), rect.width - tile.w);
+    Object.assign(preview.style, {
+      display: 'block',
+      width: tile.w + 'px',
+      height: tile.h + 'px',
+      left: video.offsetLeft + left + 'px',
+      top: video.offsetTop + rect.height - SEEK_BAR_HEIGHT - tile.h - 8 + 'px',
+      backgroundImage: 'url("' + tile.url + '")',
+      backgroundPosition: -tile.x + 'px ' + -tile.y + 'px',
+    });
+  });
+
+  video.addEventListener('mouseleave', () => {
+    preview.style.display = 'none';
+  });
+}
+
+document.querySelectorAll('video').forEach((video) => {
+  const trackEl = video.querySelector('track[kind="metadata"][label="thumbnails"]');
+  if (trackEl) {
+    attachScrubber(video, /** @type {HTMLTrackElement} */ (trackEl));
+  }
+});